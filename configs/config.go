@@ -1,7 +1,11 @@
 package configs
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -36,10 +40,118 @@ type RedisConfig struct {
 	URL string
 }
 
+type AuthConfig struct {
+	// AdminUsernames is the list of users allowed to call the admin API
+	AdminUsernames []string
+}
+
+const (
+	defaultFXCacheTTL   = time.Minute
+	defaultFXMaxRateAge = 10 * time.Minute
+)
+
+type FXConfig struct {
+	// Provider is either "static" which reads RatesFile or "http" which fetches from URL
+	Provider  string
+	RatesFile string
+	URL       string
+	// Spreads is keyed by currency pair such as "SGD/JPY" with the basis points as value
+	Spreads  map[string]string
+	CacheTTL time.Duration
+	// MaxRateAge refuses to quote the rate published before it, zero disables the check
+	MaxRateAge time.Duration
+	// Rounding is the rounding mode of the converted amount, one of "down" (default), "up", "half_up" or "half_even"
+	Rounding string
+}
+
+// defaultCurrencies are enabled when CURRENCIES isn't set
+var defaultCurrencies = []string{"SGD", "JPY"}
+
+type CurrencyConfig struct {
+	// Enabled is the list of ISO 4217 codes supported by the deployment
+	Enabled []string
+	// CustomUnitsFile is the optional json file of the non ISO 4217 units such as loyalty points
+	CustomUnitsFile string
+}
+
+const (
+	defaultOutboxStream       = "wallet-events"
+	defaultOutboxStreamMaxLen = 1_000_000
+	defaultOutboxRetention    = 7 * 24 * time.Hour
+)
+
+type OutboxConfig struct {
+	// Stream is the redis stream the wallet events are published to
+	Stream string
+	// StreamMaxLen trims the stream to about this number of entries, zero keeps every entry
+	StreamMaxLen int64
+	// Retention is how long the published events are kept in the outbox table
+	Retention time.Duration
+}
+
+const defaultRecoveryPendingTimeout = 15 * time.Minute
+
+type RecoveryConfig struct {
+	// PendingTimeout is how long a transaction stays pending before the recovery worker finalizes it
+	PendingTimeout time.Duration
+}
+
 type Config struct {
 	Mode           Mode
 	DatabaseConfig *DatabaseConfig
 	RedisConfig    *RedisConfig
+	AuthConfig     *AuthConfig
+	FXConfig       *FXConfig
+	CurrencyConfig *CurrencyConfig
+	OutboxConfig   *OutboxConfig
+	RecoveryConfig *RecoveryConfig
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// splitPairs parses "k1=v1,k2=v2" into a map.
+func splitPairs(value string) map[string]string {
+	pairs := map[string]string{}
+	for _, v := range splitList(value) {
+		if k, v, ok := strings.Cut(v, "="); ok {
+			pairs[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return pairs
+}
+
+// getDuration parses the env such as "10m", def is returned when it isn't set.
+func getDuration(key string, def time.Duration) (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// getInt64 parses the env as integer, def is returned when it isn't set.
+func getInt64(key string, def int64) (int64, error) {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return def, nil
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return i, nil
 }
 
 func New() (*Config, error) {
@@ -49,6 +161,34 @@ func New() (*Config, error) {
 			return nil, err
 		}
 	}
+	fxCacheTTL, err := getDuration("FX_CACHE_TTL", defaultFXCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	fxMaxRateAge, err := getDuration("FX_MAX_RATE_AGE", defaultFXMaxRateAge)
+	if err != nil {
+		return nil, err
+	}
+	outboxStreamMaxLen, err := getInt64("OUTBOX_STREAM_MAXLEN", defaultOutboxStreamMaxLen)
+	if err != nil {
+		return nil, err
+	}
+	outboxRetention, err := getDuration("OUTBOX_RETENTION", defaultOutboxRetention)
+	if err != nil {
+		return nil, err
+	}
+	recoveryPendingTimeout, err := getDuration("RECOVERY_PENDING_TIMEOUT", defaultRecoveryPendingTimeout)
+	if err != nil {
+		return nil, err
+	}
+	outboxStream := os.Getenv("OUTBOX_STREAM")
+	if outboxStream == "" {
+		outboxStream = defaultOutboxStream
+	}
+	currencies := splitList(os.Getenv("CURRENCIES"))
+	if len(currencies) == 0 {
+		currencies = defaultCurrencies
+	}
 	return &Config{
 		DatabaseConfig: &DatabaseConfig{
 			DSN: os.Getenv("DATABASE_DSN"),
@@ -56,6 +196,30 @@ func New() (*Config, error) {
 		RedisConfig: &RedisConfig{
 			URL: os.Getenv("REDIS_URL"),
 		},
+		AuthConfig: &AuthConfig{
+			AdminUsernames: splitList(os.Getenv("ADMIN_USERNAMES")),
+		},
+		FXConfig: &FXConfig{
+			Provider:   os.Getenv("FX_PROVIDER"),
+			RatesFile:  os.Getenv("FX_RATES_FILE"),
+			URL:        os.Getenv("FX_PROVIDER_URL"),
+			Spreads:    splitPairs(os.Getenv("FX_SPREADS")),
+			CacheTTL:   fxCacheTTL,
+			MaxRateAge: fxMaxRateAge,
+			Rounding:   os.Getenv("FX_ROUNDING"),
+		},
+		CurrencyConfig: &CurrencyConfig{
+			Enabled:         currencies,
+			CustomUnitsFile: os.Getenv("CUSTOM_CURRENCIES_FILE"),
+		},
+		OutboxConfig: &OutboxConfig{
+			Stream:       outboxStream,
+			StreamMaxLen: outboxStreamMaxLen,
+			Retention:    outboxRetention,
+		},
+		RecoveryConfig: &RecoveryConfig{
+			PendingTimeout: recoveryPendingTimeout,
+		},
 		Mode: getMode(),
 	}, nil
 }
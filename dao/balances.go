@@ -0,0 +1,266 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+// balanceDateLayout is the format of the DATE column of the daily balances
+const balanceDateLayout = "2006-01-02"
+
+//go:generate mockery --name BalancesRepository --output ./mocks --outpkg mocks --case=underscore
+type BalancesRepository interface {
+	AsOf(ctx context.Context, username, currency string, before time.Time) (*BalanceAsOfModel, error)
+	ListAsOf(ctx context.Context, before time.Time, filter BalanceFilter) ([]BalanceAsOfModel, bool, error)
+	LatestClosedPeriod(ctx context.Context) (*AccountingPeriodsModel, error)
+	ClosePeriod(ctx context.Context, day time.Time) (*AccountingPeriodsModel, error)
+}
+
+type WalletDailyBalancesModel struct {
+	ID          int64     `db:"id"`
+	Username    string    `db:"username"`
+	Currency    string    `db:"currency"`
+	BalanceDate time.Time `db:"balance_date"`
+	Balance     int64     `db:"balance"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// AccountingPeriodsModel is a closed UTC day, its daily balances are final as no ledger can be created within it.
+type AccountingPeriodsModel struct {
+	ID         int64     `db:"id"`
+	PeriodDate time.Time `db:"period_date"`
+	Wallets    int       `db:"wallets"`
+	ClosedAt   time.Time `db:"closed_at"`
+}
+
+// BalanceAsOfModel is the balance of a wallet derived from its ledgers created before a timestamp.
+type BalanceAsOfModel struct {
+	Username string `db:"username"`
+	Currency string `db:"currency"`
+	Balance  int64  `db:"balance"`
+	// CheckpointDate is the daily balance the ledgers were summed from, nil when summed from the first ledger
+	CheckpointDate *time.Time `db:"checkpoint_date"`
+}
+
+// BalanceFilter narrows the wallets of a currency, every wallet is listed when Usernames is empty.
+type BalanceFilter struct {
+	Currency      string
+	Usernames     []string
+	Limit         int
+	StartingAfter string
+}
+
+type balances struct {
+	db *sqlx.DB
+}
+
+func NewBalances(dao *DAO) *balances {
+	return &balances{
+		db: dao.db,
+	}
+}
+
+// selectBalancesAsOf sums the ledgers of every wallet created before the timestamp, starting from the latest
+// daily balance which closed no later than it, so only the ledgers of the last partial day are summed.
+func selectBalancesAsOf(before time.Time) squirrel.SelectBuilder {
+	return psql.Select("w.username", "w.currency").
+		Column("COALESCE(cp.balance, 0) + COALESCE((SELECT SUM(CASE WHEN le.direction = ? THEN le.amount ELSE -le.amount END) FROM ledgers le "+
+			"WHERE le.username = w.username AND le.currency = w.currency "+
+			"AND le.created_at >= COALESCE((cp.balance_date + 1)::timestamp, '-infinity') AND le.created_at < ?), 0) AS balance", DirectionCredit, before).
+		Column("cp.balance_date AS checkpoint_date").
+		From("wallets w").
+		JoinClause("LEFT JOIN LATERAL (SELECT b.balance_date, b.balance FROM wallet_daily_balances b "+
+			"WHERE b.username = w.username AND b.currency = w.currency AND (b.balance_date + 1)::timestamp <= ? "+
+			"ORDER BY b.balance_date DESC LIMIT 1) cp ON true", before)
+}
+
+// AsOf returns the balance of the wallet right before the timestamp.
+func (p *balances) AsOf(ctx context.Context, username, currency string, before time.Time) (*BalanceAsOfModel, error) {
+	query, args, err := selectBalancesAsOf(before).
+		Where(squirrel.Eq{
+			"w.username": username,
+			"w.currency": currency,
+		}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build balance as of query with err: %s", err)
+		return nil, fmt.Errorf("build balance as of query: %w", err)
+	}
+	balance := new(BalanceAsOfModel)
+	err = p.db.GetContext(ctx, balance, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierr.NotFound
+		}
+		log.Error(ctx, "failed to get balance of %s %s as of %s with err: %s", username, currency, before, err)
+		return nil, fmt.Errorf("get balance as of: %w", err)
+	}
+	return balance, nil
+}
+
+// ListAsOf returns the balances of the wallets right before the timestamp in the order of username.
+func (p *balances) ListAsOf(ctx context.Context, before time.Time, filter BalanceFilter) ([]BalanceAsOfModel, bool, error) {
+	sq := selectBalancesAsOf(before).
+		Where(squirrel.Eq{"w.currency": filter.Currency}).
+		OrderBy("w.username").
+		Limit(uint64(filter.Limit + 1))
+	if len(filter.Usernames) > 0 {
+		sq = sq.Where(squirrel.Eq{"w.username": filter.Usernames})
+	}
+	if filter.StartingAfter != "" {
+		sq = sq.Where(squirrel.Gt{"w.username": filter.StartingAfter})
+	}
+	query, args, err := sq.ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list balances as of query with err: %s", err)
+		return nil, false, fmt.Errorf("build list balances as of query: %w", err)
+	}
+	balances := []BalanceAsOfModel{}
+	err = p.db.SelectContext(ctx, &balances, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list balances as of %s with err: %s", before, err)
+		return nil, false, fmt.Errorf("list balances as of: %w", err)
+	}
+	hasMore := len(balances) > filter.Limit
+	if hasMore {
+		balances = balances[:filter.Limit]
+	}
+	return balances, hasMore, nil
+}
+
+// checkPeriodOpen refuses a ledger created within a closed period, such as a back-dated posting or one created
+// before midnight which is committed after the day is closed.
+func checkPeriodOpen(ctx context.Context, exec sqlx.ExtContext, createdAt time.Time) error {
+	query, args, err := psql.Select("1").
+		Prefix("SELECT EXISTS (").
+		From("accounting_periods").
+		Where("period_date >= CAST(? AS DATE)", createdAt.UTC().Format(balanceDateLayout)).
+		Suffix(")").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build period closed query: %v", err)
+		return fmt.Errorf("build period closed query: %w", err)
+	}
+	var closed bool
+	err = exec.QueryRowxContext(ctx, query, args...).Scan(&closed)
+	if err != nil {
+		log.Error(ctx, "failed to check period closed: %v", err)
+		return fmt.Errorf("check period closed: %w", err)
+	}
+	if closed {
+		log.Error(ctx, "refused ledger created at %s within a closed period", createdAt)
+		return apierr.PeriodClosed
+	}
+	return nil
+}
+
+// LatestClosedPeriod returns the latest closed day, nil before the first close.
+func (p *balances) LatestClosedPeriod(ctx context.Context) (*AccountingPeriodsModel, error) {
+	query, args, err := psql.Select("id", "period_date", "wallets", "closed_at").
+		From("accounting_periods").
+		OrderBy("period_date DESC").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build latest closed period query with err: %s", err)
+		return nil, fmt.Errorf("build latest closed period query: %w", err)
+	}
+	period := new(AccountingPeriodsModel)
+	err = p.db.GetContext(ctx, period, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error(ctx, "failed to get latest closed period with err: %s", err)
+		return nil, fmt.Errorf("get latest closed period: %w", err)
+	}
+	return period, nil
+}
+
+// ClosePeriod snapshots the closing balance of the UTC day of every wallet and marks the day closed in the same
+// database transaction. The ledgers table is locked in share mode meanwhile, so the postings in flight are committed
+// before the snapshot reads the ledgers and the new ones wait for the close, then are refused if they fall within
+// the day. The day already closed, e.g. by another instance, is refused with apierr.PeriodClosed.
+func (p *balances) ClosePeriod(ctx context.Context, day time.Time) (*AccountingPeriodsModel, error) {
+	dayStart := day.UTC().Truncate(24 * time.Hour)
+	period := new(AccountingPeriodsModel)
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		_, err := exec.ExecContext(ctx, "LOCK TABLE ledgers IN SHARE MODE")
+		if err != nil {
+			log.Error(ctx, "failed to lock ledgers with err: %s", err)
+			return fmt.Errorf("lock ledgers: %w", err)
+		}
+		written, err := snapshotDay(ctx, exec, dayStart)
+		if err != nil {
+			return err
+		}
+		query, args, err := psql.Insert("accounting_periods").
+			Columns("period_date", "wallets").
+			Values(squirrel.Expr("CAST(? AS DATE)", dayStart.Format(balanceDateLayout)), written).
+			Suffix("ON CONFLICT (period_date) DO NOTHING RETURNING id, period_date, wallets, closed_at").
+			ToSql()
+		if err != nil {
+			log.Error(ctx, "failed to build close period query with err: %s", err)
+			return fmt.Errorf("build close period query: %w", err)
+		}
+		err = sqlx.GetContext(ctx, exec, period, query, args...)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return apierr.PeriodClosed
+			}
+			log.Error(ctx, "failed to close period %s with err: %s", dayStart.Format(balanceDateLayout), err)
+			return fmt.Errorf("close period: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+// snapshotDay writes the closing balance of the UTC day of every wallet created before the end of it and returns
+// the number of balances written. The balance is chained from the snapshot of the day before when there is one,
+// the wallets which already have the snapshot of the day are left as is.
+func snapshotDay(ctx context.Context, exec sqlx.ExtContext, dayStart time.Time) (int, error) {
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	selectBuilder := psql.Select("w.username", "w.currency").
+		Column("CAST(? AS DATE)", dayStart.Format(balanceDateLayout)).
+		Column("COALESCE(prev.balance, 0) + COALESCE(SUM(CASE WHEN le.direction = ? THEN le.amount ELSE -le.amount END), 0)", DirectionCredit).
+		From("wallets w").
+		LeftJoin("wallet_daily_balances prev ON prev.username = w.username AND prev.currency = w.currency AND prev.balance_date = ?",
+			dayStart.AddDate(0, 0, -1).Format(balanceDateLayout)).
+		// Without the snapshot of the day before, the balance is summed from the first ledger of the wallet
+		LeftJoin("ledgers le ON le.username = w.username AND le.currency = w.currency AND le.created_at < ? AND (prev.id IS NULL OR le.created_at >= ?)",
+			dayEnd, dayStart).
+		Where(squirrel.Lt{"w.created_at": dayEnd}).
+		GroupBy("w.username", "w.currency", "prev.balance")
+	query, args, err := psql.Insert("wallet_daily_balances").
+		Columns("username", "currency", "balance_date", "balance").
+		Select(selectBuilder).
+		Suffix("ON CONFLICT (username, currency, balance_date) DO NOTHING").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build snapshot day query with err: %s", err)
+		return 0, fmt.Errorf("build snapshot day query: %w", err)
+	}
+	r, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to snapshot balances of %s with err: %s", dayStart.Format(balanceDateLayout), err)
+		return 0, fmt.Errorf("snapshot day: %w", err)
+	}
+	written, err := r.RowsAffected()
+	if err != nil {
+		log.Error(ctx, "unabled to get row affected due to: %s", err)
+		return 0, err
+	}
+	return int(written), nil
+}
@@ -0,0 +1,210 @@
+package dao
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectBalancesAsOfQuery = "SELECT w.username, w.currency, COALESCE(cp.balance, 0) + COALESCE((SELECT SUM(CASE WHEN le.direction = $1 THEN le.amount ELSE -le.amount END) FROM ledgers le " +
+		"WHERE le.username = w.username AND le.currency = w.currency AND le.created_at >= COALESCE((cp.balance_date + 1)::timestamp, '-infinity') AND le.created_at < $2), 0) AS balance, " +
+		"cp.balance_date AS checkpoint_date FROM wallets w LEFT JOIN LATERAL (SELECT b.balance_date, b.balance FROM wallet_daily_balances b " +
+		"WHERE b.username = w.username AND b.currency = w.currency AND (b.balance_date + 1)::timestamp <= $3 ORDER BY b.balance_date DESC LIMIT 1) cp ON true"
+	snapshotDayQuery = "INSERT INTO wallet_daily_balances (username,currency,balance_date,balance) " +
+		"SELECT w.username, w.currency, CAST($1 AS DATE), COALESCE(prev.balance, 0) + COALESCE(SUM(CASE WHEN le.direction = $2 THEN le.amount ELSE -le.amount END), 0) FROM wallets w " +
+		"LEFT JOIN wallet_daily_balances prev ON prev.username = w.username AND prev.currency = w.currency AND prev.balance_date = $3 " +
+		"LEFT JOIN ledgers le ON le.username = w.username AND le.currency = w.currency AND le.created_at < $4 AND (prev.id IS NULL OR le.created_at >= $5) " +
+		"WHERE w.created_at < $6 GROUP BY w.username, w.currency, prev.balance ON CONFLICT (username, currency, balance_date) DO NOTHING"
+	lockLedgersQuery = "LOCK TABLE ledgers IN SHARE MODE"
+	closePeriodQuery = "INSERT INTO accounting_periods (period_date,wallets) VALUES (CAST($1 AS DATE),$2) ON CONFLICT (period_date) DO NOTHING RETURNING id, period_date, wallets, closed_at"
+	periodOpenQuery  = "SELECT EXISTS ( SELECT 1 FROM accounting_periods WHERE period_date >= CAST($1 AS DATE) )"
+)
+
+var (
+	balanceAsOfColumns      = []string{"username", "currency", "balance", "checkpoint_date"}
+	accountingPeriodColumns = []string{"id", "period_date", "wallets", "closed_at"}
+)
+
+func Test_balances_AsOf(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &balances{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	before := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	checkpoint := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	query := selectBalancesAsOfQuery + " WHERE w.currency = $4 AND w.username = $5"
+
+	t.Run("ok from checkpoint", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(DirectionCredit, before, before, "SGD", "user1").
+			WillReturnRows(sqlmock.NewRows(balanceAsOfColumns).AddRow("user1", "SGD", 1500, checkpoint))
+		balance, err := p.AsOf(t.Context(), "user1", "SGD", before)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1500), balance.Balance)
+		assert.Equal(t, checkpoint, *balance.CheckpointDate)
+	})
+
+	t.Run("ok without checkpoint", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(DirectionCredit, before, before, "SGD", "user1").
+			WillReturnRows(sqlmock.NewRows(balanceAsOfColumns).AddRow("user1", "SGD", 200, nil))
+		balance, err := p.AsOf(t.Context(), "user1", "SGD", before)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(200), balance.Balance)
+		assert.Nil(t, balance.CheckpointDate)
+	})
+
+	t.Run("wallet not found", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(DirectionCredit, before, before, "SGD", "user1").
+			WillReturnRows(sqlmock.NewRows(balanceAsOfColumns))
+		_, err := p.AsOf(t.Context(), "user1", "SGD", before)
+		assert.ErrorIs(t, err, apierr.NotFound)
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_balances_ListAsOf(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &balances{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	before := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	t.Run("ok has more", func(t *testing.T) {
+		mock.ExpectQuery(selectBalancesAsOfQuery+" WHERE w.currency = $4 ORDER BY w.username LIMIT 3").
+			WithArgs(DirectionCredit, before, before, "SGD").
+			WillReturnRows(sqlmock.NewRows(balanceAsOfColumns).
+				AddRow("user1", "SGD", 100, nil).
+				AddRow("user2", "SGD", 200, nil).
+				AddRow("user3", "SGD", 300, nil))
+		balances, hasMore, err := p.ListAsOf(t.Context(), before, BalanceFilter{Currency: "SGD", Limit: 2})
+		assert.NoError(t, err)
+		assert.True(t, hasMore)
+		assert.Len(t, balances, 2)
+	})
+
+	t.Run("ok filter usernames after cursor", func(t *testing.T) {
+		mock.ExpectQuery(selectBalancesAsOfQuery+" WHERE w.currency = $4 AND w.username IN ($5,$6) AND w.username > $7 ORDER BY w.username LIMIT 3").
+			WithArgs(DirectionCredit, before, before, "SGD", "user1", "user2", "user1").
+			WillReturnRows(sqlmock.NewRows(balanceAsOfColumns).AddRow("user2", "SGD", 200, nil))
+		balances, hasMore, err := p.ListAsOf(t.Context(), before, BalanceFilter{
+			Currency: "SGD", Usernames: []string{"user1", "user2"}, Limit: 2, StartingAfter: "user1",
+		})
+		assert.NoError(t, err)
+		assert.False(t, hasMore)
+		assert.Equal(t, "user2", balances[0].Username)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		mock.ExpectQuery(selectBalancesAsOfQuery+" WHERE w.currency = $4 ORDER BY w.username LIMIT 3").
+			WithArgs(DirectionCredit, before, before, "SGD").
+			WillReturnError(errors.New("db down"))
+		_, _, err := p.ListAsOf(t.Context(), before, BalanceFilter{Currency: "SGD", Limit: 2})
+		assert.Error(t, err)
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_balances_LatestClosedPeriod(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &balances{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	query := "SELECT id, period_date, wallets, closed_at FROM accounting_periods ORDER BY period_date DESC LIMIT 1"
+
+	t.Run("ok", func(t *testing.T) {
+		day := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows(accountingPeriodColumns).AddRow(1, day, 3, day.Add(25*time.Hour)))
+		period, err := p.LatestClosedPeriod(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, day, period.PeriodDate)
+	})
+
+	t.Run("ok before first close", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows(accountingPeriodColumns))
+		period, err := p.LatestClosedPeriod(t.Context())
+		assert.NoError(t, err)
+		assert.Nil(t, period)
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_balances_ClosePeriod(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &balances{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	dayStart := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	expectSnapshot := func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(lockLedgersQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(snapshotDayQuery).
+			WithArgs("2026-03-09", DirectionCredit, "2026-03-08", dayEnd, dayStart, dayEnd).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+	}
+
+	t.Run("ok snapshot and close the day", func(t *testing.T) {
+		expectSnapshot()
+		mock.ExpectQuery(closePeriodQuery).
+			WithArgs("2026-03-09", 3).
+			WillReturnRows(sqlmock.NewRows(accountingPeriodColumns).AddRow(1, dayStart, 3, dayEnd.Add(10*time.Minute)))
+		mock.ExpectCommit()
+		period, err := p.ClosePeriod(t.Context(), dayStart.Add(13*time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 3, period.Wallets)
+		assert.Equal(t, dayStart, period.PeriodDate)
+	})
+
+	t.Run("day already closed", func(t *testing.T) {
+		expectSnapshot()
+		mock.ExpectQuery(closePeriodQuery).
+			WithArgs("2026-03-09", 3).
+			WillReturnRows(sqlmock.NewRows(accountingPeriodColumns))
+		mock.ExpectRollback()
+		_, err := p.ClosePeriod(t.Context(), dayStart)
+		assert.ErrorIs(t, err, apierr.PeriodClosed)
+	})
+
+	t.Run("snapshot failed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(lockLedgersQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(snapshotDayQuery).
+			WithArgs("2026-03-09", DirectionCredit, "2026-03-08", dayEnd, dayStart, dayEnd).
+			WillReturnError(errors.New("db down"))
+		mock.ExpectRollback()
+		_, err := p.ClosePeriod(t.Context(), dayStart)
+		assert.Error(t, err)
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectPeriodOpen expects the check of the closed period before a ledger is inserted.
+func expectPeriodOpen(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(periodOpenQuery).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+}
@@ -0,0 +1,163 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils"
+	"github.com/lengzuo/fundflow/utils/metadata"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+// BulkTransferItem is a receiver of the bulk transfer, every receiver must be unique and differ from the sender.
+type BulkTransferItem struct {
+	Receiver string
+	Amount   money.Money
+}
+
+// BulkTransferError is returned when any item of the bulk transfer is refused, none of the items is committed.
+type BulkTransferError struct {
+	// Items is keyed by the index of the refused item
+	Items map[int]error
+}
+
+func (e *BulkTransferError) Error() string {
+	return fmt.Sprintf("%d of the bulk transfer items are refused", len(e.Items))
+}
+
+// BulkTransfer debits the total of the items from the sender and credits every receiver in a single transaction,
+// all the legs share the uid of the transaction which is returned.
+func (p *wallets) BulkTransfer(ctx context.Context, sender, reference string, items []BulkTransferItem, meta metadata.Metadata) (string, error) {
+	if len(items) == 0 {
+		return "", errors.New("bulk transfer without item")
+	}
+	total := money.New(0, items[0].Amount.Currency())
+	for _, item := range items {
+		var err error
+		total, err = total.Add(item.Amount)
+		if err != nil {
+			return "", err
+		}
+	}
+	currency := total.Currency().Code
+
+	var uid string
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		usernames := make([]string, 0, len(items)+1)
+		usernames = append(usernames, sender)
+		for _, item := range items {
+			usernames = append(usernames, item.Receiver)
+		}
+		// Lock the wallets in the same sequence as Transfer to avoid deadlock with the concurrent transfers
+		slices.Sort(usernames)
+
+		locked := make(map[string]*WalletsModel, len(usernames))
+		for _, username := range slices.Compact(usernames) {
+			wallet, err := get(ctx, exec, username, currency, true)
+			if err != nil {
+				if errors.Is(err, apierr.NotFound) && username != sender {
+					continue
+				}
+				return err
+			}
+			locked[username] = wallet
+		}
+
+		refused := map[int]error{}
+		for i, item := range items {
+			err := checkBulkTransferReceiver(ctx, exec, locked[item.Receiver], item.Amount)
+			if err != nil {
+				if !isRefusedItem(err) {
+					return err
+				}
+				refused[i] = err
+			}
+		}
+		if len(refused) > 0 {
+			return &BulkTransferError{Items: refused}
+		}
+		err := checkDebitLimits(ctx, exec, sender, TypeBulkTransfer, total)
+		if err != nil {
+			return err
+		}
+
+		transaction := TransactionsModel{
+			UID:         utils.UUID(),
+			Type:        TypeBulkTransfer,
+			InitiatedBy: sender,
+			Status:      StatusCompleted,
+			Amount:      total.Amount(),
+			Currency:    currency,
+			Reference:   reference,
+			Metadata:    meta,
+		}
+		err = insertTransaction(ctx, exec, transaction)
+		if err != nil {
+			log.Error(ctx, "failed in insert into transactions with err: %s", err)
+			return err
+		}
+
+		err = updateBalanceAndInsertLedger(ctx, exec, transaction.UID, sender, currency, total.Amount(), DirectionDebit)
+		if err != nil {
+			log.Error(ctx, "failed in update sender and add ledger with err: %s", err)
+			return err
+		}
+		for _, item := range items {
+			err = updateBalanceAndInsertLedger(ctx, exec, transaction.UID, item.Receiver, currency, item.Amount.Amount(), DirectionCredit)
+			if err != nil {
+				log.Error(ctx, "failed in update receiver %s and add ledger with err: %s", item.Receiver, err)
+				return err
+			}
+		}
+
+		// The sender is debited once with the total, so its event has no single counterparty
+		events := make([]WalletEvent, 0, len(items)+1)
+		events = append(events, WalletEvent{TxUID: transaction.UID, TxType: TypeBulkTransfer, Username: sender, Currency: currency, Amount: total.Amount(), Direction: DirectionDebit, Reference: reference})
+		for _, item := range items {
+			events = append(events, WalletEvent{TxUID: transaction.UID, TxType: TypeBulkTransfer, Username: item.Receiver, Currency: currency, Amount: item.Amount.Amount(), Direction: DirectionCredit, Reference: reference, Counterparty: sender})
+		}
+		for _, event := range events {
+			err = insertWalletEvent(ctx, exec, event)
+			if err != nil {
+				return err
+			}
+		}
+		uid = transaction.UID
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	log.Info(ctx, "bulk transfer %s of %s paid %d receivers", uid, total, len(items))
+	return uid, nil
+}
+
+// checkBulkTransferReceiver checks the locked wallet of the receiver is able to be credited with the amount,
+// wallet is nil when the receiver doesn't have the wallet of the currency.
+func checkBulkTransferReceiver(ctx context.Context, exec sqlx.ExtContext, wallet *WalletsModel, amount money.Money) error {
+	if wallet == nil {
+		return apierr.NotFound
+	}
+	switch wallet.Status {
+	case WalletStatusFrozen:
+		return apierr.WalletFrozen
+	case WalletStatusClosed:
+		return apierr.WalletClosed
+	}
+	return checkCreditLimits(ctx, exec, wallet, amount, false)
+}
+
+// isRefusedItem tells whether the error is caused by the item itself rather than a failure of the database.
+func isRefusedItem(err error) bool {
+	for _, target := range []error{apierr.NotFound, apierr.WalletFrozen, apierr.WalletClosed, apierr.LimitExceeded, money.ErrOverflow} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package dao
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_wallets_BulkTransfer(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &wallets{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	items := []BulkTransferItem{
+		{Receiver: "user3", Amount: money.New(100, currency.SGD)},
+		{Receiver: "user2", Amount: money.New(200, currency.SGD)},
+	}
+	expectLocked := func(statuses map[string]string) {
+		for i, username := range []string{"company", "user2", "user3"} {
+			rows := sqlmock.NewRows(walletColumns)
+			if status, ok := statuses[username]; ok {
+				rows.AddRow(i+1, username, 1000, status)
+			}
+			mock.ExpectQuery(lockWalletQuery).
+				WithArgs(username, "SGD").
+				WillReturnRows(rows)
+		}
+	}
+
+	t.Run("ok bulk transfer", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLocked(map[string]string{"company": "active", "user2": "active", "user3": "active"})
+		expectNoLimits(mock, "user3", "SGD")
+		expectNoLimits(mock, "user2", "SGD")
+		expectNoLimits(mock, "company", "SGD")
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), TypeBulkTransfer, "company", "SGD", 300, StatusCompleted, "payroll").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-300, "SGD", "company", WalletStatusActive, 300).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "company", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "company", "SGD", 300, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		for _, item := range items {
+			mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+				WithArgs(item.Amount.Amount(), "SGD", item.Receiver, WalletStatusActive).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectLastLedgerHash(mock, item.Receiver, "SGD")
+			expectPeriodOpen(mock)
+			mock.ExpectExec(insertLedgerQuery).
+				WithArgs(sqlmock.AnyArg(), item.Receiver, "SGD", item.Amount.Amount(), DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		expectWalletEvent(mock, EventWalletDebited, "company", "SGD")
+		for _, item := range items {
+			expectWalletEvent(mock, EventWalletCredited, item.Receiver, "SGD")
+		}
+		mock.ExpectCommit()
+
+		uid, err := p.BulkTransfer(t.Context(), "company", "payroll", items, nil)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, uid)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("refused items roll back every leg", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLocked(map[string]string{"company": "active", "user2": "frozen"})
+		mock.ExpectRollback()
+
+		uid, err := p.BulkTransfer(t.Context(), "company", "payroll", items, nil)
+		assert.Empty(t, uid)
+		var bulkErr *BulkTransferError
+		assert.ErrorAs(t, err, &bulkErr)
+		assert.Len(t, bulkErr.Items, 2)
+		assert.ErrorIs(t, bulkErr.Items[0], apierr.NotFound)
+		assert.ErrorIs(t, bulkErr.Items[1], apierr.WalletFrozen)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("receiver over max balance", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLocked(map[string]string{"company": "active", "user2": "active", "user3": "active"})
+		expectNoLimits(mock, "user3", "SGD")
+		expectLimits(mock, "user2", "SGD", LimitsModel{Username: "user2", MaxBalance: 1100})
+		mock.ExpectRollback()
+
+		_, err := p.BulkTransfer(t.Context(), "company", "payroll", items, nil)
+		var bulkErr *BulkTransferError
+		assert.ErrorAs(t, err, &bulkErr)
+		assert.Len(t, bulkErr.Items, 1)
+		assert.ErrorIs(t, bulkErr.Items[1], apierr.LimitExceeded)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("sender without wallet", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("company", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns))
+		mock.ExpectRollback()
+
+		_, err := p.BulkTransfer(t.Context(), "company", "payroll", items, nil)
+		assert.ErrorIs(t, err, apierr.NotFound)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("mixed currencies", func(t *testing.T) {
+		_, err := p.BulkTransfer(t.Context(), "company", "payroll", []BulkTransferItem{
+			{Receiver: "user2", Amount: money.New(100, currency.SGD)},
+			{Receiver: "user3", Amount: money.New(100, currency.JPY)},
+		}, nil)
+		assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+	})
+}
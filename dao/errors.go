@@ -2,4 +2,8 @@ package dao
 
 import "errors"
 
-var ErrAlreadyExists = errors.New("already exists")
+var (
+	ErrAlreadyExists           = errors.New("already exists")
+	ErrInvalidStatusTransition = errors.New("invalid wallet status transition")
+	ErrNonZeroBalance          = errors.New("wallet balance is not zero")
+)
@@ -0,0 +1,214 @@
+package dao
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+//go:generate mockery --name ExchangesRepository --output ./mocks --outpkg mocks --case=underscore
+type ExchangesRepository interface {
+	InsertQuote(ctx context.Context, quote *FXQuotesModel) error
+	Exchange(ctx context.Context, quoteUID, username, receiver, reference string) (*TransactionsModel, *FXQuotesModel, error)
+}
+
+var (
+	ErrQuoteUsed    = errors.New("fx quote is already used")
+	ErrQuoteExpired = errors.New("fx quote is expired")
+)
+
+type FXQuotesModel struct {
+	ID           int        `db:"id"`
+	UID          string     `db:"uid"`
+	Username     string     `db:"username"`
+	FromCurrency string     `db:"from_currency"`
+	ToCurrency   string     `db:"to_currency"`
+	Rate         string     `db:"rate"`
+	FromAmount   int64      `db:"from_amount"`
+	ToAmount     int64      `db:"to_amount"`
+	TxUID        string     `db:"tx_uid"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	UsedAt       *time.Time `db:"used_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+type exchanges struct {
+	db *sqlx.DB
+}
+
+func NewExchanges(dao *DAO) *exchanges {
+	return &exchanges{
+		db: dao.db,
+	}
+}
+
+func (p *exchanges) InsertQuote(ctx context.Context, quote *FXQuotesModel) error {
+	query, args, err := psql.Insert("fx_quotes").
+		Columns("uid", "username", "from_currency", "to_currency", "rate", "from_amount", "to_amount", "expires_at").
+		Values(quote.UID, quote.Username, quote.FromCurrency, quote.ToCurrency, quote.Rate, quote.FromAmount, quote.ToAmount, quote.ExpiresAt).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build fx quote insert query: %v", err)
+		return fmt.Errorf("build fx quote insert query: %w", err)
+	}
+	_, err = p.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to insert fx quote: %v", err)
+		return fmt.Errorf("insert fx quote: %w", err)
+	}
+	return nil
+}
+
+// Exchange consumes the quote of username to debit the from currency wallet of username and credit
+// the to currency wallet of receiver with the locked amounts. The receiver is username itself when empty.
+func (p *exchanges) Exchange(ctx context.Context, quoteUID, username, receiver, reference string) (*TransactionsModel, *FXQuotesModel, error) {
+	if receiver == "" {
+		receiver = username
+	}
+	var transaction *TransactionsModel
+	var quote *FXQuotesModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		var err error
+		quote, err = getQuoteForUpdate(ctx, exec, quoteUID, username)
+		if err != nil {
+			return err
+		}
+		if quote.UsedAt != nil {
+			return ErrQuoteUsed
+		}
+		if !quote.ExpiresAt.After(time.Now()) {
+			return ErrQuoteExpired
+		}
+
+		// Lock both wallets in a stable sequence to avoid deadlock with concurrent transfer
+		wallets := [][2]string{{username, quote.FromCurrency}, {receiver, quote.ToCurrency}}
+		slices.SortFunc(wallets, func(a, b [2]string) int {
+			return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+		})
+		var credited *WalletsModel
+		for _, w := range wallets {
+			wallet, err := get(ctx, exec, w[0], w[1], true)
+			if err != nil {
+				return err
+			}
+			if w == [2]string{receiver, quote.ToCurrency} {
+				credited = wallet
+			}
+		}
+		// An exchange moves money like a transfer when it pays another user, so it is held to the transfer limits
+		fromAmount, err := money.Of(quote.FromAmount, quote.FromCurrency)
+		if err != nil {
+			return err
+		}
+		err = checkDebitLimits(ctx, exec, username, TypeExchange, fromAmount)
+		if err != nil {
+			return err
+		}
+		toAmount, err := money.Of(quote.ToAmount, quote.ToCurrency)
+		if err != nil {
+			return err
+		}
+		// The owner initiates the credit when the exchange is into its own wallet
+		err = checkCreditLimits(ctx, exec, credited, toAmount, receiver == username)
+		if err != nil {
+			return err
+		}
+
+		transaction = &TransactionsModel{
+			UID:         utils.UUID(),
+			Type:        TypeExchange,
+			InitiatedBy: username,
+			Status:      StatusCompleted,
+			Amount:      quote.FromAmount,
+			Currency:    quote.FromCurrency,
+			Reference:   reference,
+		}
+		err = insertTransaction(ctx, exec, *transaction)
+		if err != nil {
+			log.Error(ctx, "failed in insert into transactions with err: %s", err)
+			return err
+		}
+		err = updateBalanceAndInsertLedger(ctx, exec, transaction.UID, username, quote.FromCurrency, quote.FromAmount, DirectionDebit)
+		if err != nil {
+			log.Error(ctx, "failed in debit exchange %s with err: %s", transaction.UID, err)
+			return err
+		}
+		err = updateBalanceAndInsertLedger(ctx, exec, transaction.UID, receiver, quote.ToCurrency, quote.ToAmount, DirectionCredit)
+		if err != nil {
+			log.Error(ctx, "failed in credit exchange %s with err: %s", transaction.UID, err)
+			return err
+		}
+		// The counterparty is only set when the exchange is paid to another user
+		debit := WalletEvent{TxUID: transaction.UID, TxType: TypeExchange, Username: username, Currency: quote.FromCurrency, Amount: quote.FromAmount, Direction: DirectionDebit, Reference: reference}
+		credit := WalletEvent{TxUID: transaction.UID, TxType: TypeExchange, Username: receiver, Currency: quote.ToCurrency, Amount: quote.ToAmount, Direction: DirectionCredit, Reference: reference}
+		if receiver != username {
+			debit.Counterparty, credit.Counterparty = receiver, username
+		}
+		for _, event := range []WalletEvent{debit, credit} {
+			err = insertWalletEvent(ctx, exec, event)
+			if err != nil {
+				return err
+			}
+		}
+		quote.TxUID = transaction.UID
+		return markQuoteUsed(ctx, exec, quote)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return transaction, quote, nil
+}
+
+func getQuoteForUpdate(ctx context.Context, exec sqlx.ExtContext, uid, username string) (*FXQuotesModel, error) {
+	query, args, err := psql.Select("id", "uid", "username", "from_currency", "to_currency", "rate", "from_amount", "to_amount", "tx_uid", "expires_at", "used_at", "created_at").
+		From("fx_quotes").
+		Where(squirrel.Eq{
+			"uid":      uid,
+			"username": username,
+		}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build get fx quote query with err: %s", err)
+		return nil, fmt.Errorf("build get fx quote query: %w", err)
+	}
+	quote := new(FXQuotesModel)
+	err = sqlx.GetContext(ctx, exec, quote, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierr.NotFound
+		}
+		log.Error(ctx, "failed to get fx quote %s with err: %s", uid, err)
+		return nil, fmt.Errorf("get fx quote: %w", err)
+	}
+	return quote, nil
+}
+
+func markQuoteUsed(ctx context.Context, exec sqlx.ExtContext, quote *FXQuotesModel) error {
+	query, args, err := psql.Update("fx_quotes").
+		Set("used_at", squirrel.Expr("NOW()")).
+		Set("tx_uid", quote.TxUID).
+		Where(squirrel.Eq{"id": quote.ID}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build fx quote update query with err: %s", err)
+		return fmt.Errorf("build fx quote update query: %w", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to mark fx quote used with err: %s", err)
+		return fmt.Errorf("mark fx quote used: %w", err)
+	}
+	return nil
+}
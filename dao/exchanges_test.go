@@ -0,0 +1,309 @@
+package dao
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/money"
+	"github.com/stretchr/testify/assert"
+)
+
+const selectQuoteForUpdateQuery = "SELECT id, uid, username, from_currency, to_currency, rate, from_amount, to_amount, tx_uid, expires_at, used_at, created_at FROM fx_quotes WHERE uid = $1 AND username = $2 FOR UPDATE"
+
+var quoteColumns = []string{"id", "uid", "username", "from_currency", "to_currency", "rate", "from_amount", "to_amount", "tx_uid", "expires_at", "used_at", "created_at"}
+
+func TestNewExchanges(t *testing.T) {
+	mockDB, _, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	t.Run("correct init", func(t *testing.T) {
+		daoInstance := &DAO{sqlx.NewDb(mockDB, "sqlmock")}
+		exchangeDAO := NewExchanges(daoInstance)
+		assert.Equal(t, daoInstance.db.DriverName(), exchangeDAO.db.DriverName())
+		assert.Implements(t, (*ExchangesRepository)(nil), exchangeDAO)
+	})
+}
+
+func Test_exchanges_InsertQuote(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &exchanges{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+
+	t.Run("ok insert quote", func(t *testing.T) {
+		expiresAt := time.Now()
+		mock.ExpectExec("INSERT INTO fx_quotes (uid,username,from_currency,to_currency,rate,from_amount,to_amount,expires_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)").
+			WithArgs("uid", "user1", "SGD", "JPY", "110.250000000000", 1000, 1102, expiresAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		err := p.InsertQuote(t.Context(), &FXQuotesModel{
+			UID: "uid", Username: "user1", FromCurrency: "SGD", ToCurrency: "JPY", Rate: "110.250000000000", FromAmount: 1000, ToAmount: 1102, ExpiresAt: expiresAt,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_exchanges_Exchange(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &exchanges{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	now := time.Now()
+
+	t.Run("ok exchange into own wallet", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuoteForUpdateQuery).
+			WithArgs("quote", "user1").
+			WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow(1, "quote", "user1", "SGD", "JPY", "110.25", 1000, 1102, "", now.Add(time.Minute), nil, now))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(2, "user1", 0, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 1000, "active"))
+		expectNoLimits(mock, "user1", "SGD")
+		expectNoLimits(mock, "user1", "JPY")
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), TypeExchange, "user1", "SGD", 1000, StatusCompleted, "ref").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-1000, "SGD", "user1", WalletStatusActive, 1000).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user1", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user1", "SGD", 1000, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(1102, "JPY", "user1", WalletStatusActive).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user1", "JPY")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user1", "JPY", 1102, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletDebited, "user1", "SGD")
+		expectWalletEvent(mock, EventWalletCredited, "user1", "JPY")
+		mock.ExpectExec("UPDATE fx_quotes SET used_at = NOW(), tx_uid = $1 WHERE id = $2").
+			WithArgs(sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		tx, quote, err := p.Exchange(t.Context(), "quote", "user1", "", "ref")
+		assert.NoError(t, err)
+		assert.Equal(t, TypeExchange, tx.Type)
+		assert.Equal(t, tx.UID, quote.TxUID)
+		assert.Equal(t, int64(1102), quote.ToAmount)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("ok exchange paid to other user", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuoteForUpdateQuery).
+			WithArgs("quote", "user1").
+			WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow(1, "quote", "user1", "SGD", "JPY", "110.25", 1000, 1102, "", now.Add(time.Minute), nil, now))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 1000, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user2", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(3, "user2", 0, "active"))
+		expectNoLimits(mock, "user1", "SGD")
+		expectNoLimits(mock, "user2", "JPY")
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), TypeExchange, "user1", "SGD", 1000, StatusCompleted, "ref").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-1000, "SGD", "user1", WalletStatusActive, 1000).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user1", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user1", "SGD", 1000, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(1102, "JPY", "user2", WalletStatusActive).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user2", "JPY")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user2", "JPY", 1102, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletDebited, "user1", "SGD")
+		expectWalletEvent(mock, EventWalletCredited, "user2", "JPY")
+		mock.ExpectExec("UPDATE fx_quotes SET used_at = NOW(), tx_uid = $1 WHERE id = $2").
+			WithArgs(sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		tx, _, err := p.Exchange(t.Context(), "quote", "user1", "user2", "ref")
+		assert.NoError(t, err)
+		assert.Equal(t, TypeExchange, tx.Type)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("exchange to other user over daily transfer limit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuoteForUpdateQuery).
+			WithArgs("quote", "user1").
+			WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow(1, "quote", "user1", "SGD", "JPY", "110.25", 1000, 1102, "", now.Add(time.Minute), nil, now))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 5000, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user2", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(3, "user2", 0, "active"))
+		expectLimits(mock, "user1", "SGD", LimitsModel{DailyTransfer: 1500})
+		// the exchanges and transfers of the day already moved 600
+		expectLimitUsage(mock, "user1", "SGD", LimitUsageModel{DailyTransfer: 600, MonthlyTransfer: 600})
+		mock.ExpectRollback()
+
+		_, _, err := p.Exchange(t.Context(), "quote", "user1", "user2", "ref")
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.ErrorContains(t, err, "daily exchange limit of 1500 SGD")
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("exchange over per transaction limit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuoteForUpdateQuery).
+			WithArgs("quote", "user1").
+			WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow(1, "quote", "user1", "SGD", "JPY", "110.25", 1000, 1102, "", now.Add(time.Minute), nil, now))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 5000, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user2", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(3, "user2", 0, "active"))
+		expectLimits(mock, "user1", "SGD", LimitsModel{PerTransaction: 500})
+		mock.ExpectRollback()
+
+		_, _, err := p.Exchange(t.Context(), "quote", "user1", "user2", "ref")
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.ErrorContains(t, err, "per transaction limit of 500 SGD")
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("exchange over max balance of receiver", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuoteForUpdateQuery).
+			WithArgs("quote", "user1").
+			WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow(1, "quote", "user1", "SGD", "JPY", "110.25", 1000, 1102, "", now.Add(time.Minute), nil, now))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 1000, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user2", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(3, "user2", 9000, "active"))
+		expectNoLimits(mock, "user1", "SGD")
+		expectLimits(mock, "user2", "JPY", LimitsModel{Username: "user2", MaxBalance: 10000})
+		mock.ExpectRollback()
+
+		_, _, err := p.Exchange(t.Context(), "quote", "user1", "user2", "ref")
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.ErrorContains(t, err, "max balance limit of 10000 JPY")
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("exchange overflow balance of receiver", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuoteForUpdateQuery).
+			WithArgs("quote", "user1").
+			WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow(1, "quote", "user1", "SGD", "JPY", "110.25", 1000, 1102, "", now.Add(time.Minute), nil, now))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(2, "user1", math.MaxInt64-1000, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 1000, "active"))
+		expectNoLimits(mock, "user1", "SGD")
+		mock.ExpectRollback()
+
+		_, _, err := p.Exchange(t.Context(), "quote", "user1", "", "ref")
+		assert.ErrorIs(t, err, money.ErrOverflow)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("exchange with used quote", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuoteForUpdateQuery).
+			WithArgs("quote", "user1").
+			WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow(1, "quote", "user1", "SGD", "JPY", "110.25", 1000, 1102, "tx", now.Add(time.Minute), now, now))
+		mock.ExpectRollback()
+
+		tx, quote, err := p.Exchange(t.Context(), "quote", "user1", "user2", "ref")
+		assert.ErrorIs(t, err, ErrQuoteUsed)
+		assert.Nil(t, tx)
+		assert.Nil(t, quote)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("exchange with expired quote", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuoteForUpdateQuery).
+			WithArgs("quote", "user1").
+			WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow(1, "quote", "user1", "SGD", "JPY", "110.25", 1000, 1102, "", now.Add(-time.Second), nil, now))
+		mock.ExpectRollback()
+
+		_, _, err := p.Exchange(t.Context(), "quote", "user1", "", "ref")
+		assert.ErrorIs(t, err, ErrQuoteExpired)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("exchange with quote of other user", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuoteForUpdateQuery).
+			WithArgs("quote", "user2").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, _, err := p.Exchange(t.Context(), "quote", "user2", "", "ref")
+		assert.ErrorIs(t, err, apierr.NotFound)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("exchange insufficient fund", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuoteForUpdateQuery).
+			WithArgs("quote", "user1").
+			WillReturnRows(sqlmock.NewRows(quoteColumns).AddRow(1, "quote", "user1", "SGD", "JPY", "110.25", 1000, 1102, "", now.Add(time.Minute), nil, now))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 10, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user2", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(3, "user2", 0, "active"))
+		expectNoLimits(mock, "user1", "SGD")
+		expectNoLimits(mock, "user2", "JPY")
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), TypeExchange, "user1", "SGD", 1000, StatusCompleted, "ref").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-1000, "SGD", "user1", WalletStatusActive, 1000).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 10, "active"))
+		mock.ExpectRollback()
+
+		_, _, err := p.Exchange(t.Context(), "quote", "user1", "user2", "ref")
+		assert.ErrorIs(t, err, apierr.InsufficientFund)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
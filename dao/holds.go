@@ -0,0 +1,395 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+//go:generate mockery --name HoldsRepository --output ./mocks --outpkg mocks --case=underscore
+type HoldsRepository interface {
+	Authorize(ctx context.Context, username, receiver, reference string, amount money.Money, expiresAt time.Time) (*HoldsModel, error)
+	Capture(ctx context.Context, txUID, actor string, amount int64) (*HoldsModel, error)
+	Void(ctx context.Context, txUID, actor string) (*HoldsModel, error)
+	Get(ctx context.Context, txUID, actor string) (*HoldsModel, error)
+	ExpireDue(ctx context.Context, limit int) (int, error)
+}
+
+type HoldStatus string
+
+const (
+	HoldStatusAuthorized HoldStatus = "authorized"
+	HoldStatusCaptured   HoldStatus = "captured"
+	HoldStatusVoided     HoldStatus = "voided"
+	HoldStatusExpired    HoldStatus = "expired"
+)
+
+type HoldsModel struct {
+	ID             int        `db:"id"`
+	TxUID          string     `db:"tx_uid"`
+	Username       string     `db:"username"`
+	Receiver       string     `db:"receiver"`
+	Currency       string     `db:"currency"`
+	Amount         int64      `db:"amount"`
+	CapturedAmount int64      `db:"captured_amount"`
+	Status         HoldStatus `db:"status"`
+	ExpiresAt      time.Time  `db:"expires_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+}
+
+var (
+	ErrHoldNotAuthorized = errors.New("hold is not in authorized status")
+	ErrHoldExpired       = errors.New("hold is expired")
+	ErrCaptureExceedHold = errors.New("capture amount exceed the authorized amount")
+	ErrNotHoldReceiver   = errors.New("only the receiver is able to capture or void the hold")
+)
+
+type holds struct {
+	db *sqlx.DB
+}
+
+func NewHolds(dao *DAO) *holds {
+	return &holds{
+		db: dao.db,
+	}
+}
+
+func (p *holds) Authorize(ctx context.Context, username, receiver, reference string, amount money.Money, expiresAt time.Time) (*HoldsModel, error) {
+	currency := amount.Currency().Code
+	hold := &HoldsModel{
+		TxUID:     utils.UUID(),
+		Username:  username,
+		Receiver:  receiver,
+		Currency:  currency,
+		Amount:    amount.Amount(),
+		Status:    HoldStatusAuthorized,
+		ExpiresAt: expiresAt,
+	}
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		if receiver != "" {
+			// Make sure the fund is able to be captured into receiver later
+			_, err := get(ctx, exec, receiver, currency, false)
+			if err != nil {
+				return err
+			}
+		}
+		// The usage of the payer can't change until the hold is committed while its wallet is locked
+		_, err := get(ctx, exec, username, currency, true)
+		if err != nil {
+			return err
+		}
+		// The hold is paid with the transfer limits, its amount is counted from the authorization
+		err = checkDebitLimits(ctx, exec, username, TypeTransfer, amount)
+		if err != nil {
+			return err
+		}
+		err = insertTransaction(ctx, exec, TransactionsModel{
+			UID:         hold.TxUID,
+			Type:        TypeHold,
+			InitiatedBy: username,
+			Status:      StatusPending,
+			Amount:      hold.Amount,
+			Currency:    currency,
+			Reference:   reference,
+		})
+		if err != nil {
+			log.Error(ctx, "failed in insert into transactions with err: %s", err)
+			return err
+		}
+		err = updateHeldAmount(ctx, exec, username, currency, hold.Amount)
+		if err != nil {
+			return err
+		}
+		return insertHold(ctx, exec, hold)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (p *holds) Capture(ctx context.Context, txUID, actor string, amount int64) (*HoldsModel, error) {
+	var hold *HoldsModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		var err error
+		hold, err = getHoldToSettle(ctx, exec, txUID, actor)
+		if err != nil {
+			return err
+		}
+		if hold.Status != HoldStatusAuthorized {
+			return ErrHoldNotAuthorized
+		}
+		if !hold.ExpiresAt.After(time.Now()) {
+			return ErrHoldExpired
+		}
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount > hold.Amount {
+			return ErrCaptureExceedHold
+		}
+
+		if hold.Receiver != "" {
+			// Lock both wallets in the same sequence as Transfer to avoid deadlock
+			strs := []string{hold.Username, hold.Receiver}
+			slices.Sort(strs)
+			locked := make(map[string]*WalletsModel, len(strs))
+			for _, username := range strs {
+				if locked[username], err = get(ctx, exec, username, hold.Currency, true); err != nil {
+					return err
+				}
+			}
+			// The debit limits were taken at the authorization, only the receiver is checked for the credit
+			captured, err := money.Of(amount, hold.Currency)
+			if err != nil {
+				return err
+			}
+			err = checkCreditLimits(ctx, exec, locked[hold.Receiver], captured, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Release the whole hold, the uncaptured remainder become available again
+		err = updateHeldAmount(ctx, exec, hold.Username, hold.Currency, -hold.Amount)
+		if err != nil {
+			return err
+		}
+		err = updateBalanceAndInsertLedger(ctx, exec, hold.TxUID, hold.Username, hold.Currency, amount, DirectionDebit)
+		if err != nil {
+			log.Error(ctx, "failed in debit hold %s with err: %s", hold.TxUID, err)
+			return err
+		}
+		events := []WalletEvent{{TxUID: hold.TxUID, TxType: TypeHold, Username: hold.Username, Currency: hold.Currency, Amount: amount, Direction: DirectionDebit, Counterparty: hold.Receiver}}
+		if hold.Receiver != "" {
+			err = updateBalanceAndInsertLedger(ctx, exec, hold.TxUID, hold.Receiver, hold.Currency, amount, DirectionCredit)
+			if err != nil {
+				log.Error(ctx, "failed in credit hold %s receiver with err: %s", hold.TxUID, err)
+				return err
+			}
+			events = append(events, WalletEvent{TxUID: hold.TxUID, TxType: TypeHold, Username: hold.Receiver, Currency: hold.Currency, Amount: amount, Direction: DirectionCredit, Counterparty: hold.Username})
+		}
+		for _, event := range events {
+			err = insertWalletEvent(ctx, exec, event)
+			if err != nil {
+				return err
+			}
+		}
+		err = updateTransactionStatus(ctx, exec, hold.TxUID, StatusCompleted, amount)
+		if err != nil {
+			return err
+		}
+		hold.Status = HoldStatusCaptured
+		hold.CapturedAmount = amount
+		return updateHoldStatus(ctx, exec, hold)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (p *holds) Void(ctx context.Context, txUID, actor string) (*HoldsModel, error) {
+	var hold *HoldsModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		var err error
+		hold, err = getHoldToSettle(ctx, exec, txUID, actor)
+		if err != nil {
+			return err
+		}
+		if hold.Status != HoldStatusAuthorized {
+			return ErrHoldNotAuthorized
+		}
+		return releaseHold(ctx, exec, hold, HoldStatusVoided)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (p *holds) Get(ctx context.Context, txUID, actor string) (*HoldsModel, error) {
+	return getHold(ctx, p.db, txUID, actor, false)
+}
+
+// ExpireDue releases the authorized holds which passed their expiry and returns the number of holds expired.
+// SKIP LOCKED allow multiple instances to run the expiry concurrently without blocking each other.
+// expires_at is a UTC timestamp without time zone, so it is compared with the UTC now whatever the session time zone is.
+func (p *holds) ExpireDue(ctx context.Context, limit int) (int, error) {
+	expired := 0
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		query, args, err := selectHolds().
+			Where(squirrel.Eq{"status": HoldStatusAuthorized}).
+			Where(squirrel.Expr("expires_at <= (now() AT TIME ZONE 'utc')")).
+			OrderBy("expires_at").
+			Limit(uint64(limit)).
+			Suffix("FOR UPDATE SKIP LOCKED").
+			ToSql()
+		if err != nil {
+			log.Error(ctx, "failed to build due holds query with err: %s", err)
+			return fmt.Errorf("build due holds query: %w", err)
+		}
+		dueHolds := []HoldsModel{}
+		err = sqlx.SelectContext(ctx, exec, &dueHolds, query, args...)
+		if err != nil {
+			log.Error(ctx, "failed to select due holds with err: %s", err)
+			return fmt.Errorf("select due holds: %w", err)
+		}
+		for i := range dueHolds {
+			err = releaseHold(ctx, exec, &dueHolds[i], HoldStatusExpired)
+			if err != nil {
+				return err
+			}
+		}
+		expired = len(dueHolds)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+func selectHolds() squirrel.SelectBuilder {
+	return psql.Select("id", "tx_uid", "username", "receiver", "currency", "amount", "captured_amount", "status", "expires_at", "created_at", "updated_at").
+		From("holds")
+}
+
+func getHold(ctx context.Context, exec sqlx.ExtContext, txUID, actor string, forUpdate bool) (*HoldsModel, error) {
+	queryBuilder := selectHolds().
+		Where(squirrel.Eq{"tx_uid": txUID}).
+		Where(squirrel.Or{
+			squirrel.Eq{"username": actor},
+			squirrel.Eq{"receiver": actor},
+		}).
+		Limit(1)
+	if forUpdate {
+		queryBuilder = queryBuilder.Suffix("FOR UPDATE")
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build get hold query with err: %s", err)
+		return nil, fmt.Errorf("build get hold query: %w", err)
+	}
+	hold := new(HoldsModel)
+	err = sqlx.GetContext(ctx, exec, hold, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierr.NotFound
+		}
+		log.Error(ctx, "failed to get hold %s with err: %s", txUID, err)
+		return nil, fmt.Errorf("get hold: %w", err)
+	}
+	return hold, nil
+}
+
+// getHoldToSettle locks the hold which the actor is able to capture or void. The hold with a receiver is settled by the
+// receiver only, as the payer voiding it would take back the fund the receiver was promised.
+func getHoldToSettle(ctx context.Context, exec sqlx.ExtContext, txUID, actor string) (*HoldsModel, error) {
+	hold, err := getHold(ctx, exec, txUID, actor, true)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Receiver != "" && hold.Receiver != actor {
+		return nil, ErrNotHoldReceiver
+	}
+	return hold, nil
+}
+
+func insertHold(ctx context.Context, exec sqlx.ExtContext, hold *HoldsModel) error {
+	query, args, err := psql.Insert("holds").
+		Columns("tx_uid", "username", "receiver", "currency", "amount", "status", "expires_at").
+		Values(hold.TxUID, hold.Username, hold.Receiver, hold.Currency, hold.Amount, hold.Status, hold.ExpiresAt).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build hold insert query: %v", err)
+		return fmt.Errorf("build hold insert query: %w", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to insert hold: %v", err)
+		return fmt.Errorf("insert hold: %w", err)
+	}
+	log.Debug(ctx, "Hold created : %s", hold.TxUID)
+	return nil
+}
+
+func updateHoldStatus(ctx context.Context, exec sqlx.ExtContext, hold *HoldsModel) error {
+	query, args, err := psql.Update("holds").
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Set("status", hold.Status).
+		Set("captured_amount", hold.CapturedAmount).
+		Where(squirrel.Eq{"id": hold.ID}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build hold update query: %v", err)
+		return fmt.Errorf("build hold update query: %w", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to update hold: %v", err)
+		return fmt.Errorf("update hold: %w", err)
+	}
+	return nil
+}
+
+// releaseHold gives the held amount back to the available balance without moving any fund.
+func releaseHold(ctx context.Context, exec sqlx.ExtContext, hold *HoldsModel, status HoldStatus) error {
+	err := updateHeldAmount(ctx, exec, hold.Username, hold.Currency, -hold.Amount)
+	if err != nil {
+		return err
+	}
+	err = updateTransactionStatus(ctx, exec, hold.TxUID, StatusCancelled, hold.Amount)
+	if err != nil {
+		return err
+	}
+	hold.Status = status
+	return updateHoldStatus(ctx, exec, hold)
+}
+
+// updateHeldAmount reserves (positive amount) or releases (negative amount) the fund of a wallet.
+// Reserving only succeed for active wallet with enough available balance.
+func updateHeldAmount(ctx context.Context, exec sqlx.ExtContext, username, currency string, amount int64) error {
+	updateBuilder := psql.Update("wallets").
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Set("held_amount", squirrel.Expr("held_amount + ?", amount)).
+		Where(squirrel.Eq{
+			"username": username,
+			"currency": currency,
+		})
+	if amount > 0 {
+		updateBuilder = updateBuilder.
+			Where(squirrel.Eq{"status": WalletStatusActive}).
+			Where(squirrel.Expr("amount - held_amount >= ?", amount))
+	}
+	query, args, err := updateBuilder.ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build held amount query with err: %s", err)
+		return fmt.Errorf("build held amount query: %w", err)
+	}
+	r, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to update held amount with err: %s", err)
+		return fmt.Errorf("update held amount: %w", err)
+	}
+	rowAffected, err := r.RowsAffected()
+	if err != nil {
+		log.Error(ctx, "unabled to get row affected due to: %s", err)
+		return err
+	}
+	if rowAffected == 0 {
+		return noRowsUpdatedErr(ctx, exec, username, currency, -amount)
+	}
+	return nil
+}
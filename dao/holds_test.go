@@ -0,0 +1,466 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectHoldForUpdateQuery = "SELECT id, tx_uid, username, receiver, currency, amount, captured_amount, status, expires_at, created_at, updated_at FROM holds WHERE tx_uid = $1 AND (username = $2 OR receiver = $3) LIMIT 1 FOR UPDATE"
+	reserveHeldAmountQuery   = "UPDATE wallets SET updated_at = NOW(), held_amount = held_amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5"
+	releaseHeldAmountQuery   = "UPDATE wallets SET updated_at = NOW(), held_amount = held_amount + $1 WHERE currency = $2 AND username = $3"
+	updateTransactionQuery   = "UPDATE transactions SET updated_at = NOW(), status = $1, amount = $2 WHERE uid = $3"
+	updateHoldQuery          = "UPDATE holds SET updated_at = NOW(), status = $1, captured_amount = $2 WHERE id = $3"
+)
+
+var holdColumns = []string{"id", "tx_uid", "username", "receiver", "currency", "amount", "captured_amount", "status", "expires_at", "created_at", "updated_at"}
+
+func TestNewHolds(t *testing.T) {
+	mockDB, _, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	t.Run("correct init", func(t *testing.T) {
+		daoInstance := &DAO{sqlx.NewDb(mockDB, "sqlmock")}
+		holdDAO := NewHolds(daoInstance)
+		assert.Equal(t, daoInstance.db.DriverName(), holdDAO.db.DriverName())
+		assert.Implements(t, (*HoldsRepository)(nil), holdDAO)
+	})
+}
+
+func Test_holds_Authorize(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &holds{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	expiresAt := time.Now().Add(time.Hour)
+
+	t.Run("ok authorize hold", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name", 150, "active"))
+		expectNoLimits(mock, "name", "SGD")
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), TypeHold, "name", "SGD", 100, StatusPending, "ref").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(reserveHeldAmountQuery).
+			WithArgs(100, "SGD", "name", WalletStatusActive, 100).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO holds (tx_uid,username,receiver,currency,amount,status,expires_at) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), "name", "", "SGD", 100, HoldStatusAuthorized, expiresAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		hold, err := p.Authorize(t.Context(), "name", "", "ref", money.New(100, currency.SGD), expiresAt)
+		assert.NoError(t, err)
+		assert.Equal(t, HoldStatusAuthorized, hold.Status)
+		assert.NotEmpty(t, hold.TxUID)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("authorize hold exceed daily transfer limit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name", 1000, "active"))
+		expectLimits(mock, "name", "SGD", LimitsModel{DailyTransfer: 500})
+		// an authorized hold of 450 is pending in the usage
+		expectLimitUsage(mock, "name", "SGD", LimitUsageModel{DailyTransfer: 450, MonthlyTransfer: 450})
+		mock.ExpectRollback()
+
+		hold, err := p.Authorize(t.Context(), "name", "", "ref", money.New(100, currency.SGD), expiresAt)
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.ErrorContains(t, err, "daily transfer limit of 500 SGD")
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("authorize hold receiver not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+			WithArgs("merchant", "SGD").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		hold, err := p.Authorize(t.Context(), "name", "merchant", "ref", money.New(100, currency.SGD), expiresAt)
+		assert.ErrorIs(t, err, apierr.NotFound)
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("authorize hold insufficient available balance", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name", 150, "active"))
+		expectNoLimits(mock, "name", "SGD")
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), TypeHold, "name", "SGD", 100, StatusPending, "ref").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(reserveHeldAmountQuery).
+			WithArgs(100, "SGD", "name", WalletStatusActive, 100).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 150, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+			WithArgs("name", "SGD").
+			WillReturnRows(rows)
+		mock.ExpectRollback()
+
+		hold, err := p.Authorize(t.Context(), "name", "", "ref", money.New(100, currency.SGD), expiresAt)
+		assert.ErrorIs(t, err, apierr.InsufficientFund)
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_holds_Capture(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &holds{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	now := time.Now()
+
+	t.Run("ok partial capture into receiver", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "merchant", "SGD", 100, 0, "authorized", now.Add(time.Hour), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "merchant", "merchant").
+			WillReturnRows(holdRows)
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(2, "merchant", 0, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+			WithArgs("merchant", "SGD").
+			WillReturnRows(rows)
+		rows = sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 100, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+			WithArgs("name", "SGD").
+			WillReturnRows(rows)
+		expectNoLimits(mock, "merchant", "SGD")
+		mock.ExpectExec(releaseHeldAmountQuery).
+			WithArgs(-100, "SGD", "name").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-80, "SGD", "name", WalletStatusActive, 80).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "name", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs("uid", "name", "SGD", 80, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(80, "SGD", "merchant", WalletStatusActive).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "merchant", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs("uid", "merchant", "SGD", 80, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletDebited, "name", "SGD")
+		expectWalletEvent(mock, EventWalletCredited, "merchant", "SGD")
+		mock.ExpectExec(updateTransactionQuery).
+			WithArgs(StatusCompleted, 80, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateHoldQuery).
+			WithArgs(HoldStatusCaptured, 80, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		hold, err := p.Capture(t.Context(), "uid", "merchant", 80)
+		assert.NoError(t, err)
+		assert.Equal(t, HoldStatusCaptured, hold.Status)
+		assert.Equal(t, int64(80), hold.CapturedAmount)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("ok full capture without receiver", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "", "SGD", 100, 0, "authorized", now.Add(time.Hour), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "name", "name").
+			WillReturnRows(holdRows)
+		mock.ExpectExec(releaseHeldAmountQuery).
+			WithArgs(-100, "SGD", "name").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-100, "SGD", "name", WalletStatusActive, 100).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "name", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs("uid", "name", "SGD", 100, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletDebited, "name", "SGD")
+		mock.ExpectExec(updateTransactionQuery).
+			WithArgs(StatusCompleted, 100, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateHoldQuery).
+			WithArgs(HoldStatusCaptured, 100, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		hold, err := p.Capture(t.Context(), "uid", "name", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(100), hold.CapturedAmount)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("capture into receiver over max balance", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "merchant", "SGD", 100, 0, "authorized", now.Add(time.Hour), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "merchant", "merchant").
+			WillReturnRows(holdRows)
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("merchant", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(2, "merchant", 950, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name", 100, "active"))
+		expectLimits(mock, "merchant", "SGD", LimitsModel{Username: "merchant", MaxBalance: 1000})
+		mock.ExpectRollback()
+
+		hold, err := p.Capture(t.Context(), "uid", "merchant", 0)
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.ErrorContains(t, err, "max balance limit of 1000 SGD")
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("capture more than authorized", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "", "SGD", 100, 0, "authorized", now.Add(time.Hour), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "name", "name").
+			WillReturnRows(holdRows)
+		mock.ExpectRollback()
+
+		hold, err := p.Capture(t.Context(), "uid", "name", 101)
+		assert.ErrorIs(t, err, ErrCaptureExceedHold)
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("capture expired hold", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "", "SGD", 100, 0, "authorized", now.Add(-time.Second), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "name", "name").
+			WillReturnRows(holdRows)
+		mock.ExpectRollback()
+
+		hold, err := p.Capture(t.Context(), "uid", "name", 0)
+		assert.ErrorIs(t, err, ErrHoldExpired)
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("capture voided hold", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "", "SGD", 100, 0, "voided", now.Add(time.Hour), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "name", "name").
+			WillReturnRows(holdRows)
+		mock.ExpectRollback()
+
+		hold, err := p.Capture(t.Context(), "uid", "name", 0)
+		assert.ErrorIs(t, err, ErrHoldNotAuthorized)
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("payer capture hold of receiver", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "merchant", "SGD", 100, 0, "authorized", now.Add(time.Hour), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "name", "name").
+			WillReturnRows(holdRows)
+		mock.ExpectRollback()
+
+		hold, err := p.Capture(t.Context(), "uid", "name", 0)
+		assert.ErrorIs(t, err, ErrNotHoldReceiver)
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("capture hold of other user", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "other", "other").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		hold, err := p.Capture(t.Context(), "uid", "other", 0)
+		assert.ErrorIs(t, err, apierr.NotFound)
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_holds_Void(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &holds{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	now := time.Now()
+
+	t.Run("ok void hold", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "", "SGD", 100, 0, "authorized", now.Add(time.Hour), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "name", "name").
+			WillReturnRows(holdRows)
+		mock.ExpectExec(releaseHeldAmountQuery).
+			WithArgs(-100, "SGD", "name").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateTransactionQuery).
+			WithArgs(StatusCancelled, 100, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateHoldQuery).
+			WithArgs(HoldStatusVoided, 0, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		hold, err := p.Void(t.Context(), "uid", "name")
+		assert.NoError(t, err)
+		assert.Equal(t, HoldStatusVoided, hold.Status)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("ok receiver void hold", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "merchant", "SGD", 100, 0, "authorized", now.Add(time.Hour), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "merchant", "merchant").
+			WillReturnRows(holdRows)
+		mock.ExpectExec(releaseHeldAmountQuery).
+			WithArgs(-100, "SGD", "name").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateTransactionQuery).
+			WithArgs(StatusCancelled, 100, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateHoldQuery).
+			WithArgs(HoldStatusVoided, 0, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		hold, err := p.Void(t.Context(), "uid", "merchant")
+		assert.NoError(t, err)
+		assert.Equal(t, HoldStatusVoided, hold.Status)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("payer void hold of receiver", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "merchant", "SGD", 100, 0, "authorized", now.Add(time.Hour), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "name", "name").
+			WillReturnRows(holdRows)
+		mock.ExpectRollback()
+
+		hold, err := p.Void(t.Context(), "uid", "name")
+		assert.ErrorIs(t, err, ErrNotHoldReceiver)
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("void captured hold", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid", "name", "", "SGD", 100, 100, "captured", now.Add(time.Hour), now, now)
+		mock.ExpectQuery(selectHoldForUpdateQuery).
+			WithArgs("uid", "name", "name").
+			WillReturnRows(holdRows)
+		mock.ExpectRollback()
+
+		hold, err := p.Void(t.Context(), "uid", "name")
+		assert.ErrorIs(t, err, ErrHoldNotAuthorized)
+		assert.Nil(t, hold)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_holds_ExpireDue(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &holds{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	now := time.Now()
+	dueQuery := "SELECT id, tx_uid, username, receiver, currency, amount, captured_amount, status, expires_at, created_at, updated_at FROM holds WHERE status = $1 AND expires_at <= (now() AT TIME ZONE 'utc') ORDER BY expires_at LIMIT 10 FOR UPDATE SKIP LOCKED"
+
+	t.Run("ok expire due holds", func(t *testing.T) {
+		mock.ExpectBegin()
+		holdRows := sqlmock.NewRows(holdColumns)
+		holdRows.AddRow(1, "uid1", "name", "", "SGD", 100, 0, "authorized", now.Add(-time.Minute), now, now)
+		mock.ExpectQuery(dueQuery).
+			WithArgs(HoldStatusAuthorized).
+			WillReturnRows(holdRows)
+		mock.ExpectExec(releaseHeldAmountQuery).
+			WithArgs(-100, "SGD", "name").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateTransactionQuery).
+			WithArgs(StatusCancelled, 100, "uid1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateHoldQuery).
+			WithArgs(HoldStatusExpired, 0, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		expired, err := p.ExpireDue(t.Context(), 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, expired)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("expire due holds error", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(dueQuery).
+			WithArgs(HoldStatusAuthorized).
+			WillReturnError(errors.New("err"))
+		mock.ExpectRollback()
+
+		expired, err := p.ExpireDue(t.Context(), 10)
+		assert.Error(t, err)
+		assert.Equal(t, 0, expired)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
@@ -2,18 +2,27 @@ package dao
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/metadata"
 )
 
 //go:generate mockery --name LedgersRepository --output ./mocks --outpkg mocks --case=underscore
 type LedgersRepository interface {
 	Insert(ctx context.Context, user *LedgersModel) error
-	List(ctx context.Context, limit int, startingAfter, currency, username string) ([]TxHistoryModel, bool, error)
+	List(ctx context.Context, page HistoryPage, currency, username string, filter HistoryFilter) ([]TxHistoryModel, bool, error)
+	VerifyChains(ctx context.Context, username, currency string) ([]ChainVerificationModel, error)
+	Statement(ctx context.Context, username, currency string, from, to time.Time, write func(summary StatementSummaryModel, entries StatementEntries) error) error
 }
 
 type Direction string
@@ -39,34 +48,145 @@ type LedgersModel struct {
 	ID        int       `db:"id"`
 	TxUID     string    `db:"tx_uid"`
 	Username  string    `db:"username"`
-	Amount    int       `db:"amount"`
+	Amount    int64     `db:"amount"`
 	Currency  string    `db:"currency"`
 	Direction Direction `db:"direction"`
 	CreatedAt time.Time `db:"created_at"`
+	// PrevHash is the Hash of the previous entry of the same wallet
+	PrevHash string `db:"prev_hash"`
+	Hash     string `db:"hash"`
+}
+
+type BrokenLinkReason string
+
+const (
+	// ReasonHashMismatch means the content of the entry was changed after it was inserted
+	ReasonHashMismatch BrokenLinkReason = "hash_mismatch"
+	// ReasonPrevHashMismatch means an entry before it was removed, inserted or reordered
+	ReasonPrevHashMismatch BrokenLinkReason = "prev_hash_mismatch"
+	// ReasonMissingHash means the hash of a chained entry was cleared
+	ReasonMissingHash BrokenLinkReason = "missing_hash"
+)
+
+// ChainVerificationModel is the result of walking the ledger chain of a wallet.
+type ChainVerificationModel struct {
+	Username string
+	Currency string
+	// Checked is the number of entries walked until the end of the chain or the first broken link
+	Checked int
+	// Unchained is the number of entries inserted before the chain was introduced
+	Unchained  int
+	BrokenLink *BrokenLinkModel
+}
+
+type BrokenLinkModel struct {
+	LedgerID     int
+	TxUID        string
+	Reason       BrokenLinkReason
+	ExpectedHash string
+	ActualHash   string
 }
 
 type TxHistoryModel struct {
-	UID       string    `db:"uid"`
-	Type      TxType    `db:"type"`
-	Status    TxStatus  `db:"status"`
-	Direction Direction `db:"direction"`
-	Amount    int       `db:"amount"`
-	Currency  string    `db:"currency"`
-	CreatedAt time.Time `db:"created_at"`
+	LedgerID  int               `db:"ledger_id"`
+	UID       string            `db:"uid"`
+	Type      TxType            `db:"type"`
+	Status    TxStatus          `db:"status"`
+	Direction Direction         `db:"direction"`
+	Amount    int64             `db:"amount"`
+	Currency  string            `db:"currency"`
+	Metadata  metadata.Metadata `db:"metadata"`
+	CreatedAt time.Time         `db:"created_at"`
+}
+
+// HistoryCursor is the keyset of a ledger in the history, which is ordered by created_at then ledger id
+// so the two legs of a transfer with the same uid and created_at are still apart.
+type HistoryCursor struct {
+	CreatedAt time.Time
+	LedgerID  int
+}
+
+// HistoryPage is the page of the history, newest first. StartingAfter pages toward the older entries and
+// EndingBefore toward the newer ones, at most one of them is set.
+type HistoryPage struct {
+	Limit         int
+	StartingAfter *HistoryCursor
+	EndingBefore  *HistoryCursor
+}
+
+// HistoryFilter narrows the history, a zero field is not filtered on.
+type HistoryFilter struct {
+	Types     []TxType
+	Statuses  []TxStatus
+	Direction Direction
+	// MinAmount and MaxAmount are the inclusive range of the amount in the smallest unit of the currency
+	MinAmount int64
+	MaxAmount int64
+	// CreatedFrom is inclusive and CreatedTo is exclusive
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Reference   string
+}
+
+// historyColumns are the columns of the history query which the filter is applied on,
+// direction is empty when the query has no ledger to filter on.
+type historyColumns struct {
+	typ, status, direction, amount, createdAt, reference string
+}
+
+var (
+	ledgerHistoryColumns = historyColumns{
+		typ: "t.type", status: "t.status", direction: "le.direction", amount: "le.amount", createdAt: "le.created_at", reference: "t.reference",
+	}
+	transactionHistoryColumns = historyColumns{
+		typ: "type", status: "status", amount: "amount", createdAt: "created_at", reference: "reference",
+	}
+)
+
+func (f HistoryFilter) apply(sq squirrel.SelectBuilder, columns historyColumns) squirrel.SelectBuilder {
+	if len(f.Types) > 0 {
+		sq = sq.Where(squirrel.Eq{columns.typ: f.Types})
+	}
+	if len(f.Statuses) > 0 {
+		sq = sq.Where(squirrel.Eq{columns.status: f.Statuses})
+	}
+	if f.Direction != "" && columns.direction != "" {
+		sq = sq.Where(squirrel.Eq{columns.direction: f.Direction})
+	}
+	if f.MinAmount > 0 {
+		sq = sq.Where(squirrel.GtOrEq{columns.amount: f.MinAmount})
+	}
+	if f.MaxAmount > 0 {
+		sq = sq.Where(squirrel.LtOrEq{columns.amount: f.MaxAmount})
+	}
+	if !f.CreatedFrom.IsZero() {
+		sq = sq.Where(squirrel.GtOrEq{columns.createdAt: f.CreatedFrom})
+	}
+	if !f.CreatedTo.IsZero() {
+		sq = sq.Where(squirrel.Lt{columns.createdAt: f.CreatedTo})
+	}
+	if f.Reference != "" {
+		sq = sq.Where(squirrel.Eq{columns.reference: f.Reference})
+	}
+	return sq
 }
 
 func (p *ledgers) Insert(ctx context.Context, ledger *LedgersModel) error {
 	return insertLedgers(ctx, p.db, ledger)
 }
 
-func (p *ledgers) List(ctx context.Context, limit int, startingAfter, currency, username string) ([]TxHistoryModel, bool, error) {
+// List returns a page of the history of the wallet newest first, hasMore tells whether there are more entries
+// beyond the page in the direction of the paging.
+func (p *ledgers) List(ctx context.Context, page HistoryPage, currency, username string, filter HistoryFilter) ([]TxHistoryModel, bool, error) {
 	sq := psql.Select(
+		"le.id AS ledger_id",
 		"t.uid",
 		"t.type",
 		"t.status",
 		"le.direction",
 		"le.amount",
 		"le.currency",
+		"t.metadata",
 		"le.created_at",
 	).
 		From("ledgers le").
@@ -75,12 +195,20 @@ func (p *ledgers) List(ctx context.Context, limit int, startingAfter, currency,
 			"le.username": username,
 			"le.currency": currency,
 		}).
-		OrderBy("le.created_at DESC").
-		Limit(uint64(limit + 1))
+		Limit(uint64(page.Limit + 1))
 
-	if startingAfter != "" {
-		sq = sq.Where("t.uid < ?", startingAfter)
+	switch {
+	case page.EndingBefore != nil:
+		// Read the newer entries nearest to the cursor first, then reverse them into the order of the history
+		sq = sq.Where("(le.created_at, le.id) > (?, ?)", page.EndingBefore.CreatedAt, page.EndingBefore.LedgerID).
+			OrderBy("le.created_at", "le.id")
+	case page.StartingAfter != nil:
+		sq = sq.Where("(le.created_at, le.id) < (?, ?)", page.StartingAfter.CreatedAt, page.StartingAfter.LedgerID).
+			OrderBy("le.created_at DESC", "le.id DESC")
+	default:
+		sq = sq.OrderBy("le.created_at DESC", "le.id DESC")
 	}
+	sq = filter.apply(sq, ledgerHistoryColumns)
 	txQuery, txArgs, err := sq.ToSql()
 	if err != nil {
 		log.Error(ctx, "failed to build tx list query: %v", err)
@@ -93,17 +221,81 @@ func (p *ledgers) List(ctx context.Context, limit int, startingAfter, currency,
 		log.Error(ctx, "failed to list tx hisotries: %v", err)
 		return nil, false, fmt.Errorf("list tx histories: %w", err)
 	}
-	hasMore := len(txHistories) > limit
+	hasMore := len(txHistories) > page.Limit
 	if hasMore {
-		txHistories = txHistories[:limit]
+		txHistories = txHistories[:page.Limit]
+	}
+	if page.EndingBefore != nil {
+		slices.Reverse(txHistories)
 	}
 	return txHistories, hasMore, nil
 }
 
+// ledgerTimeLayout keeps the microsecond precision of postgres timestamp,
+// so created_at hashes the same after it is read back from the database.
+const ledgerTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+// ledgerHash is the sha256 of the entry content chained with the hash of the previous entry of the same wallet,
+// the content is encoded as json array so no field is able to spill into the next one.
+func ledgerHash(prevHash string, ledger *LedgersModel) string {
+	content, _ := json.Marshal([]any{
+		prevHash,
+		ledger.TxUID,
+		ledger.Username,
+		ledger.Currency,
+		ledger.Amount,
+		ledger.Direction,
+		ledger.CreatedAt.UTC().Format(ledgerTimeLayout),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// lastLedgerHash returns the hash of the latest entry of the wallet, it is empty when the wallet doesn't have any chained entry.
+// The caller updates the wallet balance before inserting the ledger, so the wallet row lock serializes the chain of a wallet.
+func lastLedgerHash(ctx context.Context, exec sqlx.ExtContext, username, currency string) (string, error) {
+	query, args, err := psql.Select("hash").
+		From("ledgers").
+		Where(squirrel.Eq{
+			"username": username,
+			"currency": currency,
+		}).
+		OrderBy("id DESC").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build last ledger hash query: %v", err)
+		return "", fmt.Errorf("build last ledger hash query: %w", err)
+	}
+	var hash string
+	err = exec.QueryRowxContext(ctx, query, args...).Scan(&hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		log.Error(ctx, "failed to get last ledger hash: %v", err)
+		return "", fmt.Errorf("get last ledger hash: %w", err)
+	}
+	return hash, nil
+}
+
 func insertLedgers(ctx context.Context, exec sqlx.ExtContext, ledger *LedgersModel) error {
+	prevHash, err := lastLedgerHash(ctx, exec, ledger.Username, ledger.Currency)
+	if err != nil {
+		return err
+	}
+	// created_at is hashed, so it is set here instead of the database default
+	ledger.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	err = checkPeriodOpen(ctx, exec, ledger.CreatedAt)
+	if err != nil {
+		return err
+	}
+	ledger.PrevHash = prevHash
+	ledger.Hash = ledgerHash(prevHash, ledger)
+
 	query, args, err := psql.Insert("ledgers").
-		Columns("tx_uid", "username", "currency", "amount", "direction").
-		Values(ledger.TxUID, ledger.Username, ledger.Currency, ledger.Amount, ledger.Direction).
+		Columns("tx_uid", "username", "currency", "amount", "direction", "created_at", "prev_hash", "hash").
+		Values(ledger.TxUID, ledger.Username, ledger.Currency, ledger.Amount, ledger.Direction, ledger.CreatedAt, ledger.PrevHash, ledger.Hash).
 		ToSql()
 	if err != nil {
 		log.Error(ctx, "failed to build ledger insert query: %v", err)
@@ -118,3 +310,97 @@ func insertLedgers(ctx context.Context, exec sqlx.ExtContext, ledger *LedgersMod
 	log.Debug(ctx, "Ledgers created : %s", ledger.TxUID)
 	return nil
 }
+
+// VerifyChains walks the ledger chain of every wallet matching username and currency, both are optional.
+func (p *ledgers) VerifyChains(ctx context.Context, username, currency string) ([]ChainVerificationModel, error) {
+	sq := psql.Select("username", "currency").
+		From("wallets").
+		OrderBy("username", "currency")
+	if username != "" {
+		sq = sq.Where(squirrel.Eq{"username": username})
+	}
+	if currency != "" {
+		sq = sq.Where(squirrel.Eq{"currency": currency})
+	}
+	query, args, err := sq.ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list chain wallets query: %v", err)
+		return nil, fmt.Errorf("build list chain wallets query: %w", err)
+	}
+	wallets := []WalletsModel{}
+	err = p.db.SelectContext(ctx, &wallets, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list chain wallets: %v", err)
+		return nil, fmt.Errorf("list chain wallets: %w", err)
+	}
+	results := make([]ChainVerificationModel, 0, len(wallets))
+	for _, wallet := range wallets {
+		result, err := p.verifyChain(ctx, wallet.Username, wallet.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if result.BrokenLink != nil {
+			log.Error(ctx, "ledger chain of %s %s is broken at ledger %d: %s", wallet.Username, wallet.Currency, result.BrokenLink.LedgerID, result.BrokenLink.Reason)
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// verifyChain recomputes the hash of every entry of the wallet in the inserted order and stops at the first broken link.
+// The entries before the first chained entry were inserted before the chain was introduced, they are counted as unchained.
+func (p *ledgers) verifyChain(ctx context.Context, username, currency string) (*ChainVerificationModel, error) {
+	query, args, err := psql.Select("id", "tx_uid", "username", "currency", "amount", "direction", "created_at", "prev_hash", "hash").
+		From("ledgers").
+		Where(squirrel.Eq{
+			"username": username,
+			"currency": currency,
+		}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build ledger chain query: %v", err)
+		return nil, fmt.Errorf("build ledger chain query: %w", err)
+	}
+	rows, err := p.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to query ledger chain of %s %s: %v", username, currency, err)
+		return nil, fmt.Errorf("query ledger chain: %w", err)
+	}
+	defer rows.Close()
+
+	result := &ChainVerificationModel{Username: username, Currency: currency}
+	prevHash := ""
+	chained := false
+	for rows.Next() {
+		ledger := LedgersModel{}
+		err = rows.StructScan(&ledger)
+		if err != nil {
+			log.Error(ctx, "failed to scan ledger chain of %s %s: %v", username, currency, err)
+			return nil, fmt.Errorf("scan ledger chain: %w", err)
+		}
+		result.Checked++
+		expected := ledgerHash(ledger.PrevHash, &ledger)
+		switch {
+		case ledger.Hash == "" && !chained:
+			result.Unchained++
+			continue
+		case ledger.Hash == "":
+			result.BrokenLink = &BrokenLinkModel{LedgerID: ledger.ID, TxUID: ledger.TxUID, Reason: ReasonMissingHash, ExpectedHash: expected}
+		case ledger.PrevHash != prevHash:
+			result.BrokenLink = &BrokenLinkModel{LedgerID: ledger.ID, TxUID: ledger.TxUID, Reason: ReasonPrevHashMismatch, ExpectedHash: prevHash, ActualHash: ledger.PrevHash}
+		case expected != ledger.Hash:
+			result.BrokenLink = &BrokenLinkModel{LedgerID: ledger.ID, TxUID: ledger.TxUID, Reason: ReasonHashMismatch, ExpectedHash: expected, ActualHash: ledger.Hash}
+		default:
+			chained = true
+			prevHash = ledger.Hash
+			continue
+		}
+		return result, nil
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(ctx, "failed to walk ledger chain of %s %s: %v", username, currency, err)
+		return nil, fmt.Errorf("walk ledger chain: %w", err)
+	}
+	return result, nil
+}
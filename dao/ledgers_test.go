@@ -2,12 +2,47 @@ package dao
 
 import (
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
 	"github.com/stretchr/testify/assert"
 )
 
+const (
+	insertLedgerQuery   = "INSERT INTO ledgers (tx_uid,username,currency,amount,direction,created_at,prev_hash,hash) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)"
+	lastLedgerHashQuery = "SELECT hash FROM ledgers WHERE currency = $1 AND username = $2 ORDER BY id DESC LIMIT 1"
+	ledgerChainQuery    = "SELECT id, tx_uid, username, currency, amount, direction, created_at, prev_hash, hash FROM ledgers WHERE currency = $1 AND username = $2 ORDER BY id"
+)
+
+var ledgerChainColumns = []string{"id", "tx_uid", "username", "currency", "amount", "direction", "created_at", "prev_hash", "hash"}
+
+// expectLastLedgerHash expects the lookup of the previous hash of a wallet without any chained entry.
+func expectLastLedgerHash(mock sqlmock.Sqlmock, username, currency string) {
+	mock.ExpectQuery(lastLedgerHashQuery).
+		WithArgs(currency, username).
+		WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+}
+
+// chainedLedgers builds the valid chain of the entries in order.
+func chainedLedgers(prevHash string, entries ...LedgersModel) []LedgersModel {
+	for i := range entries {
+		entries[i].PrevHash = prevHash
+		entries[i].Hash = ledgerHash(prevHash, &entries[i])
+		prevHash = entries[i].Hash
+	}
+	return entries
+}
+
+func ledgerChainRows(entries ...LedgersModel) *sqlmock.Rows {
+	rows := sqlmock.NewRows(ledgerChainColumns)
+	for _, e := range entries {
+		rows.AddRow(e.ID, e.TxUID, e.Username, e.Currency, e.Amount, e.Direction, e.CreatedAt, e.PrevHash, e.Hash)
+	}
+	return rows
+}
+
 func TestNewLedgers(t *testing.T) {
 	mockDB, _, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	if err != nil {
@@ -22,3 +57,252 @@ func TestNewLedgers(t *testing.T) {
 		assert.Implements(t, (*LedgersRepository)(nil), ledgerDAO)
 	})
 }
+
+func Test_ledgerHash(t *testing.T) {
+	createdAt := time.Date(2026, 10, 17, 1, 2, 3, 456789000, time.UTC)
+	ledger := LedgersModel{TxUID: "tx1", Username: "user1", Currency: "SGD", Amount: 100, Direction: DirectionCredit, CreatedAt: createdAt}
+	hash := ledgerHash("", &ledger)
+	assert.Len(t, hash, 64)
+
+	t.Run("same content in other timezone has same hash", func(t *testing.T) {
+		other := ledger
+		other.CreatedAt = createdAt.In(time.FixedZone("SGT", 8*60*60))
+		assert.Equal(t, hash, ledgerHash("", &other))
+	})
+
+	t.Run("any change of content changes hash", func(t *testing.T) {
+		changes := []func(l *LedgersModel){
+			func(l *LedgersModel) { l.Amount = 101 },
+			func(l *LedgersModel) { l.Direction = DirectionDebit },
+			func(l *LedgersModel) { l.Username = "user2" },
+			func(l *LedgersModel) { l.TxUID = "tx2" },
+			func(l *LedgersModel) { l.CreatedAt = l.CreatedAt.Add(time.Microsecond) },
+		}
+		for _, change := range changes {
+			changed := ledger
+			change(&changed)
+			assert.NotEqual(t, hash, ledgerHash("", &changed))
+		}
+		assert.NotEqual(t, hash, ledgerHash("prev", &ledger))
+	})
+}
+
+func Test_insertLedgers(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &ledgers{db: sqlx.NewDb(mockDB, "sqlmock")}
+
+	t.Run("chain to the last entry of the wallet", func(t *testing.T) {
+		mock.ExpectQuery(lastLedgerHashQuery).
+			WithArgs("SGD", "user1").
+			WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("abc"))
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs("tx1", "user1", "SGD", 100, DirectionCredit, sqlmock.AnyArg(), "abc", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		ledger := &LedgersModel{TxUID: "tx1", Username: "user1", Currency: "SGD", Amount: 100, Direction: DirectionCredit}
+		err := p.Insert(t.Context(), ledger)
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", ledger.PrevHash)
+		assert.Equal(t, ledgerHash("abc", ledger), ledger.Hash)
+		assert.Equal(t, ledger.CreatedAt, ledger.CreatedAt.Truncate(time.Microsecond))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("first entry of the wallet", func(t *testing.T) {
+		expectLastLedgerHash(mock, "user1", "JPY")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs("tx1", "user1", "JPY", 100, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := p.Insert(t.Context(), &LedgersModel{TxUID: "tx1", Username: "user1", Currency: "JPY", Amount: 100, Direction: DirectionDebit})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuse entry within closed period", func(t *testing.T) {
+		expectLastLedgerHash(mock, "user1", "SGD")
+		mock.ExpectQuery(periodOpenQuery).
+			WithArgs(time.Now().UTC().Format(balanceDateLayout)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		err := p.Insert(t.Context(), &LedgersModel{TxUID: "tx1", Username: "user1", Currency: "SGD", Amount: 100, Direction: DirectionCredit})
+		assert.ErrorIs(t, err, apierr.PeriodClosed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_ledgers_VerifyChains(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &ledgers{db: sqlx.NewDb(mockDB, "sqlmock")}
+
+	createdAt := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	entry := func(id int, amount int64, direction Direction) LedgersModel {
+		return LedgersModel{ID: id, TxUID: "tx", Username: "user1", Currency: "SGD", Amount: amount, Direction: direction, CreatedAt: createdAt.Add(time.Duration(id) * time.Second)}
+	}
+	expectWallet := func() {
+		mock.ExpectQuery("SELECT username, currency FROM wallets WHERE username = $1 AND currency = $2 ORDER BY username, currency").
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows([]string{"username", "currency"}).AddRow("user1", "SGD"))
+	}
+
+	t.Run("valid chain after legacy entries", func(t *testing.T) {
+		legacy := entry(1, 500, DirectionCredit)
+		chain := chainedLedgers("", entry(2, 100, DirectionDebit), entry(3, 50, DirectionCredit))
+		expectWallet()
+		mock.ExpectQuery(ledgerChainQuery).
+			WithArgs("SGD", "user1").
+			WillReturnRows(ledgerChainRows(legacy, chain[0], chain[1]))
+
+		results, err := p.VerifyChains(t.Context(), "user1", "SGD")
+		assert.NoError(t, err)
+		assert.Equal(t, []ChainVerificationModel{{Username: "user1", Currency: "SGD", Checked: 3, Unchained: 1}}, results)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("edited amount", func(t *testing.T) {
+		chain := chainedLedgers("", entry(1, 100, DirectionCredit), entry(2, 50, DirectionDebit), entry(3, 10, DirectionDebit))
+		chain[1].Amount = 5
+		expectWallet()
+		mock.ExpectQuery(ledgerChainQuery).
+			WithArgs("SGD", "user1").
+			WillReturnRows(ledgerChainRows(chain...))
+
+		results, err := p.VerifyChains(t.Context(), "user1", "SGD")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, results[0].Checked)
+		assert.Equal(t, 2, results[0].BrokenLink.LedgerID)
+		assert.Equal(t, ReasonHashMismatch, results[0].BrokenLink.Reason)
+		assert.Equal(t, chain[1].Hash, results[0].BrokenLink.ActualHash)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deleted entry", func(t *testing.T) {
+		chain := chainedLedgers("", entry(1, 100, DirectionCredit), entry(2, 50, DirectionDebit), entry(3, 10, DirectionDebit))
+		expectWallet()
+		mock.ExpectQuery(ledgerChainQuery).
+			WithArgs("SGD", "user1").
+			WillReturnRows(ledgerChainRows(chain[0], chain[2]))
+
+		results, err := p.VerifyChains(t.Context(), "user1", "SGD")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, results[0].BrokenLink.LedgerID)
+		assert.Equal(t, ReasonPrevHashMismatch, results[0].BrokenLink.Reason)
+		assert.Equal(t, chain[0].Hash, results[0].BrokenLink.ExpectedHash)
+		assert.Equal(t, chain[1].Hash, results[0].BrokenLink.ActualHash)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("cleared hash after chain started", func(t *testing.T) {
+		chain := chainedLedgers("", entry(1, 100, DirectionCredit), entry(2, 50, DirectionDebit))
+		chain[1].Hash = ""
+		expectWallet()
+		mock.ExpectQuery(ledgerChainQuery).
+			WithArgs("SGD", "user1").
+			WillReturnRows(ledgerChainRows(chain...))
+
+		results, err := p.VerifyChains(t.Context(), "user1", "SGD")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, results[0].BrokenLink.LedgerID)
+		assert.Equal(t, ReasonMissingHash, results[0].BrokenLink.Reason)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("verify every wallet", func(t *testing.T) {
+		mock.ExpectQuery("SELECT username, currency FROM wallets ORDER BY username, currency").
+			WillReturnRows(sqlmock.NewRows([]string{"username", "currency"}).AddRow("user1", "JPY").AddRow("user1", "SGD"))
+		mock.ExpectQuery(ledgerChainQuery).
+			WithArgs("JPY", "user1").
+			WillReturnRows(ledgerChainRows())
+		mock.ExpectQuery(ledgerChainQuery).
+			WithArgs("SGD", "user1").
+			WillReturnRows(ledgerChainRows(chainedLedgers("", entry(1, 100, DirectionCredit))...))
+
+		results, err := p.VerifyChains(t.Context(), "", "")
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, 0, results[0].Checked)
+		assert.Equal(t, 1, results[1].Checked)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_ledgers_List(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &ledgers{db: sqlx.NewDb(mockDB, "sqlmock")}
+
+	listQuery := "SELECT le.id AS ledger_id, t.uid, t.type, t.status, le.direction, le.amount, le.currency, t.metadata, le.created_at FROM ledgers le " +
+		"JOIN transactions t ON t.uid = le.tx_uid WHERE le.currency = $1 AND le.username = $2"
+	historyColumns := []string{"ledger_id", "uid", "type", "status", "direction", "amount", "currency", "metadata", "created_at"}
+	createdAt := time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC)
+	cursor := &HistoryCursor{CreatedAt: createdAt, LedgerID: 7}
+
+	t.Run("ok first page", func(t *testing.T) {
+		mock.ExpectQuery(listQuery+" ORDER BY le.created_at DESC, le.id DESC LIMIT 2").
+			WithArgs("SGD", "user1").
+			WillReturnRows(sqlmock.NewRows(historyColumns).
+				AddRow(2, "uid1", TypeTransfer, StatusCompleted, DirectionCredit, 100, "SGD", nil, createdAt).
+				AddRow(1, "uid1", TypeTransfer, StatusCompleted, DirectionDebit, 100, "SGD", nil, createdAt))
+
+		histories, hasMore, err := p.List(t.Context(), HistoryPage{Limit: 1}, "SGD", "user1", HistoryFilter{})
+		assert.NoError(t, err)
+		assert.True(t, hasMore)
+		assert.Len(t, histories, 1)
+		assert.Equal(t, 2, histories[0].LedgerID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ok starting after with every filter", func(t *testing.T) {
+		from := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 1, 0)
+		mock.ExpectQuery(listQuery+" AND (le.created_at, le.id) < ($3, $4) AND t.type IN ($5,$6) AND t.status IN ($7) AND le.direction = $8 "+
+			"AND le.amount >= $9 AND le.amount <= $10 AND le.created_at >= $11 AND le.created_at < $12 AND t.reference = $13 "+
+			"ORDER BY le.created_at DESC, le.id DESC LIMIT 21").
+			WithArgs("SGD", "user1", createdAt, 7, TypeWithdraw, TypeTransfer, StatusFailed, DirectionDebit, 10000, 50000, from, to, "rent").
+			WillReturnRows(sqlmock.NewRows(historyColumns).
+				AddRow(5, "uid1", TypeWithdraw, StatusFailed, DirectionDebit, 20000, "SGD", nil, createdAt))
+
+		histories, hasMore, err := p.List(t.Context(), HistoryPage{Limit: 20, StartingAfter: cursor}, "SGD", "user1", HistoryFilter{
+			Types:       []TxType{TypeWithdraw, TypeTransfer},
+			Statuses:    []TxStatus{StatusFailed},
+			Direction:   DirectionDebit,
+			MinAmount:   10000,
+			MaxAmount:   50000,
+			CreatedFrom: from,
+			CreatedTo:   to,
+			Reference:   "rent",
+		})
+		assert.NoError(t, err)
+		assert.False(t, hasMore)
+		assert.Equal(t, StatusFailed, histories[0].Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ok ending before is returned newest first", func(t *testing.T) {
+		mock.ExpectQuery(listQuery+" AND (le.created_at, le.id) > ($3, $4) ORDER BY le.created_at, le.id LIMIT 3").
+			WithArgs("SGD", "user1", createdAt, 7).
+			WillReturnRows(sqlmock.NewRows(historyColumns).
+				AddRow(8, "uid2", TypeDeposit, StatusCompleted, DirectionCredit, 100, "SGD", nil, createdAt).
+				AddRow(9, "uid3", TypeDeposit, StatusCompleted, DirectionCredit, 100, "SGD", nil, createdAt.Add(time.Second)).
+				AddRow(10, "uid4", TypeDeposit, StatusCompleted, DirectionCredit, 100, "SGD", nil, createdAt.Add(2*time.Second)))
+
+		histories, hasMore, err := p.List(t.Context(), HistoryPage{Limit: 2, EndingBefore: cursor}, "SGD", "user1", HistoryFilter{})
+		assert.NoError(t, err)
+		assert.True(t, hasMore)
+		assert.Equal(t, []int{9, 8}, []int{histories[0].LedgerID, histories[1].LedgerID})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,230 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+//go:generate mockery --name LimitsRepository --output ./mocks --outpkg mocks --case=underscore
+type LimitsRepository interface {
+	Usage(ctx context.Context, username, currency string) (*LimitsModel, *LimitUsageModel, error)
+	Set(ctx context.Context, limits LimitsModel) (*LimitsModel, error)
+}
+
+// LimitsModel caps the amount moved by a wallet, zero means unlimited.
+// The row with empty username is the default of every user without own limits.
+type LimitsModel struct {
+	ID              int       `db:"id"`
+	Username        string    `db:"username"`
+	Currency        string    `db:"currency"`
+	PerTransaction  int64     `db:"per_transaction"`
+	DailyWithdraw   int64     `db:"daily_withdraw"`
+	MonthlyWithdraw int64     `db:"monthly_withdraw"`
+	DailyTransfer   int64     `db:"daily_transfer"`
+	MonthlyTransfer int64     `db:"monthly_transfer"`
+	MaxBalance      int64     `db:"max_balance"`
+	UpdatedBy       string    `db:"updated_by"`
+	CreatedAt       time.Time `db:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at"`
+}
+
+// LimitUsageModel is the completed amount initiated by the wallet within the current UTC day and month.
+type LimitUsageModel struct {
+	DailyWithdraw   int64 `db:"daily_withdraw"`
+	MonthlyWithdraw int64 `db:"monthly_withdraw"`
+	DailyTransfer   int64 `db:"daily_transfer"`
+	MonthlyTransfer int64 `db:"monthly_transfer"`
+	Balance         int64 `db:"-"`
+}
+
+var limitsColumns = []string{"id", "username", "currency", "per_transaction", "daily_withdraw", "monthly_withdraw", "daily_transfer", "monthly_transfer", "max_balance", "updated_by", "created_at", "updated_at"}
+
+type limits struct {
+	db *sqlx.DB
+}
+
+func NewLimits(dao *DAO) *limits {
+	return &limits{
+		db: dao.db,
+	}
+}
+
+func (p *limits) Usage(ctx context.Context, username, currency string) (*LimitsModel, *LimitUsageModel, error) {
+	wallet, err := get(ctx, p.db, username, currency, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	limits, err := getLimits(ctx, p.db, username, currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	usage, err := getLimitUsage(ctx, p.db, username, currency, time.Now().UTC())
+	if err != nil {
+		return nil, nil, err
+	}
+	usage.Balance = wallet.Amount
+	return limits, usage, nil
+}
+
+func (p *limits) Set(ctx context.Context, limits LimitsModel) (*LimitsModel, error) {
+	query, args, err := psql.Insert("transaction_limits").
+		Columns("username", "currency", "per_transaction", "daily_withdraw", "monthly_withdraw", "daily_transfer", "monthly_transfer", "max_balance", "updated_by").
+		Values(limits.Username, limits.Currency, limits.PerTransaction, limits.DailyWithdraw, limits.MonthlyWithdraw, limits.DailyTransfer, limits.MonthlyTransfer, limits.MaxBalance, limits.UpdatedBy).
+		Suffix("ON CONFLICT (username, currency) DO UPDATE SET " +
+			"per_transaction = EXCLUDED.per_transaction, daily_withdraw = EXCLUDED.daily_withdraw, monthly_withdraw = EXCLUDED.monthly_withdraw, " +
+			"daily_transfer = EXCLUDED.daily_transfer, monthly_transfer = EXCLUDED.monthly_transfer, max_balance = EXCLUDED.max_balance, " +
+			"updated_by = EXCLUDED.updated_by, updated_at = NOW()").
+		Suffix("RETURNING " + strings.Join(limitsColumns, ", ")).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build set limits query with err: %s", err)
+		return nil, fmt.Errorf("build set limits query: %w", err)
+	}
+	saved := new(LimitsModel)
+	err = p.db.QueryRowxContext(ctx, query, args...).StructScan(saved)
+	if err != nil {
+		log.Error(ctx, "failed to set limits of %q %s with err: %s", limits.Username, limits.Currency, err)
+		return nil, fmt.Errorf("set limits: %w", err)
+	}
+	log.Info(ctx, "limits of %q %s changed by %s", limits.Username, limits.Currency, limits.UpdatedBy)
+	return saved, nil
+}
+
+// getLimits returns the limits of the user, or the default limits when the user doesn't have own limits.
+// It is unlimited when neither is configured.
+func getLimits(ctx context.Context, exec sqlx.ExtContext, username, currency string) (*LimitsModel, error) {
+	query, args, err := psql.Select(limitsColumns...).
+		From("transaction_limits").
+		Where(squirrel.Eq{
+			"username": []string{username, ""},
+			"currency": currency,
+		}).
+		// the own limits of the user sort before the default with empty username
+		OrderBy("username DESC").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build get limits query with err: %s", err)
+		return nil, fmt.Errorf("build get limits query: %w", err)
+	}
+	limits := new(LimitsModel)
+	err = exec.QueryRowxContext(ctx, query, args...).StructScan(limits)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &LimitsModel{Currency: currency}, nil
+		}
+		log.Error(ctx, "failed to get limits of %s %s with err: %s", username, currency, err)
+		return nil, fmt.Errorf("get limits: %w", err)
+	}
+	return limits, nil
+}
+
+// getLimitUsage sums the completed withdraw and transfer initiated by the user since the start of the UTC day and month of now,
+// the bulk transfer, the hold and the exchange are counted as transfer. A hold still authorized counts with its whole amount, so the
+// limit is reserved until it is captured or released.
+func getLimitUsage(ctx context.Context, exec sqlx.ExtContext, username, currency string, now time.Time) (*LimitUsageModel, error) {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	query, args, err := psql.Select().
+		Column("COALESCE(SUM(amount) FILTER (WHERE type = ? AND created_at >= ?), 0) AS daily_withdraw", TypeWithdraw, dayStart).
+		Column("COALESCE(SUM(amount) FILTER (WHERE type = ?), 0) AS monthly_withdraw", TypeWithdraw).
+		Column("COALESCE(SUM(amount) FILTER (WHERE type IN (?,?,?,?) AND created_at >= ?), 0) AS daily_transfer", TypeTransfer, TypeBulkTransfer, TypeHold, TypeExchange, dayStart).
+		Column("COALESCE(SUM(amount) FILTER (WHERE type IN (?,?,?,?)), 0) AS monthly_transfer", TypeTransfer, TypeBulkTransfer, TypeHold, TypeExchange).
+		From("transactions").
+		Where(squirrel.Eq{
+			"initiated_by": username,
+			"currency":     currency,
+			"type":         []TxType{TypeWithdraw, TypeTransfer, TypeBulkTransfer, TypeHold, TypeExchange},
+		}).
+		Where(squirrel.Or{
+			squirrel.Eq{"status": StatusCompleted},
+			squirrel.Eq{"type": TypeHold, "status": StatusPending},
+		}).
+		Where("created_at >= ?", monthStart).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build limit usage query with err: %s", err)
+		return nil, fmt.Errorf("build limit usage query: %w", err)
+	}
+	usage := new(LimitUsageModel)
+	err = exec.QueryRowxContext(ctx, query, args...).StructScan(usage)
+	if err != nil {
+		log.Error(ctx, "failed to get limit usage of %s %s with err: %s", username, currency, err)
+		return nil, fmt.Errorf("get limit usage: %w", err)
+	}
+	return usage, nil
+}
+
+func limitExceeded(name string, limit int64, currency string) error {
+	return fmt.Errorf("%w: %s limit of %d %s", apierr.LimitExceeded, name, limit, currency)
+}
+
+// checkDebitLimits checks the per transaction limit and the cumulative limit of txType before the wallet is debited,
+// the bulk transfer is checked against the transfer limits with the total of its items, the hold with its authorized amount
+// and the exchange with its debited amount.
+// The caller must hold the lock of the wallet, so the usage can't change until the transaction is committed.
+func checkDebitLimits(ctx context.Context, exec sqlx.ExtContext, username string, txType TxType, amount money.Money) error {
+	currency := amount.Currency().Code
+	limits, err := getLimits(ctx, exec, username, currency)
+	if err != nil {
+		return err
+	}
+	if limits.PerTransaction > 0 && amount.Amount() > limits.PerTransaction {
+		return limitExceeded("per transaction", limits.PerTransaction, currency)
+	}
+	daily, monthly := limits.DailyWithdraw, limits.MonthlyWithdraw
+	if txType != TypeWithdraw {
+		daily, monthly = limits.DailyTransfer, limits.MonthlyTransfer
+	}
+	if daily == 0 && monthly == 0 {
+		return nil
+	}
+	usage, err := getLimitUsage(ctx, exec, username, currency, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	dailyUsed, monthlyUsed := usage.DailyWithdraw, usage.MonthlyWithdraw
+	if txType != TypeWithdraw {
+		dailyUsed, monthlyUsed = usage.DailyTransfer, usage.MonthlyTransfer
+	}
+	if daily > 0 && dailyUsed > daily-amount.Amount() {
+		return limitExceeded("daily "+string(txType), daily, currency)
+	}
+	if monthly > 0 && monthlyUsed > monthly-amount.Amount() {
+		return limitExceeded("monthly "+string(txType), monthly, currency)
+	}
+	return nil
+}
+
+// checkCreditLimits checks the max balance of the wallet locked by the caller before it is credited,
+// the per transaction limit only applies when the wallet owner initiates the credit.
+// The credit is refused as well when the balance would overflow.
+func checkCreditLimits(ctx context.Context, exec sqlx.ExtContext, wallet *WalletsModel, amount money.Money, initiator bool) error {
+	currency := amount.Currency().Code
+	balance, err := money.New(wallet.Amount, amount.Currency()).Add(amount)
+	if err != nil {
+		log.Error(ctx, "failed to credit %s to wallet %s with balance %d with err: %s", amount, wallet.Username, wallet.Amount, err)
+		return err
+	}
+	limits, err := getLimits(ctx, exec, wallet.Username, currency)
+	if err != nil {
+		return err
+	}
+	if initiator && limits.PerTransaction > 0 && amount.Amount() > limits.PerTransaction {
+		return limitExceeded("per transaction", limits.PerTransaction, currency)
+	}
+	if limits.MaxBalance > 0 && balance.Amount() > limits.MaxBalance {
+		return limitExceeded("max balance", limits.MaxBalance, currency)
+	}
+	return nil
+}
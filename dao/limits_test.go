@@ -0,0 +1,257 @@
+package dao
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	getLimitsQuery = "SELECT id, username, currency, per_transaction, daily_withdraw, monthly_withdraw, daily_transfer, monthly_transfer, max_balance, updated_by, created_at, updated_at " +
+		"FROM transaction_limits WHERE currency = $1 AND username IN ($2,$3) ORDER BY username DESC LIMIT 1"
+	limitUsageQuery = "SELECT COALESCE(SUM(amount) FILTER (WHERE type = $1 AND created_at >= $2), 0) AS daily_withdraw, " +
+		"COALESCE(SUM(amount) FILTER (WHERE type = $3), 0) AS monthly_withdraw, " +
+		"COALESCE(SUM(amount) FILTER (WHERE type IN ($4,$5,$6,$7) AND created_at >= $8), 0) AS daily_transfer, " +
+		"COALESCE(SUM(amount) FILTER (WHERE type IN ($9,$10,$11,$12)), 0) AS monthly_transfer " +
+		"FROM transactions WHERE currency = $13 AND initiated_by = $14 AND type IN ($15,$16,$17,$18,$19) AND (status = $20 OR status = $21 AND type = $22) AND created_at >= $23"
+)
+
+var limitUsageColumns = []string{"daily_withdraw", "monthly_withdraw", "daily_transfer", "monthly_transfer"}
+
+// expectNoLimits expects the limits lookup of a wallet without own or default limits.
+func expectNoLimits(mock sqlmock.Sqlmock, username, currency string) {
+	mock.ExpectQuery(getLimitsQuery).
+		WithArgs(currency, username, "").
+		WillReturnRows(sqlmock.NewRows(limitsColumns))
+}
+
+func expectLimits(mock sqlmock.Sqlmock, username, currency string, l LimitsModel) {
+	now := time.Now()
+	mock.ExpectQuery(getLimitsQuery).
+		WithArgs(currency, username, "").
+		WillReturnRows(sqlmock.NewRows(limitsColumns).AddRow(1, l.Username, currency, l.PerTransaction, l.DailyWithdraw, l.MonthlyWithdraw, l.DailyTransfer, l.MonthlyTransfer, l.MaxBalance, "admin", now, now))
+}
+
+func expectLimitUsage(mock sqlmock.Sqlmock, username, currency string, u LimitUsageModel) {
+	mock.ExpectQuery(limitUsageQuery).
+		WithArgs(TypeWithdraw, sqlmock.AnyArg(), TypeWithdraw, TypeTransfer, TypeBulkTransfer, TypeHold, TypeExchange, sqlmock.AnyArg(), TypeTransfer, TypeBulkTransfer, TypeHold, TypeExchange,
+			currency, username, TypeWithdraw, TypeTransfer, TypeBulkTransfer, TypeHold, TypeExchange, StatusCompleted, StatusPending, TypeHold, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(limitUsageColumns).AddRow(u.DailyWithdraw, u.MonthlyWithdraw, u.DailyTransfer, u.MonthlyTransfer))
+}
+
+func Test_NewLimits(t *testing.T) {
+	mockDB, _, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	daoInstance := &DAO{sqlx.NewDb(mockDB, "sqlmocklimits")}
+	limitsDao := NewLimits(daoInstance)
+	assert.Equal(t, daoInstance.db.DriverName(), limitsDao.db.DriverName())
+	assert.Implements(t, (*LimitsRepository)(nil), limitsDao)
+}
+
+func Test_limits_Usage(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &limits{db: sqlx.NewDb(mockDB, "sqlmock")}
+
+	t.Run("ok usage with default limits", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 5000, "active"))
+		expectLimits(mock, "user1", "SGD", LimitsModel{DailyWithdraw: 1000, MaxBalance: 10000})
+		expectLimitUsage(mock, "user1", "SGD", LimitUsageModel{DailyWithdraw: 300, MonthlyWithdraw: 800, MonthlyTransfer: 50})
+
+		l, usage, err := p.Usage(t.Context(), "user1", "SGD")
+		assert.NoError(t, err)
+		assert.Equal(t, "", l.Username)
+		assert.Equal(t, int64(1000), l.DailyWithdraw)
+		assert.Equal(t, LimitUsageModel{DailyWithdraw: 300, MonthlyWithdraw: 800, MonthlyTransfer: 50, Balance: 5000}, *usage)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("wallet not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+			WithArgs("user1", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns))
+
+		_, _, err := p.Usage(t.Context(), "user1", "JPY")
+		assert.ErrorIs(t, err, apierr.NotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_limits_Set(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &limits{db: sqlx.NewDb(mockDB, "sqlmock")}
+	query := "INSERT INTO transaction_limits (username,currency,per_transaction,daily_withdraw,monthly_withdraw,daily_transfer,monthly_transfer,max_balance,updated_by) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) " +
+		"ON CONFLICT (username, currency) DO UPDATE SET per_transaction = EXCLUDED.per_transaction, daily_withdraw = EXCLUDED.daily_withdraw, monthly_withdraw = EXCLUDED.monthly_withdraw, " +
+		"daily_transfer = EXCLUDED.daily_transfer, monthly_transfer = EXCLUDED.monthly_transfer, max_balance = EXCLUDED.max_balance, updated_by = EXCLUDED.updated_by, updated_at = NOW() " +
+		"RETURNING id, username, currency, per_transaction, daily_withdraw, monthly_withdraw, daily_transfer, monthly_transfer, max_balance, updated_by, created_at, updated_at"
+
+	t.Run("ok set limits", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectQuery(query).
+			WithArgs("user1", "SGD", 500, 1000, 5000, 0, 0, 0, "admin").
+			WillReturnRows(sqlmock.NewRows(limitsColumns).AddRow(3, "user1", "SGD", 500, 1000, 5000, 0, 0, 0, "admin", now, now))
+
+		saved, err := p.Set(t.Context(), LimitsModel{Username: "user1", Currency: "SGD", PerTransaction: 500, DailyWithdraw: 1000, MonthlyWithdraw: 5000, UpdatedBy: "admin"})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, saved.ID)
+		assert.Equal(t, int64(5000), saved.MonthlyWithdraw)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("set limits error", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("", "SGD", 0, 0, 0, 0, 0, 0, "admin").
+			WillReturnError(errors.New("err"))
+
+		_, err := p.Set(t.Context(), LimitsModel{Currency: "SGD", UpdatedBy: "admin"})
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_wallets_Limits(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &wallets{db: sqlx.NewDb(mockDB, "sqlmock")}
+
+	expectLock := func(username string, amount int) {
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs(username, "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, username, amount, "active"))
+	}
+
+	t.Run("withdraw exceed per transaction limit", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLock("user1", 1000)
+		expectLimits(mock, "user1", "SGD", LimitsModel{PerTransaction: 100})
+		mock.ExpectRollback()
+
+		_, err := p.Withdraw(t.Context(), "user1", "ref", money.New(101, currency.SGD), nil)
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.ErrorContains(t, err, "per transaction limit of 100 SGD")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("withdraw exceed daily limit", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLock("user1", 1000)
+		expectLimits(mock, "user1", "SGD", LimitsModel{Username: "user1", DailyWithdraw: 500, MonthlyWithdraw: 5000})
+		expectLimitUsage(mock, "user1", "SGD", LimitUsageModel{DailyWithdraw: 450, MonthlyWithdraw: 450})
+		mock.ExpectRollback()
+
+		_, err := p.Withdraw(t.Context(), "user1", "ref", money.New(51, currency.SGD), nil)
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.ErrorContains(t, err, "daily withdraw limit of 500 SGD")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("withdraw within limits", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLock("user1", 1000)
+		expectLimits(mock, "user1", "SGD", LimitsModel{DailyWithdraw: 500})
+		// the transfer usage doesn't count toward the withdraw limit
+		expectLimitUsage(mock, "user1", "SGD", LimitUsageModel{DailyWithdraw: 450, DailyTransfer: 1000})
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), TypeWithdraw, "user1", "SGD", 50, StatusCompleted, "ref").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-50, "SGD", "user1", WalletStatusActive, 50).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user1", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user1", "SGD", 50, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletDebited, "user1", "SGD")
+		mock.ExpectCommit()
+
+		_, err := p.Withdraw(t.Context(), "user1", "ref", money.New(50, currency.SGD), nil)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("transfer exceed monthly limit", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLock("user1", 1000)
+		expectLock("user2", 0)
+		expectLimits(mock, "user1", "SGD", LimitsModel{MonthlyTransfer: 1000})
+		expectLimitUsage(mock, "user1", "SGD", LimitUsageModel{MonthlyTransfer: 900})
+		mock.ExpectRollback()
+
+		_, err := p.Transfer(t.Context(), "user1", "user2", "ref", money.New(101, currency.SGD), nil)
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.ErrorContains(t, err, "monthly transfer limit of 1000 SGD")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("transfer exceed max balance of receiver", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLock("user1", 1000)
+		expectLock("user2", 950)
+		expectNoLimits(mock, "user1", "SGD")
+		// the per transaction limit of receiver doesn't apply to the transfer it doesn't initiate
+		expectLimits(mock, "user2", "SGD", LimitsModel{PerTransaction: 10, MaxBalance: 1000})
+		mock.ExpectRollback()
+
+		_, err := p.Transfer(t.Context(), "user1", "user2", "ref", money.New(51, currency.SGD), nil)
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.ErrorContains(t, err, "max balance limit of 1000 SGD")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deposit exceed max balance", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLock("user1", 1000)
+		expectLimits(mock, "user1", "SGD", LimitsModel{MaxBalance: 1000})
+		mock.ExpectRollback()
+
+		_, err := p.Deposit(t.Context(), "user1", "ref", money.New(1, currency.SGD), nil)
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deposit exceed per transaction limit", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLock("user1", 0)
+		expectLimits(mock, "user1", "SGD", LimitsModel{PerTransaction: 100})
+		mock.ExpectRollback()
+
+		_, err := p.Deposit(t.Context(), "user1", "ref", money.New(101, currency.SGD), nil)
+		assert.ErrorIs(t, err, apierr.LimitExceeded)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deposit overflow balance", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectLock("user1", math.MaxInt64)
+		mock.ExpectRollback()
+
+		_, err := p.Deposit(t.Context(), "user1", "ref", money.New(1, currency.SGD), nil)
+		assert.ErrorIs(t, err, money.ErrOverflow)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
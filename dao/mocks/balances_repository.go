@@ -0,0 +1,158 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	dao "github.com/lengzuo/fundflow/dao"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// BalancesRepository is an autogenerated mock type for the BalancesRepository type
+type BalancesRepository struct {
+	mock.Mock
+}
+
+// AsOf provides a mock function with given fields: ctx, username, currency, before
+func (_m *BalancesRepository) AsOf(ctx context.Context, username string, currency string, before time.Time) (*dao.BalanceAsOfModel, error) {
+	ret := _m.Called(ctx, username, currency, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AsOf")
+	}
+
+	var r0 *dao.BalanceAsOfModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (*dao.BalanceAsOfModel, error)); ok {
+		return rf(ctx, username, currency, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) *dao.BalanceAsOfModel); ok {
+		r0 = rf(ctx, username, currency, before)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.BalanceAsOfModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, username, currency, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClosePeriod provides a mock function with given fields: ctx, day
+func (_m *BalancesRepository) ClosePeriod(ctx context.Context, day time.Time) (*dao.AccountingPeriodsModel, error) {
+	ret := _m.Called(ctx, day)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClosePeriod")
+	}
+
+	var r0 *dao.AccountingPeriodsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (*dao.AccountingPeriodsModel, error)); ok {
+		return rf(ctx, day)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) *dao.AccountingPeriodsModel); ok {
+		r0 = rf(ctx, day)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.AccountingPeriodsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, day)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LatestClosedPeriod provides a mock function with given fields: ctx
+func (_m *BalancesRepository) LatestClosedPeriod(ctx context.Context) (*dao.AccountingPeriodsModel, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LatestClosedPeriod")
+	}
+
+	var r0 *dao.AccountingPeriodsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*dao.AccountingPeriodsModel, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *dao.AccountingPeriodsModel); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.AccountingPeriodsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAsOf provides a mock function with given fields: ctx, before, filter
+func (_m *BalancesRepository) ListAsOf(ctx context.Context, before time.Time, filter dao.BalanceFilter) ([]dao.BalanceAsOfModel, bool, error) {
+	ret := _m.Called(ctx, before, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAsOf")
+	}
+
+	var r0 []dao.BalanceAsOfModel
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, dao.BalanceFilter) ([]dao.BalanceAsOfModel, bool, error)); ok {
+		return rf(ctx, before, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, dao.BalanceFilter) []dao.BalanceAsOfModel); ok {
+		r0 = rf(ctx, before, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.BalanceAsOfModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, dao.BalanceFilter) bool); ok {
+		r1 = rf(ctx, before, filter)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, time.Time, dao.BalanceFilter) error); ok {
+		r2 = rf(ctx, before, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// NewBalancesRepository creates a new instance of BalancesRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewBalancesRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *BalancesRepository {
+	mock := &BalancesRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
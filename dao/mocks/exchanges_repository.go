@@ -0,0 +1,86 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	dao "github.com/lengzuo/fundflow/dao"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ExchangesRepository is an autogenerated mock type for the ExchangesRepository type
+type ExchangesRepository struct {
+	mock.Mock
+}
+
+// Exchange provides a mock function with given fields: ctx, quoteUID, username, receiver, reference
+func (_m *ExchangesRepository) Exchange(ctx context.Context, quoteUID string, username string, receiver string, reference string) (*dao.TransactionsModel, *dao.FXQuotesModel, error) {
+	ret := _m.Called(ctx, quoteUID, username, receiver, reference)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Exchange")
+	}
+
+	var r0 *dao.TransactionsModel
+	var r1 *dao.FXQuotesModel
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) (*dao.TransactionsModel, *dao.FXQuotesModel, error)); ok {
+		return rf(ctx, quoteUID, username, receiver, reference)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) *dao.TransactionsModel); ok {
+		r0 = rf(ctx, quoteUID, username, receiver, reference)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.TransactionsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) *dao.FXQuotesModel); ok {
+		r1 = rf(ctx, quoteUID, username, receiver, reference)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*dao.FXQuotesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, string, string) error); ok {
+		r2 = rf(ctx, quoteUID, username, receiver, reference)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// InsertQuote provides a mock function with given fields: ctx, quote
+func (_m *ExchangesRepository) InsertQuote(ctx context.Context, quote *dao.FXQuotesModel) error {
+	ret := _m.Called(ctx, quote)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InsertQuote")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *dao.FXQuotesModel) error); ok {
+		r0 = rf(ctx, quote)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewExchangesRepository creates a new instance of ExchangesRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewExchangesRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ExchangesRepository {
+	mock := &ExchangesRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,181 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	dao "github.com/lengzuo/fundflow/dao"
+	mock "github.com/stretchr/testify/mock"
+
+	money "github.com/lengzuo/fundflow/utils/money"
+
+	time "time"
+)
+
+// HoldsRepository is an autogenerated mock type for the HoldsRepository type
+type HoldsRepository struct {
+	mock.Mock
+}
+
+// Authorize provides a mock function with given fields: ctx, username, receiver, reference, amount, expiresAt
+func (_m *HoldsRepository) Authorize(ctx context.Context, username string, receiver string, reference string, amount money.Money, expiresAt time.Time) (*dao.HoldsModel, error) {
+	ret := _m.Called(ctx, username, receiver, reference, amount, expiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Authorize")
+	}
+
+	var r0 *dao.HoldsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, money.Money, time.Time) (*dao.HoldsModel, error)); ok {
+		return rf(ctx, username, receiver, reference, amount, expiresAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, money.Money, time.Time) *dao.HoldsModel); ok {
+		r0 = rf(ctx, username, receiver, reference, amount, expiresAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.HoldsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, money.Money, time.Time) error); ok {
+		r1 = rf(ctx, username, receiver, reference, amount, expiresAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Capture provides a mock function with given fields: ctx, txUID, actor, amount
+func (_m *HoldsRepository) Capture(ctx context.Context, txUID string, actor string, amount int64) (*dao.HoldsModel, error) {
+	ret := _m.Called(ctx, txUID, actor, amount)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Capture")
+	}
+
+	var r0 *dao.HoldsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) (*dao.HoldsModel, error)); ok {
+		return rf(ctx, txUID, actor, amount)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) *dao.HoldsModel); ok {
+		r0 = rf(ctx, txUID, actor, amount)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.HoldsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64) error); ok {
+		r1 = rf(ctx, txUID, actor, amount)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExpireDue provides a mock function with given fields: ctx, limit
+func (_m *HoldsRepository) ExpireDue(ctx context.Context, limit int) (int, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExpireDue")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (int, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) int); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Get provides a mock function with given fields: ctx, txUID, actor
+func (_m *HoldsRepository) Get(ctx context.Context, txUID string, actor string) (*dao.HoldsModel, error) {
+	ret := _m.Called(ctx, txUID, actor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 *dao.HoldsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dao.HoldsModel, error)); ok {
+		return rf(ctx, txUID, actor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dao.HoldsModel); ok {
+		r0 = rf(ctx, txUID, actor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.HoldsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, txUID, actor)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Void provides a mock function with given fields: ctx, txUID, actor
+func (_m *HoldsRepository) Void(ctx context.Context, txUID string, actor string) (*dao.HoldsModel, error) {
+	ret := _m.Called(ctx, txUID, actor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Void")
+	}
+
+	var r0 *dao.HoldsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dao.HoldsModel, error)); ok {
+		return rf(ctx, txUID, actor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dao.HoldsModel); ok {
+		r0 = rf(ctx, txUID, actor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.HoldsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, txUID, actor)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewHoldsRepository creates a new instance of HoldsRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewHoldsRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *HoldsRepository {
+	mock := &HoldsRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
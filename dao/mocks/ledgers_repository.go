@@ -7,6 +7,8 @@ import (
 
 	dao "github.com/lengzuo/fundflow/dao"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // LedgersRepository is an autogenerated mock type for the LedgersRepository type
@@ -32,9 +34,9 @@ func (_m *LedgersRepository) Insert(ctx context.Context, user *dao.LedgersModel)
 	return r0
 }
 
-// List provides a mock function with given fields: ctx, limit, startingAfter, currency, username
-func (_m *LedgersRepository) List(ctx context.Context, limit int, startingAfter string, currency string, username string) ([]dao.TxHistoryModel, bool, error) {
-	ret := _m.Called(ctx, limit, startingAfter, currency, username)
+// List provides a mock function with given fields: ctx, page, currency, username, filter
+func (_m *LedgersRepository) List(ctx context.Context, page dao.HistoryPage, currency string, username string, filter dao.HistoryFilter) ([]dao.TxHistoryModel, bool, error) {
+	ret := _m.Called(ctx, page, currency, username, filter)
 
 	if len(ret) == 0 {
 		panic("no return value specified for List")
@@ -43,25 +45,25 @@ func (_m *LedgersRepository) List(ctx context.Context, limit int, startingAfter
 	var r0 []dao.TxHistoryModel
 	var r1 bool
 	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, string, string, string) ([]dao.TxHistoryModel, bool, error)); ok {
-		return rf(ctx, limit, startingAfter, currency, username)
+	if rf, ok := ret.Get(0).(func(context.Context, dao.HistoryPage, string, string, dao.HistoryFilter) ([]dao.TxHistoryModel, bool, error)); ok {
+		return rf(ctx, page, currency, username, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int, string, string, string) []dao.TxHistoryModel); ok {
-		r0 = rf(ctx, limit, startingAfter, currency, username)
+	if rf, ok := ret.Get(0).(func(context.Context, dao.HistoryPage, string, string, dao.HistoryFilter) []dao.TxHistoryModel); ok {
+		r0 = rf(ctx, page, currency, username, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]dao.TxHistoryModel)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int, string, string, string) bool); ok {
-		r1 = rf(ctx, limit, startingAfter, currency, username)
+	if rf, ok := ret.Get(1).(func(context.Context, dao.HistoryPage, string, string, dao.HistoryFilter) bool); ok {
+		r1 = rf(ctx, page, currency, username, filter)
 	} else {
 		r1 = ret.Get(1).(bool)
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, int, string, string, string) error); ok {
-		r2 = rf(ctx, limit, startingAfter, currency, username)
+	if rf, ok := ret.Get(2).(func(context.Context, dao.HistoryPage, string, string, dao.HistoryFilter) error); ok {
+		r2 = rf(ctx, page, currency, username, filter)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -69,6 +71,54 @@ func (_m *LedgersRepository) List(ctx context.Context, limit int, startingAfter
 	return r0, r1, r2
 }
 
+// Statement provides a mock function with given fields: ctx, username, currency, from, to, write
+func (_m *LedgersRepository) Statement(ctx context.Context, username string, currency string, from time.Time, to time.Time, write func(dao.StatementSummaryModel, dao.StatementEntries) error) error {
+	ret := _m.Called(ctx, username, currency, from, to, write)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Statement")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time, time.Time, func(dao.StatementSummaryModel, dao.StatementEntries) error) error); ok {
+		r0 = rf(ctx, username, currency, from, to, write)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyChains provides a mock function with given fields: ctx, username, currency
+func (_m *LedgersRepository) VerifyChains(ctx context.Context, username string, currency string) ([]dao.ChainVerificationModel, error) {
+	ret := _m.Called(ctx, username, currency)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyChains")
+	}
+
+	var r0 []dao.ChainVerificationModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]dao.ChainVerificationModel, error)); ok {
+		return rf(ctx, username, currency)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []dao.ChainVerificationModel); ok {
+		r0 = rf(ctx, username, currency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.ChainVerificationModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, username, currency)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewLedgersRepository creates a new instance of LedgersRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewLedgersRepository(t interface {
@@ -0,0 +1,98 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	dao "github.com/lengzuo/fundflow/dao"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// LimitsRepository is an autogenerated mock type for the LimitsRepository type
+type LimitsRepository struct {
+	mock.Mock
+}
+
+// Set provides a mock function with given fields: ctx, limits
+func (_m *LimitsRepository) Set(ctx context.Context, limits dao.LimitsModel) (*dao.LimitsModel, error) {
+	ret := _m.Called(ctx, limits)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Set")
+	}
+
+	var r0 *dao.LimitsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dao.LimitsModel) (*dao.LimitsModel, error)); ok {
+		return rf(ctx, limits)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dao.LimitsModel) *dao.LimitsModel); ok {
+		r0 = rf(ctx, limits)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.LimitsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dao.LimitsModel) error); ok {
+		r1 = rf(ctx, limits)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Usage provides a mock function with given fields: ctx, username, currency
+func (_m *LimitsRepository) Usage(ctx context.Context, username string, currency string) (*dao.LimitsModel, *dao.LimitUsageModel, error) {
+	ret := _m.Called(ctx, username, currency)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Usage")
+	}
+
+	var r0 *dao.LimitsModel
+	var r1 *dao.LimitUsageModel
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dao.LimitsModel, *dao.LimitUsageModel, error)); ok {
+		return rf(ctx, username, currency)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dao.LimitsModel); ok {
+		r0 = rf(ctx, username, currency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.LimitsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) *dao.LimitUsageModel); ok {
+		r1 = rf(ctx, username, currency)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*dao.LimitUsageModel)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, string) error); ok {
+		r2 = rf(ctx, username, currency)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// NewLimitsRepository creates a new instance of LimitsRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewLimitsRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *LimitsRepository {
+	mock := &LimitsRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
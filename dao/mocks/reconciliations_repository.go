@@ -0,0 +1,128 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	dao "github.com/lengzuo/fundflow/dao"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ReconciliationsRepository is an autogenerated mock type for the ReconciliationsRepository type
+type ReconciliationsRepository struct {
+	mock.Mock
+}
+
+// ListDiscrepancies provides a mock function with given fields: ctx, runID
+func (_m *ReconciliationsRepository) ListDiscrepancies(ctx context.Context, runID int) ([]dao.ReconciliationDiscrepanciesModel, error) {
+	ret := _m.Called(ctx, runID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDiscrepancies")
+	}
+
+	var r0 []dao.ReconciliationDiscrepanciesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]dao.ReconciliationDiscrepanciesModel, error)); ok {
+		return rf(ctx, runID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []dao.ReconciliationDiscrepanciesModel); ok {
+		r0 = rf(ctx, runID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.ReconciliationDiscrepanciesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, runID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRuns provides a mock function with given fields: ctx, limit
+func (_m *ReconciliationsRepository) ListRuns(ctx context.Context, limit int) ([]dao.ReconciliationRunsModel, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRuns")
+	}
+
+	var r0 []dao.ReconciliationRunsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]dao.ReconciliationRunsModel, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []dao.ReconciliationRunsModel); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.ReconciliationRunsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Run provides a mock function with given fields: ctx, trigger, triggeredBy
+func (_m *ReconciliationsRepository) Run(ctx context.Context, trigger dao.ReconciliationTrigger, triggeredBy string) (*dao.ReconciliationRunsModel, []dao.ReconciliationDiscrepanciesModel, error) {
+	ret := _m.Called(ctx, trigger, triggeredBy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Run")
+	}
+
+	var r0 *dao.ReconciliationRunsModel
+	var r1 []dao.ReconciliationDiscrepanciesModel
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, dao.ReconciliationTrigger, string) (*dao.ReconciliationRunsModel, []dao.ReconciliationDiscrepanciesModel, error)); ok {
+		return rf(ctx, trigger, triggeredBy)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dao.ReconciliationTrigger, string) *dao.ReconciliationRunsModel); ok {
+		r0 = rf(ctx, trigger, triggeredBy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.ReconciliationRunsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dao.ReconciliationTrigger, string) []dao.ReconciliationDiscrepanciesModel); ok {
+		r1 = rf(ctx, trigger, triggeredBy)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]dao.ReconciliationDiscrepanciesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, dao.ReconciliationTrigger, string) error); ok {
+		r2 = rf(ctx, trigger, triggeredBy)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// NewReconciliationsRepository creates a new instance of ReconciliationsRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewReconciliationsRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ReconciliationsRepository {
+	mock := &ReconciliationsRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,121 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	dao "github.com/lengzuo/fundflow/dao"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// RecoveriesRepository is an autogenerated mock type for the RecoveriesRepository type
+type RecoveriesRepository struct {
+	mock.Mock
+}
+
+// List provides a mock function with given fields: ctx, txUID
+func (_m *RecoveriesRepository) List(ctx context.Context, txUID string) ([]dao.TransactionRecoveriesModel, error) {
+	ret := _m.Called(ctx, txUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []dao.TransactionRecoveriesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dao.TransactionRecoveriesModel, error)); ok {
+		return rf(ctx, txUID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dao.TransactionRecoveriesModel); ok {
+		r0 = rf(ctx, txUID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.TransactionRecoveriesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, txUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListStuck provides a mock function with given fields: ctx, before, limit
+func (_m *RecoveriesRepository) ListStuck(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	ret := _m.Called(ctx, before, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListStuck")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) ([]string, error)); ok {
+		return rf(ctx, before, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) []string); ok {
+		r0 = rf(ctx, before, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Recover provides a mock function with given fields: ctx, uid, action, recoveredBy, reason
+func (_m *RecoveriesRepository) Recover(ctx context.Context, uid string, action dao.RecoveryAction, recoveredBy string, reason string) (*dao.TransactionRecoveriesModel, error) {
+	ret := _m.Called(ctx, uid, action, recoveredBy, reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Recover")
+	}
+
+	var r0 *dao.TransactionRecoveriesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, dao.RecoveryAction, string, string) (*dao.TransactionRecoveriesModel, error)); ok {
+		return rf(ctx, uid, action, recoveredBy, reason)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, dao.RecoveryAction, string, string) *dao.TransactionRecoveriesModel); ok {
+		r0 = rf(ctx, uid, action, recoveredBy, reason)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.TransactionRecoveriesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, dao.RecoveryAction, string, string) error); ok {
+		r1 = rf(ctx, uid, action, recoveredBy, reason)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewRecoveriesRepository creates a new instance of RecoveriesRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRecoveriesRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RecoveriesRepository {
+	mock := &RecoveriesRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
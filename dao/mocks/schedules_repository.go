@@ -0,0 +1,271 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	dao "github.com/lengzuo/fundflow/dao"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// SchedulesRepository is an autogenerated mock type for the SchedulesRepository type
+type SchedulesRepository struct {
+	mock.Mock
+}
+
+// Cancel provides a mock function with given fields: ctx, uid, username
+func (_m *SchedulesRepository) Cancel(ctx context.Context, uid string, username string) (*dao.TransferSchedulesModel, error) {
+	ret := _m.Called(ctx, uid, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Cancel")
+	}
+
+	var r0 *dao.TransferSchedulesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dao.TransferSchedulesModel, error)); ok {
+		return rf(ctx, uid, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dao.TransferSchedulesModel); ok {
+		r0 = rf(ctx, uid, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.TransferSchedulesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, uid, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Create provides a mock function with given fields: ctx, schedule
+func (_m *SchedulesRepository) Create(ctx context.Context, schedule dao.TransferSchedulesModel) (*dao.TransferSchedulesModel, error) {
+	ret := _m.Called(ctx, schedule)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *dao.TransferSchedulesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dao.TransferSchedulesModel) (*dao.TransferSchedulesModel, error)); ok {
+		return rf(ctx, schedule)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dao.TransferSchedulesModel) *dao.TransferSchedulesModel); ok {
+		r0 = rf(ctx, schedule)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.TransferSchedulesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dao.TransferSchedulesModel) error); ok {
+		r1 = rf(ctx, schedule)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Execute provides a mock function with given fields: ctx, uid, now
+func (_m *SchedulesRepository) Execute(ctx context.Context, uid string, now time.Time) (*dao.TransferScheduleRunsModel, error) {
+	ret := _m.Called(ctx, uid, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Execute")
+	}
+
+	var r0 *dao.TransferScheduleRunsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) (*dao.TransferScheduleRunsModel, error)); ok {
+		return rf(ctx, uid, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) *dao.TransferScheduleRunsModel); ok {
+		r0 = rf(ctx, uid, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.TransferScheduleRunsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(ctx, uid, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Get provides a mock function with given fields: ctx, uid, username
+func (_m *SchedulesRepository) Get(ctx context.Context, uid string, username string) (*dao.TransferSchedulesModel, error) {
+	ret := _m.Called(ctx, uid, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 *dao.TransferSchedulesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dao.TransferSchedulesModel, error)); ok {
+		return rf(ctx, uid, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dao.TransferSchedulesModel); ok {
+		r0 = rf(ctx, uid, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.TransferSchedulesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, uid, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx, username
+func (_m *SchedulesRepository) List(ctx context.Context, username string) ([]dao.TransferSchedulesModel, error) {
+	ret := _m.Called(ctx, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []dao.TransferSchedulesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dao.TransferSchedulesModel, error)); ok {
+		return rf(ctx, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dao.TransferSchedulesModel); ok {
+		r0 = rf(ctx, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.TransferSchedulesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDue provides a mock function with given fields: ctx, now, limit
+func (_m *SchedulesRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	ret := _m.Called(ctx, now, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDue")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) ([]string, error)); ok {
+		return rf(ctx, now, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) []string); ok {
+		r0 = rf(ctx, now, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, now, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRuns provides a mock function with given fields: ctx, scheduleID, limit
+func (_m *SchedulesRepository) ListRuns(ctx context.Context, scheduleID int, limit int) ([]dao.TransferScheduleRunsModel, error) {
+	ret := _m.Called(ctx, scheduleID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListRuns")
+	}
+
+	var r0 []dao.TransferScheduleRunsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) ([]dao.TransferScheduleRunsModel, error)); ok {
+		return rf(ctx, scheduleID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) []dao.TransferScheduleRunsModel); ok {
+		r0 = rf(ctx, scheduleID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.TransferScheduleRunsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, scheduleID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, uid, username, update
+func (_m *SchedulesRepository) Update(ctx context.Context, uid string, username string, update dao.ScheduleUpdate) (*dao.TransferSchedulesModel, error) {
+	ret := _m.Called(ctx, uid, username, update)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *dao.TransferSchedulesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, dao.ScheduleUpdate) (*dao.TransferSchedulesModel, error)); ok {
+		return rf(ctx, uid, username, update)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, dao.ScheduleUpdate) *dao.TransferSchedulesModel); ok {
+		r0 = rf(ctx, uid, username, update)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.TransferSchedulesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, dao.ScheduleUpdate) error); ok {
+		r1 = rf(ctx, uid, username, update)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewSchedulesRepository creates a new instance of SchedulesRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSchedulesRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *SchedulesRepository {
+	mock := &SchedulesRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
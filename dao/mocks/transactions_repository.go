@@ -62,9 +62,46 @@ func (_m *TransactionsRepository) Insert(ctx context.Context, user dao.Transacti
 	return r0
 }
 
-// ListByReference provides a mock function with given fields: ctx, limit, startingAfter, reference, username
-func (_m *TransactionsRepository) ListByReference(ctx context.Context, limit int, startingAfter string, reference string, username string) ([]dao.TransactionsModel, bool, error) {
-	ret := _m.Called(ctx, limit, startingAfter, reference, username)
+// ListByMetadata provides a mock function with given fields: ctx, limit, startingAfter, username, key, value
+func (_m *TransactionsRepository) ListByMetadata(ctx context.Context, limit int, startingAfter string, username string, key string, value string) ([]dao.TransactionsModel, bool, error) {
+	ret := _m.Called(ctx, limit, startingAfter, username, key, value)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByMetadata")
+	}
+
+	var r0 []dao.TransactionsModel
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string, string, string) ([]dao.TransactionsModel, bool, error)); ok {
+		return rf(ctx, limit, startingAfter, username, key, value)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string, string, string) []dao.TransactionsModel); ok {
+		r0 = rf(ctx, limit, startingAfter, username, key, value)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.TransactionsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, string, string, string) bool); ok {
+		r1 = rf(ctx, limit, startingAfter, username, key, value)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int, string, string, string, string) error); ok {
+		r2 = rf(ctx, limit, startingAfter, username, key, value)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListByReference provides a mock function with given fields: ctx, limit, startingAfter, reference, username, filter
+func (_m *TransactionsRepository) ListByReference(ctx context.Context, limit int, startingAfter string, reference string, username string, filter dao.HistoryFilter) ([]dao.TransactionsModel, bool, error) {
+	ret := _m.Called(ctx, limit, startingAfter, reference, username, filter)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ListByReference")
@@ -73,25 +110,25 @@ func (_m *TransactionsRepository) ListByReference(ctx context.Context, limit int
 	var r0 []dao.TransactionsModel
 	var r1 bool
 	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, string, string, string) ([]dao.TransactionsModel, bool, error)); ok {
-		return rf(ctx, limit, startingAfter, reference, username)
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string, string, dao.HistoryFilter) ([]dao.TransactionsModel, bool, error)); ok {
+		return rf(ctx, limit, startingAfter, reference, username, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int, string, string, string) []dao.TransactionsModel); ok {
-		r0 = rf(ctx, limit, startingAfter, reference, username)
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string, string, dao.HistoryFilter) []dao.TransactionsModel); ok {
+		r0 = rf(ctx, limit, startingAfter, reference, username, filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]dao.TransactionsModel)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int, string, string, string) bool); ok {
-		r1 = rf(ctx, limit, startingAfter, reference, username)
+	if rf, ok := ret.Get(1).(func(context.Context, int, string, string, string, dao.HistoryFilter) bool); ok {
+		r1 = rf(ctx, limit, startingAfter, reference, username, filter)
 	} else {
 		r1 = ret.Get(1).(bool)
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, int, string, string, string) error); ok {
-		r2 = rf(ctx, limit, startingAfter, reference, username)
+	if rf, ok := ret.Get(2).(func(context.Context, int, string, string, string, dao.HistoryFilter) error); ok {
+		r2 = rf(ctx, limit, startingAfter, reference, username, filter)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -99,6 +136,66 @@ func (_m *TransactionsRepository) ListByReference(ctx context.Context, limit int
 	return r0, r1, r2
 }
 
+// Refund provides a mock function with given fields: ctx, originalUID, initiatedBy, reference, amount
+func (_m *TransactionsRepository) Refund(ctx context.Context, originalUID string, initiatedBy string, reference string, amount int64) (*dao.TransactionsModel, error) {
+	ret := _m.Called(ctx, originalUID, initiatedBy, reference, amount)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Refund")
+	}
+
+	var r0 *dao.TransactionsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64) (*dao.TransactionsModel, error)); ok {
+		return rf(ctx, originalUID, initiatedBy, reference, amount)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64) *dao.TransactionsModel); ok {
+		r0 = rf(ctx, originalUID, initiatedBy, reference, amount)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.TransactionsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, int64) error); ok {
+		r1 = rf(ctx, originalUID, initiatedBy, reference, amount)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reverse provides a mock function with given fields: ctx, originalUID, initiatedBy, reference, amount
+func (_m *TransactionsRepository) Reverse(ctx context.Context, originalUID string, initiatedBy string, reference string, amount int64) (*dao.TransactionsModel, error) {
+	ret := _m.Called(ctx, originalUID, initiatedBy, reference, amount)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reverse")
+	}
+
+	var r0 *dao.TransactionsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64) (*dao.TransactionsModel, error)); ok {
+		return rf(ctx, originalUID, initiatedBy, reference, amount)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64) *dao.TransactionsModel); ok {
+		r0 = rf(ctx, originalUID, initiatedBy, reference, amount)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.TransactionsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, int64) error); ok {
+		r1 = rf(ctx, originalUID, initiatedBy, reference, amount)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewTransactionsRepository creates a new instance of TransactionsRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewTransactionsRepository(t interface {
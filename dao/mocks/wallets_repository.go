@@ -6,7 +6,11 @@ import (
 	context "context"
 
 	dao "github.com/lengzuo/fundflow/dao"
+	metadata "github.com/lengzuo/fundflow/utils/metadata"
+
 	mock "github.com/stretchr/testify/mock"
+
+	money "github.com/lengzuo/fundflow/utils/money"
 )
 
 // WalletsRepository is an autogenerated mock type for the WalletsRepository type
@@ -44,22 +48,60 @@ func (_m *WalletsRepository) Balance(ctx context.Context, username string, curre
 	return r0, r1
 }
 
-// Deposit provides a mock function with given fields: ctx, username, reference, currency, amount
-func (_m *WalletsRepository) Deposit(ctx context.Context, username string, reference string, currency string, amount int) error {
-	ret := _m.Called(ctx, username, reference, currency, amount)
+// BulkTransfer provides a mock function with given fields: ctx, sender, reference, items, meta
+func (_m *WalletsRepository) BulkTransfer(ctx context.Context, sender string, reference string, items []dao.BulkTransferItem, meta metadata.Metadata) (string, error) {
+	ret := _m.Called(ctx, sender, reference, items, meta)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BulkTransfer")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []dao.BulkTransferItem, metadata.Metadata) (string, error)); ok {
+		return rf(ctx, sender, reference, items, meta)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []dao.BulkTransferItem, metadata.Metadata) string); ok {
+		r0 = rf(ctx, sender, reference, items, meta)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, []dao.BulkTransferItem, metadata.Metadata) error); ok {
+		r1 = rf(ctx, sender, reference, items, meta)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Deposit provides a mock function with given fields: ctx, username, reference, amount, meta
+func (_m *WalletsRepository) Deposit(ctx context.Context, username string, reference string, amount money.Money, meta metadata.Metadata) (string, error) {
+	ret := _m.Called(ctx, username, reference, amount, meta)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Deposit")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int) error); ok {
-		r0 = rf(ctx, username, reference, currency, amount)
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, money.Money, metadata.Metadata) (string, error)); ok {
+		return rf(ctx, username, reference, amount, meta)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, money.Money, metadata.Metadata) string); ok {
+		r0 = rf(ctx, username, reference, amount, meta)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(string)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, money.Money, metadata.Metadata) error); ok {
+		r1 = rf(ctx, username, reference, amount, meta)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // Get provides a mock function with given fields: ctx, username, currency
@@ -92,40 +134,120 @@ func (_m *WalletsRepository) Get(ctx context.Context, username string, currency
 	return r0, r1
 }
 
-// Transfer provides a mock function with given fields: ctx, sender, receiver, reference, currency, amount
-func (_m *WalletsRepository) Transfer(ctx context.Context, sender string, receiver string, reference string, currency string, amount int) error {
-	ret := _m.Called(ctx, sender, receiver, reference, currency, amount)
+// ListStatusHistories provides a mock function with given fields: ctx, username, currency
+func (_m *WalletsRepository) ListStatusHistories(ctx context.Context, username string, currency string) ([]dao.WalletStatusHistoriesModel, error) {
+	ret := _m.Called(ctx, username, currency)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListStatusHistories")
+	}
+
+	var r0 []dao.WalletStatusHistoriesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]dao.WalletStatusHistoriesModel, error)); ok {
+		return rf(ctx, username, currency)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []dao.WalletStatusHistoriesModel); ok {
+		r0 = rf(ctx, username, currency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.WalletStatusHistoriesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, username, currency)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Transfer provides a mock function with given fields: ctx, sender, receiver, reference, amount, meta
+func (_m *WalletsRepository) Transfer(ctx context.Context, sender string, receiver string, reference string, amount money.Money, meta metadata.Metadata) (string, error) {
+	ret := _m.Called(ctx, sender, receiver, reference, amount, meta)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Transfer")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, int) error); ok {
-		r0 = rf(ctx, sender, receiver, reference, currency, amount)
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, money.Money, metadata.Metadata) (string, error)); ok {
+		return rf(ctx, sender, receiver, reference, amount, meta)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, money.Money, metadata.Metadata) string); ok {
+		r0 = rf(ctx, sender, receiver, reference, amount, meta)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(string)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, money.Money, metadata.Metadata) error); ok {
+		r1 = rf(ctx, sender, receiver, reference, amount, meta)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// Withdraw provides a mock function with given fields: ctx, username, reference, currency, amount
-func (_m *WalletsRepository) Withdraw(ctx context.Context, username string, reference string, currency string, amount int) error {
-	ret := _m.Called(ctx, username, reference, currency, amount)
+// UpdateStatus provides a mock function with given fields: ctx, username, currency, status, reason, changedBy
+func (_m *WalletsRepository) UpdateStatus(ctx context.Context, username string, currency string, status dao.WalletStatus, reason string, changedBy string) (*dao.WalletsModel, error) {
+	ret := _m.Called(ctx, username, currency, status, reason, changedBy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateStatus")
+	}
+
+	var r0 *dao.WalletsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, dao.WalletStatus, string, string) (*dao.WalletsModel, error)); ok {
+		return rf(ctx, username, currency, status, reason, changedBy)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, dao.WalletStatus, string, string) *dao.WalletsModel); ok {
+		r0 = rf(ctx, username, currency, status, reason, changedBy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.WalletsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, dao.WalletStatus, string, string) error); ok {
+		r1 = rf(ctx, username, currency, status, reason, changedBy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Withdraw provides a mock function with given fields: ctx, username, reference, amount, meta
+func (_m *WalletsRepository) Withdraw(ctx context.Context, username string, reference string, amount money.Money, meta metadata.Metadata) (string, error) {
+	ret := _m.Called(ctx, username, reference, amount, meta)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Withdraw")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int) error); ok {
-		r0 = rf(ctx, username, reference, currency, amount)
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, money.Money, metadata.Metadata) (string, error)); ok {
+		return rf(ctx, username, reference, amount, meta)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, money.Money, metadata.Metadata) string); ok {
+		r0 = rf(ctx, username, reference, amount, meta)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, money.Money, metadata.Metadata) error); ok {
+		r1 = rf(ctx, username, reference, amount, meta)
 	} else {
-		r0 = ret.Error(0)
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
 // NewWalletsRepository creates a new instance of WalletsRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
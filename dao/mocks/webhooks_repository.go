@@ -0,0 +1,317 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	dao "github.com/lengzuo/fundflow/dao"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// WebhooksRepository is an autogenerated mock type for the WebhooksRepository type
+type WebhooksRepository struct {
+	mock.Mock
+}
+
+// ClaimDue provides a mock function with given fields: ctx, now, lease, limit
+func (_m *WebhooksRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]dao.WebhookDeliveryJob, error) {
+	ret := _m.Called(ctx, now, lease, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimDue")
+	}
+
+	var r0 []dao.WebhookDeliveryJob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int) ([]dao.WebhookDeliveryJob, error)); ok {
+		return rf(ctx, now, lease, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, time.Duration, int) []dao.WebhookDeliveryJob); ok {
+		r0 = rf(ctx, now, lease, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.WebhookDeliveryJob)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, time.Duration, int) error); ok {
+		r1 = rf(ctx, now, lease, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateEndpoint provides a mock function with given fields: ctx, endpoint
+func (_m *WebhooksRepository) CreateEndpoint(ctx context.Context, endpoint dao.WebhookEndpointsModel) (*dao.WebhookEndpointsModel, error) {
+	ret := _m.Called(ctx, endpoint)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateEndpoint")
+	}
+
+	var r0 *dao.WebhookEndpointsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dao.WebhookEndpointsModel) (*dao.WebhookEndpointsModel, error)); ok {
+		return rf(ctx, endpoint)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dao.WebhookEndpointsModel) *dao.WebhookEndpointsModel); ok {
+		r0 = rf(ctx, endpoint)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.WebhookEndpointsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dao.WebhookEndpointsModel) error); ok {
+		r1 = rf(ctx, endpoint)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DisableEndpoint provides a mock function with given fields: ctx, uid, username
+func (_m *WebhooksRepository) DisableEndpoint(ctx context.Context, uid string, username string) (*dao.WebhookEndpointsModel, error) {
+	ret := _m.Called(ctx, uid, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DisableEndpoint")
+	}
+
+	var r0 *dao.WebhookEndpointsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dao.WebhookEndpointsModel, error)); ok {
+		return rf(ctx, uid, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dao.WebhookEndpointsModel); ok {
+		r0 = rf(ctx, uid, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.WebhookEndpointsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, uid, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Enqueue provides a mock function with given fields: ctx, event
+func (_m *WebhooksRepository) Enqueue(ctx context.Context, event dao.WebhookEvent) (int, error) {
+	ret := _m.Called(ctx, event)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Enqueue")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, dao.WebhookEvent) (int, error)); ok {
+		return rf(ctx, event)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, dao.WebhookEvent) int); ok {
+		r0 = rf(ctx, event)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, dao.WebhookEvent) error); ok {
+		r1 = rf(ctx, event)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDelivery provides a mock function with given fields: ctx, uid, username
+func (_m *WebhooksRepository) GetDelivery(ctx context.Context, uid string, username string) (*dao.WebhookDeliveriesModel, error) {
+	ret := _m.Called(ctx, uid, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDelivery")
+	}
+
+	var r0 *dao.WebhookDeliveriesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*dao.WebhookDeliveriesModel, error)); ok {
+		return rf(ctx, uid, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *dao.WebhookDeliveriesModel); ok {
+		r0 = rf(ctx, uid, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.WebhookDeliveriesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, uid, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAttempts provides a mock function with given fields: ctx, deliveryID, limit
+func (_m *WebhooksRepository) ListAttempts(ctx context.Context, deliveryID int64, limit int) ([]dao.WebhookAttemptsModel, error) {
+	ret := _m.Called(ctx, deliveryID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAttempts")
+	}
+
+	var r0 []dao.WebhookAttemptsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]dao.WebhookAttemptsModel, error)); ok {
+		return rf(ctx, deliveryID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []dao.WebhookAttemptsModel); ok {
+		r0 = rf(ctx, deliveryID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.WebhookAttemptsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, deliveryID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDeliveries provides a mock function with given fields: ctx, username, filter
+func (_m *WebhooksRepository) ListDeliveries(ctx context.Context, username string, filter dao.WebhookDeliveryFilter) ([]dao.WebhookDeliveriesModel, error) {
+	ret := _m.Called(ctx, username, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDeliveries")
+	}
+
+	var r0 []dao.WebhookDeliveriesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, dao.WebhookDeliveryFilter) ([]dao.WebhookDeliveriesModel, error)); ok {
+		return rf(ctx, username, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, dao.WebhookDeliveryFilter) []dao.WebhookDeliveriesModel); ok {
+		r0 = rf(ctx, username, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.WebhookDeliveriesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, dao.WebhookDeliveryFilter) error); ok {
+		r1 = rf(ctx, username, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListEndpoints provides a mock function with given fields: ctx, username
+func (_m *WebhooksRepository) ListEndpoints(ctx context.Context, username string) ([]dao.WebhookEndpointsModel, error) {
+	ret := _m.Called(ctx, username)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEndpoints")
+	}
+
+	var r0 []dao.WebhookEndpointsModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]dao.WebhookEndpointsModel, error)); ok {
+		return rf(ctx, username)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []dao.WebhookEndpointsModel); ok {
+		r0 = rf(ctx, username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]dao.WebhookEndpointsModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordAttempt provides a mock function with given fields: ctx, delivery, attempt
+func (_m *WebhooksRepository) RecordAttempt(ctx context.Context, delivery dao.WebhookDeliveriesModel, attempt dao.WebhookAttemptsModel) error {
+	ret := _m.Called(ctx, delivery, attempt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordAttempt")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, dao.WebhookDeliveriesModel, dao.WebhookAttemptsModel) error); ok {
+		r0 = rf(ctx, delivery, attempt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Redeliver provides a mock function with given fields: ctx, uid, username, now
+func (_m *WebhooksRepository) Redeliver(ctx context.Context, uid string, username string, now time.Time) (*dao.WebhookDeliveriesModel, error) {
+	ret := _m.Called(ctx, uid, username, now)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Redeliver")
+	}
+
+	var r0 *dao.WebhookDeliveriesModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (*dao.WebhookDeliveriesModel, error)); ok {
+		return rf(ctx, uid, username, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) *dao.WebhookDeliveriesModel); ok {
+		r0 = rf(ctx, uid, username, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dao.WebhookDeliveriesModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, uid, username, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewWebhooksRepository creates a new instance of WebhooksRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewWebhooksRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *WebhooksRepository {
+	mock := &WebhooksRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
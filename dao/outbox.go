@@ -0,0 +1,186 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils"
+)
+
+// outboxLockKey is the postgres advisory lock held by the dispatching instance, so the events are published
+// by one instance at a time in the order of their id.
+const outboxLockKey int64 = 0x6f7574626f78 // "outbox" in hex
+
+//go:generate mockery --name OutboxRepository --output ./mocks --outpkg mocks --case=underscore
+type OutboxRepository interface {
+	Dispatch(ctx context.Context, limit int, publish func(ctx context.Context, event OutboxEventsModel) error) (int, error)
+	DeletePublished(ctx context.Context, before time.Time) (int, error)
+}
+
+type EventType string
+
+const (
+	EventWalletCredited EventType = "wallet.credited"
+	EventWalletDebited  EventType = "wallet.debited"
+	// EventTransactionRecovered notifies the initiator that their stuck transaction was finalized by the recovery
+	EventTransactionRecovered EventType = "transaction.recovered"
+)
+
+type OutboxEventsModel struct {
+	ID          int64           `db:"id"`
+	UID         string          `db:"uid"`
+	Type        EventType       `db:"type"`
+	Username    string          `db:"username"`
+	Currency    string          `db:"currency"`
+	Payload     json.RawMessage `db:"payload"`
+	CreatedAt   time.Time       `db:"created_at"`
+	PublishedAt *time.Time      `db:"published_at"`
+}
+
+// WalletEvent is the payload of the event emitted for every wallet moved by a deposit, withdraw or transfer.
+type WalletEvent struct {
+	TxUID     string    `json:"tx_uid"`
+	TxType    TxType    `json:"tx_type"`
+	Username  string    `json:"username"`
+	Currency  string    `json:"currency"`
+	Amount    int64     `json:"amount"`
+	Direction Direction `json:"direction"`
+	Reference string    `json:"reference"`
+	// Counterparty is the other wallet of a transfer, empty for deposit and withdraw
+	Counterparty string `json:"counterparty,omitempty"`
+}
+
+type outbox struct {
+	db *sqlx.DB
+}
+
+func NewOutbox(dao *DAO) *outbox {
+	return &outbox{
+		db: dao.db,
+	}
+}
+
+// Dispatch publishes the unpublished events in the order of their id and marks them as published, it stops at
+// the first failed event which is published again by the next dispatch. An event may be published more than once
+// when the mark isn't committed, the consumers deduplicate by the event uid. Nothing is dispatched while another
+// instance holds the outbox lock.
+func (p *outbox) Dispatch(ctx context.Context, limit int, publish func(ctx context.Context, event OutboxEventsModel) error) (int, error) {
+	var published []int64
+	var publishErr error
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		var locked bool
+		err := sqlx.GetContext(ctx, exec, &locked, "SELECT pg_try_advisory_xact_lock($1)", outboxLockKey)
+		if err != nil {
+			log.Error(ctx, "failed to take outbox lock with err: %s", err)
+			return fmt.Errorf("take outbox lock: %w", err)
+		}
+		if !locked {
+			log.Debug(ctx, "outbox is dispatched by another instance")
+			return nil
+		}
+
+		query, args, err := psql.Select("id", "uid", "type", "username", "currency", "payload", "created_at", "published_at").
+			From("outbox_events").
+			Where(squirrel.Eq{"published_at": nil}).
+			OrderBy("id").
+			Limit(uint64(limit)).
+			ToSql()
+		if err != nil {
+			log.Error(ctx, "failed to build unpublished events query with err: %s", err)
+			return fmt.Errorf("build unpublished events query: %w", err)
+		}
+		events := []OutboxEventsModel{}
+		err = sqlx.SelectContext(ctx, exec, &events, query, args...)
+		if err != nil {
+			log.Error(ctx, "failed to list unpublished events with err: %s", err)
+			return fmt.Errorf("list unpublished events: %w", err)
+		}
+
+		for _, event := range events {
+			if publishErr = publish(ctx, event); publishErr != nil {
+				log.Error(ctx, "failed to publish event %s with err: %s", event.UID, publishErr)
+				break
+			}
+			published = append(published, event.ID)
+		}
+		if len(published) == 0 {
+			return nil
+		}
+		query, args, err = psql.Update("outbox_events").
+			Set("published_at", squirrel.Expr("(now() AT TIME ZONE 'utc')")).
+			Where(squirrel.Eq{"id": published}).
+			ToSql()
+		if err != nil {
+			log.Error(ctx, "failed to build publish events query with err: %s", err)
+			return fmt.Errorf("build publish events query: %w", err)
+		}
+		_, err = exec.ExecContext(ctx, query, args...)
+		if err != nil {
+			log.Error(ctx, "failed to mark events as published with err: %s", err)
+			return fmt.Errorf("mark events as published: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(published), publishErr
+}
+
+// DeletePublished removes the events published before the retention cutoff, the unpublished events are always kept.
+func (p *outbox) DeletePublished(ctx context.Context, before time.Time) (int, error) {
+	query, args, err := psql.Delete("outbox_events").
+		Where(squirrel.NotEq{"published_at": nil}).
+		Where(squirrel.Lt{"published_at": before}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build delete published events query with err: %s", err)
+		return 0, fmt.Errorf("build delete published events query: %w", err)
+	}
+	result, err := p.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to delete published events with err: %s", err)
+		return 0, fmt.Errorf("delete published events: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete published events: %w", err)
+	}
+	return int(deleted), nil
+}
+
+// insertWalletEvent writes the event of the wallet into the outbox within the database transaction of exec,
+// so the event is only published when the movement is committed.
+func insertWalletEvent(ctx context.Context, exec sqlx.ExtContext, event WalletEvent) error {
+	eventType := EventWalletCredited
+	if event.Direction == DirectionDebit {
+		eventType = EventWalletDebited
+	}
+	return insertOutboxEvent(ctx, exec, eventType, event.Username, event.Currency, event)
+}
+
+func insertOutboxEvent(ctx context.Context, exec sqlx.ExtContext, eventType EventType, username, currency string, event any) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", eventType, err)
+	}
+	query, args, err := psql.Insert("outbox_events").
+		Columns("uid", "type", "username", "currency", "payload").
+		Values(utils.UUID(), eventType, username, currency, string(payload)).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build insert outbox event query with err: %s", err)
+		return fmt.Errorf("build insert outbox event query: %w", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to insert outbox event with err: %s", err)
+		return fmt.Errorf("insert outbox event: %w", err)
+	}
+	return nil
+}
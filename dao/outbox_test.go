@@ -0,0 +1,121 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	insertOutboxEventQuery = "INSERT INTO outbox_events (uid,type,username,currency,payload) VALUES ($1,$2,$3,$4,$5)"
+	outboxLockQuery        = "SELECT pg_try_advisory_xact_lock($1)"
+	unpublishedEventsQuery = "SELECT id, uid, type, username, currency, payload, created_at, published_at FROM outbox_events WHERE published_at IS NULL ORDER BY id LIMIT 10"
+	publishEventsQuery     = "UPDATE outbox_events SET published_at = (now() AT TIME ZONE 'utc') WHERE id IN ($1,$2)"
+)
+
+var outboxEventColumns = []string{"id", "uid", "type", "username", "currency", "payload", "created_at", "published_at"}
+
+func expectWalletEvent(mock sqlmock.Sqlmock, eventType EventType, username, currency string) {
+	mock.ExpectExec(insertOutboxEventQuery).
+		WithArgs(sqlmock.AnyArg(), eventType, username, currency, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func Test_outbox_Dispatch(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &outbox{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	now := time.Now()
+	unpublished := func() *sqlmock.Rows {
+		return sqlmock.NewRows(outboxEventColumns).
+			AddRow(1, "evt1", EventWalletDebited, "user1", "SGD", []byte(`{"amount":100}`), now, nil).
+			AddRow(2, "evt2", EventWalletCredited, "user2", "SGD", []byte(`{"amount":100}`), now, nil).
+			AddRow(3, "evt3", EventWalletCredited, "user1", "SGD", []byte(`{"amount":50}`), now, nil)
+	}
+
+	t.Run("ok publish in order of id", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(outboxLockQuery).WithArgs(outboxLockKey).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(unpublishedEventsQuery).WillReturnRows(unpublished())
+		mock.ExpectExec("UPDATE outbox_events SET published_at = (now() AT TIME ZONE 'utc') WHERE id IN ($1,$2,$3)").
+			WithArgs(1, 2, 3).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		var uids []string
+		published, err := p.Dispatch(t.Context(), 10, func(ctx context.Context, event OutboxEventsModel) error {
+			uids = append(uids, event.UID)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, published)
+		assert.Equal(t, []string{"evt1", "evt2", "evt3"}, uids)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("stop at the failed event and keep the published", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(outboxLockQuery).WithArgs(outboxLockKey).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(unpublishedEventsQuery).WillReturnRows(unpublished())
+		mock.ExpectExec(publishEventsQuery).
+			WithArgs(1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		published, err := p.Dispatch(t.Context(), 10, func(ctx context.Context, event OutboxEventsModel) error {
+			if event.UID == "evt3" {
+				return errors.New("redis down")
+			}
+			return nil
+		})
+		assert.EqualError(t, err, "redis down")
+		assert.Equal(t, 2, published)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("dispatched by another instance", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(outboxLockQuery).WithArgs(outboxLockKey).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+		mock.ExpectCommit()
+
+		published, err := p.Dispatch(t.Context(), 10, func(ctx context.Context, event OutboxEventsModel) error {
+			t.Fatal("nothing should be published")
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Zero(t, published)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_outbox_DeletePublished(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &outbox{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	before := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+
+	t.Run("ok delete published events", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM outbox_events WHERE published_at IS NOT NULL AND published_at < $1").
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 5))
+		deleted, err := p.DeletePublished(t.Context(), before)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, deleted)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
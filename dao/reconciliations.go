@@ -0,0 +1,218 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+//go:generate mockery --name ReconciliationsRepository --output ./mocks --outpkg mocks --case=underscore
+type ReconciliationsRepository interface {
+	Run(ctx context.Context, trigger ReconciliationTrigger, triggeredBy string) (*ReconciliationRunsModel, []ReconciliationDiscrepanciesModel, error)
+	ListRuns(ctx context.Context, limit int) ([]ReconciliationRunsModel, error)
+	ListDiscrepancies(ctx context.Context, runID int) ([]ReconciliationDiscrepanciesModel, error)
+}
+
+type ReconciliationTrigger string
+
+const (
+	ReconciliationScheduled ReconciliationTrigger = "scheduled"
+	ReconciliationManual    ReconciliationTrigger = "manual"
+)
+
+type ReconciliationStatus string
+
+const (
+	ReconciliationRunning   ReconciliationStatus = "running"
+	ReconciliationCompleted ReconciliationStatus = "completed"
+	ReconciliationFailed    ReconciliationStatus = "failed"
+)
+
+type ReconciliationRunsModel struct {
+	ID             int                   `db:"id"`
+	Trigger        ReconciliationTrigger `db:"trigger"`
+	TriggeredBy    string                `db:"triggered_by"`
+	Status         ReconciliationStatus  `db:"status"`
+	WalletsChecked int                   `db:"wallets_checked"`
+	Discrepancies  int                   `db:"discrepancies"`
+	Error          string                `db:"error"`
+	StartedAt      time.Time             `db:"started_at"`
+	FinishedAt     *time.Time            `db:"finished_at"`
+}
+
+type ReconciliationDiscrepanciesModel struct {
+	ID           int       `db:"id"`
+	RunID        int       `db:"run_id"`
+	WalletID     int       `db:"wallet_id"`
+	Username     string    `db:"username"`
+	Currency     string    `db:"currency"`
+	WalletAmount int64     `db:"wallet_amount"`
+	LedgerAmount int64     `db:"ledger_amount"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+type reconciliations struct {
+	db *sqlx.DB
+}
+
+func NewReconciliations(dao *DAO) *reconciliations {
+	return &reconciliations{
+		db: dao.db,
+	}
+}
+
+// Run compares the balance of every wallet against the sum of its ledgers and records the run with its discrepancies.
+// The comparison is a single statement so it reads a consistent snapshot, where every write path updates
+// wallets and ledgers in the same transaction.
+func (p *reconciliations) Run(ctx context.Context, trigger ReconciliationTrigger, triggeredBy string) (*ReconciliationRunsModel, []ReconciliationDiscrepanciesModel, error) {
+	run := &ReconciliationRunsModel{
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		Status:      ReconciliationRunning,
+	}
+	query, args, err := psql.Insert("reconciliation_runs").
+		Columns("trigger", "triggered_by", "status").
+		Values(run.Trigger, run.TriggeredBy, run.Status).
+		Suffix("RETURNING id, started_at").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build reconciliation run insert query with err: %s", err)
+		return nil, nil, fmt.Errorf("build reconciliation run insert query: %w", err)
+	}
+	err = p.db.QueryRowxContext(ctx, query, args...).Scan(&run.ID, &run.StartedAt)
+	if err != nil {
+		log.Error(ctx, "failed to insert reconciliation run with err: %s", err)
+		return nil, nil, fmt.Errorf("insert reconciliation run: %w", err)
+	}
+
+	discrepancies := []ReconciliationDiscrepanciesModel{}
+	err = lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		query, args, err := psql.Select(
+			"w.id AS wallet_id",
+			"w.username",
+			"w.currency",
+			"w.amount AS wallet_amount",
+			"COALESCE(SUM(CASE WHEN le.direction = 'c' THEN le.amount ELSE -le.amount END), 0) AS ledger_amount",
+		).
+			From("wallets w").
+			LeftJoin("ledgers le ON le.username = w.username AND le.currency = w.currency").
+			GroupBy("w.id", "w.username", "w.currency", "w.amount").
+			OrderBy("w.id").
+			ToSql()
+		if err != nil {
+			log.Error(ctx, "failed to build reconciliation query with err: %s", err)
+			return fmt.Errorf("build reconciliation query: %w", err)
+		}
+		balances := []ReconciliationDiscrepanciesModel{}
+		err = sqlx.SelectContext(ctx, exec, &balances, query, args...)
+		if err != nil {
+			log.Error(ctx, "failed to compute ledger balances with err: %s", err)
+			return fmt.Errorf("compute ledger balances: %w", err)
+		}
+		run.WalletsChecked = len(balances)
+		for _, b := range balances {
+			if b.WalletAmount == b.LedgerAmount {
+				continue
+			}
+			b.RunID = run.ID
+			log.Error(ctx, "reconciliation run %d found wallet %s %s balance %d but ledgers sum to %d", run.ID, b.Username, b.Currency, b.WalletAmount, b.LedgerAmount)
+			if err = insertDiscrepancy(ctx, exec, b); err != nil {
+				return err
+			}
+			discrepancies = append(discrepancies, b)
+		}
+		run.Discrepancies = len(discrepancies)
+		run.Status = ReconciliationCompleted
+		return finishRun(ctx, exec, run)
+	})
+	if err != nil {
+		run.Status = ReconciliationFailed
+		run.Error = err.Error()
+		// The failure is recorded outside of the rolled back transaction so auditors still see the run
+		if finishErr := finishRun(ctx, p.db, run); finishErr != nil {
+			log.Error(ctx, "failed to mark reconciliation run %d failed with err: %s", run.ID, finishErr)
+		}
+		return nil, nil, err
+	}
+	return run, discrepancies, nil
+}
+
+func (p *reconciliations) ListRuns(ctx context.Context, limit int) ([]ReconciliationRunsModel, error) {
+	query, args, err := psql.Select("id", "trigger", "triggered_by", "status", "wallets_checked", "discrepancies", "error", "started_at", "finished_at").
+		From("reconciliation_runs").
+		OrderBy("id DESC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list reconciliation runs query with err: %s", err)
+		return nil, fmt.Errorf("build list reconciliation runs query: %w", err)
+	}
+	runs := []ReconciliationRunsModel{}
+	err = p.db.SelectContext(ctx, &runs, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list reconciliation runs with err: %s", err)
+		return nil, fmt.Errorf("list reconciliation runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (p *reconciliations) ListDiscrepancies(ctx context.Context, runID int) ([]ReconciliationDiscrepanciesModel, error) {
+	query, args, err := psql.Select("id", "run_id", "wallet_id", "username", "currency", "wallet_amount", "ledger_amount", "created_at").
+		From("reconciliation_discrepancies").
+		Where(squirrel.Eq{"run_id": runID}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list discrepancies query with err: %s", err)
+		return nil, fmt.Errorf("build list discrepancies query: %w", err)
+	}
+	discrepancies := []ReconciliationDiscrepanciesModel{}
+	err = p.db.SelectContext(ctx, &discrepancies, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list discrepancies with err: %s", err)
+		return nil, fmt.Errorf("list discrepancies: %w", err)
+	}
+	return discrepancies, nil
+}
+
+func insertDiscrepancy(ctx context.Context, exec sqlx.ExtContext, d ReconciliationDiscrepanciesModel) error {
+	query, args, err := psql.Insert("reconciliation_discrepancies").
+		Columns("run_id", "wallet_id", "username", "currency", "wallet_amount", "ledger_amount").
+		Values(d.RunID, d.WalletID, d.Username, d.Currency, d.WalletAmount, d.LedgerAmount).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build discrepancy insert query with err: %s", err)
+		return fmt.Errorf("build discrepancy insert query: %w", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to insert discrepancy with err: %s", err)
+		return fmt.Errorf("insert discrepancy: %w", err)
+	}
+	return nil
+}
+
+func finishRun(ctx context.Context, exec sqlx.ExecerContext, run *ReconciliationRunsModel) error {
+	query, args, err := psql.Update("reconciliation_runs").
+		Set("status", run.Status).
+		Set("wallets_checked", run.WalletsChecked).
+		Set("discrepancies", run.Discrepancies).
+		Set("error", run.Error).
+		Set("finished_at", squirrel.Expr("NOW()")).
+		Where(squirrel.Eq{"id": run.ID}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build reconciliation run update query with err: %s", err)
+		return fmt.Errorf("build reconciliation run update query: %w", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to update reconciliation run with err: %s", err)
+		return fmt.Errorf("update reconciliation run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package dao
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	insertRunQuery         = "INSERT INTO reconciliation_runs (trigger,triggered_by,status) VALUES ($1,$2,$3) RETURNING id, started_at"
+	reconcileQuery         = "SELECT w.id AS wallet_id, w.username, w.currency, w.amount AS wallet_amount, COALESCE(SUM(CASE WHEN le.direction = 'c' THEN le.amount ELSE -le.amount END), 0) AS ledger_amount FROM wallets w LEFT JOIN ledgers le ON le.username = w.username AND le.currency = w.currency GROUP BY w.id, w.username, w.currency, w.amount ORDER BY w.id"
+	insertDiscrepancyQuery = "INSERT INTO reconciliation_discrepancies (run_id,wallet_id,username,currency,wallet_amount,ledger_amount) VALUES ($1,$2,$3,$4,$5,$6)"
+	finishRunQuery         = "UPDATE reconciliation_runs SET status = $1, wallets_checked = $2, discrepancies = $3, error = $4, finished_at = NOW() WHERE id = $5"
+)
+
+var reconcileColumns = []string{"wallet_id", "username", "currency", "wallet_amount", "ledger_amount"}
+
+func TestNewReconciliations(t *testing.T) {
+	mockDB, _, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	t.Run("correct init", func(t *testing.T) {
+		daoInstance := &DAO{sqlx.NewDb(mockDB, "sqlmock")}
+		reconciliationDAO := NewReconciliations(daoInstance)
+		assert.Equal(t, daoInstance.db.DriverName(), reconciliationDAO.db.DriverName())
+		assert.Implements(t, (*ReconciliationsRepository)(nil), reconciliationDAO)
+	})
+}
+
+func Test_reconciliations_Run(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &reconciliations{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+
+	t.Run("ok run with discrepancy", func(t *testing.T) {
+		mock.ExpectQuery(insertRunQuery).
+			WithArgs(ReconciliationManual, "admin", ReconciliationRunning).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "started_at"}).AddRow(1, time.Now()))
+		mock.ExpectBegin()
+		mock.ExpectQuery(reconcileQuery).
+			WillReturnRows(sqlmock.NewRows(reconcileColumns).
+				AddRow(1, "user1", "SGD", 100, 100).
+				AddRow(2, "user1", "JPY", 50, 40))
+		mock.ExpectExec(insertDiscrepancyQuery).
+			WithArgs(1, 2, "user1", "JPY", 50, 40).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(finishRunQuery).
+			WithArgs(ReconciliationCompleted, 2, 1, "", 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		run, discrepancies, err := p.Run(t.Context(), ReconciliationManual, "admin")
+		assert.NoError(t, err)
+		assert.Equal(t, ReconciliationCompleted, run.Status)
+		assert.Equal(t, 2, run.WalletsChecked)
+		assert.Equal(t, 1, run.Discrepancies)
+		assert.Equal(t, []ReconciliationDiscrepanciesModel{
+			{RunID: 1, WalletID: 2, Username: "user1", Currency: "JPY", WalletAmount: 50, LedgerAmount: 40},
+		}, discrepancies)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("run failed is recorded", func(t *testing.T) {
+		mock.ExpectQuery(insertRunQuery).
+			WithArgs(ReconciliationScheduled, "", ReconciliationRunning).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "started_at"}).AddRow(2, time.Now()))
+		mock.ExpectBegin()
+		mock.ExpectQuery(reconcileQuery).
+			WillReturnError(errors.New("err"))
+		mock.ExpectRollback()
+		mock.ExpectExec(finishRunQuery).
+			WithArgs(ReconciliationFailed, 0, 0, "compute ledger balances: err", 2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		run, discrepancies, err := p.Run(t.Context(), ReconciliationScheduled, "")
+		assert.Error(t, err)
+		assert.Nil(t, run)
+		assert.Nil(t, discrepancies)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("insert run error", func(t *testing.T) {
+		mock.ExpectQuery(insertRunQuery).
+			WithArgs(ReconciliationScheduled, "", ReconciliationRunning).
+			WillReturnError(errors.New("err"))
+
+		run, _, err := p.Run(t.Context(), ReconciliationScheduled, "")
+		assert.Error(t, err)
+		assert.Nil(t, run)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_reconciliations_ListRuns(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &reconciliations{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+
+	t.Run("ok list runs", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectQuery("SELECT id, trigger, triggered_by, status, wallets_checked, discrepancies, error, started_at, finished_at FROM reconciliation_runs ORDER BY id DESC LIMIT 10").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "trigger", "triggered_by", "status", "wallets_checked", "discrepancies", "error", "started_at", "finished_at"}).
+				AddRow(2, "scheduled", "", "running", 0, 0, "", now, nil).
+				AddRow(1, "manual", "admin", "completed", 20, 1, "", now, now))
+		runs, err := p.ListRuns(t.Context(), 10)
+		assert.NoError(t, err)
+		assert.Len(t, runs, 2)
+		assert.Nil(t, runs[0].FinishedAt)
+		assert.Equal(t, 1, runs[1].Discrepancies)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_reconciliations_ListDiscrepancies(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &reconciliations{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+
+	t.Run("list discrepancies error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, run_id, wallet_id, username, currency, wallet_amount, ledger_amount, created_at FROM reconciliation_discrepancies WHERE run_id = $1 ORDER BY id").
+			WithArgs(1).
+			WillReturnError(errors.New("err"))
+		discrepancies, err := p.ListDiscrepancies(t.Context(), 1)
+		assert.Error(t, err)
+		assert.Nil(t, discrepancies)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
@@ -0,0 +1,242 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+var (
+	ErrTxNotPending = errors.New("transaction is not pending")
+	// ErrTxNotStuck is returned on retry of the hold which is authorized within its expiry, it is pending by design
+	ErrTxNotStuck = errors.New("transaction is not stuck")
+)
+
+//go:generate mockery --name RecoveriesRepository --output ./mocks --outpkg mocks --case=underscore
+type RecoveriesRepository interface {
+	ListStuck(ctx context.Context, before time.Time, limit int) ([]string, error)
+	Recover(ctx context.Context, uid string, action RecoveryAction, recoveredBy, reason string) (*TransactionRecoveriesModel, error)
+	List(ctx context.Context, txUID string) ([]TransactionRecoveriesModel, error)
+}
+
+type RecoveryAction string
+
+const (
+	// RecoveryRetry finalizes the hold transaction with the status its hold already reached, the authorized hold
+	// past its expiry is expired
+	RecoveryRetry RecoveryAction = "retry"
+	// RecoveryFail releases the authorized hold and fails its transaction
+	RecoveryFail RecoveryAction = "fail"
+)
+
+type TransactionRecoveriesModel struct {
+	ID     int64          `db:"id"`
+	TxUID  string         `db:"tx_uid"`
+	Action RecoveryAction `db:"action"`
+	Status TxStatus       `db:"status"`
+	Reason string         `db:"reason"`
+	// RecoveredBy is the admin who recovered the transaction, empty for the recovery worker
+	RecoveredBy string    `db:"recovered_by"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// TransactionRecoveredEvent is the payload of the event emitted to the initiator of the recovered transaction.
+type TransactionRecoveredEvent struct {
+	TxUID  string         `json:"tx_uid"`
+	TxType TxType         `json:"tx_type"`
+	Status TxStatus       `json:"status"`
+	Action RecoveryAction `json:"action"`
+	Amount int64          `json:"amount"`
+	Reason string         `json:"reason"`
+}
+
+type recoveries struct {
+	db *sqlx.DB
+}
+
+func NewRecoveries(dao *DAO) *recoveries {
+	return &recoveries{
+		db: dao.db,
+	}
+}
+
+// ListStuck returns the uid of the hold transactions pending since before, oldest first. Only a hold is written
+// pending, and the hold which is authorized is only stuck once its expiry passed before as well, until then it is
+// pending by design.
+func (p *recoveries) ListStuck(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	query, args, err := psql.Select("t.uid").
+		From("transactions t").
+		Join("holds h ON h.tx_uid = t.uid").
+		Where(squirrel.Eq{"t.status": StatusPending}).
+		Where(squirrel.LtOrEq{"t.updated_at": before}).
+		Where(squirrel.Or{
+			squirrel.NotEq{"h.status": HoldStatusAuthorized},
+			squirrel.LtOrEq{"h.expires_at": before},
+		}).
+		OrderBy("t.updated_at").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list stuck transactions query with err: %s", err)
+		return nil, fmt.Errorf("build list stuck transactions query: %w", err)
+	}
+	uids := []string{}
+	err = p.db.SelectContext(ctx, &uids, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list stuck transactions with err: %s", err)
+		return nil, fmt.Errorf("list stuck transactions: %w", err)
+	}
+	return uids, nil
+}
+
+// Recover finalizes the pending hold transaction deterministically and records the recovery. The held amount of an
+// authorized hold is released without moving any fund, and the initiator is notified through the outbox.
+func (p *recoveries) Recover(ctx context.Context, uid string, action RecoveryAction, recoveredBy, reason string) (*TransactionRecoveriesModel, error) {
+	var recovery *TransactionRecoveriesModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		// Lock the hold before the transaction in the same sequence as Capture to avoid deadlock
+		hold, err := getHoldForRecovery(ctx, exec, uid)
+		if err != nil {
+			return err
+		}
+		tx, err := getTransactionForUpdate(ctx, exec, uid)
+		if err != nil {
+			return err
+		}
+		// Every other transaction is written completed, a pending one without hold is never left to recover
+		if tx.Status != StatusPending || hold == nil {
+			return ErrTxNotPending
+		}
+
+		status, amount, err := recoveredStatus(hold, action, time.Now())
+		if err != nil {
+			return err
+		}
+		if hold.Status == HoldStatusAuthorized {
+			err = updateHeldAmount(ctx, exec, hold.Username, hold.Currency, -hold.Amount)
+			if err != nil {
+				return err
+			}
+			hold.Status = HoldStatusVoided
+			if action == RecoveryRetry {
+				hold.Status = HoldStatusExpired
+			}
+			err = updateHoldStatus(ctx, exec, hold)
+			if err != nil {
+				return err
+			}
+		}
+		err = updateTransactionStatus(ctx, exec, uid, status, amount)
+		if err != nil {
+			return err
+		}
+
+		recovery = &TransactionRecoveriesModel{
+			TxUID:       uid,
+			Action:      action,
+			Status:      status,
+			Reason:      reason,
+			RecoveredBy: recoveredBy,
+		}
+		err = insertRecovery(ctx, exec, recovery)
+		if err != nil {
+			return err
+		}
+		return insertOutboxEvent(ctx, exec, EventTransactionRecovered, tx.InitiatedBy, tx.Currency, TransactionRecoveredEvent{
+			TxUID:  uid,
+			TxType: tx.Type,
+			Status: status,
+			Action: action,
+			Amount: amount,
+			Reason: reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recovery, nil
+}
+
+func (p *recoveries) List(ctx context.Context, txUID string) ([]TransactionRecoveriesModel, error) {
+	query, args, err := psql.Select("id", "tx_uid", "action", "status", "reason", "recovered_by", "created_at").
+		From("transaction_recoveries").
+		Where(squirrel.Eq{"tx_uid": txUID}).
+		OrderBy("id DESC").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list recoveries query with err: %s", err)
+		return nil, fmt.Errorf("build list recoveries query: %w", err)
+	}
+	recoveries := []TransactionRecoveriesModel{}
+	err = p.db.SelectContext(ctx, &recoveries, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list recoveries of %s with err: %s", txUID, err)
+		return nil, fmt.Errorf("list recoveries: %w", err)
+	}
+	return recoveries, nil
+}
+
+// recoveredStatus decides the status and amount the pending hold transaction is finalized with. The hold follows
+// its hold record, the authorized hold is failed on fail and cancelled on retry once its expiry passed.
+func recoveredStatus(hold *HoldsModel, action RecoveryAction, now time.Time) (TxStatus, int64, error) {
+	switch hold.Status {
+	case HoldStatusCaptured:
+		return StatusCompleted, hold.CapturedAmount, nil
+	case HoldStatusAuthorized:
+		if action == RecoveryFail {
+			return StatusFailed, hold.Amount, nil
+		}
+		if hold.ExpiresAt.After(now) {
+			return "", 0, ErrTxNotStuck
+		}
+	}
+	return StatusCancelled, hold.Amount, nil
+}
+
+// getHoldForRecovery locks the hold of the transaction regardless of the actor, nil is returned for the
+// transaction without hold.
+func getHoldForRecovery(ctx context.Context, exec sqlx.ExtContext, txUID string) (*HoldsModel, error) {
+	query, args, err := selectHolds().
+		Where(squirrel.Eq{"tx_uid": txUID}).
+		Limit(1).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build get hold for recovery query with err: %s", err)
+		return nil, fmt.Errorf("build get hold for recovery query: %w", err)
+	}
+	hold := new(HoldsModel)
+	err = sqlx.GetContext(ctx, exec, hold, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error(ctx, "failed to get hold %s for recovery with err: %s", txUID, err)
+		return nil, fmt.Errorf("get hold for recovery: %w", err)
+	}
+	return hold, nil
+}
+
+func insertRecovery(ctx context.Context, exec sqlx.ExtContext, recovery *TransactionRecoveriesModel) error {
+	query, args, err := psql.Insert("transaction_recoveries").
+		Columns("tx_uid", "action", "status", "reason", "recovered_by").
+		Values(recovery.TxUID, recovery.Action, recovery.Status, recovery.Reason, recovery.RecoveredBy).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build insert recovery query with err: %s", err)
+		return fmt.Errorf("build insert recovery query: %w", err)
+	}
+	err = exec.QueryRowxContext(ctx, query, args...).Scan(&recovery.ID, &recovery.CreatedAt)
+	if err != nil {
+		log.Error(ctx, "failed to insert recovery of %s with err: %s", recovery.TxUID, err)
+		return fmt.Errorf("insert recovery: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package dao
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectHoldForRecoveryQuery = "SELECT id, tx_uid, username, receiver, currency, amount, captured_amount, status, expires_at, created_at, updated_at FROM holds WHERE tx_uid = $1 LIMIT 1 FOR UPDATE"
+	insertRecoveryQuery        = "INSERT INTO transaction_recoveries (tx_uid,action,status,reason,recovered_by) VALUES ($1,$2,$3,$4,$5) RETURNING id, created_at"
+)
+
+func expectInsertRecovery(mock sqlmock.Sqlmock, action RecoveryAction, status TxStatus, recoveredBy string) {
+	mock.ExpectQuery(insertRecoveryQuery).
+		WithArgs("uid", action, status, "stuck", recoveredBy).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+}
+
+func TestNewRecoveries(t *testing.T) {
+	mockDB, _, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	t.Run("correct init", func(t *testing.T) {
+		daoInstance := &DAO{sqlx.NewDb(mockDB, "sqlmock")}
+		recoveryDAO := NewRecoveries(daoInstance)
+		assert.Equal(t, daoInstance.db.DriverName(), recoveryDAO.db.DriverName())
+		assert.Implements(t, (*RecoveriesRepository)(nil), recoveryDAO)
+	})
+}
+
+func Test_recoveries_ListStuck(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &recoveries{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	before := time.Now().Add(-time.Hour)
+	query := "SELECT t.uid FROM transactions t JOIN holds h ON h.tx_uid = t.uid WHERE t.status = $1 AND t.updated_at <= $2 AND (h.status <> $3 OR h.expires_at <= $4) ORDER BY t.updated_at LIMIT 100"
+
+	t.Run("ok list stuck transactions", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(StatusPending, before, HoldStatusAuthorized, before).
+			WillReturnRows(sqlmock.NewRows([]string{"uid"}).AddRow("uid1").AddRow("uid2"))
+
+		uids, err := p.ListStuck(t.Context(), before, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"uid1", "uid2"}, uids)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("list stuck transactions with db error", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(StatusPending, before, HoldStatusAuthorized, before).
+			WillReturnError(errors.New("db error"))
+
+		uids, err := p.ListStuck(t.Context(), before, 100)
+		assert.Error(t, err)
+		assert.Nil(t, uids)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_recoveries_Recover(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &recoveries{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	now := time.Now()
+
+	t.Run("ok retry captured hold follows its record", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectHoldForRecoveryQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(holdColumns).AddRow(1, "uid", "name", "", "SGD", 100, 60, "captured", now.Add(-time.Hour), now, now))
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "hold", "name", "pending", 100, "SGD", 0))
+		mock.ExpectExec(updateTransactionQuery).
+			WithArgs(StatusCompleted, 60, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectInsertRecovery(mock, RecoveryRetry, StatusCompleted, "")
+		expectWalletEvent(mock, EventTransactionRecovered, "name", "SGD")
+		mock.ExpectCommit()
+
+		recovery, err := p.Recover(t.Context(), "uid", RecoveryRetry, "", "stuck")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusCompleted, recovery.Status)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("ok retry expired hold is released", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectHoldForRecoveryQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(holdColumns).AddRow(1, "uid", "name", "", "SGD", 100, 0, "authorized", now.Add(-time.Hour), now, now))
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "hold", "name", "pending", 100, "SGD", 0))
+		mock.ExpectExec(releaseHeldAmountQuery).
+			WithArgs(-100, "SGD", "name").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateHoldQuery).
+			WithArgs(HoldStatusExpired, 0, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateTransactionQuery).
+			WithArgs(StatusCancelled, 100, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectInsertRecovery(mock, RecoveryRetry, StatusCancelled, "")
+		expectWalletEvent(mock, EventTransactionRecovered, "name", "SGD")
+		mock.ExpectCommit()
+
+		recovery, err := p.Recover(t.Context(), "uid", RecoveryRetry, "", "stuck")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusCancelled, recovery.Status)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("ok fail authorized hold by admin", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectHoldForRecoveryQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(holdColumns).AddRow(1, "uid", "name", "", "SGD", 100, 0, "authorized", now.Add(time.Hour), now, now))
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "hold", "name", "pending", 100, "SGD", 0))
+		mock.ExpectExec(releaseHeldAmountQuery).
+			WithArgs(-100, "SGD", "name").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateHoldQuery).
+			WithArgs(HoldStatusVoided, 0, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(updateTransactionQuery).
+			WithArgs(StatusFailed, 100, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectInsertRecovery(mock, RecoveryFail, StatusFailed, "admin")
+		expectWalletEvent(mock, EventTransactionRecovered, "name", "SGD")
+		mock.ExpectCommit()
+
+		recovery, err := p.Recover(t.Context(), "uid", RecoveryFail, "admin", "stuck")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusFailed, recovery.Status)
+		assert.Equal(t, "admin", recovery.RecoveredBy)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("retry hold within expiry", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectHoldForRecoveryQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(holdColumns).AddRow(1, "uid", "name", "", "SGD", 100, 0, "authorized", now.Add(time.Hour), now, now))
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "hold", "name", "pending", 100, "SGD", 0))
+		mock.ExpectRollback()
+
+		recovery, err := p.Recover(t.Context(), "uid", RecoveryRetry, "", "stuck")
+		assert.ErrorIs(t, err, ErrTxNotStuck)
+		assert.Nil(t, recovery)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("recover transaction which is not pending", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectHoldForRecoveryQuery).WithArgs("uid").WillReturnRows(sqlmock.NewRows(holdColumns))
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "deposit", "name", "completed", 100, "SGD", 0))
+		mock.ExpectRollback()
+
+		recovery, err := p.Recover(t.Context(), "uid", RecoveryFail, "admin", "stuck")
+		assert.ErrorIs(t, err, ErrTxNotPending)
+		assert.Nil(t, recovery)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("recover pending transaction without hold", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectHoldForRecoveryQuery).WithArgs("uid").WillReturnRows(sqlmock.NewRows(holdColumns))
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "transfer", "name", "pending", 100, "SGD", 0))
+		mock.ExpectRollback()
+
+		recovery, err := p.Recover(t.Context(), "uid", RecoveryRetry, "", "stuck")
+		assert.ErrorIs(t, err, ErrTxNotPending)
+		assert.Nil(t, recovery)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
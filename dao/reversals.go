@@ -0,0 +1,221 @@
+package dao
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils"
+)
+
+var (
+	ErrTxNotReversible    = errors.New("transaction is not reversible")
+	ErrTxAlreadyReversed  = errors.New("transaction is already fully reversed")
+	ErrReverseExceedTxAmt = errors.New("reverse amount exceed the refundable amount")
+	// ErrPartialBulkReversal is returned as the partial amount can't be split across the receivers of a bulk transfer
+	ErrPartialBulkReversal = errors.New("bulk transfer can only be reversed in full")
+)
+
+// refundableTypes are the transactions which the credited user is able to refund by themselves,
+// deposit and withdraw can only be reversed by admin.
+var refundableTypes = []TxType{TypeTransfer, TypeHold}
+
+// Reverse is the admin correction of a transaction, it is allowed on every completed transaction
+// other than reversal and refund themselves.
+func (p *transactions) Reverse(ctx context.Context, originalUID, initiatedBy, reference string, amount int64) (*TransactionsModel, error) {
+	return p.reverse(ctx, TypeReversal, originalUID, initiatedBy, reference, amount)
+}
+
+// Refund returns the fund of a transfer or captured hold back to the payer, only the credited user is able to refund it.
+func (p *transactions) Refund(ctx context.Context, originalUID, initiatedBy, reference string, amount int64) (*TransactionsModel, error) {
+	return p.reverse(ctx, TypeRefund, originalUID, initiatedBy, reference, amount)
+}
+
+// reverse writes the mirrored ledger legs of the original transaction for the given amount,
+// an amount of zero reverse the whole remaining refundable amount.
+func (p *transactions) reverse(ctx context.Context, txType TxType, originalUID, initiatedBy, reference string, amount int64) (*TransactionsModel, error) {
+	var reversal *TransactionsModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		// Lock the original transaction first so concurrent reversals of it are serialized
+		original, err := getTransactionForUpdate(ctx, exec, originalUID)
+		if err != nil {
+			return err
+		}
+		if original.Status != StatusCompleted || original.Type == TypeReversal || original.Type == TypeRefund {
+			return ErrTxNotReversible
+		}
+		refundable := original.Amount - original.RefundedAmount
+		if refundable <= 0 {
+			return ErrTxAlreadyReversed
+		}
+		if amount == 0 {
+			amount = refundable
+		}
+		if amount > refundable {
+			return ErrReverseExceedTxAmt
+		}
+		if original.Type == TypeBulkTransfer && amount != original.Amount {
+			return ErrPartialBulkReversal
+		}
+
+		legs, err := listLedgersByTxUID(ctx, exec, original.UID)
+		if err != nil {
+			return err
+		}
+		if len(legs) == 0 {
+			return ErrTxNotReversible
+		}
+		if txType == TypeRefund {
+			if !slices.Contains(refundableTypes, original.Type) {
+				return ErrTxNotReversible
+			}
+			// Hide the transaction from user who wasn't credited by it
+			credited := slices.ContainsFunc(legs, func(leg LedgersModel) bool {
+				return leg.Direction == DirectionCredit && leg.Username == initiatedBy
+			})
+			if !credited {
+				return apierr.NotFound
+			}
+		}
+
+		// Lock the wallets in the same sequence as Transfer and Exchange to avoid deadlock,
+		// the legs of an exchange are in two currencies
+		wallets := make([][2]string, 0, len(legs))
+		for _, leg := range legs {
+			wallets = append(wallets, [2]string{leg.Username, leg.Currency})
+		}
+		slices.SortFunc(wallets, func(a, b [2]string) int {
+			return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+		})
+		for _, w := range slices.Compact(wallets) {
+			if _, err = get(ctx, exec, w[0], w[1], true); err != nil {
+				return err
+			}
+		}
+
+		reversal = &TransactionsModel{
+			UID:         utils.UUID(),
+			OriginalUID: original.UID,
+			Type:        txType,
+			InitiatedBy: initiatedBy,
+			Status:      StatusCompleted,
+			Amount:      amount,
+			Currency:    original.Currency,
+			Reference:   reference,
+		}
+		err = insertTransaction(ctx, exec, *reversal)
+		if err != nil {
+			log.Error(ctx, "failed in insert into transactions with err: %s", err)
+			return err
+		}
+		for _, leg := range legs {
+			direction := DirectionCredit
+			if leg.Direction == DirectionCredit {
+				direction = DirectionDebit
+			}
+			// Every leg is reversed in its own currency with the share of its own amount, e.g. the credited leg of
+			// an exchange is in the to currency and each leg of a bulk transfer has the amount of its receiver
+			legAmount := proportionalAmount(leg.Amount, original.RefundedAmount, amount, original.Amount)
+			err = updateBalanceAndInsertLedger(ctx, exec, reversal.UID, leg.Username, leg.Currency, legAmount, direction)
+			if err != nil {
+				log.Error(ctx, "failed in mirror ledger of %s for %s with err: %s", original.UID, leg.Username, err)
+				return err
+			}
+			err = insertWalletEvent(ctx, exec, WalletEvent{
+				TxUID:     reversal.UID,
+				TxType:    txType,
+				Username:  leg.Username,
+				Currency:  leg.Currency,
+				Amount:    legAmount,
+				Direction: direction,
+				Reference: reference,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return addRefundedAmount(ctx, exec, original.UID, amount)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// proportionalAmount returns the share of amount out of total of the leg amount, taken as the difference of the
+// cumulative shares rounded down before and after the refunded amount, so a reversal never returns more than the leg
+// moved and the last of successive partial reversals closes the leg exactly.
+func proportionalAmount(legAmount, refunded, amount, total int64) int64 {
+	cumulativeShare := func(reversed int64) *big.Int {
+		share := new(big.Int).Mul(big.NewInt(legAmount), big.NewInt(reversed))
+		return share.Quo(share, big.NewInt(total))
+	}
+	return new(big.Int).Sub(cumulativeShare(refunded+amount), cumulativeShare(refunded)).Int64()
+}
+
+func getTransactionForUpdate(ctx context.Context, exec sqlx.ExtContext, uid string) (*TransactionsModel, error) {
+	query, args, err := psql.Select("uid", "type", "initiated_by", "status", "amount", "currency", "refunded_amount").
+		From("transactions").
+		Where(squirrel.Eq{"uid": uid}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build get txn for update query with err: %s", err)
+		return nil, fmt.Errorf("build get txn for update query: %w", err)
+	}
+	transaction := new(TransactionsModel)
+	err = sqlx.GetContext(ctx, exec, transaction, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierr.NotFound
+		}
+		log.Error(ctx, "failed to get txn %s for update with err: %s", uid, err)
+		return nil, fmt.Errorf("get txn for update: %w", err)
+	}
+	return transaction, nil
+}
+
+func listLedgersByTxUID(ctx context.Context, exec sqlx.ExtContext, txUID string) ([]LedgersModel, error) {
+	query, args, err := psql.Select("tx_uid", "username", "currency", "amount", "direction").
+		From("ledgers").
+		Where(squirrel.Eq{"tx_uid": txUID}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list ledgers by tx query with err: %s", err)
+		return nil, fmt.Errorf("build list ledgers by tx query: %w", err)
+	}
+	legs := []LedgersModel{}
+	err = sqlx.SelectContext(ctx, exec, &legs, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list ledgers of %s with err: %s", txUID, err)
+		return nil, fmt.Errorf("list ledgers by tx: %w", err)
+	}
+	return legs, nil
+}
+
+func addRefundedAmount(ctx context.Context, exec sqlx.ExtContext, uid string, amount int64) error {
+	query, args, err := psql.Update("transactions").
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Set("refunded_amount", squirrel.Expr("refunded_amount + ?", amount)).
+		Where(squirrel.Eq{"uid": uid}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build refunded amount query with err: %s", err)
+		return fmt.Errorf("build refunded amount query: %w", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to update refunded amount of %s with err: %s", uid, err)
+		return fmt.Errorf("update refunded amount: %w", err)
+	}
+	return nil
+}
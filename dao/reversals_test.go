@@ -0,0 +1,442 @@
+package dao
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	selectTxForUpdateQuery = "SELECT uid, type, initiated_by, status, amount, currency, refunded_amount FROM transactions WHERE uid = $1 FOR UPDATE"
+	selectTxLedgersQuery   = "SELECT tx_uid, username, currency, amount, direction FROM ledgers WHERE tx_uid = $1 ORDER BY id"
+	lockWalletQuery        = "SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE"
+)
+
+var (
+	txForUpdateColumns = []string{"uid", "type", "initiated_by", "status", "amount", "currency", "refunded_amount"}
+	txLedgerColumns    = []string{"tx_uid", "username", "currency", "amount", "direction"}
+	walletColumns      = []string{"id", "username", "amount", "status"}
+)
+
+func Test_transactions_Refund(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &transactions{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+
+	t.Run("ok partial refund of transfer", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "transfer", "sender", "completed", 100, "SGD", 30))
+		mock.ExpectQuery(selectTxLedgersQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txLedgerColumns).
+				AddRow("uid", "sender", "SGD", 100, "d").
+				AddRow("uid", "receiver", "SGD", 100, "c"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("receiver", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(2, "receiver", 100, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("sender", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "sender", 0, "active"))
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference,original_uid) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)").
+			WithArgs(sqlmock.AnyArg(), TypeRefund, "receiver", "SGD", 50, StatusCompleted, "ref", "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(50, "SGD", "sender", WalletStatusActive).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "sender", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "sender", "SGD", 50, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletCredited, "sender", "SGD")
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-50, "SGD", "receiver", WalletStatusActive, 50).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "receiver", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "receiver", "SGD", 50, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletDebited, "receiver", "SGD")
+		mock.ExpectExec("UPDATE transactions SET updated_at = NOW(), refunded_amount = refunded_amount + $1 WHERE uid = $2").
+			WithArgs(50, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		refund, err := p.Refund(t.Context(), "uid", "receiver", "ref", 50)
+		assert.NoError(t, err)
+		assert.Equal(t, TypeRefund, refund.Type)
+		assert.Equal(t, "uid", refund.OriginalUID)
+		assert.Equal(t, int64(50), refund.Amount)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("refund exceed refundable amount", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "transfer", "sender", "completed", 100, "SGD", 30))
+		mock.ExpectRollback()
+
+		refund, err := p.Refund(t.Context(), "uid", "receiver", "ref", 71)
+		assert.ErrorIs(t, err, ErrReverseExceedTxAmt)
+		assert.Nil(t, refund)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("refund fully refunded transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "transfer", "sender", "completed", 100, "SGD", 100))
+		mock.ExpectRollback()
+
+		refund, err := p.Refund(t.Context(), "uid", "receiver", "ref", 0)
+		assert.ErrorIs(t, err, ErrTxAlreadyReversed)
+		assert.Nil(t, refund)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("refund by user not credited", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "transfer", "sender", "completed", 100, "SGD", 0))
+		mock.ExpectQuery(selectTxLedgersQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txLedgerColumns).
+				AddRow("uid", "sender", "SGD", 100, "d").
+				AddRow("uid", "receiver", "SGD", 100, "c"))
+		mock.ExpectRollback()
+
+		refund, err := p.Refund(t.Context(), "uid", "sender", "ref", 0)
+		assert.ErrorIs(t, err, apierr.NotFound)
+		assert.Nil(t, refund)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("refund deposit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "deposit", "user1", "completed", 100, "SGD", 0))
+		mock.ExpectQuery(selectTxLedgersQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txLedgerColumns).AddRow("uid", "user1", "SGD", 100, "c"))
+		mock.ExpectRollback()
+
+		refund, err := p.Refund(t.Context(), "uid", "user1", "ref", 0)
+		assert.ErrorIs(t, err, ErrTxNotReversible)
+		assert.Nil(t, refund)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_transactions_Reverse(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &transactions{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+
+	t.Run("ok full reversal of withdraw", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "withdraw", "user1", "completed", 100, "JPY", 0))
+		mock.ExpectQuery(selectTxLedgersQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txLedgerColumns).AddRow("uid", "user1", "JPY", 100, "d"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 0, "active"))
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference,original_uid) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)").
+			WithArgs(sqlmock.AnyArg(), TypeReversal, "admin", "JPY", 100, StatusCompleted, "ref", "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(100, "JPY", "user1", WalletStatusActive).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user1", "JPY")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user1", "JPY", 100, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletCredited, "user1", "JPY")
+		mock.ExpectExec("UPDATE transactions SET updated_at = NOW(), refunded_amount = refunded_amount + $1 WHERE uid = $2").
+			WithArgs(100, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		reversal, err := p.Reverse(t.Context(), "uid", "admin", "ref", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, TypeReversal, reversal.Type)
+		assert.Equal(t, int64(100), reversal.Amount)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("ok partial reversal of exchange in both currencies", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "exchange", "user1", "completed", 1000, "SGD", 0))
+		mock.ExpectQuery(selectTxLedgersQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txLedgerColumns).
+				AddRow("uid", "user1", "SGD", 1000, "d").
+				AddRow("uid", "user2", "JPY", 11001, "c"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 0, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user2", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(2, "user2", 11001, "active"))
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference,original_uid) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)").
+			WithArgs(sqlmock.AnyArg(), TypeReversal, "admin", "SGD", 500, StatusCompleted, "ref", "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		// The SGD of the sender is credited back
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(500, "SGD", "user1", WalletStatusActive).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user1", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user1", "SGD", 500, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletCredited, "user1", "SGD")
+		// The JPY of the receiver is debited by half of the credited amount, rounded down
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-5500, "JPY", "user2", WalletStatusActive, 5500).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user2", "JPY")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user2", "JPY", 5500, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletDebited, "user2", "JPY")
+		mock.ExpectExec("UPDATE transactions SET updated_at = NOW(), refunded_amount = refunded_amount + $1 WHERE uid = $2").
+			WithArgs(500, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		reversal, err := p.Reverse(t.Context(), "uid", "admin", "ref", 500)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(500), reversal.Amount)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("ok successive partial reversals of exchange close the leg", func(t *testing.T) {
+		expectPartialReversal := func(refunded, amount, jpyAmount int) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(selectTxForUpdateQuery).
+				WithArgs("uid").
+				WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "exchange", "user1", "completed", 1001, "SGD", refunded))
+			mock.ExpectQuery(selectTxLedgersQuery).
+				WithArgs("uid").
+				WillReturnRows(sqlmock.NewRows(txLedgerColumns).
+					AddRow("uid", "user1", "SGD", 1001, "d").
+					AddRow("uid", "user2", "JPY", 1103, "c"))
+			mock.ExpectQuery(lockWalletQuery).
+				WithArgs("user1", "SGD").
+				WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 0, "active"))
+			mock.ExpectQuery(lockWalletQuery).
+				WithArgs("user2", "JPY").
+				WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(2, "user2", 1103, "active"))
+			mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference,original_uid) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)").
+				WithArgs(sqlmock.AnyArg(), TypeReversal, "admin", "SGD", amount, StatusCompleted, "ref", "uid").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+				WithArgs(amount, "SGD", "user1", WalletStatusActive).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectLastLedgerHash(mock, "user1", "SGD")
+			expectPeriodOpen(mock)
+			mock.ExpectExec(insertLedgerQuery).
+				WithArgs(sqlmock.AnyArg(), "user1", "SGD", amount, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectWalletEvent(mock, EventWalletCredited, "user1", "SGD")
+			mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+				WithArgs(-jpyAmount, "JPY", "user2", WalletStatusActive, jpyAmount).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectLastLedgerHash(mock, "user2", "JPY")
+			expectPeriodOpen(mock)
+			mock.ExpectExec(insertLedgerQuery).
+				WithArgs(sqlmock.AnyArg(), "user2", "JPY", jpyAmount, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectWalletEvent(mock, EventWalletDebited, "user2", "JPY")
+			mock.ExpectExec("UPDATE transactions SET updated_at = NOW(), refunded_amount = refunded_amount + $1 WHERE uid = $2").
+				WithArgs(amount, "uid").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+		}
+		// 1001 SGD cents exchanged to 1103 JPY is reversed in 500 and 501, the JPY leg is debited 550 and then the
+		// remaining 553, rather than 550 and 552 which would leave 1 JPY never reversed
+		expectPartialReversal(0, 500, 550)
+		expectPartialReversal(500, 501, 553)
+
+		_, err := p.Reverse(t.Context(), "uid", "admin", "ref", 500)
+		assert.NoError(t, err)
+		_, err = p.Reverse(t.Context(), "uid", "admin", "ref", 501)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("reverse exchange without fund of the receiver", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "exchange", "user1", "completed", 1000, "SGD", 0))
+		mock.ExpectQuery(selectTxLedgersQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txLedgerColumns).
+				AddRow("uid", "user1", "SGD", 1000, "d").
+				AddRow("uid", "user2", "JPY", 11000, "c"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 0, "active"))
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("user2", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(2, "user2", 0, "active"))
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference,original_uid) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)").
+			WithArgs(sqlmock.AnyArg(), TypeReversal, "admin", "SGD", 1000, StatusCompleted, "ref", "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(1000, "SGD", "user1", WalletStatusActive).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user1", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user1", "SGD", 1000, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletCredited, "user1", "SGD")
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-11000, "JPY", "user2", WalletStatusActive, 11000).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+			WithArgs("user2", "JPY").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(2, "user2", 0, "active"))
+		mock.ExpectRollback()
+
+		reversal, err := p.Reverse(t.Context(), "uid", "admin", "ref", 0)
+		assert.ErrorIs(t, err, apierr.InsufficientFund)
+		assert.Nil(t, reversal)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("reverse a reversal", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "reversal", "admin", "completed", 100, "JPY", 0))
+		mock.ExpectRollback()
+
+		reversal, err := p.Reverse(t.Context(), "uid", "admin", "ref", 0)
+		assert.ErrorIs(t, err, ErrTxNotReversible)
+		assert.Nil(t, reversal)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("reverse pending hold", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "hold", "user1", "pending", 100, "JPY", 0))
+		mock.ExpectRollback()
+
+		reversal, err := p.Reverse(t.Context(), "uid", "admin", "ref", 0)
+		assert.ErrorIs(t, err, ErrTxNotReversible)
+		assert.Nil(t, reversal)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("ok full reversal of bulk transfer", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "bulk_transfer", "user1", "completed", 300, "SGD", 0))
+		mock.ExpectQuery(selectTxLedgersQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txLedgerColumns).
+				AddRow("uid", "user1", "SGD", 300, "d").
+				AddRow("uid", "user3", "SGD", 100, "c").
+				AddRow("uid", "user2", "SGD", 200, "c"))
+		for i, username := range []string{"user1", "user2", "user3"} {
+			mock.ExpectQuery(lockWalletQuery).
+				WithArgs(username, "SGD").
+				WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(i+1, username, 1000, "active"))
+		}
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference,original_uid) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)").
+			WithArgs(sqlmock.AnyArg(), TypeReversal, "admin", "SGD", 300, StatusCompleted, "ref", "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(300, "SGD", "user1", WalletStatusActive).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user1", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user1", "SGD", 300, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletCredited, "user1", "SGD")
+		for _, leg := range []struct {
+			username string
+			amount   int
+		}{{"user3", 100}, {"user2", 200}} {
+			mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+				WithArgs(-leg.amount, "SGD", leg.username, WalletStatusActive, leg.amount).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectLastLedgerHash(mock, leg.username, "SGD")
+			expectPeriodOpen(mock)
+			mock.ExpectExec(insertLedgerQuery).
+				WithArgs(sqlmock.AnyArg(), leg.username, "SGD", leg.amount, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectWalletEvent(mock, EventWalletDebited, leg.username, "SGD")
+		}
+		mock.ExpectExec("UPDATE transactions SET updated_at = NOW(), refunded_amount = refunded_amount + $1 WHERE uid = $2").
+			WithArgs(300, "uid").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		reversal, err := p.Reverse(t.Context(), "uid", "admin", "ref", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(300), reversal.Amount)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("reverse part of bulk transfer", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("uid").
+			WillReturnRows(sqlmock.NewRows(txForUpdateColumns).AddRow("uid", "bulk_transfer", "user1", "completed", 300, "SGD", 0))
+		mock.ExpectRollback()
+
+		reversal, err := p.Reverse(t.Context(), "uid", "admin", "ref", 100)
+		assert.ErrorIs(t, err, ErrPartialBulkReversal)
+		assert.Nil(t, reversal)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("reverse unknown transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectTxForUpdateQuery).
+			WithArgs("unknown").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		reversal, err := p.Reverse(t.Context(), "unknown", "admin", "ref", 0)
+		assert.ErrorIs(t, err, apierr.NotFound)
+		assert.Nil(t, reversal)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
@@ -0,0 +1,527 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/metadata"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+//go:generate mockery --name SchedulesRepository --output ./mocks --outpkg mocks --case=underscore
+type SchedulesRepository interface {
+	Create(ctx context.Context, schedule TransferSchedulesModel) (*TransferSchedulesModel, error)
+	Get(ctx context.Context, uid, username string) (*TransferSchedulesModel, error)
+	List(ctx context.Context, username string) ([]TransferSchedulesModel, error)
+	Update(ctx context.Context, uid, username string, update ScheduleUpdate) (*TransferSchedulesModel, error)
+	Cancel(ctx context.Context, uid, username string) (*TransferSchedulesModel, error)
+	ListRuns(ctx context.Context, scheduleID, limit int) ([]TransferScheduleRunsModel, error)
+	ListDue(ctx context.Context, now time.Time, limit int) ([]string, error)
+	Execute(ctx context.Context, uid string, now time.Time) (*TransferScheduleRunsModel, error)
+}
+
+type Frequency string
+
+const (
+	FrequencyOnce    Frequency = "once"
+	FrequencyWeekly  Frequency = "weekly"
+	FrequencyMonthly Frequency = "monthly"
+)
+
+// InsufficientFundPolicy decides what happens to an occurrence which the sender can't afford.
+type InsufficientFundPolicy string
+
+const (
+	// PolicyRetry attempts the occurrence again after utils.ScheduleRetryInterval up to the max retries, then skips it
+	PolicyRetry InsufficientFundPolicy = "retry"
+	// PolicySkip moves to the next occurrence straight away
+	PolicySkip InsufficientFundPolicy = "skip"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleStatusActive    ScheduleStatus = "active"
+	ScheduleStatusCompleted ScheduleStatus = "completed"
+	ScheduleStatusCancelled ScheduleStatus = "cancelled"
+)
+
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunCompleted ScheduleRunStatus = "completed"
+	// ScheduleRunFailed is retried later
+	ScheduleRunFailed ScheduleRunStatus = "failed"
+	// ScheduleRunSkipped gives up the occurrence
+	ScheduleRunSkipped ScheduleRunStatus = "skipped"
+)
+
+var (
+	ErrScheduleNotActive = errors.New("schedule is not active")
+	// ErrScheduleNotDue is returned when the schedule was run or changed by someone else since it was listed as due
+	ErrScheduleNotDue = errors.New("schedule is not due")
+)
+
+type TransferSchedulesModel struct {
+	ID                 int                    `db:"id"`
+	UID                string                 `db:"uid"`
+	Username           string                 `db:"username"`
+	Receiver           string                 `db:"receiver"`
+	Currency           string                 `db:"currency"`
+	Amount             int64                  `db:"amount"`
+	Reference          string                 `db:"reference"`
+	Frequency          Frequency              `db:"frequency"`
+	StartAt            time.Time              `db:"start_at"`
+	EndAt              *time.Time             `db:"end_at"`
+	Occurrence         int                    `db:"occurrence"`
+	DueAt              time.Time              `db:"due_at"`
+	NextRunAt          time.Time              `db:"next_run_at"`
+	Attempts           int                    `db:"attempts"`
+	OnInsufficientFund InsufficientFundPolicy `db:"on_insufficient_fund"`
+	MaxRetries         int                    `db:"max_retries"`
+	Status             ScheduleStatus         `db:"status"`
+	CreatedAt          time.Time              `db:"created_at"`
+	UpdatedAt          time.Time              `db:"updated_at"`
+}
+
+// ExecutionKey identifies the current occurrence, every attempt of the occurrence shares it.
+func (s *TransferSchedulesModel) ExecutionKey() string {
+	return fmt.Sprintf("%s:%d", s.UID, s.Occurrence)
+}
+
+// advance moves the schedule to its next occurrence, it is completed when there is none.
+func (s *TransferSchedulesModel) advance() {
+	s.Occurrence++
+	s.Attempts = 0
+	if s.Frequency == FrequencyOnce {
+		s.Status = ScheduleStatusCompleted
+		return
+	}
+	next := nextOccurrence(s.StartAt, s.Frequency, s.Occurrence)
+	if s.EndAt != nil && next.After(*s.EndAt) {
+		s.Status = ScheduleStatusCompleted
+		return
+	}
+	s.DueAt = next
+	s.NextRunAt = next
+}
+
+// nextOccurrence returns the nth occurrence counted from start. The monthly occurrence falls on the
+// last day of the month when the month is shorter than the day of start, e.g. 31 Jan is followed by 28 Feb then 31 Mar.
+func nextOccurrence(start time.Time, frequency Frequency, n int) time.Time {
+	switch frequency {
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case FrequencyMonthly:
+		next := start.AddDate(0, n, 0)
+		if next.Day() != start.Day() {
+			// AddDate normalizes 31 Feb into 3 Mar, go back to the last day of Feb
+			next = next.AddDate(0, 0, -next.Day())
+		}
+		return next
+	}
+	return start
+}
+
+type TransferScheduleRunsModel struct {
+	ID           int               `db:"id"`
+	ScheduleID   int               `db:"schedule_id"`
+	ExecutionKey string            `db:"execution_key"`
+	DueAt        time.Time         `db:"due_at"`
+	Attempt      int               `db:"attempt"`
+	Status       ScheduleRunStatus `db:"status"`
+	TxUID        string            `db:"tx_uid"`
+	Error        string            `db:"error"`
+	CreatedAt    time.Time         `db:"created_at"`
+}
+
+// ScheduleUpdate changes the schedule, a nil field is left unchanged.
+type ScheduleUpdate struct {
+	Amount             *int64
+	EndAt              *time.Time
+	OnInsufficientFund *InsufficientFundPolicy
+	MaxRetries         *int
+}
+
+var schedulesColumns = []string{"id", "uid", "username", "receiver", "currency", "amount", "reference", "frequency", "start_at", "end_at",
+	"occurrence", "due_at", "next_run_at", "attempts", "on_insufficient_fund", "max_retries", "status", "created_at", "updated_at"}
+
+type schedules struct {
+	db *sqlx.DB
+}
+
+func NewSchedules(dao *DAO) *schedules {
+	return &schedules{
+		db: dao.db,
+	}
+}
+
+// Create saves the active schedule after making sure the wallets of both sender and receiver exist.
+func (p *schedules) Create(ctx context.Context, schedule TransferSchedulesModel) (*TransferSchedulesModel, error) {
+	for _, username := range []string{schedule.Username, schedule.Receiver} {
+		if _, err := get(ctx, p.db, username, schedule.Currency, false); err != nil {
+			return nil, err
+		}
+	}
+	query, args, err := psql.Insert("transfer_schedules").
+		Columns("uid", "username", "receiver", "currency", "amount", "reference", "frequency", "start_at", "end_at",
+			"due_at", "next_run_at", "on_insufficient_fund", "max_retries", "status").
+		Values(utils.UUID(), schedule.Username, schedule.Receiver, schedule.Currency, schedule.Amount, schedule.Reference, schedule.Frequency, schedule.StartAt, schedule.EndAt,
+			schedule.StartAt, schedule.StartAt, schedule.OnInsufficientFund, schedule.MaxRetries, ScheduleStatusActive).
+		Suffix("RETURNING " + strings.Join(schedulesColumns, ", ")).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build schedule insert query with err: %s", err)
+		return nil, fmt.Errorf("build schedule insert query: %w", err)
+	}
+	created := new(TransferSchedulesModel)
+	err = p.db.QueryRowxContext(ctx, query, args...).StructScan(created)
+	if err != nil {
+		log.Error(ctx, "failed to insert schedule with err: %s", err)
+		return nil, fmt.Errorf("insert schedule: %w", err)
+	}
+	log.Info(ctx, "schedule %s created by %s", created.UID, created.Username)
+	return created, nil
+}
+
+func (p *schedules) Get(ctx context.Context, uid, username string) (*TransferSchedulesModel, error) {
+	return getSchedule(ctx, p.db, squirrel.Eq{"uid": uid, "username": username}, "")
+}
+
+func (p *schedules) List(ctx context.Context, username string) ([]TransferSchedulesModel, error) {
+	query, args, err := psql.Select(schedulesColumns...).
+		From("transfer_schedules").
+		Where(squirrel.Eq{"username": username}).
+		OrderBy("id DESC").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list schedules query with err: %s", err)
+		return nil, fmt.Errorf("build list schedules query: %w", err)
+	}
+	schedules := []TransferSchedulesModel{}
+	err = p.db.SelectContext(ctx, &schedules, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list schedules with err: %s", err)
+		return nil, fmt.Errorf("list schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// Update changes the active schedule, the new amount applies from the current occurrence.
+func (p *schedules) Update(ctx context.Context, uid, username string, update ScheduleUpdate) (*TransferSchedulesModel, error) {
+	var schedule *TransferSchedulesModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		var err error
+		schedule, err = getSchedule(ctx, exec, squirrel.Eq{"uid": uid, "username": username}, "FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		if schedule.Status != ScheduleStatusActive {
+			return ErrScheduleNotActive
+		}
+		if update.Amount != nil {
+			schedule.Amount = *update.Amount
+		}
+		if update.EndAt != nil {
+			schedule.EndAt = update.EndAt
+		}
+		if update.OnInsufficientFund != nil {
+			schedule.OnInsufficientFund = *update.OnInsufficientFund
+		}
+		if update.MaxRetries != nil {
+			schedule.MaxRetries = *update.MaxRetries
+		}
+		if schedule.EndAt != nil && schedule.DueAt.After(*schedule.EndAt) {
+			schedule.Status = ScheduleStatusCompleted
+		}
+		return updateSchedule(ctx, exec, schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Cancel stops the active schedule, the completed runs are kept.
+func (p *schedules) Cancel(ctx context.Context, uid, username string) (*TransferSchedulesModel, error) {
+	var schedule *TransferSchedulesModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		var err error
+		schedule, err = getSchedule(ctx, exec, squirrel.Eq{"uid": uid, "username": username}, "FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		if schedule.Status != ScheduleStatusActive {
+			return ErrScheduleNotActive
+		}
+		schedule.Status = ScheduleStatusCancelled
+		return updateSchedule(ctx, exec, schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info(ctx, "schedule %s cancelled by %s", uid, username)
+	return schedule, nil
+}
+
+// ListRuns returns the latest runs of the schedule first.
+func (p *schedules) ListRuns(ctx context.Context, scheduleID, limit int) ([]TransferScheduleRunsModel, error) {
+	query, args, err := psql.Select("id", "schedule_id", "execution_key", "due_at", "attempt", "status", "tx_uid", "error", "created_at").
+		From("transfer_schedule_runs").
+		Where(squirrel.Eq{"schedule_id": scheduleID}).
+		OrderBy("id DESC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list schedule runs query with err: %s", err)
+		return nil, fmt.Errorf("build list schedule runs query: %w", err)
+	}
+	runs := []TransferScheduleRunsModel{}
+	err = p.db.SelectContext(ctx, &runs, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list schedule runs with err: %s", err)
+		return nil, fmt.Errorf("list schedule runs: %w", err)
+	}
+	return runs, nil
+}
+
+// ListDue returns the uid of the active schedules which next run is due by now, the earliest first.
+func (p *schedules) ListDue(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	query, args, err := psql.Select("uid").
+		From("transfer_schedules").
+		Where(squirrel.Eq{"status": ScheduleStatusActive}).
+		Where(squirrel.LtOrEq{"next_run_at": now}).
+		OrderBy("next_run_at").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build due schedules query with err: %s", err)
+		return nil, fmt.Errorf("build due schedules query: %w", err)
+	}
+	uids := []string{}
+	err = p.db.SelectContext(ctx, &uids, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list due schedules with err: %s", err)
+		return nil, fmt.Errorf("list due schedules: %w", err)
+	}
+	return uids, nil
+}
+
+// Execute transfers the current occurrence of the due schedule through the same path as Transfer. The transfer,
+// its run and the move to the next occurrence are committed together, and the run is keyed by the execution key
+// so an occurrence is never paid twice. When the transfer is refused the failed run is recorded by itself and
+// the occurrence is retried or skipped by the policy of the schedule.
+func (p *schedules) Execute(ctx context.Context, uid string, now time.Time) (*TransferScheduleRunsModel, error) {
+	var run *TransferScheduleRunsModel
+	var attempted TransferSchedulesModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		schedule, err := getDueSchedule(ctx, exec, uid, now)
+		if err != nil {
+			return err
+		}
+		attempted = *schedule
+		paid, err := isOccurrencePaid(ctx, exec, schedule.ExecutionKey())
+		if err != nil {
+			return err
+		}
+		if paid {
+			log.Info(ctx, "occurrence %s is already paid, moving to the next occurrence", schedule.ExecutionKey())
+			schedule.advance()
+			return updateSchedule(ctx, exec, schedule)
+		}
+
+		cur, err := currency.Get(schedule.Currency)
+		if err != nil {
+			return err
+		}
+		meta := metadata.Metadata{"schedule_uid": schedule.UID, "execution_key": schedule.ExecutionKey()}
+		transaction, err := transfer(ctx, exec, schedule.Username, schedule.Receiver, schedule.Reference, money.New(schedule.Amount, cur), meta)
+		if err != nil {
+			return err
+		}
+		run = &TransferScheduleRunsModel{
+			ScheduleID:   schedule.ID,
+			ExecutionKey: schedule.ExecutionKey(),
+			DueAt:        schedule.DueAt,
+			Attempt:      schedule.Attempts + 1,
+			Status:       ScheduleRunCompleted,
+			TxUID:        transaction.UID,
+		}
+		err = insertScheduleRun(ctx, exec, run)
+		if err != nil {
+			return err
+		}
+		schedule.advance()
+		return updateSchedule(ctx, exec, schedule)
+	})
+	if err == nil || !isRefusedTransfer(err) {
+		return run, err
+	}
+	return p.recordRefused(ctx, &attempted, now, err)
+}
+
+// recordRefused records the refused run of the current occurrence and applies the policy of the schedule.
+// Only the insufficient fund is retried, the occurrence refused for any other reason is skipped.
+func (p *schedules) recordRefused(ctx context.Context, attempted *TransferSchedulesModel, now time.Time, refused error) (*TransferScheduleRunsModel, error) {
+	var run *TransferScheduleRunsModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		schedule, err := getDueSchedule(ctx, exec, attempted.UID, now)
+		if err != nil {
+			return err
+		}
+		// The lock was released after the refused transfer, make sure nobody has recorded the same attempt since
+		if schedule.Occurrence != attempted.Occurrence || schedule.Attempts != attempted.Attempts {
+			return ErrScheduleNotDue
+		}
+		run = &TransferScheduleRunsModel{
+			ScheduleID:   schedule.ID,
+			ExecutionKey: schedule.ExecutionKey(),
+			DueAt:        schedule.DueAt,
+			Attempt:      schedule.Attempts + 1,
+			Status:       ScheduleRunSkipped,
+			Error:        refused.Error(),
+		}
+		retry := errors.Is(refused, apierr.InsufficientFund) && schedule.OnInsufficientFund == PolicyRetry && schedule.Attempts < schedule.MaxRetries
+		if retry {
+			run.Status = ScheduleRunFailed
+			schedule.Attempts++
+			schedule.NextRunAt = now.Add(utils.ScheduleRetryInterval)
+		} else {
+			schedule.advance()
+		}
+		err = insertScheduleRun(ctx, exec, run)
+		if err != nil {
+			return err
+		}
+		return updateSchedule(ctx, exec, schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info(ctx, "run %s of schedule is %s with err: %s", run.ExecutionKey, run.Status, refused)
+	return run, nil
+}
+
+// isRefusedTransfer tells whether the transfer is refused by the state of the wallets rather than a failure of the database.
+func isRefusedTransfer(err error) bool {
+	for _, target := range []error{apierr.InsufficientFund, apierr.NotFound, apierr.WalletFrozen, apierr.WalletClosed, apierr.LimitExceeded, money.ErrOverflow, currency.ErrUnSupportedCurrency} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func getSchedule(ctx context.Context, exec sqlx.ExtContext, where squirrel.Eq, suffix string) (*TransferSchedulesModel, error) {
+	queryBuilder := psql.Select(schedulesColumns...).
+		From("transfer_schedules").
+		Where(where)
+	if suffix != "" {
+		queryBuilder = queryBuilder.Suffix(suffix)
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build get schedule query with err: %s", err)
+		return nil, fmt.Errorf("build get schedule query: %w", err)
+	}
+	schedule := new(TransferSchedulesModel)
+	err = sqlx.GetContext(ctx, exec, schedule, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierr.NotFound
+		}
+		log.Error(ctx, "failed to get schedule with err: %s", err)
+		return nil, fmt.Errorf("get schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// getDueSchedule locks the schedule if it is still due by now. SKIP LOCKED leaves the schedule being
+// executed by another instance to that instance.
+func getDueSchedule(ctx context.Context, exec sqlx.ExtContext, uid string, now time.Time) (*TransferSchedulesModel, error) {
+	schedule, err := getSchedule(ctx, exec, squirrel.Eq{"uid": uid}, "FOR UPDATE SKIP LOCKED")
+	if err != nil {
+		if errors.Is(err, apierr.NotFound) {
+			return nil, ErrScheduleNotDue
+		}
+		return nil, err
+	}
+	if schedule.Status != ScheduleStatusActive || schedule.NextRunAt.After(now) {
+		return nil, ErrScheduleNotDue
+	}
+	return schedule, nil
+}
+
+func isOccurrencePaid(ctx context.Context, exec sqlx.ExtContext, executionKey string) (bool, error) {
+	query, args, err := psql.Select("COUNT(*)").
+		From("transfer_schedule_runs").
+		Where(squirrel.Eq{
+			"execution_key": executionKey,
+			"status":        ScheduleRunCompleted,
+		}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build paid occurrence query with err: %s", err)
+		return false, fmt.Errorf("build paid occurrence query: %w", err)
+	}
+	var count int
+	err = sqlx.GetContext(ctx, exec, &count, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to check paid occurrence %s with err: %s", executionKey, err)
+		return false, fmt.Errorf("check paid occurrence: %w", err)
+	}
+	return count > 0, nil
+}
+
+func insertScheduleRun(ctx context.Context, exec sqlx.ExtContext, run *TransferScheduleRunsModel) error {
+	query, args, err := psql.Insert("transfer_schedule_runs").
+		Columns("schedule_id", "execution_key", "due_at", "attempt", "status", "tx_uid", "error").
+		Values(run.ScheduleID, run.ExecutionKey, run.DueAt, run.Attempt, run.Status, run.TxUID, run.Error).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build schedule run insert query with err: %s", err)
+		return fmt.Errorf("build schedule run insert query: %w", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to insert schedule run with err: %s", err)
+		return fmt.Errorf("insert schedule run: %w", err)
+	}
+	return nil
+}
+
+func updateSchedule(ctx context.Context, exec sqlx.ExtContext, schedule *TransferSchedulesModel) error {
+	query, args, err := psql.Update("transfer_schedules").
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Set("amount", schedule.Amount).
+		Set("end_at", schedule.EndAt).
+		Set("occurrence", schedule.Occurrence).
+		Set("due_at", schedule.DueAt).
+		Set("next_run_at", schedule.NextRunAt).
+		Set("attempts", schedule.Attempts).
+		Set("on_insufficient_fund", schedule.OnInsufficientFund).
+		Set("max_retries", schedule.MaxRetries).
+		Set("status", schedule.Status).
+		Where(squirrel.Eq{"id": schedule.ID}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build schedule update query with err: %s", err)
+		return fmt.Errorf("build schedule update query: %w", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to update schedule %s with err: %s", schedule.UID, err)
+		return fmt.Errorf("update schedule: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	getDueScheduleQuery = "SELECT id, uid, username, receiver, currency, amount, reference, frequency, start_at, end_at, occurrence, due_at, next_run_at, attempts, on_insufficient_fund, max_retries, status, created_at, updated_at " +
+		"FROM transfer_schedules WHERE uid = $1 FOR UPDATE SKIP LOCKED"
+	paidOccurrenceQuery    = "SELECT COUNT(*) FROM transfer_schedule_runs WHERE execution_key = $1 AND status = $2"
+	insertScheduleRunQuery = "INSERT INTO transfer_schedule_runs (schedule_id,execution_key,due_at,attempt,status,tx_uid,error) VALUES ($1,$2,$3,$4,$5,$6,$7)"
+	updateScheduleQuery    = "UPDATE transfer_schedules SET updated_at = NOW(), amount = $1, end_at = $2, occurrence = $3, due_at = $4, next_run_at = $5, attempts = $6, on_insufficient_fund = $7, max_retries = $8, status = $9 WHERE id = $10"
+)
+
+func scheduleRows(s TransferSchedulesModel) *sqlmock.Rows {
+	return sqlmock.NewRows(schedulesColumns).
+		AddRow(s.ID, s.UID, s.Username, s.Receiver, s.Currency, s.Amount, s.Reference, s.Frequency, s.StartAt, s.EndAt,
+			s.Occurrence, s.DueAt, s.NextRunAt, s.Attempts, s.OnInsufficientFund, s.MaxRetries, s.Status, s.CreatedAt, s.UpdatedAt)
+}
+
+func Test_nextOccurrence(t *testing.T) {
+	start := time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		frequency Frequency
+		n         int
+		want      time.Time
+	}{
+		{name: "weekly", frequency: FrequencyWeekly, n: 2, want: time.Date(2026, 2, 14, 9, 0, 0, 0, time.UTC)},
+		{name: "monthly clamps to end of short month", frequency: FrequencyMonthly, n: 1, want: time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC)},
+		{name: "monthly keeps day of start after short month", frequency: FrequencyMonthly, n: 2, want: time.Date(2026, 3, 31, 9, 0, 0, 0, time.UTC)},
+		{name: "monthly clamps to 30th", frequency: FrequencyMonthly, n: 3, want: time.Date(2026, 4, 30, 9, 0, 0, 0, time.UTC)},
+		{name: "once", frequency: FrequencyOnce, n: 1, want: start},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nextOccurrence(start, tt.frequency, tt.n))
+		})
+	}
+}
+
+func TestTransferSchedulesModel_advance(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("once is completed", func(t *testing.T) {
+		s := TransferSchedulesModel{Frequency: FrequencyOnce, StartAt: start, DueAt: start, Status: ScheduleStatusActive}
+		s.advance()
+		assert.Equal(t, ScheduleStatusCompleted, s.Status)
+		assert.Equal(t, 1, s.Occurrence)
+	})
+
+	t.Run("weekly moves to next week and resets attempts", func(t *testing.T) {
+		s := TransferSchedulesModel{Frequency: FrequencyWeekly, StartAt: start, DueAt: start, NextRunAt: start.Add(time.Hour), Attempts: 2, Status: ScheduleStatusActive}
+		s.advance()
+		assert.Equal(t, ScheduleStatusActive, s.Status)
+		assert.Equal(t, start.AddDate(0, 0, 7), s.DueAt)
+		assert.Equal(t, s.DueAt, s.NextRunAt)
+		assert.Equal(t, 0, s.Attempts)
+	})
+
+	t.Run("completed after end", func(t *testing.T) {
+		end := start.AddDate(0, 0, 10)
+		s := TransferSchedulesModel{Frequency: FrequencyWeekly, StartAt: start, EndAt: &end, Occurrence: 1, DueAt: start.AddDate(0, 0, 7), Status: ScheduleStatusActive}
+		s.advance()
+		assert.Equal(t, ScheduleStatusCompleted, s.Status)
+	})
+}
+
+func Test_schedules_Execute(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &schedules{db: sqlx.NewDb(mockDB, "sqlmock")}
+
+	now := time.Date(2026, 10, 17, 0, 0, 30, 0, time.UTC)
+	due := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	schedule := TransferSchedulesModel{
+		ID: 1, UID: "sch1", Username: "user1", Receiver: "user2", Currency: "SGD", Amount: 100, Reference: "rent",
+		Frequency: FrequencyMonthly, StartAt: due.AddDate(0, -1, 0), Occurrence: 1, DueAt: due, NextRunAt: due,
+		OnInsufficientFund: PolicyRetry, MaxRetries: 1, Status: ScheduleStatusActive,
+	}
+	expectTransferLocks := func() {
+		for i, username := range []string{"user1", "user2"} {
+			mock.ExpectQuery(lockWalletQuery).
+				WithArgs(username, "SGD").
+				WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(i+1, username, 50, "active"))
+		}
+		expectNoLimits(mock, "user1", "SGD")
+		expectNoLimits(mock, "user2", "SGD")
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference,metadata) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)").
+			WithArgs(sqlmock.AnyArg(), TypeTransfer, "user1", "SGD", 100, StatusCompleted, "rent", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+
+	t.Run("ok execute moves to next occurrence", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(getDueScheduleQuery).WithArgs("sch1").WillReturnRows(scheduleRows(schedule))
+		mock.ExpectQuery(paidOccurrenceQuery).WithArgs("sch1:1", ScheduleRunCompleted).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		expectTransferLocks()
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-100, "SGD", "user1", WalletStatusActive, 100).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user1", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user1", "SGD", 100, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(100, "SGD", "user2", WalletStatusActive).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLastLedgerHash(mock, "user2", "SGD")
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "user2", "SGD", 100, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectWalletEvent(mock, EventWalletDebited, "user1", "SGD")
+		expectWalletEvent(mock, EventWalletCredited, "user2", "SGD")
+		mock.ExpectExec(insertScheduleRunQuery).
+			WithArgs(1, "sch1:1", due, 1, ScheduleRunCompleted, sqlmock.AnyArg(), "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		next := time.Date(2026, 11, 17, 0, 0, 0, 0, time.UTC)
+		mock.ExpectExec(updateScheduleQuery).
+			WithArgs(100, nil, 2, next, next, 0, PolicyRetry, 1, ScheduleStatusActive, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		run, err := p.Execute(t.Context(), "sch1", now)
+		assert.NoError(t, err)
+		assert.Equal(t, ScheduleRunCompleted, run.Status)
+		assert.NotEmpty(t, run.TxUID)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("paid occurrence is not paid again", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(getDueScheduleQuery).WithArgs("sch1").WillReturnRows(scheduleRows(schedule))
+		mock.ExpectQuery(paidOccurrenceQuery).WithArgs("sch1:1", ScheduleRunCompleted).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		next := time.Date(2026, 11, 17, 0, 0, 0, 0, time.UTC)
+		mock.ExpectExec(updateScheduleQuery).
+			WithArgs(100, nil, 2, next, next, 0, PolicyRetry, 1, ScheduleStatusActive, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		run, err := p.Execute(t.Context(), "sch1", now)
+		assert.NoError(t, err)
+		assert.Nil(t, run)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	expectInsufficientFund := func(s TransferSchedulesModel) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(getDueScheduleQuery).WithArgs("sch1").WillReturnRows(scheduleRows(s))
+		mock.ExpectQuery(paidOccurrenceQuery).WithArgs("sch1:1", ScheduleRunCompleted).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		expectTransferLocks()
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-100, "SGD", "user1", WalletStatusActive, 100).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+			WithArgs("user1", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "user1", 50, "active"))
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectQuery(getDueScheduleQuery).WithArgs("sch1").WillReturnRows(scheduleRows(s))
+	}
+
+	t.Run("insufficient fund is retried", func(t *testing.T) {
+		expectInsufficientFund(schedule)
+		mock.ExpectExec(insertScheduleRunQuery).
+			WithArgs(1, "sch1:1", due, 1, ScheduleRunFailed, "", apierr.InsufficientFund.Error()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		retryAt := now.Add(time.Hour)
+		mock.ExpectExec(updateScheduleQuery).
+			WithArgs(100, nil, 1, due, retryAt, 1, PolicyRetry, 1, ScheduleStatusActive, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		run, err := p.Execute(t.Context(), "sch1", now)
+		assert.NoError(t, err)
+		assert.Equal(t, ScheduleRunFailed, run.Status)
+		assert.Equal(t, 1, run.Attempt)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("insufficient fund is skipped after max retries", func(t *testing.T) {
+		retried := schedule
+		retried.Attempts = 1
+		expectInsufficientFund(retried)
+		mock.ExpectExec(insertScheduleRunQuery).
+			WithArgs(1, "sch1:1", due, 2, ScheduleRunSkipped, "", apierr.InsufficientFund.Error()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		next := time.Date(2026, 11, 17, 0, 0, 0, 0, time.UTC)
+		mock.ExpectExec(updateScheduleQuery).
+			WithArgs(100, nil, 2, next, next, 0, PolicyRetry, 1, ScheduleStatusActive, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		run, err := p.Execute(t.Context(), "sch1", now)
+		assert.NoError(t, err)
+		assert.Equal(t, ScheduleRunSkipped, run.Status)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("not due anymore", func(t *testing.T) {
+		later := schedule
+		later.NextRunAt = now.Add(time.Hour)
+		mock.ExpectBegin()
+		mock.ExpectQuery(getDueScheduleQuery).WithArgs("sch1").WillReturnRows(scheduleRows(later))
+		mock.ExpectRollback()
+
+		run, err := p.Execute(t.Context(), "sch1", now)
+		assert.ErrorIs(t, err, ErrScheduleNotDue)
+		assert.Nil(t, run)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("locked by another instance", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(getDueScheduleQuery).WithArgs("sch1").WillReturnRows(sqlmock.NewRows(schedulesColumns))
+		mock.ExpectRollback()
+
+		_, err := p.Execute(t.Context(), "sch1", now)
+		assert.ErrorIs(t, err, ErrScheduleNotDue)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_schedules_Cancel(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &schedules{db: sqlx.NewDb(mockDB, "sqlmock")}
+	getForUpdateQuery := "SELECT id, uid, username, receiver, currency, amount, reference, frequency, start_at, end_at, occurrence, due_at, next_run_at, attempts, on_insufficient_fund, max_retries, status, created_at, updated_at " +
+		"FROM transfer_schedules WHERE uid = $1 AND username = $2 FOR UPDATE"
+	due := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	schedule := TransferSchedulesModel{ID: 1, UID: "sch1", Username: "user1", Amount: 100, Frequency: FrequencyOnce, StartAt: due, DueAt: due, NextRunAt: due, OnInsufficientFund: PolicySkip, Status: ScheduleStatusActive}
+
+	t.Run("ok cancel", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(getForUpdateQuery).WithArgs("sch1", "user1").WillReturnRows(scheduleRows(schedule))
+		mock.ExpectExec(updateScheduleQuery).
+			WithArgs(100, nil, 0, due, due, 0, PolicySkip, 0, ScheduleStatusCancelled, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		cancelled, err := p.Cancel(t.Context(), "sch1", "user1")
+		assert.NoError(t, err)
+		assert.Equal(t, ScheduleStatusCancelled, cancelled.Status)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("cancel completed schedule", func(t *testing.T) {
+		completed := schedule
+		completed.Status = ScheduleStatusCompleted
+		mock.ExpectBegin()
+		mock.ExpectQuery(getForUpdateQuery).WithArgs("sch1", "user1").WillReturnRows(scheduleRows(completed))
+		mock.ExpectRollback()
+
+		_, err := p.Cancel(t.Context(), "sch1", "user1")
+		assert.ErrorIs(t, err, ErrScheduleNotActive)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
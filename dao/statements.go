@@ -0,0 +1,121 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+// StatementSummaryModel is the balances and totals of a wallet over the period of a statement.
+type StatementSummaryModel struct {
+	// OpeningBalance is the sum of the ledgers before the period
+	OpeningBalance int64 `db:"opening_balance"`
+	TotalCredit    int64 `db:"total_credit"`
+	TotalDebit     int64 `db:"total_debit"`
+	CreditCount    int   `db:"credit_count"`
+	DebitCount     int   `db:"debit_count"`
+}
+
+// ClosingBalance is the opening balance moved by every ledger within the period.
+func (s StatementSummaryModel) ClosingBalance() int64 {
+	return s.OpeningBalance + s.TotalCredit - s.TotalDebit
+}
+
+type StatementEntryModel struct {
+	LedgerID  int       `db:"id"`
+	TxUID     string    `db:"tx_uid"`
+	Type      TxType    `db:"type"`
+	Reference string    `db:"reference"`
+	Direction Direction `db:"direction"`
+	Amount    int64     `db:"amount"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// StatementEntries calls yield with the ledgers of the statement one by one in booking order, it stops at
+// the first error returned by yield.
+type StatementEntries func(yield func(entry StatementEntryModel) error) error
+
+// Statement reads the summary and the ledgers of the wallet created within [from, to) from the same read only
+// snapshot, so the closing balance always adds up to the entries written. The entries are scanned from the
+// database cursor while write consumes them instead of being loaded at once, the snapshot lives until write returns.
+func (p *ledgers) Statement(ctx context.Context, username, currency string, from, to time.Time, write func(summary StatementSummaryModel, entries StatementEntries) error) error {
+	tx, err := p.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		log.Error(ctx, "failed to begin statement transaction: %v", err)
+		return fmt.Errorf("begin statement transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Error(ctx, "failed in statement txn rollback with err: %s", err)
+		}
+	}()
+
+	query, args, err := psql.Select().
+		Column("COALESCE(SUM(CASE WHEN direction = ? THEN amount ELSE -amount END) FILTER (WHERE created_at < ?), 0) AS opening_balance", DirectionCredit, from).
+		Column("COALESCE(SUM(amount) FILTER (WHERE direction = ? AND created_at >= ?), 0) AS total_credit", DirectionCredit, from).
+		Column("COALESCE(SUM(amount) FILTER (WHERE direction = ? AND created_at >= ?), 0) AS total_debit", DirectionDebit, from).
+		Column("COUNT(*) FILTER (WHERE direction = ? AND created_at >= ?) AS credit_count", DirectionCredit, from).
+		Column("COUNT(*) FILTER (WHERE direction = ? AND created_at >= ?) AS debit_count", DirectionDebit, from).
+		From("ledgers").
+		Where(squirrel.Eq{
+			"username": username,
+			"currency": currency,
+		}).
+		Where(squirrel.Lt{"created_at": to}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build statement summary query: %v", err)
+		return fmt.Errorf("build statement summary query: %w", err)
+	}
+	var summary StatementSummaryModel
+	err = tx.GetContext(ctx, &summary, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to get statement summary of %s %s: %v", username, currency, err)
+		return fmt.Errorf("get statement summary: %w", err)
+	}
+
+	entries := func(yield func(entry StatementEntryModel) error) error {
+		query, args, err := psql.Select("le.id", "le.tx_uid", "t.type", "t.reference", "le.direction", "le.amount", "le.created_at").
+			From("ledgers le").
+			Join("transactions t ON t.uid = le.tx_uid").
+			Where(squirrel.Eq{
+				"le.username": username,
+				"le.currency": currency,
+			}).
+			Where(squirrel.GtOrEq{"le.created_at": from}).
+			Where(squirrel.Lt{"le.created_at": to}).
+			OrderBy("le.created_at", "le.id").
+			ToSql()
+		if err != nil {
+			log.Error(ctx, "failed to build statement entries query: %v", err)
+			return fmt.Errorf("build statement entries query: %w", err)
+		}
+		rows, err := tx.QueryxContext(ctx, query, args...)
+		if err != nil {
+			log.Error(ctx, "failed to query statement entries of %s %s: %v", username, currency, err)
+			return fmt.Errorf("query statement entries: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var entry StatementEntryModel
+			if err = rows.StructScan(&entry); err != nil {
+				log.Error(ctx, "failed to scan statement entry: %v", err)
+				return fmt.Errorf("scan statement entry: %w", err)
+			}
+			if err = yield(entry); err != nil {
+				return err
+			}
+		}
+		if err = rows.Err(); err != nil {
+			log.Error(ctx, "failed to iterate statement entries: %v", err)
+			return fmt.Errorf("iterate statement entries: %w", err)
+		}
+		return nil
+	}
+	return write(summary, entries)
+}
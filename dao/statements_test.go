@@ -0,0 +1,107 @@
+package dao
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	statementSummaryQuery = "SELECT COALESCE(SUM(CASE WHEN direction = $1 THEN amount ELSE -amount END) FILTER (WHERE created_at < $2), 0) AS opening_balance, " +
+		"COALESCE(SUM(amount) FILTER (WHERE direction = $3 AND created_at >= $4), 0) AS total_credit, " +
+		"COALESCE(SUM(amount) FILTER (WHERE direction = $5 AND created_at >= $6), 0) AS total_debit, " +
+		"COUNT(*) FILTER (WHERE direction = $7 AND created_at >= $8) AS credit_count, " +
+		"COUNT(*) FILTER (WHERE direction = $9 AND created_at >= $10) AS debit_count " +
+		"FROM ledgers WHERE currency = $11 AND username = $12 AND created_at < $13"
+	statementEntriesQuery = "SELECT le.id, le.tx_uid, t.type, t.reference, le.direction, le.amount, le.created_at FROM ledgers le " +
+		"JOIN transactions t ON t.uid = le.tx_uid WHERE le.currency = $1 AND le.username = $2 AND le.created_at >= $3 AND le.created_at < $4 " +
+		"ORDER BY le.created_at, le.id"
+)
+
+var (
+	statementSummaryColumns = []string{"opening_balance", "total_credit", "total_debit", "credit_count", "debit_count"}
+	statementEntryColumns   = []string{"id", "tx_uid", "type", "reference", "direction", "amount", "created_at"}
+)
+
+func Test_ledgers_Statement(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &ledgers{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	expectSummary := func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(statementSummaryQuery).
+			WithArgs(DirectionCredit, from, DirectionCredit, from, DirectionDebit, from, DirectionCredit, from, DirectionDebit, from, "SGD", "user1", to).
+			WillReturnRows(sqlmock.NewRows(statementSummaryColumns).AddRow(1000, 500, 250, 1, 1))
+	}
+
+	t.Run("ok stream entries from the same snapshot", func(t *testing.T) {
+		expectSummary()
+		mock.ExpectQuery(statementEntriesQuery).
+			WithArgs("SGD", "user1", from, to).
+			WillReturnRows(sqlmock.NewRows(statementEntryColumns).
+				AddRow(1, "tx1", "deposit", "salary", "c", 500, from.Add(time.Hour)).
+				AddRow(2, "tx2", "transfer", "rent", "d", 250, from.Add(2*time.Hour)))
+		mock.ExpectRollback()
+
+		var entries []StatementEntryModel
+		err := p.Statement(t.Context(), "user1", "SGD", from, to, func(summary StatementSummaryModel, each StatementEntries) error {
+			assert.Equal(t, int64(1000), summary.OpeningBalance)
+			assert.Equal(t, int64(1250), summary.ClosingBalance())
+			return each(func(entry StatementEntryModel) error {
+				entries = append(entries, entry)
+				return nil
+			})
+		})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, DirectionDebit, entries[1].Direction)
+		assert.Equal(t, "rent", entries[1].Reference)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("writer error stops the stream", func(t *testing.T) {
+		expectSummary()
+		mock.ExpectQuery(statementEntriesQuery).
+			WithArgs("SGD", "user1", from, to).
+			WillReturnRows(sqlmock.NewRows(statementEntryColumns).
+				AddRow(1, "tx1", "deposit", "salary", "c", 500, from.Add(time.Hour)).
+				AddRow(2, "tx2", "transfer", "rent", "d", 250, from.Add(2*time.Hour)))
+		mock.ExpectRollback()
+
+		written := 0
+		writeErr := errors.New("client gone")
+		err := p.Statement(t.Context(), "user1", "SGD", from, to, func(summary StatementSummaryModel, each StatementEntries) error {
+			return each(func(entry StatementEntryModel) error {
+				written++
+				return writeErr
+			})
+		})
+		assert.ErrorIs(t, err, writeErr)
+		assert.Equal(t, 1, written)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("summary error", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(statementSummaryQuery).WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		err := p.Statement(t.Context(), "user1", "SGD", from, to, func(summary StatementSummaryModel, each StatementEntries) error {
+			t.Fatal("shouldn't called")
+			return nil
+		})
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
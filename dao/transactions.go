@@ -2,19 +2,26 @@ package dao
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
 	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/metadata"
 )
 
 //go:generate mockery --name TransactionsRepository --output ./mocks --outpkg mocks --case=underscore
 type TransactionsRepository interface {
 	Insert(ctx context.Context, user TransactionsModel) error
-	ListByReference(ctx context.Context, limit int, startingAfter, reference, username string) ([]TransactionsModel, bool, error)
+	ListByReference(ctx context.Context, limit int, startingAfter, reference, username string, filter HistoryFilter) ([]TransactionsModel, bool, error)
 	GetByUID(ctx context.Context, uid string) (*TransactionsModel, error)
+	ListByMetadata(ctx context.Context, limit int, startingAfter, username, key, value string) ([]TransactionsModel, bool, error)
+	Reverse(ctx context.Context, originalUID, initiatedBy, reference string, amount int64) (*TransactionsModel, error)
+	Refund(ctx context.Context, originalUID, initiatedBy, reference string, amount int64) (*TransactionsModel, error)
 }
 
 type TxType string
@@ -23,6 +30,16 @@ const (
 	TypeDeposit  TxType = "deposit"
 	TypeWithdraw TxType = "withdraw"
 	TypeTransfer TxType = "transfer"
+	// TypeBulkTransfer debits the sender once and credits many receivers with their own amount
+	TypeBulkTransfer TxType = "bulk_transfer"
+	// TypeHold is pending until the held fund is captured or released
+	TypeHold TxType = "hold"
+	// TypeReversal is the admin correction which mirrors the ledgers of the original transaction
+	TypeReversal TxType = "reversal"
+	// TypeRefund is initiated by the credited user to return the fund of the original transaction
+	TypeRefund TxType = "refund"
+	// TypeExchange debits one currency and credits another with the rate locked by a fx quote
+	TypeExchange TxType = "exchange"
 )
 
 type TxStatus string
@@ -35,17 +52,20 @@ const (
 )
 
 type TransactionsModel struct {
-	ID          int       `db:"id"`
-	UID         string    `db:"uid"`
-	Reference   string    `db:"reference"`
-	Type        TxType    `db:"type"`
-	InitiatedBy string    `db:"initiated_by"`
-	Status      TxStatus  `db:"status"`
-	Amount      int       `db:"amount"`
-	Currency    string    `db:"currency"`
-	Metadata    string    `db:"metadata"`
-	CreatedAt   time.Time `db:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"`
+	ID          int               `db:"id"`
+	UID         string            `db:"uid"`
+	Reference   string            `db:"reference"`
+	Type        TxType            `db:"type"`
+	InitiatedBy string            `db:"initiated_by"`
+	Status      TxStatus          `db:"status"`
+	Amount      int64             `db:"amount"`
+	Currency    string            `db:"currency"`
+	Metadata    metadata.Metadata `db:"metadata"`
+	// OriginalUID is the transaction reversed or refunded by this transaction
+	OriginalUID    string    `db:"original_uid"`
+	RefundedAmount int64     `db:"refunded_amount"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
 }
 
 type transactions struct {
@@ -59,7 +79,7 @@ func NewTransactions(dao *DAO) *transactions {
 }
 
 func buildSelect() squirrel.SelectBuilder {
-	return psql.Select("reference", "initiated_by", "uid", "type", "status", "amount", "currency", "created_at").
+	return psql.Select("reference", "initiated_by", "uid", "type", "status", "amount", "currency", "original_uid", "refunded_amount", "metadata", "created_at").
 		From("transactions")
 }
 
@@ -67,7 +87,9 @@ func (p *transactions) Insert(ctx context.Context, tx TransactionsModel) error {
 	return insertTransaction(ctx, p.db, tx)
 }
 
-func (p *transactions) ListByReference(ctx context.Context, limit int, startingAfter, reference, username string) ([]TransactionsModel, bool, error) {
+// ListByReference lists the transactions of the reference initiated by the user, an empty reference lists the ones
+// without reference. The direction of the filter doesn't apply as a transaction has no direction.
+func (p *transactions) ListByReference(ctx context.Context, limit int, startingAfter, reference, username string, filter HistoryFilter) ([]TransactionsModel, bool, error) {
 	sq := buildSelect().
 		Where(squirrel.Eq{
 			"reference":    reference,
@@ -78,6 +100,7 @@ func (p *transactions) ListByReference(ctx context.Context, limit int, startingA
 	if startingAfter != "" {
 		sq = sq.Where("uid < ?", startingAfter)
 	}
+	sq = filter.apply(sq, transactionHistoryColumns)
 	query, args, err := sq.ToSql()
 	if err != nil {
 		log.Error(ctx, "failed to build list txn by reference with err: %s", err)
@@ -109,16 +132,62 @@ func (p *transactions) GetByUID(ctx context.Context, uid string) (*TransactionsM
 	transaction := new(TransactionsModel)
 	err = p.db.GetContext(ctx, transaction, query, args...)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierr.NotFound
+		}
 		log.Error(ctx, "failed to get tx by uid: %v", err)
 		return nil, fmt.Errorf("failed to get tx by uid: %s", err)
 	}
 	return transaction, nil
 }
 
+// ListByMetadata lists the transactions initiated by username which metadata has the key,
+// the value is matched as well when it is not empty. Both operators are served by the GIN index of metadata.
+func (p *transactions) ListByMetadata(ctx context.Context, limit int, startingAfter, username, key, value string) ([]TransactionsModel, bool, error) {
+	sq := buildSelect().
+		Where(squirrel.Eq{"initiated_by": username}).
+		OrderBy("created_at DESC").
+		Limit(uint64(limit + 1))
+	if value == "" {
+		sq = sq.Where("metadata ?? ?", key)
+	} else {
+		sq = sq.Where("metadata @> ?", metadata.Metadata{key: value})
+	}
+	if startingAfter != "" {
+		sq = sq.Where("uid < ?", startingAfter)
+	}
+	query, args, err := sq.ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list txn by metadata with err: %s", err)
+		return nil, false, err
+	}
+	transactions := []TransactionsModel{}
+	err = p.db.SelectContext(ctx, &transactions, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list tx from metadata: %v", err)
+		return nil, false, fmt.Errorf("list tx from metadata: %s", err)
+	}
+	hasMore := len(transactions) > limit
+	if hasMore {
+		transactions = transactions[:limit]
+	}
+	return transactions, hasMore, nil
+}
+
 func insertTransaction(ctx context.Context, exec sqlx.ExtContext, tx TransactionsModel) error {
+	columns := []string{"uid", "type", "initiated_by", "currency", "amount", "status", "reference"}
+	values := []any{tx.UID, tx.Type, tx.InitiatedBy, tx.Currency, tx.Amount, tx.Status, tx.Reference}
+	if tx.OriginalUID != "" {
+		columns = append(columns, "original_uid")
+		values = append(values, tx.OriginalUID)
+	}
+	if len(tx.Metadata) > 0 {
+		columns = append(columns, "metadata")
+		values = append(values, tx.Metadata)
+	}
 	txQuery, txArgs, err := psql.Insert("transactions").
-		Columns("uid", "type", "initiated_by", "currency", "amount", "status", "reference").
-		Values(tx.UID, tx.Type, tx.InitiatedBy, tx.Currency, tx.Amount, tx.Status, tx.Reference).
+		Columns(columns...).
+		Values(values...).
 		ToSql()
 	if err != nil {
 		log.Error(ctx, "failed to build tx insert query: %v", err)
@@ -132,3 +201,23 @@ func insertTransaction(ctx context.Context, exec sqlx.ExtContext, tx Transaction
 	log.Debug(ctx, "Transactions created : %s", tx.UID)
 	return nil
 }
+
+func updateTransactionStatus(ctx context.Context, exec sqlx.ExtContext, uid string, status TxStatus, amount int64) error {
+	query, args, err := psql.Update("transactions").
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Set("status", status).
+		Set("amount", amount).
+		Where(squirrel.Eq{"uid": uid}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build tx update query: %v", err)
+		return fmt.Errorf("build tx update query: %s", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to update transactions: %v", err)
+		return fmt.Errorf("update transactions: %s", err)
+	}
+	log.Debug(ctx, "Transactions %s updated to %s", uid, status)
+	return nil
+}
@@ -1,14 +1,20 @@
 package dao
 
 import (
+	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/metadata"
 	"github.com/stretchr/testify/assert"
 )
 
+var transactionColumns = []string{"reference", "initiated_by", "uid", "type", "status", "amount", "currency", "original_uid", "refunded_amount", "metadata", "created_at"}
+
 func TestNewTransactions(t *testing.T) {
 	mockDB, _, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	if err != nil {
@@ -47,7 +53,7 @@ func Test_transactions_Insert(t *testing.T) {
 			Status:      "completed",
 			Amount:      100,
 			Currency:    "SGD",
-			Metadata:    "",
+			Metadata:    nil,
 		})
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
@@ -65,9 +71,176 @@ func Test_transactions_Insert(t *testing.T) {
 			Status:      "completed",
 			Amount:      100,
 			Currency:    "SGD",
-			Metadata:    "",
+			Metadata:    nil,
 		})
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
 }
+
+func Test_transactions_InsertWithMetadata(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	p := &transactions{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	t.Run("ok transaction insert with metadata", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference,metadata) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)").
+			WithArgs("uid", "type", "initiator", "SGD", 100, "completed", "ref", `{"order_id":"123"}`).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		err := p.Insert(t.Context(), TransactionsModel{
+			Reference:   "ref",
+			UID:         "uid",
+			Type:        "type",
+			InitiatedBy: "initiator",
+			Status:      "completed",
+			Amount:      100,
+			Currency:    "SGD",
+			Metadata:    metadata.Metadata{"order_id": "123"},
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_transactions_GetByUID(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	p := &transactions{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	query := "SELECT reference, initiated_by, uid, type, status, amount, currency, original_uid, refunded_amount, metadata, created_at FROM transactions WHERE uid = $1"
+
+	t.Run("ok get transaction with metadata", func(t *testing.T) {
+		now := time.Now()
+		rows := sqlmock.NewRows(transactionColumns).
+			AddRow("ref", "user1", "uid", "deposit", "completed", 100, "SGD", "", 0, []byte(`{"order_id":"123"}`), now)
+		mock.ExpectQuery(query).
+			WithArgs("uid").
+			WillReturnRows(rows)
+		tx, err := p.GetByUID(t.Context(), "uid")
+		assert.NoError(t, err)
+		assert.Equal(t, metadata.Metadata{"order_id": "123"}, tx.Metadata)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("get transaction not found", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("uid").
+			WillReturnError(sql.ErrNoRows)
+		tx, err := p.GetByUID(t.Context(), "uid")
+		assert.ErrorIs(t, err, apierr.NotFound)
+		assert.Nil(t, tx)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_transactions_ListByMetadata(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	p := &transactions{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	now := time.Now()
+
+	t.Run("ok list by metadata key and value", func(t *testing.T) {
+		rows := sqlmock.NewRows(transactionColumns).
+			AddRow("ref", "user1", "uid2", "deposit", "completed", 100, "SGD", "", 0, []byte(`{"order_id":"123"}`), now).
+			AddRow("ref", "user1", "uid1", "deposit", "completed", 100, "SGD", "", 0, []byte(`{"order_id":"123"}`), now)
+		mock.ExpectQuery("SELECT reference, initiated_by, uid, type, status, amount, currency, original_uid, refunded_amount, metadata, created_at FROM transactions WHERE initiated_by = $1 AND metadata @> $2 ORDER BY created_at DESC LIMIT 2").
+			WithArgs("user1", `{"order_id":"123"}`).
+			WillReturnRows(rows)
+		txs, hasMore, err := p.ListByMetadata(t.Context(), 1, "", "user1", "order_id", "123")
+		assert.NoError(t, err)
+		assert.True(t, hasMore)
+		assert.Len(t, txs, 1)
+		assert.Equal(t, "uid2", txs[0].UID)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("ok list by metadata key only", func(t *testing.T) {
+		mock.ExpectQuery("SELECT reference, initiated_by, uid, type, status, amount, currency, original_uid, refunded_amount, metadata, created_at FROM transactions WHERE initiated_by = $1 AND metadata ? $2 AND uid < $3 ORDER BY created_at DESC LIMIT 11").
+			WithArgs("user1", "order_id", "uid1").
+			WillReturnRows(sqlmock.NewRows(transactionColumns))
+		txs, hasMore, err := p.ListByMetadata(t.Context(), 10, "uid1", "user1", "order_id", "")
+		assert.NoError(t, err)
+		assert.False(t, hasMore)
+		assert.Empty(t, txs)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("list by metadata error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT reference, initiated_by, uid, type, status, amount, currency, original_uid, refunded_amount, metadata, created_at FROM transactions WHERE initiated_by = $1 AND metadata ? $2 ORDER BY created_at DESC LIMIT 11").
+			WithArgs("user1", "order_id").
+			WillReturnError(errors.New("err"))
+		txs, hasMore, err := p.ListByMetadata(t.Context(), 10, "", "user1", "order_id", "")
+		assert.Error(t, err)
+		assert.False(t, hasMore)
+		assert.Nil(t, txs)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_transactions_ListByReference(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &transactions{db: sqlx.NewDb(mockDB, "sqlmock")}
+	selectQuery := "SELECT reference, initiated_by, uid, type, status, amount, currency, original_uid, refunded_amount, metadata, created_at FROM transactions"
+
+	t.Run("ok filter ignores direction", func(t *testing.T) {
+		from := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(selectQuery+" WHERE initiated_by = $1 AND reference = $2 AND type IN ($3) AND status IN ($4) AND amount >= $5 AND created_at >= $6 ORDER BY created_at DESC LIMIT 11").
+			WithArgs("user1", "ref", TypeTransfer, StatusFailed, 100, from).
+			WillReturnRows(sqlmock.NewRows(transactionColumns).
+				AddRow("ref", "user1", "uid1", TypeTransfer, StatusFailed, 500, "SGD", "", 0, nil, from))
+
+		txs, hasMore, err := p.ListByReference(t.Context(), 10, "", "ref", "user1", HistoryFilter{
+			Types:       []TxType{TypeTransfer},
+			Statuses:    []TxStatus{StatusFailed},
+			Direction:   DirectionDebit,
+			MinAmount:   100,
+			CreatedFrom: from,
+		})
+		assert.NoError(t, err)
+		assert.False(t, hasMore)
+		assert.Equal(t, "uid1", txs[0].UID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty reference only lists transactions without reference", func(t *testing.T) {
+		mock.ExpectQuery(selectQuery+" WHERE initiated_by = $1 AND reference = $2 ORDER BY created_at DESC LIMIT 11").
+			WithArgs("user1", "").
+			WillReturnRows(sqlmock.NewRows(transactionColumns))
+
+		txs, hasMore, err := p.ListByReference(t.Context(), 10, "", "", "user1", HistoryFilter{})
+		assert.NoError(t, err)
+		assert.False(t, hasMore)
+		assert.Empty(t, txs)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		mock.ExpectQuery(selectQuery+" WHERE initiated_by = $1 AND reference = $2 AND uid < $3 ORDER BY created_at DESC LIMIT 11").
+			WithArgs("user1", "ref", "uid9").
+			WillReturnError(errors.New("db down"))
+
+		_, _, err := p.ListByReference(t.Context(), 10, "uid9", "ref", "user1", HistoryFilter{})
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -2,17 +2,22 @@ package dao
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"time"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
 	"github.com/lengzuo/fundflow/pkg/log"
 	"github.com/lib/pq"
 )
 
+//go:generate mockery --name UserRepository --output ./mocks --outpkg mocks --case=underscore
 type UserRepository interface {
 	Insert(ctx context.Context, user *UsersModel) error
+	Get(ctx context.Context, username string) (*UsersModel, error)
 }
 
 type UsersModel struct {
@@ -92,3 +97,25 @@ func (p *users) Insert(ctx context.Context, user *UsersModel) error {
 	}
 	return nil
 }
+
+func (p *users) Get(ctx context.Context, username string) (*UsersModel, error) {
+	query, args, err := psql.Select("id", "username", "password", "active", "created_at", "updated_at").
+		From("users").
+		Where(squirrel.Eq{"username": username}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build get user query: %v", err)
+		return nil, fmt.Errorf("build get user query: %w", err)
+	}
+	user := new(UsersModel)
+	err = p.db.GetContext(ctx, user, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierr.NotFound
+		}
+		log.Error(ctx, "failed to get user %s with err: %s", username, err)
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	return user, nil
+}
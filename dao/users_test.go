@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUsers(t *testing.T) {
+	mockDB, _, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	t.Run("correct init", func(t *testing.T) {
+		daoInstance := &DAO{sqlx.NewDb(mockDB, "sqlmock")}
+		userDAO := NewUsers(daoInstance)
+		assert.Equal(t, daoInstance.db.DriverName(), userDAO.db.DriverName())
+		assert.Implements(t, (*UserRepository)(nil), userDAO)
+	})
+}
+
+func Test_users_Get(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &users{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	query := "SELECT id, username, password, active, created_at, updated_at FROM users WHERE username = $1 LIMIT 1"
+
+	t.Run("ok user get", func(t *testing.T) {
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "username", "password", "active", "created_at", "updated_at"})
+		rows.AddRow(1, "name", "hashed", true, now, now)
+		mock.ExpectQuery(query).
+			WithArgs("name").
+			WillReturnRows(rows)
+		user, err := p.Get(t.Context(), "name")
+		assert.NoError(t, err)
+		assert.Equal(t, &UsersModel{ID: 1, Username: "name", Password: "hashed", Active: true, CreatedAt: now, UpdatedAt: now}, user)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("user get no row", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("name").
+			WillReturnError(sql.ErrNoRows)
+		user, err := p.Get(t.Context(), "name")
+		assert.ErrorIs(t, err, apierr.NotFound)
+		assert.Nil(t, user)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("user get error", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("name").
+			WillReturnError(errors.New("err"))
+		user, err := p.Get(t.Context(), "name")
+		assert.Error(t, err)
+		assert.Nil(t, user)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
@@ -13,24 +13,54 @@ import (
 	"github.com/lengzuo/fundflow/internal/apierr"
 	"github.com/lengzuo/fundflow/pkg/log"
 	"github.com/lengzuo/fundflow/utils"
+	"github.com/lengzuo/fundflow/utils/metadata"
+	"github.com/lengzuo/fundflow/utils/money"
 )
 
 //go:generate mockery --name WalletsRepository --output ./mocks --outpkg mocks --case=underscore
 type WalletsRepository interface {
-	Deposit(ctx context.Context, username, reference, currency string, amount int) error
-	Withdraw(ctx context.Context, username, reference, currency string, amount int) error
+	Deposit(ctx context.Context, username, reference string, amount money.Money, meta metadata.Metadata) (string, error)
+	Withdraw(ctx context.Context, username, reference string, amount money.Money, meta metadata.Metadata) (string, error)
 	Balance(ctx context.Context, username string, currencies []string) ([]WalletsModel, error)
-	Transfer(ctx context.Context, sender, receiver, reference, currency string, amount int) error
+	Transfer(ctx context.Context, sender, receiver, reference string, amount money.Money, meta metadata.Metadata) (string, error)
+	BulkTransfer(ctx context.Context, sender, reference string, items []BulkTransferItem, meta metadata.Metadata) (string, error)
 	Get(ctx context.Context, username, currency string) (*WalletsModel, error)
+	UpdateStatus(ctx context.Context, username, currency string, status WalletStatus, reason, changedBy string) (*WalletsModel, error)
+	ListStatusHistories(ctx context.Context, username, currency string) ([]WalletStatusHistoriesModel, error)
 }
 
+type WalletStatus string
+
+const (
+	WalletStatusActive WalletStatus = "active"
+	// Frozen wallet is not allowed to move any fund until it is unfrozen
+	WalletStatusFrozen WalletStatus = "frozen"
+	// Closed wallet is terminal, it can't be reopened
+	WalletStatusClosed WalletStatus = "closed"
+)
+
 type WalletsModel struct {
-	ID        int       `db:"id"`
-	Username  string    `db:"username"`
-	Amount    int       `db:"amount"`
-	Currency  string    `db:"currency"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID       int          `db:"id"`
+	Username string       `db:"username"`
+	Amount   int64        `db:"amount"`
+	Currency string       `db:"currency"`
+	Status   WalletStatus `db:"status"`
+	// HeldAmount is reserved by authorized holds, the available balance is Amount - HeldAmount
+	HeldAmount int64     `db:"held_amount"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+type WalletStatusHistoriesModel struct {
+	ID         int          `db:"id"`
+	WalletID   int          `db:"wallet_id"`
+	Username   string       `db:"username"`
+	Currency   string       `db:"currency"`
+	FromStatus WalletStatus `db:"from_status"`
+	ToStatus   WalletStatus `db:"to_status"`
+	Reason     string       `db:"reason"`
+	ChangedBy  string       `db:"changed_by"`
+	CreatedAt  time.Time    `db:"created_at"`
 }
 
 type wallets struct {
@@ -43,48 +73,103 @@ func NewWallets(dao *DAO) *wallets {
 	}
 }
 
-func (p *wallets) Deposit(ctx context.Context, username, reference, currency string, amount int) error {
-	return lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+func (p *wallets) Deposit(ctx context.Context, username, reference string, amount money.Money, meta metadata.Metadata) (string, error) {
+	currency := amount.Currency().Code
+	uid := utils.UUID()
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		wallet, err := get(ctx, exec, username, currency, true)
+		if err != nil {
+			return err
+		}
+		err = checkCreditLimits(ctx, exec, wallet, amount, true)
+		if err != nil {
+			return err
+		}
 		transaction := TransactionsModel{
-			UID:         utils.UUID(),
+			UID:         uid,
 			Type:        TypeDeposit,
 			InitiatedBy: username,
 			Status:      StatusCompleted,
-			Amount:      amount,
+			Amount:      amount.Amount(),
 			Currency:    currency,
 			Reference:   reference,
+			Metadata:    meta,
 		}
-		err := insertTransaction(ctx, exec, transaction)
+		err = insertTransaction(ctx, exec, transaction)
 		if err != nil {
 			log.Error(ctx, "failed in insert into transactions with err: %s", err)
 			return err
 		}
-		return updateBalanceAndInsertLedger(ctx, exec, transaction.UID, username, currency, amount, DirectionCredit)
+		err = updateBalanceAndInsertLedger(ctx, exec, transaction.UID, username, currency, amount.Amount(), DirectionCredit)
+		if err != nil {
+			return err
+		}
+		return insertWalletEvent(ctx, exec, WalletEvent{
+			TxUID:     transaction.UID,
+			TxType:    TypeDeposit,
+			Username:  username,
+			Currency:  currency,
+			Amount:    amount.Amount(),
+			Direction: DirectionCredit,
+			Reference: reference,
+		})
 	})
+	if err != nil {
+		return "", err
+	}
+	return uid, nil
 }
 
-func (p *wallets) Withdraw(ctx context.Context, username, reference, currency string, amount int) error {
-	return lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+func (p *wallets) Withdraw(ctx context.Context, username, reference string, amount money.Money, meta metadata.Metadata) (string, error) {
+	currency := amount.Currency().Code
+	uid := utils.UUID()
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		// Lock the wallet before checking the limits, so the concurrent withdraw waits for the usage of this one
+		_, err := get(ctx, exec, username, currency, true)
+		if err != nil {
+			return err
+		}
+		err = checkDebitLimits(ctx, exec, username, TypeWithdraw, amount)
+		if err != nil {
+			return err
+		}
 		transaction := TransactionsModel{
-			UID:         utils.UUID(),
+			UID:         uid,
 			Type:        TypeWithdraw,
 			InitiatedBy: username,
 			Status:      StatusCompleted,
-			Amount:      amount,
+			Amount:      amount.Amount(),
 			Currency:    currency,
 			Reference:   reference,
+			Metadata:    meta,
 		}
-		err := insertTransaction(ctx, exec, transaction)
+		err = insertTransaction(ctx, exec, transaction)
 		if err != nil {
 			log.Error(ctx, "failed in insert into transactions with err: %s", err)
 			return err
 		}
-		return updateBalanceAndInsertLedger(ctx, exec, transaction.UID, username, currency, amount, DirectionDebit)
+		err = updateBalanceAndInsertLedger(ctx, exec, transaction.UID, username, currency, amount.Amount(), DirectionDebit)
+		if err != nil {
+			return err
+		}
+		return insertWalletEvent(ctx, exec, WalletEvent{
+			TxUID:     transaction.UID,
+			TxType:    TypeWithdraw,
+			Username:  username,
+			Currency:  currency,
+			Amount:    amount.Amount(),
+			Direction: DirectionDebit,
+			Reference: reference,
+		})
 	})
+	if err != nil {
+		return "", err
+	}
+	return uid, nil
 }
 
 func (p *wallets) Balance(ctx context.Context, username string, currencies []string) ([]WalletsModel, error) {
-	query, args, err := psql.Select("amount", "currency").
+	query, args, err := psql.Select("amount", "held_amount", "currency").
 		From("wallets").
 		Where(squirrel.Eq{
 			"username": username,
@@ -109,57 +194,177 @@ func (p *wallets) Balance(ctx context.Context, username string, currencies []str
 	return wallets, nil
 }
 
-func (p *wallets) Transfer(ctx context.Context, sender, receiver, reference, currency string, amount int) error {
-	return lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
-		strs := []string{sender, receiver}
-		// Sort the keys to ensure select...for update always in the same sequence for both user, eg, user A transfer to user B and user B transfer to user A at the same time.
-		// the locker is able to locked the data properly without causing race condition.
-		slices.Sort(strs)
-
-		_, err := get(ctx, exec, strs[0], currency, true)
+func (p *wallets) Transfer(ctx context.Context, sender, receiver, reference string, amount money.Money, meta metadata.Metadata) (string, error) {
+	var uid string
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		transaction, err := transfer(ctx, exec, sender, receiver, reference, amount, meta)
 		if err != nil {
 			return err
 		}
-		_, err = get(ctx, exec, strs[1], currency, true)
+		uid = transaction.UID
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return uid, nil
+}
+
+// transfer moves the amount from sender to receiver within the database transaction of exec and returns the transfer transaction.
+func transfer(ctx context.Context, exec sqlx.ExtContext, sender, receiver, reference string, amount money.Money, meta metadata.Metadata) (*TransactionsModel, error) {
+	currency := amount.Currency().Code
+	strs := []string{sender, receiver}
+	// Sort the keys to ensure select...for update always in the same sequence for both user, eg, user A transfer to user B and user B transfer to user A at the same time.
+	// the locker is able to locked the data properly without causing race condition.
+	slices.Sort(strs)
+
+	locked := make(map[string]*WalletsModel, len(strs))
+	for _, username := range strs {
+		wallet, err := get(ctx, exec, username, currency, true)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		locked[username] = wallet
+	}
+	err := checkDebitLimits(ctx, exec, sender, TypeTransfer, amount)
+	if err != nil {
+		return nil, err
+	}
+	err = checkCreditLimits(ctx, exec, locked[receiver], amount, false)
+	if err != nil {
+		return nil, err
+	}
 
-		transaction := TransactionsModel{
-			UID:         utils.UUID(),
-			Type:        TypeTransfer,
-			InitiatedBy: sender,
-			Status:      StatusCompleted,
-			Amount:      amount,
-			Currency:    currency,
-			Reference:   reference,
+	transaction := &TransactionsModel{
+		UID:         utils.UUID(),
+		Type:        TypeTransfer,
+		InitiatedBy: sender,
+		Status:      StatusCompleted,
+		Amount:      amount.Amount(),
+		Currency:    currency,
+		Reference:   reference,
+		Metadata:    meta,
+	}
+	err = insertTransaction(ctx, exec, *transaction)
+	if err != nil {
+		log.Error(ctx, "failed in insert into transactions with err: %s", err)
+		return nil, err
+	}
+
+	err = updateBalanceAndInsertLedger(ctx, exec, transaction.UID, sender, currency, amount.Amount(), DirectionDebit)
+	if err != nil {
+		log.Error(ctx, "failed in update sender and add ledger with err: %s", err)
+		return nil, err
+	}
+
+	err = updateBalanceAndInsertLedger(ctx, exec, transaction.UID, receiver, currency, amount.Amount(), DirectionCredit)
+	if err != nil {
+		log.Error(ctx, "failed in update receiver and add ledger with err: %s", err)
+		return nil, err
+	}
+
+	// Both events are written under the locks of the wallets, so the events of a wallet are in the order of its movements
+	for _, event := range []WalletEvent{
+		{TxUID: transaction.UID, TxType: TypeTransfer, Username: sender, Currency: currency, Amount: amount.Amount(), Direction: DirectionDebit, Reference: reference, Counterparty: receiver},
+		{TxUID: transaction.UID, TxType: TypeTransfer, Username: receiver, Currency: currency, Amount: amount.Amount(), Direction: DirectionCredit, Reference: reference, Counterparty: sender},
+	} {
+		err = insertWalletEvent(ctx, exec, event)
+		if err != nil {
+			return nil, err
 		}
-		err = insertTransaction(ctx, exec, transaction)
+	}
+	return transaction, nil
+}
+
+func (p *wallets) Get(ctx context.Context, username, currency string) (*WalletsModel, error) {
+	return get(ctx, p.db, username, currency, false)
+}
+
+// allowedStatusTransitions list the statuses a wallet is able to move into from its current status.
+var allowedStatusTransitions = map[WalletStatus][]WalletStatus{
+	WalletStatusActive: {WalletStatusFrozen, WalletStatusClosed},
+	WalletStatusFrozen: {WalletStatusActive, WalletStatusClosed},
+}
+
+func (p *wallets) UpdateStatus(ctx context.Context, username, currency string, status WalletStatus, reason, changedBy string) (*WalletsModel, error) {
+	var wallet *WalletsModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		var err error
+		wallet, err = get(ctx, exec, username, currency, true)
 		if err != nil {
-			log.Error(ctx, "failed in insert into transactions with err: %s", err)
 			return err
 		}
+		if wallet.Status == WalletStatusClosed {
+			return apierr.WalletClosed
+		}
+		if !slices.Contains(allowedStatusTransitions[wallet.Status], status) {
+			return ErrInvalidStatusTransition
+		}
+		if status == WalletStatusClosed && wallet.Amount != 0 {
+			return ErrNonZeroBalance
+		}
 
-		err = updateBalanceAndInsertLedger(ctx, exec, transaction.UID, sender, currency, amount, DirectionDebit)
+		query, args, err := psql.Update("wallets").
+			Set("updated_at", squirrel.Expr("NOW()")).
+			Set("status", status).
+			Where(squirrel.Eq{"id": wallet.ID}).
+			ToSql()
 		if err != nil {
-			log.Error(ctx, "failed in update sender and add ledger with err: %s", err)
-			return err
+			log.Error(ctx, "failed to build wallet status query with err: %s", err)
+			return fmt.Errorf("build wallet status query: %w", err)
+		}
+		_, err = exec.ExecContext(ctx, query, args...)
+		if err != nil {
+			log.Error(ctx, "failed to update wallet status with err: %s", err)
+			return fmt.Errorf("update wallet status: %w", err)
 		}
 
-		err = updateBalanceAndInsertLedger(ctx, exec, transaction.UID, receiver, currency, amount, DirectionCredit)
+		query, args, err = psql.Insert("wallet_status_histories").
+			Columns("wallet_id", "username", "currency", "from_status", "to_status", "reason", "changed_by").
+			Values(wallet.ID, username, currency, wallet.Status, status, reason, changedBy).
+			ToSql()
 		if err != nil {
-			log.Error(ctx, "failed in update receiver and add ledger with err: %s", err)
-			return err
+			log.Error(ctx, "failed to build wallet status history query with err: %s", err)
+			return fmt.Errorf("build wallet status history query: %w", err)
 		}
+		_, err = exec.ExecContext(ctx, query, args...)
+		if err != nil {
+			log.Error(ctx, "failed to insert wallet status history with err: %s", err)
+			return fmt.Errorf("insert wallet status history: %w", err)
+		}
+		log.Info(ctx, "wallet %s %s status changed from %s to %s by %s", username, currency, wallet.Status, status, changedBy)
+		wallet.Status = status
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return wallet, nil
 }
 
-func (p *wallets) Get(ctx context.Context, username, currency string) (*WalletsModel, error) {
-	return get(ctx, p.db, username, currency, false)
+func (p *wallets) ListStatusHistories(ctx context.Context, username, currency string) ([]WalletStatusHistoriesModel, error) {
+	query, args, err := psql.Select("id", "wallet_id", "username", "currency", "from_status", "to_status", "reason", "changed_by", "created_at").
+		From("wallet_status_histories").
+		Where(squirrel.Eq{
+			"username": username,
+			"currency": currency,
+		}).
+		OrderBy("id DESC").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list wallet status histories query with err: %s", err)
+		return nil, fmt.Errorf("build list wallet status histories query: %w", err)
+	}
+	histories := []WalletStatusHistoriesModel{}
+	err = p.db.SelectContext(ctx, &histories, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list wallet status histories with err: %s", err)
+		return nil, fmt.Errorf("list wallet status histories: %w", err)
+	}
+	return histories, nil
 }
 
-func updateBalance(ctx context.Context, exec sqlx.ExtContext, username, currency string, amount int) error {
+func updateBalance(ctx context.Context, exec sqlx.ExtContext, username, currency string, amount int64) error {
 	if amount == 0 {
 		return fmt.Errorf("amount cannot be zero")
 	}
@@ -169,10 +374,11 @@ func updateBalance(ctx context.Context, exec sqlx.ExtContext, username, currency
 		Where(squirrel.Eq{
 			"username": username,
 			"currency": currency,
-		})
+		}).
+		Where(squirrel.Eq{"status": WalletStatusActive})
 
 	if amount < 0 {
-		updateBuilder = updateBuilder.Where(squirrel.Expr("amount >= ?", -amount))
+		updateBuilder = updateBuilder.Where(squirrel.Expr("amount - held_amount >= ?", -amount))
 	}
 
 	query, args, err := updateBuilder.ToSql()
@@ -191,16 +397,31 @@ func updateBalance(ctx context.Context, exec sqlx.ExtContext, username, currency
 		return err
 	}
 	if rowAffected == 0 {
-		if amount <= 0 {
-			return apierr.InsufficientFund
-		}
-		return errors.New("unexpected: no rows affected by update")
+		return noRowsUpdatedErr(ctx, exec, username, currency, amount)
 	}
 	log.Debug(ctx, "Wallets updated: %s", username)
 	return nil
 }
 
-func updateBalanceAndInsertLedger(ctx context.Context, exec sqlx.ExtContext, txUID, username, currency string, amount int, direction Direction) error {
+// noRowsUpdatedErr looks up the wallet to explain why the balance update didn't affect any row.
+func noRowsUpdatedErr(ctx context.Context, exec sqlx.ExtContext, username, currency string, amount int64) error {
+	wallet, err := get(ctx, exec, username, currency, false)
+	if err != nil {
+		return err
+	}
+	switch wallet.Status {
+	case WalletStatusFrozen:
+		return apierr.WalletFrozen
+	case WalletStatusClosed:
+		return apierr.WalletClosed
+	}
+	if amount < 0 {
+		return apierr.InsufficientFund
+	}
+	return errors.New("unexpected: no rows affected by update")
+}
+
+func updateBalanceAndInsertLedger(ctx context.Context, exec sqlx.ExtContext, txUID, username, currency string, amount int64, direction Direction) error {
 	// Ensure the amount always positive in ledgers table
 	ledger := &LedgersModel{
 		TxUID:     txUID,
@@ -226,7 +447,7 @@ func updateBalanceAndInsertLedger(ctx context.Context, exec sqlx.ExtContext, txU
 }
 
 func get(ctx context.Context, exec sqlx.ExtContext, username, currency string, forUpdate bool) (*WalletsModel, error) {
-	queryBuilder := psql.Select("id", "username", "amount").
+	queryBuilder := psql.Select("id", "username", "amount", "status").
 		From("wallets").
 		Where(
 			squirrel.And{
@@ -4,10 +4,13 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
 	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -39,43 +42,61 @@ func Test_wallets_Deposit(t *testing.T) {
 	t.Run("ok, deposit wallet no error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name", 10, "active"))
+		expectNoLimits(mock, "name", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "deposit", "name", "SGD", 100, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3").
-			WithArgs(100, "SGD", "name").
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(100, "SGD", "name", WalletStatusActive).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name", "SGD", 100, DirectionCredit).
+		expectLastLedgerHash(mock, "name", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name", "SGD", 100, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
+		expectWalletEvent(mock, EventWalletCredited, "name", "SGD")
 		mock.ExpectCommit().WillReturnError(nil)
 
-		err := p.Deposit(t.Context(), "name", "ref", "SGD", 100)
+		uid, err := p.Deposit(t.Context(), "name", "ref", money.New(100, currency.SGD), nil)
 		assert.NoError(t, err, "deposit err")
+		assert.NotEmpty(t, uid)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
 
 	t.Run("insert deposit ledgers error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name", 10, "active"))
+		expectNoLimits(mock, "name", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "deposit", "name", "SGD", 100, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3").
-			WithArgs(100, "SGD", "name").
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(100, "SGD", "name", WalletStatusActive).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name", "SGD", 100, DirectionCredit).
+		expectLastLedgerHash(mock, "name", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name", "SGD", 100, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err := p.Deposit(t.Context(), "name", "ref", "SGD", 100)
+		_, err := p.Deposit(t.Context(), "name", "ref", money.New(100, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -83,17 +104,22 @@ func Test_wallets_Deposit(t *testing.T) {
 	t.Run("update deposit wallets error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name", 10, "active"))
+		expectNoLimits(mock, "name", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "deposit", "name", "SGD", 100, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3").
-			WithArgs(100, "SGD", "name").
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(100, "SGD", "name", WalletStatusActive).
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err := p.Deposit(t.Context(), "name", "ref", "SGD", 100)
+		_, err := p.Deposit(t.Context(), "name", "ref", money.New(100, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -101,20 +127,54 @@ func Test_wallets_Deposit(t *testing.T) {
 	t.Run("insert deposit transactions error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name", 10, "active"))
+		expectNoLimits(mock, "name", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "deposit", "name", "SGD", 100, "completed", "ref").
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err := p.Deposit(t.Context(), "name", "ref", "SGD", 100)
+		_, err := p.Deposit(t.Context(), "name", "ref", money.New(100, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
 
+	t.Run("deposit into frozen wallet", func(t *testing.T) {
+		mock.ExpectBegin().WillReturnError(nil)
+
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name", 10, "active"))
+		expectNoLimits(mock, "name", "SGD")
+
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), "deposit", "name", "SGD", 100, "completed", "ref").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(100, "SGD", "name", WalletStatusActive).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 10, "frozen")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+			WithArgs("name", "SGD").
+			WillReturnRows(rows)
+
+		mock.ExpectRollback().WillReturnError(nil)
+
+		_, err := p.Deposit(t.Context(), "name", "ref", money.New(100, currency.SGD), nil)
+		assert.ErrorIs(t, err, apierr.WalletFrozen)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
 	t.Run("begin deposit tx error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(errors.New("err"))
-		err := p.Deposit(t.Context(), "name", "ref", "SGD", 100)
+		_, err := p.Deposit(t.Context(), "name", "ref", money.New(100, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -134,43 +194,61 @@ func Test_wallets_Withdraw(t *testing.T) {
 	t.Run("ok, withdraw wallet no error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name2", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name2", 10, "active"))
+		expectNoLimits(mock, "name2", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "withdraw", "name2", "SGD", 100, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND amount >= $4").
-			WithArgs(-100, "SGD", "name2", 100).
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-100, "SGD", "name2", WalletStatusActive, 100).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name2", "SGD", 100, DirectionDebit).
+		expectLastLedgerHash(mock, "name2", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name2", "SGD", 100, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
+		expectWalletEvent(mock, EventWalletDebited, "name2", "SGD")
 		mock.ExpectCommit().WillReturnError(nil)
 
-		err := p.Withdraw(t.Context(), "name2", "ref", "SGD", 100)
+		uid, err := p.Withdraw(t.Context(), "name2", "ref", money.New(100, currency.SGD), nil)
 		assert.NoError(t, err, "withdraw err")
+		assert.NotEmpty(t, uid)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
 
 	t.Run("insert withdraw ledgers error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name2", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name2", 10, "active"))
+		expectNoLimits(mock, "name2", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "withdraw", "name2", "SGD", 100, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND amount >= $4").
-			WithArgs(-100, "SGD", "name2", 100).
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-100, "SGD", "name2", WalletStatusActive, 100).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name2", "SGD", 100, DirectionDebit).
+		expectLastLedgerHash(mock, "name2", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name2", "SGD", 100, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err := p.Withdraw(t.Context(), "name2", "ref", "SGD", 100)
+		_, err := p.Withdraw(t.Context(), "name2", "ref", money.New(100, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -178,17 +256,22 @@ func Test_wallets_Withdraw(t *testing.T) {
 	t.Run("update withdraw wallet error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name2", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name2", 10, "active"))
+		expectNoLimits(mock, "name2", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "withdraw", "name2", "SGD", 100, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND amount >= $4").
-			WithArgs(-100, "SGD", "name2", 100).
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-100, "SGD", "name2", WalletStatusActive, 100).
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err := p.Withdraw(t.Context(), "name2", "ref", "SGD", 100)
+		_, err := p.Withdraw(t.Context(), "name2", "ref", money.New(100, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -196,20 +279,83 @@ func Test_wallets_Withdraw(t *testing.T) {
 	t.Run("insert withdraw transactions error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name2", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name2", 10, "active"))
+		expectNoLimits(mock, "name2", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "withdraw", "name2", "SGD", 101, "completed", "ref").
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err := p.Withdraw(t.Context(), "name2", "ref", "SGD", 101)
+		_, err := p.Withdraw(t.Context(), "name2", "ref", money.New(101, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
 
+	t.Run("withdraw insufficient fund", func(t *testing.T) {
+		mock.ExpectBegin().WillReturnError(nil)
+
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name2", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name2", 10, "active"))
+		expectNoLimits(mock, "name2", "SGD")
+
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), "withdraw", "name2", "SGD", 100, "completed", "ref").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-100, "SGD", "name2", WalletStatusActive, 100).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name2", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+			WithArgs("name2", "SGD").
+			WillReturnRows(rows)
+
+		mock.ExpectRollback().WillReturnError(nil)
+
+		_, err := p.Withdraw(t.Context(), "name2", "ref", money.New(100, currency.SGD), nil)
+		assert.ErrorIs(t, err, apierr.InsufficientFund)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("withdraw from closed wallet", func(t *testing.T) {
+		mock.ExpectBegin().WillReturnError(nil)
+
+		mock.ExpectQuery(lockWalletQuery).
+			WithArgs("name2", "SGD").
+			WillReturnRows(sqlmock.NewRows(walletColumns).AddRow(1, "name2", 10, "active"))
+		expectNoLimits(mock, "name2", "SGD")
+
+		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(sqlmock.AnyArg(), "withdraw", "name2", "SGD", 100, "completed", "ref").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-100, "SGD", "name2", WalletStatusActive, 100).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name2", 0, "closed")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+			WithArgs("name2", "SGD").
+			WillReturnRows(rows)
+
+		mock.ExpectRollback().WillReturnError(nil)
+
+		_, err := p.Withdraw(t.Context(), "name2", "ref", money.New(100, currency.SGD), nil)
+		assert.ErrorIs(t, err, apierr.WalletClosed)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
 	t.Run("begin withdraw tx error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(errors.New("err"))
-		err := p.Withdraw(t.Context(), "name2", "ref", "SGD", 100)
+		_, err := p.Withdraw(t.Context(), "name2", "ref", money.New(100, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -226,24 +372,25 @@ func Test_wallets_Balance(t *testing.T) {
 	}
 
 	t.Run("ok get wallet balance", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"amount", "currency"})
-		rows.AddRow(20, "SGD")
-		rows.AddRow(10, "JPY")
-		mock.ExpectQuery("SELECT amount, currency FROM wallets WHERE currency IN ($1,$2) AND username = $3").
+		rows := sqlmock.NewRows([]string{"amount", "held_amount", "currency"})
+		rows.AddRow(20, 5, "SGD")
+		rows.AddRow(10, 0, "JPY")
+		mock.ExpectQuery("SELECT amount, held_amount, currency FROM wallets WHERE currency IN ($1,$2) AND username = $3").
 			WithArgs("SGD", "JPY", "name").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 		wallets, err := p.Balance(t.Context(), "name", []string{"SGD", "JPY"})
 		assert.NoError(t, err)
 		assert.Equal(t, wallets[0].Currency, "SGD")
-		assert.Equal(t, wallets[0].Amount, 20)
+		assert.Equal(t, wallets[0].Amount, int64(20))
+		assert.Equal(t, wallets[0].HeldAmount, int64(5))
 		assert.Equal(t, wallets[1].Currency, "JPY")
-		assert.Equal(t, wallets[1].Amount, 10)
+		assert.Equal(t, wallets[1].Amount, int64(10))
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
 
 	t.Run("err wallet balance no row return", func(t *testing.T) {
-		mock.ExpectQuery("SELECT amount, currency FROM wallets WHERE currency IN ($1) AND username = $2").
+		mock.ExpectQuery("SELECT amount, held_amount, currency FROM wallets WHERE currency IN ($1) AND username = $2").
 			WithArgs("JPY", "name22").
 			WillReturnError(sql.ErrNoRows)
 		wallets, err := p.Balance(t.Context(), "name22", []string{"JPY"})
@@ -255,7 +402,7 @@ func Test_wallets_Balance(t *testing.T) {
 
 	t.Run("err wallet balance other", func(t *testing.T) {
 		expectedErr := errors.New("err")
-		mock.ExpectQuery("SELECT amount, currency FROM wallets WHERE currency IN ($1,$2) AND username = $3").
+		mock.ExpectQuery("SELECT amount, held_amount, currency FROM wallets WHERE currency IN ($1,$2) AND username = $3").
 			WithArgs("JPY", "SGD", "name").
 			WillReturnError(expectedErr)
 		wallets, err := p.Balance(t.Context(), "name", []string{"JPY", "SGD"})
@@ -276,24 +423,24 @@ func Test_wallets_Get(t *testing.T) {
 		db: sqlx.NewDb(mockDB, "sqlmock"),
 	}
 	t.Run("ok wallet get", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
 			WithArgs("name", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 		wallet, err := p.Get(t.Context(), "name", "SGD")
 		assert.NoError(t, err)
 		assert.Equal(t, &WalletsModel{
-			ID: 1, Username: "name", Amount: 10,
+			ID: 1, Username: "name", Amount: 10, Status: WalletStatusActive,
 		}, wallet)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
 
 	t.Run("query execute wallet get error", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
 			WithArgs("name", "SGD").
 			WillReturnError(errors.New("err"))
 		wallet, err := p.Get(t.Context(), "name", "SGD")
@@ -303,9 +450,9 @@ func Test_wallets_Get(t *testing.T) {
 	})
 
 	t.Run("query execute wallet get no row", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1").
 			WithArgs("name", "SGD").
 			WillReturnError(sql.ErrNoRows)
 		wallet, err := p.Get(t.Context(), "name", "SGD")
@@ -328,43 +475,54 @@ func Test_wallets_Transfer(t *testing.T) {
 	t.Run("ok wallets transfer", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name1", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name1", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name1", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
-		rows = sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(2, "name2", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows = sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(2, "name2", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name2", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
+		expectNoLimits(mock, "name2", "SGD")
+		expectNoLimits(mock, "name1", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "transfer", "name2", "SGD", 100, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND amount >= $4").
-			WithArgs(-100, "SGD", "name2", 100).
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-100, "SGD", "name2", WalletStatusActive, 100).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name2", "SGD", 100, DirectionDebit).
+		expectLastLedgerHash(mock, "name2", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name2", "SGD", 100, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3").
-			WithArgs(100, "SGD", "name1").
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(100, "SGD", "name1", WalletStatusActive).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name1", "SGD", 100, DirectionCredit).
+		expectLastLedgerHash(mock, "name1", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name1", "SGD", 100, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
+		expectWalletEvent(mock, EventWalletDebited, "name2", "SGD")
+		expectWalletEvent(mock, EventWalletCredited, "name1", "SGD")
 		mock.ExpectCommit().WillReturnError(nil)
 
-		err = p.Transfer(t.Context(), "name2", "name1", "ref", "SGD", 100)
+		_, err = p.Transfer(t.Context(), "name2", "name1", "ref", money.New(100, currency.SGD), nil)
 		assert.NoError(t, err, "transfer err")
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -372,43 +530,54 @@ func Test_wallets_Transfer(t *testing.T) {
 	t.Run("reverse name1 and name2 for update still need to be same sequence", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name1", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name1", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name1", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
-		rows = sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(2, "name2", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows = sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(2, "name2", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name2", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
+		expectNoLimits(mock, "name1", "SGD")
+		expectNoLimits(mock, "name2", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "transfer", "name1", "SGD", 100, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND amount >= $4").
-			WithArgs(-100, "SGD", "name1", 100).
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-100, "SGD", "name1", WalletStatusActive, 100).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name1", "SGD", 100, DirectionDebit).
+		expectLastLedgerHash(mock, "name1", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name1", "SGD", 100, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3").
-			WithArgs(100, "SGD", "name2").
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(100, "SGD", "name2", WalletStatusActive).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name2", "SGD", 100, DirectionCredit).
+		expectLastLedgerHash(mock, "name2", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name2", "SGD", 100, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
+		expectWalletEvent(mock, EventWalletDebited, "name1", "SGD")
+		expectWalletEvent(mock, EventWalletCredited, "name2", "SGD")
 		mock.ExpectCommit().WillReturnError(nil)
 
-		err = p.Transfer(t.Context(), "name1", "name2", "ref", "SGD", 100)
+		_, err = p.Transfer(t.Context(), "name1", "name2", "ref", money.New(100, currency.SGD), nil)
 		assert.NoError(t, err, "transfer err")
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -416,43 +585,52 @@ func Test_wallets_Transfer(t *testing.T) {
 	t.Run("insert receiver ledgers failed wallets transfer", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name1", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name1", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name1", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
-		rows = sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(2, "name2", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows = sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(2, "name2", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name2", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
+		expectNoLimits(mock, "name1", "SGD")
+		expectNoLimits(mock, "name2", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "transfer", "name1", "SGD", 10, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND amount >= $4").
-			WithArgs(-10, "SGD", "name1", 10).
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-10, "SGD", "name1", WalletStatusActive, 10).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name1", "SGD", 10, DirectionDebit).
+		expectLastLedgerHash(mock, "name1", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name1", "SGD", 10, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3").
-			WithArgs(10, "SGD", "name2").
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(10, "SGD", "name2", WalletStatusActive).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name2", "SGD", 10, DirectionCredit).
+		expectLastLedgerHash(mock, "name2", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name2", "SGD", 10, DirectionCredit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err = p.Transfer(t.Context(), "name1", "name2", "ref", "SGD", 10)
+		_, err = p.Transfer(t.Context(), "name1", "name2", "ref", money.New(10, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -460,39 +638,45 @@ func Test_wallets_Transfer(t *testing.T) {
 	t.Run("update receiver wallets failed wallets transfer", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name1", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name1", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name1", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
-		rows = sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(2, "name2", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows = sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(2, "name2", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name2", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
+		expectNoLimits(mock, "name1", "SGD")
+		expectNoLimits(mock, "name2", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "transfer", "name1", "SGD", 10, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND amount >= $4").
-			WithArgs(-10, "SGD", "name1", 10).
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-10, "SGD", "name1", WalletStatusActive, 10).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name1", "SGD", 10, DirectionDebit).
+		expectLastLedgerHash(mock, "name1", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name1", "SGD", 10, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3").
-			WithArgs(10, "SGD", "name2").
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4").
+			WithArgs(10, "SGD", "name2", WalletStatusActive).
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err = p.Transfer(t.Context(), "name1", "name2", "ref", "SGD", 10)
+		_, err = p.Transfer(t.Context(), "name1", "name2", "ref", money.New(10, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -500,35 +684,41 @@ func Test_wallets_Transfer(t *testing.T) {
 	t.Run("insert sender ledgers failed wallets transfer", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name1", 11)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name1", 11, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name1", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
-		rows = sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(2, "name2", 11)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows = sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(2, "name2", 11, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name2", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
+		expectNoLimits(mock, "name1", "SGD")
+		expectNoLimits(mock, "name2", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "transfer", "name1", "SGD", 11, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND amount >= $4").
-			WithArgs(-11, "SGD", "name1", 11).
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-11, "SGD", "name1", WalletStatusActive, 11).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("INSERT INTO ledgers (tx_uid,username,currency,amount,direction) VALUES ($1,$2,$3,$4,$5)").
-			WithArgs(sqlmock.AnyArg(), "name1", "SGD", 11, DirectionDebit).
+		expectLastLedgerHash(mock, "name1", "SGD")
+
+		expectPeriodOpen(mock)
+		mock.ExpectExec(insertLedgerQuery).
+			WithArgs(sqlmock.AnyArg(), "name1", "SGD", 11, DirectionDebit, sqlmock.AnyArg(), "", sqlmock.AnyArg()).
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err = p.Transfer(t.Context(), "name1", "name2", "ref", "SGD", 11)
+		_, err = p.Transfer(t.Context(), "name1", "name2", "ref", money.New(11, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -536,31 +726,34 @@ func Test_wallets_Transfer(t *testing.T) {
 	t.Run("update sender wallets failed wallets transfer", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name1", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name1", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name1", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
-		rows = sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(2, "name2", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows = sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(2, "name2", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name2", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
+		expectNoLimits(mock, "name1", "SGD")
+		expectNoLimits(mock, "name2", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "transfer", "name1", "SGD", 10, "completed", "ref").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND amount >= $4").
-			WithArgs(-10, "SGD", "name1", 10).
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), amount = amount + $1 WHERE currency = $2 AND username = $3 AND status = $4 AND amount - held_amount >= $5").
+			WithArgs(-10, "SGD", "name1", WalletStatusActive, 10).
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err = p.Transfer(t.Context(), "name1", "name2", "ref", "SGD", 10)
+		_, err = p.Transfer(t.Context(), "name1", "name2", "ref", money.New(10, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
@@ -568,61 +761,201 @@ func Test_wallets_Transfer(t *testing.T) {
 	t.Run("insert transactions failed wallets transfer", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
 
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name1", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name1", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name1", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
-		rows = sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(2, "name2", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows = sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(2, "name2", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name2", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
+		expectNoLimits(mock, "name2", "SGD")
+		expectNoLimits(mock, "name1", "SGD")
+
 		mock.ExpectExec("INSERT INTO transactions (uid,type,initiated_by,currency,amount,status,reference) VALUES ($1,$2,$3,$4,$5,$6,$7)").
 			WithArgs(sqlmock.AnyArg(), "transfer", "name2", "SGD", 10, "completed", "ref").
 			WillReturnError(errors.New("err"))
 
 		mock.ExpectRollback().WillReturnError(nil)
 
-		err = p.Transfer(t.Context(), "name2", "name1", "ref", "SGD", 10)
+		_, err = p.Transfer(t.Context(), "name2", "name1", "ref", money.New(10, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
 
 	t.Run("select second wallet error wallets transfer", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name1", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name1", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name1", "SGD").
 			WillReturnRows(rows).
 			WillReturnError(nil)
 
-		rows = sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(2, "name2", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows = sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(2, "name2", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name2", "SGD").
 			WillReturnError(errors.New("err"))
 		mock.ExpectRollback().WillReturnError(nil)
-		err = p.Transfer(t.Context(), "name2", "name1", "ref", "SGD", 10)
+		_, err = p.Transfer(t.Context(), "name2", "name1", "ref", money.New(10, currency.SGD), nil)
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
 
 	t.Run("select first wallet error", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(nil)
-		rows := sqlmock.NewRows([]string{"id", "username", "amount"})
-		rows.AddRow(1, "name1", 10)
-		mock.ExpectQuery("SELECT id, username, amount FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name1", 10, "active")
+		mock.ExpectQuery("SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE").
 			WithArgs("name1", "SGD").
 			WillReturnError(errors.New("err"))
 		mock.ExpectRollback().WillReturnError(nil)
-		err = p.Transfer(t.Context(), "name2", "name1", "ref", "SGD", 10)
+		_, err = p.Transfer(t.Context(), "name2", "name1", "ref", money.New(10, currency.SGD), nil)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_wallets_UpdateStatus(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &wallets{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	selectQuery := "SELECT id, username, amount, status FROM wallets WHERE (username = $1 AND currency = $2) LIMIT 1 FOR UPDATE"
+
+	t.Run("ok freeze active wallet", func(t *testing.T) {
+		mock.ExpectBegin()
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 10, "active")
+		mock.ExpectQuery(selectQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(rows)
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), status = $1 WHERE id = $2").
+			WithArgs(WalletStatusFrozen, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO wallet_status_histories (wallet_id,username,currency,from_status,to_status,reason,changed_by) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(1, "name", "SGD", WalletStatusActive, WalletStatusFrozen, "fraud", "admin").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		wallet, err := p.UpdateStatus(t.Context(), "name", "SGD", WalletStatusFrozen, "fraud", "admin")
+		assert.NoError(t, err)
+		assert.Equal(t, WalletStatusFrozen, wallet.Status)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("unfreeze active wallet is invalid", func(t *testing.T) {
+		mock.ExpectBegin()
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 10, "active")
+		mock.ExpectQuery(selectQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(rows)
+		mock.ExpectRollback()
+
+		wallet, err := p.UpdateStatus(t.Context(), "name", "SGD", WalletStatusActive, "ok", "admin")
+		assert.ErrorIs(t, err, ErrInvalidStatusTransition)
+		assert.Nil(t, wallet)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("closed wallet can't be reopened", func(t *testing.T) {
+		mock.ExpectBegin()
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 0, "closed")
+		mock.ExpectQuery(selectQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(rows)
+		mock.ExpectRollback()
+
+		wallet, err := p.UpdateStatus(t.Context(), "name", "SGD", WalletStatusActive, "reopen", "admin")
+		assert.ErrorIs(t, err, apierr.WalletClosed)
+		assert.Nil(t, wallet)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("close wallet with balance", func(t *testing.T) {
+		mock.ExpectBegin()
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 10, "frozen")
+		mock.ExpectQuery(selectQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(rows)
+		mock.ExpectRollback()
+
+		wallet, err := p.UpdateStatus(t.Context(), "name", "SGD", WalletStatusClosed, "close", "admin")
+		assert.ErrorIs(t, err, ErrNonZeroBalance)
+		assert.Nil(t, wallet)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("insert status history error", func(t *testing.T) {
+		mock.ExpectBegin()
+		rows := sqlmock.NewRows([]string{"id", "username", "amount", "status"})
+		rows.AddRow(1, "name", 0, "frozen")
+		mock.ExpectQuery(selectQuery).
+			WithArgs("name", "SGD").
+			WillReturnRows(rows)
+		mock.ExpectExec("UPDATE wallets SET updated_at = NOW(), status = $1 WHERE id = $2").
+			WithArgs(WalletStatusClosed, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO wallet_status_histories (wallet_id,username,currency,from_status,to_status,reason,changed_by) VALUES ($1,$2,$3,$4,$5,$6,$7)").
+			WithArgs(1, "name", "SGD", WalletStatusFrozen, WalletStatusClosed, "close", "admin").
+			WillReturnError(errors.New("err"))
+		mock.ExpectRollback()
+
+		wallet, err := p.UpdateStatus(t.Context(), "name", "SGD", WalletStatusClosed, "close", "admin")
+		assert.Error(t, err)
+		assert.Nil(t, wallet)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_wallets_ListStatusHistories(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &wallets{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	query := "SELECT id, wallet_id, username, currency, from_status, to_status, reason, changed_by, created_at FROM wallet_status_histories WHERE currency = $1 AND username = $2 ORDER BY id DESC"
+
+	t.Run("ok list status histories", func(t *testing.T) {
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "wallet_id", "username", "currency", "from_status", "to_status", "reason", "changed_by", "created_at"})
+		rows.AddRow(2, 1, "name", "SGD", "frozen", "active", "cleared", "admin", now)
+		rows.AddRow(1, 1, "name", "SGD", "active", "frozen", "fraud", "admin", now)
+		mock.ExpectQuery(query).
+			WithArgs("SGD", "name").
+			WillReturnRows(rows)
+		histories, err := p.ListStatusHistories(t.Context(), "name", "SGD")
+		assert.NoError(t, err)
+		assert.Len(t, histories, 2)
+		assert.Equal(t, WalletStatusActive, histories[0].ToStatus)
+		assert.Equal(t, "fraud", histories[1].Reason)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("list status histories error", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs("SGD", "name").
+			WillReturnError(errors.New("err"))
+		histories, err := p.ListStatusHistories(t.Context(), "name", "SGD")
 		assert.Error(t, err)
+		assert.Nil(t, histories)
 		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
 	})
 }
@@ -0,0 +1,432 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils"
+)
+
+//go:generate mockery --name WebhooksRepository --output ./mocks --outpkg mocks --case=underscore
+type WebhooksRepository interface {
+	CreateEndpoint(ctx context.Context, endpoint WebhookEndpointsModel) (*WebhookEndpointsModel, error)
+	ListEndpoints(ctx context.Context, username string) ([]WebhookEndpointsModel, error)
+	DisableEndpoint(ctx context.Context, uid, username string) (*WebhookEndpointsModel, error)
+	Enqueue(ctx context.Context, event WebhookEvent) (int, error)
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDeliveryJob, error)
+	RecordAttempt(ctx context.Context, delivery WebhookDeliveriesModel, attempt WebhookAttemptsModel) error
+	ListDeliveries(ctx context.Context, username string, filter WebhookDeliveryFilter) ([]WebhookDeliveriesModel, error)
+	GetDelivery(ctx context.Context, uid, username string) (*WebhookDeliveriesModel, error)
+	ListAttempts(ctx context.Context, deliveryID int64, limit int) ([]WebhookAttemptsModel, error)
+	Redeliver(ctx context.Context, uid, username string, now time.Time) (*WebhookDeliveriesModel, error)
+}
+
+type WebhookEndpointStatus string
+
+const (
+	WebhookEndpointActive   WebhookEndpointStatus = "active"
+	WebhookEndpointDisabled WebhookEndpointStatus = "disabled"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryDead is not attempted again until it is redelivered manually
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+var (
+	ErrWebhookEndpointDisabled = errors.New("webhook endpoint is disabled")
+	ErrWebhookDeliveryPending  = errors.New("webhook delivery is still pending")
+)
+
+type WebhookEndpointsModel struct {
+	ID        int                   `db:"id"`
+	UID       string                `db:"uid"`
+	Username  string                `db:"username"`
+	URL       string                `db:"url"`
+	Secret    string                `db:"secret"`
+	Status    WebhookEndpointStatus `db:"status"`
+	CreatedAt time.Time             `db:"created_at"`
+	UpdatedAt time.Time             `db:"updated_at"`
+}
+
+type WebhookDeliveriesModel struct {
+	ID            int64                 `db:"id"`
+	UID           string                `db:"uid"`
+	EndpointID    int                   `db:"endpoint_id"`
+	EndpointUID   string                `db:"endpoint_uid"`
+	EventUID      string                `db:"event_uid"`
+	EventType     string                `db:"event_type"`
+	Payload       json.RawMessage       `db:"payload"`
+	Status        WebhookDeliveryStatus `db:"status"`
+	Attempts      int                   `db:"attempts"`
+	NextAttemptAt time.Time             `db:"next_attempt_at"`
+	DeliveredAt   *time.Time            `db:"delivered_at"`
+	CreatedAt     time.Time             `db:"created_at"`
+	UpdatedAt     time.Time             `db:"updated_at"`
+}
+
+// WebhookDeliveryJob is the claimed delivery together with where and how it is sent.
+type WebhookDeliveryJob struct {
+	WebhookDeliveriesModel
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+type WebhookAttemptsModel struct {
+	ID         int64     `db:"id"`
+	DeliveryID int64     `db:"delivery_id"`
+	Attempt    int       `db:"attempt"`
+	StatusCode int       `db:"status_code"`
+	Error      string    `db:"error"`
+	DurationMS int64     `db:"duration_ms"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// WebhookEvent is the event delivered to every active endpoint of the username.
+type WebhookEvent struct {
+	UID      string
+	Type     string
+	Username string
+	Payload  []byte
+}
+
+// WebhookDeliveryFilter narrows the deliveries of the client, the empty fields are ignored.
+type WebhookDeliveryFilter struct {
+	EndpointUID string
+	Status      WebhookDeliveryStatus
+	Limit       int
+}
+
+var (
+	webhookEndpointsColumns  = []string{"id", "uid", "username", "url", "secret", "status", "created_at", "updated_at"}
+	webhookDeliveriesColumns = []string{"d.id", "d.uid", "d.endpoint_id", "e.uid AS endpoint_uid", "d.event_uid", "d.event_type", "d.payload", "d.status",
+		"d.attempts", "d.next_attempt_at", "d.delivered_at", "d.created_at", "d.updated_at"}
+)
+
+type webhooks struct {
+	db *sqlx.DB
+}
+
+func NewWebhooks(dao *DAO) *webhooks {
+	return &webhooks{
+		db: dao.db,
+	}
+}
+
+func (p *webhooks) CreateEndpoint(ctx context.Context, endpoint WebhookEndpointsModel) (*WebhookEndpointsModel, error) {
+	query, args, err := psql.Insert("webhook_endpoints").
+		Columns("uid", "username", "url", "secret", "status").
+		Values(utils.UUID(), endpoint.Username, endpoint.URL, endpoint.Secret, WebhookEndpointActive).
+		Suffix("RETURNING " + strings.Join(webhookEndpointsColumns, ", ")).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build webhook endpoint insert query with err: %s", err)
+		return nil, fmt.Errorf("build webhook endpoint insert query: %w", err)
+	}
+	created := new(WebhookEndpointsModel)
+	err = p.db.QueryRowxContext(ctx, query, args...).StructScan(created)
+	if err != nil {
+		log.Error(ctx, "failed to insert webhook endpoint with err: %s", err)
+		return nil, fmt.Errorf("insert webhook endpoint: %w", err)
+	}
+	log.Info(ctx, "webhook endpoint %s registered by %s", created.UID, created.Username)
+	return created, nil
+}
+
+func (p *webhooks) ListEndpoints(ctx context.Context, username string) ([]WebhookEndpointsModel, error) {
+	query, args, err := psql.Select(webhookEndpointsColumns...).
+		From("webhook_endpoints").
+		Where(squirrel.Eq{"username": username}).
+		OrderBy("id DESC").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list webhook endpoints query with err: %s", err)
+		return nil, fmt.Errorf("build list webhook endpoints query: %w", err)
+	}
+	endpoints := []WebhookEndpointsModel{}
+	err = p.db.SelectContext(ctx, &endpoints, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list webhook endpoints with err: %s", err)
+		return nil, fmt.Errorf("list webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// DisableEndpoint stops the delivery to the endpoint, its pending deliveries are left as they are.
+func (p *webhooks) DisableEndpoint(ctx context.Context, uid, username string) (*WebhookEndpointsModel, error) {
+	query, args, err := psql.Update("webhook_endpoints").
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Set("status", WebhookEndpointDisabled).
+		Where(squirrel.Eq{"uid": uid, "username": username}).
+		Suffix("RETURNING " + strings.Join(webhookEndpointsColumns, ", ")).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build disable webhook endpoint query with err: %s", err)
+		return nil, fmt.Errorf("build disable webhook endpoint query: %w", err)
+	}
+	endpoint := new(WebhookEndpointsModel)
+	err = p.db.QueryRowxContext(ctx, query, args...).StructScan(endpoint)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierr.NotFound
+		}
+		log.Error(ctx, "failed to disable webhook endpoint with err: %s", err)
+		return nil, fmt.Errorf("disable webhook endpoint: %w", err)
+	}
+	log.Info(ctx, "webhook endpoint %s disabled by %s", uid, username)
+	return endpoint, nil
+}
+
+// Enqueue creates a pending delivery of the event for every active endpoint of its username and returns the
+// number of deliveries created. An event already enqueued for the endpoint is ignored.
+func (p *webhooks) Enqueue(ctx context.Context, event WebhookEvent) (int, error) {
+	query, args, err := psql.Select("id").
+		From("webhook_endpoints").
+		Where(squirrel.Eq{"username": event.Username, "status": WebhookEndpointActive}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build active webhook endpoints query with err: %s", err)
+		return 0, fmt.Errorf("build active webhook endpoints query: %w", err)
+	}
+	var endpointIDs []int
+	err = p.db.SelectContext(ctx, &endpointIDs, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list active webhook endpoints with err: %s", err)
+		return 0, fmt.Errorf("list active webhook endpoints: %w", err)
+	}
+	if len(endpointIDs) == 0 {
+		return 0, nil
+	}
+
+	insert := psql.Insert("webhook_deliveries").
+		Columns("uid", "endpoint_id", "event_uid", "event_type", "payload", "status", "next_attempt_at")
+	for _, endpointID := range endpointIDs {
+		insert = insert.Values(utils.UUID(), endpointID, event.UID, event.Type, string(event.Payload), WebhookDeliveryPending,
+			squirrel.Expr("(now() AT TIME ZONE 'utc')"))
+	}
+	query, args, err = insert.Suffix("ON CONFLICT (endpoint_id, event_uid) DO NOTHING").ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build webhook deliveries insert query with err: %s", err)
+		return 0, fmt.Errorf("build webhook deliveries insert query: %w", err)
+	}
+	result, err := p.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to insert webhook deliveries with err: %s", err)
+		return 0, fmt.Errorf("insert webhook deliveries: %w", err)
+	}
+	created, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("insert webhook deliveries: %w", err)
+	}
+	return int(created), nil
+}
+
+// ClaimDue returns the pending deliveries of the active endpoints which are due by now, the earliest first. The
+// claimed deliveries are pushed back by lease, so neither another instance nor the next run picks them up while
+// they are being sent, and a delivery left behind by a crash is attempted again once the lease is over.
+func (p *webhooks) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]WebhookDeliveryJob, error) {
+	jobs := []WebhookDeliveryJob{}
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		query, args, err := psql.Select(append(webhookDeliveriesColumns, "e.url", "e.secret")...).
+			From("webhook_deliveries d").
+			Join("webhook_endpoints e ON e.id = d.endpoint_id").
+			Where(squirrel.Eq{"d.status": WebhookDeliveryPending, "e.status": WebhookEndpointActive}).
+			Where(squirrel.LtOrEq{"d.next_attempt_at": now}).
+			OrderBy("d.next_attempt_at").
+			Limit(uint64(limit)).
+			Suffix("FOR UPDATE OF d SKIP LOCKED").
+			ToSql()
+		if err != nil {
+			log.Error(ctx, "failed to build due webhook deliveries query with err: %s", err)
+			return fmt.Errorf("build due webhook deliveries query: %w", err)
+		}
+		err = sqlx.SelectContext(ctx, exec, &jobs, query, args...)
+		if err != nil {
+			log.Error(ctx, "failed to list due webhook deliveries with err: %s", err)
+			return fmt.Errorf("list due webhook deliveries: %w", err)
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+		ids := make([]int64, 0, len(jobs))
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+		query, args, err = psql.Update("webhook_deliveries").
+			Set("updated_at", squirrel.Expr("NOW()")).
+			Set("next_attempt_at", now.Add(lease)).
+			Where(squirrel.Eq{"id": ids}).
+			ToSql()
+		if err != nil {
+			log.Error(ctx, "failed to build claim webhook deliveries query with err: %s", err)
+			return fmt.Errorf("build claim webhook deliveries query: %w", err)
+		}
+		_, err = exec.ExecContext(ctx, query, args...)
+		if err != nil {
+			log.Error(ctx, "failed to claim webhook deliveries with err: %s", err)
+			return fmt.Errorf("claim webhook deliveries: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RecordAttempt logs the attempt of the delivery and saves the status, attempts, next attempt and delivered time of it.
+func (p *webhooks) RecordAttempt(ctx context.Context, delivery WebhookDeliveriesModel, attempt WebhookAttemptsModel) error {
+	return lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		query, args, err := psql.Insert("webhook_attempts").
+			Columns("delivery_id", "attempt", "status_code", "error", "duration_ms").
+			Values(delivery.ID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMS).
+			ToSql()
+		if err != nil {
+			log.Error(ctx, "failed to build webhook attempt insert query with err: %s", err)
+			return fmt.Errorf("build webhook attempt insert query: %w", err)
+		}
+		_, err = exec.ExecContext(ctx, query, args...)
+		if err != nil {
+			log.Error(ctx, "failed to insert webhook attempt with err: %s", err)
+			return fmt.Errorf("insert webhook attempt: %w", err)
+		}
+		return updateDelivery(ctx, exec, &delivery)
+	})
+}
+
+func (p *webhooks) ListDeliveries(ctx context.Context, username string, filter WebhookDeliveryFilter) ([]WebhookDeliveriesModel, error) {
+	where := squirrel.Eq{"e.username": username}
+	if filter.EndpointUID != "" {
+		where["e.uid"] = filter.EndpointUID
+	}
+	if filter.Status != "" {
+		where["d.status"] = filter.Status
+	}
+	query, args, err := psql.Select(webhookDeliveriesColumns...).
+		From("webhook_deliveries d").
+		Join("webhook_endpoints e ON e.id = d.endpoint_id").
+		Where(where).
+		OrderBy("d.id DESC").
+		Limit(uint64(filter.Limit)).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list webhook deliveries query with err: %s", err)
+		return nil, fmt.Errorf("build list webhook deliveries query: %w", err)
+	}
+	deliveries := []WebhookDeliveriesModel{}
+	err = p.db.SelectContext(ctx, &deliveries, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list webhook deliveries with err: %s", err)
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (p *webhooks) GetDelivery(ctx context.Context, uid, username string) (*WebhookDeliveriesModel, error) {
+	return getDelivery(ctx, p.db, uid, username, "")
+}
+
+// ListAttempts returns the latest attempts of the delivery first.
+func (p *webhooks) ListAttempts(ctx context.Context, deliveryID int64, limit int) ([]WebhookAttemptsModel, error) {
+	query, args, err := psql.Select("id", "delivery_id", "attempt", "status_code", "error", "duration_ms", "created_at").
+		From("webhook_attempts").
+		Where(squirrel.Eq{"delivery_id": deliveryID}).
+		OrderBy("id DESC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build list webhook attempts query with err: %s", err)
+		return nil, fmt.Errorf("build list webhook attempts query: %w", err)
+	}
+	attempts := []WebhookAttemptsModel{}
+	err = p.db.SelectContext(ctx, &attempts, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to list webhook attempts with err: %s", err)
+		return nil, fmt.Errorf("list webhook attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// Redeliver schedules the succeeded or dead delivery to be attempted again by now with a fresh retry budget.
+func (p *webhooks) Redeliver(ctx context.Context, uid, username string, now time.Time) (*WebhookDeliveriesModel, error) {
+	var delivery *WebhookDeliveriesModel
+	err := lockExecution(ctx, p.db, func(exec sqlx.ExtContext) error {
+		var err error
+		delivery, err = getDelivery(ctx, exec, uid, username, "FOR UPDATE OF d")
+		if err != nil {
+			return err
+		}
+		if delivery.Status == WebhookDeliveryPending {
+			return ErrWebhookDeliveryPending
+		}
+		delivery.Status = WebhookDeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = now
+		return updateDelivery(ctx, exec, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info(ctx, "webhook delivery %s redelivered by %s", uid, username)
+	return delivery, nil
+}
+
+func getDelivery(ctx context.Context, exec sqlx.ExtContext, uid, username, suffix string) (*WebhookDeliveriesModel, error) {
+	queryBuilder := psql.Select(webhookDeliveriesColumns...).
+		From("webhook_deliveries d").
+		Join("webhook_endpoints e ON e.id = d.endpoint_id").
+		Where(squirrel.Eq{"d.uid": uid, "e.username": username})
+	if suffix != "" {
+		queryBuilder = queryBuilder.Suffix(suffix)
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build get webhook delivery query with err: %s", err)
+		return nil, fmt.Errorf("build get webhook delivery query: %w", err)
+	}
+	delivery := new(WebhookDeliveriesModel)
+	err = sqlx.GetContext(ctx, exec, delivery, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierr.NotFound
+		}
+		log.Error(ctx, "failed to get webhook delivery with err: %s", err)
+		return nil, fmt.Errorf("get webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+func updateDelivery(ctx context.Context, exec sqlx.ExtContext, delivery *WebhookDeliveriesModel) error {
+	query, args, err := psql.Update("webhook_deliveries").
+		Set("updated_at", squirrel.Expr("NOW()")).
+		Set("status", delivery.Status).
+		Set("attempts", delivery.Attempts).
+		Set("next_attempt_at", delivery.NextAttemptAt).
+		Set("delivered_at", delivery.DeliveredAt).
+		Where(squirrel.Eq{"id": delivery.ID}).
+		ToSql()
+	if err != nil {
+		log.Error(ctx, "failed to build webhook delivery update query with err: %s", err)
+		return fmt.Errorf("build webhook delivery update query: %w", err)
+	}
+	_, err = exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "failed to update webhook delivery %s with err: %s", delivery.UID, err)
+		return fmt.Errorf("update webhook delivery: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package dao
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	deliveryColumnsSQL  = "d.id, d.uid, d.endpoint_id, e.uid AS endpoint_uid, d.event_uid, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at, d.delivered_at, d.created_at, d.updated_at"
+	updateDeliveryQuery = "UPDATE webhook_deliveries SET updated_at = NOW(), status = $1, attempts = $2, next_attempt_at = $3, delivered_at = $4 WHERE id = $5"
+)
+
+var deliveryColumns = []string{"id", "uid", "endpoint_id", "endpoint_uid", "event_uid", "event_type", "payload", "status", "attempts", "next_attempt_at", "delivered_at", "created_at", "updated_at"}
+
+func Test_webhooks_Enqueue(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &webhooks{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	event := WebhookEvent{UID: "evt1", Type: "wallet.credited", Username: "user1", Payload: []byte(`{}`)}
+	activeEndpointsQuery := "SELECT id FROM webhook_endpoints WHERE status = $1 AND username = $2 ORDER BY id"
+
+	t.Run("ok enqueue for every active endpoint", func(t *testing.T) {
+		mock.ExpectQuery(activeEndpointsQuery).
+			WithArgs(WebhookEndpointActive, "user1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		mock.ExpectExec("INSERT INTO webhook_deliveries (uid,endpoint_id,event_uid,event_type,payload,status,next_attempt_at) VALUES "+
+			"($1,$2,$3,$4,$5,$6,(now() AT TIME ZONE 'utc')),($7,$8,$9,$10,$11,$12,(now() AT TIME ZONE 'utc')) ON CONFLICT (endpoint_id, event_uid) DO NOTHING").
+			WithArgs(sqlmock.AnyArg(), 1, "evt1", "wallet.credited", "{}", WebhookDeliveryPending,
+				sqlmock.AnyArg(), 2, "evt1", "wallet.credited", "{}", WebhookDeliveryPending).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		created, err := p.Enqueue(t.Context(), event)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, created)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("no active endpoint", func(t *testing.T) {
+		mock.ExpectQuery(activeEndpointsQuery).
+			WithArgs(WebhookEndpointActive, "user1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		created, err := p.Enqueue(t.Context(), event)
+		assert.NoError(t, err)
+		assert.Zero(t, created)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_webhooks_ClaimDue(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &webhooks{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	now := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	dueQuery := "SELECT " + deliveryColumnsSQL + ", e.url, e.secret FROM webhook_deliveries d JOIN webhook_endpoints e ON e.id = d.endpoint_id " +
+		"WHERE d.status = $1 AND e.status = $2 AND d.next_attempt_at <= $3 ORDER BY d.next_attempt_at LIMIT 10 FOR UPDATE OF d SKIP LOCKED"
+
+	t.Run("ok claim due deliveries", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(dueQuery).
+			WithArgs(WebhookDeliveryPending, WebhookEndpointActive, now).
+			WillReturnRows(sqlmock.NewRows(append(deliveryColumns, "url", "secret")).
+				AddRow(5, "dlv1", 1, "ep1", "evt1", "wallet.credited", []byte(`{}`), WebhookDeliveryPending, 0, now, nil, now, now, "https://example.com", "whsec_x"))
+		mock.ExpectExec("UPDATE webhook_deliveries SET updated_at = NOW(), next_attempt_at = $1 WHERE id IN ($2)").
+			WithArgs(now.Add(time.Minute), 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		jobs, err := p.ClaimDue(t.Context(), now, time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Len(t, jobs, 1)
+		assert.Equal(t, "dlv1", jobs[0].UID)
+		assert.Equal(t, "ep1", jobs[0].EndpointUID)
+		assert.Equal(t, "https://example.com", jobs[0].URL)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("nothing due", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(dueQuery).
+			WithArgs(WebhookDeliveryPending, WebhookEndpointActive, now).
+			WillReturnRows(sqlmock.NewRows(append(deliveryColumns, "url", "secret")))
+		mock.ExpectCommit()
+
+		jobs, err := p.ClaimDue(t.Context(), now, time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, jobs)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
+
+func Test_webhooks_Redeliver(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	p := &webhooks{
+		db: sqlx.NewDb(mockDB, "sqlmock"),
+	}
+	now := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	lockDeliveryQuery := "SELECT " + deliveryColumnsSQL + " FROM webhook_deliveries d JOIN webhook_endpoints e ON e.id = d.endpoint_id WHERE d.uid = $1 AND e.username = $2 FOR UPDATE OF d"
+	deliveryRow := func(status WebhookDeliveryStatus) *sqlmock.Rows {
+		return sqlmock.NewRows(deliveryColumns).
+			AddRow(5, "dlv1", 1, "ep1", "evt1", "wallet.credited", []byte(`{}`), status, 10, now, nil, now, now)
+	}
+
+	t.Run("ok redeliver dead delivery", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockDeliveryQuery).WithArgs("dlv1", "user1").WillReturnRows(deliveryRow(WebhookDeliveryDead))
+		mock.ExpectExec(updateDeliveryQuery).
+			WithArgs(WebhookDeliveryPending, 0, now, nil, 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		delivery, err := p.Redeliver(t.Context(), "dlv1", "user1", now)
+		assert.NoError(t, err)
+		assert.Equal(t, WebhookDeliveryPending, delivery.Status)
+		assert.Zero(t, delivery.Attempts)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("redeliver pending delivery", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockDeliveryQuery).WithArgs("dlv1", "user1").WillReturnRows(deliveryRow(WebhookDeliveryPending))
+		mock.ExpectRollback()
+
+		_, err := p.Redeliver(t.Context(), "dlv1", "user1", now)
+		assert.ErrorIs(t, err, ErrWebhookDeliveryPending)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+
+	t.Run("delivery of another client", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockDeliveryQuery).WithArgs("dlv1", "user1").WillReturnRows(sqlmock.NewRows(deliveryColumns))
+		mock.ExpectRollback()
+
+		_, err := p.Redeliver(t.Context(), "dlv1", "user1", now)
+		assert.ErrorIs(t, err, apierr.NotFound)
+		assert.NoError(t, mock.ExpectationsWereMet(), "unfulfilled expectations")
+	})
+}
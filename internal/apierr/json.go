@@ -8,6 +8,10 @@ import (
 var (
 	NotFound         = errors.New("not found")
 	InsufficientFund = errors.New("infufficient fund")
+	WalletFrozen     = errors.New("wallet frozen")
+	WalletClosed     = errors.New("wallet closed")
+	LimitExceeded    = errors.New("limit exceeded")
+	PeriodClosed     = errors.New("period closed")
 )
 
 type JSON interface {
@@ -55,6 +59,10 @@ const (
 	CodeConflict            = "CONFLICT"
 	CodeUnprocessabled      = "UNPROCESSIABLED"
 	CodeServiceUnavailable  = "SERVICE_UNAVAILABLE"
+	CodeWalletFrozen        = "WALLET_FROZEN"
+	CodeWalletClosed        = "WALLET_CLOSED"
+	CodeLimitExceeded       = "LIMIT_EXCEEDED"
+	CodePeriodClosed        = "PERIOD_CLOSED"
 )
 
 func BadRequest(message string) JSON {
@@ -85,3 +93,7 @@ func ServiceUnavailable(message string) JSON {
 func Forbidden(message string) JSON {
 	return NewJSON(http.StatusForbidden, CodeForbidden, message, errors.New(message))
 }
+
+func ResourceNotFound(message string) JSON {
+	return NewJSON(http.StatusNotFound, CodeNotFound, message, errors.New(message))
+}
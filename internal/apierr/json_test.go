@@ -31,4 +31,10 @@ func TestErrStatusCodeJSON(t *testing.T) {
 		assert.Equal(t, "error11", got.Error())
 		assert.Equal(t, http.StatusUnprocessableEntity, got.HTTPStatusCode())
 	})
+
+	t.Run("resource not found error", func(t *testing.T) {
+		got := ResourceNotFound("wallet not found")
+		assert.Equal(t, "wallet not found", got.Error())
+		assert.Equal(t, http.StatusNotFound, got.HTTPStatusCode())
+	})
 }
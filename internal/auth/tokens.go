@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const sessionKeyPrefix = "session:"
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+type ctxKey int
+
+const tokenKey ctxKey = iota
+
+// WithToken stores the verified bearer token into context, so the handler is able to revoke it.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey).(string)
+	return token, ok && token != ""
+}
+
+//go:generate mockery --name Tokens --output ./mocks --outpkg mocks --case=underscore
+type Tokens interface {
+	Issue(ctx context.Context, username string) (string, time.Time, error)
+	Verify(ctx context.Context, token string) (string, error)
+	Revoke(ctx context.Context, token string) error
+}
+
+// redisTokens issues opaque session tokens, only the sha256 of the token is kept in redis
+// so a leaked redis dump can't be replayed as a bearer token.
+// The token expires with the redis key TTL and is revoked by deleting the key.
+type redisTokens struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewTokens(client *redis.Client, ttl time.Duration) Tokens {
+	return &redisTokens{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func sessionKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return sessionKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+func (t *redisTokens) Issue(ctx context.Context, username string) (string, time.Time, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Error(ctx, "failed to generate session token: %v", err)
+		return "", time.Time{}, fmt.Errorf("generate session token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	expiresAt := time.Now().Add(t.ttl)
+	err := t.client.Set(ctx, sessionKey(token), username, t.ttl).Err()
+	if err != nil {
+		log.Error(ctx, "failed to store session token: %v", err)
+		return "", time.Time{}, fmt.Errorf("store session token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+func (t *redisTokens) Verify(ctx context.Context, token string) (string, error) {
+	username, err := t.client.Get(ctx, sessionKey(token)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrInvalidToken
+		}
+		log.Error(ctx, "failed to get session token: %v", err)
+		return "", fmt.Errorf("get session token: %w", err)
+	}
+	return username, nil
+}
+
+func (t *redisTokens) Revoke(ctx context.Context, token string) error {
+	err := t.client.Del(ctx, sessionKey(token)).Err()
+	if err != nil {
+		log.Error(ctx, "failed to revoke session token: %v", err)
+		return fmt.Errorf("revoke session token: %w", err)
+	}
+	return nil
+}
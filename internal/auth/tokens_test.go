@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTokens(t *testing.T) (*miniredis.Miniredis, Tokens) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, NewTokens(client, time.Hour)
+}
+
+func Test_redisTokens(t *testing.T) {
+	t.Run("issue and verify token", func(t *testing.T) {
+		mr, tokens := newTestTokens(t)
+		token, expiresAt, err := tokens.Issue(t.Context(), "user1")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+		assert.False(t, mr.Exists(sessionKeyPrefix+token), "raw token must not be stored")
+
+		username, err := tokens.Verify(t.Context(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, "user1", username)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		_, tokens := newTestTokens(t)
+		username, err := tokens.Verify(t.Context(), "unknown")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		assert.Empty(t, username)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		mr, tokens := newTestTokens(t)
+		token, _, err := tokens.Issue(t.Context(), "user1")
+		assert.NoError(t, err)
+		mr.FastForward(time.Hour + time.Second)
+		_, err = tokens.Verify(t.Context(), token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		_, tokens := newTestTokens(t)
+		token, _, err := tokens.Issue(t.Context(), "user1")
+		assert.NoError(t, err)
+		assert.NoError(t, tokens.Revoke(t.Context(), token))
+		_, err = tokens.Verify(t.Context(), token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("redis unavailable", func(t *testing.T) {
+		mr, tokens := newTestTokens(t)
+		mr.Close()
+		_, err := tokens.Verify(t.Context(), "token")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestTokenFromContext(t *testing.T) {
+	_, ok := TokenFromContext(t.Context())
+	assert.False(t, ok)
+	token, ok := TokenFromContext(WithToken(t.Context(), "token"))
+	assert.True(t, ok)
+	assert.Equal(t, "token", token)
+}
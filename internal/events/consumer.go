@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Message is an event read from the stream, ID is the stream entry to acknowledge once it is handled.
+type Message struct {
+	ID    string
+	Event Event
+}
+
+//go:generate mockery --name Consumer --output ./mocks --outpkg mocks --case=underscore
+type Consumer interface {
+	// Read returns up to count messages, the messages read before but not acknowledged come first
+	Read(ctx context.Context, count int64) ([]Message, error)
+	Ack(ctx context.Context, ids ...string) error
+}
+
+// redisStreamConsumer reads the stream as a member of the consumer group, every group receives every event
+// while the consumers of the same group share them.
+type redisStreamConsumer struct {
+	client   *redis.Client
+	stream   string
+	group    string
+	consumer string
+}
+
+func NewRedisStreamConsumer(client *redis.Client, stream, group, consumer string) Consumer {
+	return &redisStreamConsumer{
+		client:   client,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
+	}
+}
+
+func (c *redisStreamConsumer) Read(ctx context.Context, count int64) ([]Message, error) {
+	// The group starts from the beginning of the stream, so the events published before it existed are delivered
+	err := c.client.XGroupCreateMkStream(ctx, c.stream, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, fmt.Errorf("create consumer group %s: %w", c.group, err)
+	}
+	// "0" re-reads the pending messages of the consumer which weren't acknowledged, ">" reads the new ones
+	for _, start := range []string{"0", ">"} {
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.consumer,
+			Streams:  []string{c.stream, start},
+			Count:    count,
+			Block:    -1,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("read stream %s: %w", c.stream, err)
+		}
+		var messages []Message
+		for _, stream := range streams {
+			for _, entry := range stream.Messages {
+				messages = append(messages, toMessage(entry))
+			}
+		}
+		if len(messages) > 0 {
+			return messages, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *redisStreamConsumer) Ack(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := c.client.XAck(ctx, c.stream, c.group, ids...).Err()
+	if err != nil {
+		return fmt.Errorf("ack stream %s: %w", c.stream, err)
+	}
+	return nil
+}
+
+func toMessage(entry redis.XMessage) Message {
+	value := func(key string) string {
+		s, _ := entry.Values[key].(string)
+		return s
+	}
+	createdAt, _ := time.Parse(time.RFC3339Nano, value("created_at"))
+	return Message{
+		ID: entry.ID,
+		Event: Event{
+			UID:       value("uid"),
+			Type:      value("type"),
+			Key:       value("key"),
+			Payload:   []byte(value("payload")),
+			CreatedAt: createdAt,
+		},
+	}
+}
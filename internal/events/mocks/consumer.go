@@ -0,0 +1,84 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	events "github.com/lengzuo/fundflow/internal/events"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Consumer is an autogenerated mock type for the Consumer type
+type Consumer struct {
+	mock.Mock
+}
+
+// Ack provides a mock function with given fields: ctx, ids
+func (_m *Consumer) Ack(ctx context.Context, ids ...string) error {
+	_va := make([]interface{}, len(ids))
+	for _i := range ids {
+		_va[_i] = ids[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Ack")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, ...string) error); ok {
+		r0 = rf(ctx, ids...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Read provides a mock function with given fields: ctx, count
+func (_m *Consumer) Read(ctx context.Context, count int64) ([]events.Message, error) {
+	ret := _m.Called(ctx, count)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Read")
+	}
+
+	var r0 []events.Message
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]events.Message, error)); ok {
+		return rf(ctx, count)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []events.Message); ok {
+		r0 = rf(ctx, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]events.Message)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, count)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewConsumer creates a new instance of Consumer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewConsumer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Consumer {
+	mock := &Consumer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
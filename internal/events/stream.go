@@ -0,0 +1,59 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Event is the message delivered to the consumers, Key is the wallet the event belongs to and UID is unique across
+// the redeliveries of the same event so the consumers are able to deduplicate.
+type Event struct {
+	UID       string
+	Type      string
+	Key       string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+//go:generate mockery --name Publisher --output ./mocks --outpkg mocks --case=underscore
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// redisStream appends the events to a single redis stream, so the consumers read them in the order they are
+// published. The stream is trimmed to about maxLen entries, zero keeps every entry.
+type redisStream struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+func NewRedisStream(client *redis.Client, stream string, maxLen int64) Publisher {
+	return &redisStream{
+		client: client,
+		stream: stream,
+		maxLen: maxLen,
+	}
+}
+
+func (p *redisStream) Publish(ctx context.Context, event Event) error {
+	err := p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: p.maxLen > 0,
+		Values: map[string]any{
+			"uid":        event.UID,
+			"type":       event.Type,
+			"key":        event.Key,
+			"payload":    string(event.Payload),
+			"created_at": event.CreatedAt.UTC().Format(time.RFC3339Nano),
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("publish event %s to stream %s: %w", event.UID, p.stream, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStream(t *testing.T, maxLen int64) (*miniredis.Miniredis, *redis.Client, Publisher) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client, NewRedisStream(client, "wallet-events", maxLen)
+}
+
+func Test_redisStream_Publish(t *testing.T) {
+	createdAt := time.Date(2026, 10, 17, 1, 2, 3, 0, time.UTC)
+
+	t.Run("publish events in order", func(t *testing.T) {
+		_, client, publisher := newTestStream(t, 0)
+		for _, uid := range []string{"evt1", "evt2"} {
+			err := publisher.Publish(t.Context(), Event{UID: uid, Type: "wallet.credited", Key: "user1:SGD", Payload: []byte(`{"amount":100}`), CreatedAt: createdAt})
+			assert.NoError(t, err)
+		}
+		entries, err := client.XRange(t.Context(), "wallet-events", "-", "+").Result()
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "evt1", entries[0].Values["uid"])
+		assert.Equal(t, "evt2", entries[1].Values["uid"])
+		assert.Equal(t, "user1:SGD", entries[0].Values["key"])
+		assert.Equal(t, `{"amount":100}`, entries[0].Values["payload"])
+		assert.Equal(t, "2026-10-17T01:02:03Z", entries[0].Values["created_at"])
+	})
+
+	t.Run("redis unavailable", func(t *testing.T) {
+		mr, _, publisher := newTestStream(t, 100)
+		mr.Close()
+		err := publisher.Publish(t.Context(), Event{UID: "evt1"})
+		assert.Error(t, err)
+	})
+}
+
+func Test_redisStreamConsumer(t *testing.T) {
+	createdAt := time.Date(2026, 10, 17, 1, 2, 3, 0, time.UTC)
+
+	t.Run("read published events and pending first", func(t *testing.T) {
+		_, client, publisher := newTestStream(t, 0)
+		for _, uid := range []string{"evt1", "evt2"} {
+			assert.NoError(t, publisher.Publish(t.Context(), Event{UID: uid, Type: "wallet.debited", Key: "user1:SGD", Payload: []byte(`{}`), CreatedAt: createdAt}))
+		}
+		consumer := NewRedisStreamConsumer(client, "wallet-events", "webhooks", "instance1")
+
+		messages, err := consumer.Read(t.Context(), 1)
+		assert.NoError(t, err)
+		assert.Len(t, messages, 1)
+		assert.Equal(t, "evt1", messages[0].Event.UID)
+		assert.Equal(t, "user1:SGD", messages[0].Event.Key)
+		assert.Equal(t, createdAt, messages[0].Event.CreatedAt)
+
+		// evt1 isn't acknowledged, it is read again before evt2
+		again, err := consumer.Read(t.Context(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "evt1", again[0].Event.UID)
+
+		assert.NoError(t, consumer.Ack(t.Context(), messages[0].ID))
+		messages, err = consumer.Read(t.Context(), 10)
+		assert.NoError(t, err)
+		assert.Len(t, messages, 1)
+		assert.Equal(t, "evt2", messages[0].Event.UID)
+
+		assert.NoError(t, consumer.Ack(t.Context(), messages[0].ID))
+		messages, err = consumer.Read(t.Context(), 10)
+		assert.NoError(t, err)
+		assert.Empty(t, messages)
+	})
+
+	t.Run("redis unavailable", func(t *testing.T) {
+		mr, client, _ := newTestStream(t, 0)
+		mr.Close()
+		_, err := NewRedisStreamConsumer(client, "wallet-events", "webhooks", "instance1").Read(t.Context(), 10)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,70 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/redis/go-redis/v9"
+)
+
+const rateKeyPrefix = "fx:rate:"
+
+// CachedRates keeps the rates of next provider in redis for ttl, so the provider isn't called for every quote.
+// The rate is cached together with its AsOf, the staleness is still decided from when it is published.
+// Redis failure is logged and falls back to next provider.
+type CachedRates struct {
+	next   RateProvider
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewCachedRates(next RateProvider, client *redis.Client, ttl time.Duration) *CachedRates {
+	return &CachedRates{
+		next:   next,
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+type cachedRate struct {
+	Rate string    `json:"rate"`
+	AsOf time.Time `json:"as_of"`
+}
+
+func rateKey(base, quote string) string {
+	return rateKeyPrefix + pairKey(base, quote)
+}
+
+func (c *CachedRates) Rate(ctx context.Context, base, quote string) (*Rate, error) {
+	key := rateKey(base, quote)
+	b, err := c.client.Get(ctx, key).Bytes()
+	if err == nil {
+		cached := cachedRate{}
+		if err = json.Unmarshal(b, &cached); err == nil {
+			var value *big.Rat
+			if value, err = ParseRate(cached.Rate); err == nil {
+				return &Rate{Value: value, AsOf: cached.AsOf}, nil
+			}
+		}
+		log.Error(ctx, "failed to decode cached fx rate %s: %v", key, err)
+	} else if !errors.Is(err, redis.Nil) {
+		log.Error(ctx, "failed to get cached fx rate %s: %v", key, err)
+	}
+
+	rate, err := c.next.Rate(ctx, base, quote)
+	if err != nil {
+		return nil, err
+	}
+	b, err = json.Marshal(cachedRate{Rate: rate.Value.RatString(), AsOf: rate.AsOf})
+	if err == nil {
+		err = c.client.Set(ctx, key, b, c.ttl).Err()
+	}
+	if err != nil {
+		log.Error(ctx, "failed to cache fx rate %s: %v", key, err)
+	}
+	return rate, nil
+}
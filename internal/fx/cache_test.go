@@ -0,0 +1,68 @@
+package fx
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCachedRates(t *testing.T, next RateProvider) (*miniredis.Miniredis, *CachedRates) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, NewCachedRates(next, client, time.Minute)
+}
+
+func TestCachedRates(t *testing.T) {
+	asOf := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	t.Run("cache the rate of next provider", func(t *testing.T) {
+		next := &stubRates{rate: &Rate{Value: big.NewRat(11025, 100), AsOf: asOf}}
+		mr, rates := newTestCachedRates(t, next)
+
+		for range 2 {
+			rate, err := rates.Rate(t.Context(), "SGD", "JPY")
+			assert.NoError(t, err)
+			assert.Equal(t, big.NewRat(11025, 100), rate.Value)
+			assert.Equal(t, asOf, rate.AsOf)
+		}
+		assert.Equal(t, 1, next.calls)
+		assert.Equal(t, time.Minute, mr.TTL(rateKeyPrefix+"SGD/JPY"))
+	})
+
+	t.Run("fetch again after ttl", func(t *testing.T) {
+		next := &stubRates{rate: &Rate{Value: big.NewRat(110, 1), AsOf: asOf}}
+		mr, rates := newTestCachedRates(t, next)
+
+		_, err := rates.Rate(t.Context(), "SGD", "JPY")
+		assert.NoError(t, err)
+		mr.FastForward(time.Minute)
+		_, err = rates.Rate(t.Context(), "SGD", "JPY")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, next.calls)
+	})
+
+	t.Run("error of next provider is not cached", func(t *testing.T) {
+		next := &stubRates{err: errors.New("provider down")}
+		mr, rates := newTestCachedRates(t, next)
+
+		_, err := rates.Rate(t.Context(), "SGD", "JPY")
+		assert.Error(t, err)
+		assert.False(t, mr.Exists(rateKeyPrefix+"SGD/JPY"))
+	})
+
+	t.Run("fall back to next provider when redis is down", func(t *testing.T) {
+		next := &stubRates{rate: &Rate{Value: big.NewRat(110, 1), AsOf: asOf}}
+		mr, rates := newTestCachedRates(t, next)
+		mr.Close()
+
+		rate, err := rates.Rate(t.Context(), "SGD", "JPY")
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewRat(110, 1), rate.Value)
+	})
+}
@@ -0,0 +1,90 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+var (
+	ErrRateNotFound = errors.New("fx rate not found")
+	ErrInvalidRate  = errors.New("fx rate must be a positive decimal")
+	// ErrAmountTooSmall is returned when the converted amount is rounded down to zero
+	ErrAmountTooSmall = errors.New("converted amount is too small")
+	// ErrStaleRate is returned when the rate is older than the allowed age, it must not be quoted
+	ErrStaleRate = errors.New("fx rate is stale")
+)
+
+// Rate is how many units of quote currency one unit of base currency buys.
+type Rate struct {
+	Value *big.Rat
+	// AsOf is when the rate is published by the source,
+	// it is zero for the rates maintained by hand which never go stale.
+	AsOf time.Time
+}
+
+//go:generate mockery --name RateProvider --output ./mocks --outpkg mocks --case=underscore
+type RateProvider interface {
+	Rate(ctx context.Context, base, quote string) (*Rate, error)
+}
+
+// ParseRate parses a positive decimal rate such as "110.25".
+func ParseRate(value string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || rate.Sign() <= 0 {
+		return nil, ErrInvalidRate
+	}
+	return rate, nil
+}
+
+// FormatRate formats the rate with the scale of the fx_quotes.rate column.
+func FormatRate(rate *big.Rat) string {
+	return rate.FloatString(12)
+}
+
+// Convert converts amount into the smallest unit of to currency, the fraction of the smallest unit is rounded with mode.
+// Rounding down never credits the wallet more than the rate pays for,
+// e.g. 1001 SGD cents at 110.255 is 1103.65 JPY and is credited as 1103 JPY.
+func Convert(amount money.Money, rate *big.Rat, to currency.Value, mode money.RoundingMode) (money.Money, error) {
+	from := amount.Currency()
+	converted := big.NewRat(amount.Amount(), 1)
+	converted.Mul(converted, rate)
+	// From the smallest unit into the major unit of from currency
+	converted.Quo(converted, new(big.Rat).SetInt(pow10(from.Precise)))
+	result, err := money.FromRat(converted, to, mode)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("convert %s to %s: %w", amount, to.Code, err)
+	}
+	if !result.IsPositive() {
+		return money.Money{}, ErrAmountTooSmall
+	}
+	return result, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// parsePair parses "BASE/QUOTE" where both currencies must be registered.
+func parsePair(pair string) (string, string, error) {
+	base, quote, ok := strings.Cut(pair, "/")
+	if !ok {
+		return "", "", fmt.Errorf("invalid fx pair %q", pair)
+	}
+	for _, code := range []string{base, quote} {
+		if _, err := currency.Get(strings.ToUpper(strings.TrimSpace(code))); err != nil {
+			return "", "", fmt.Errorf("invalid fx pair %q: %w", pair, err)
+		}
+	}
+	return base, quote, nil
+}
+
+func pairKey(base, quote string) string {
+	return strings.ToUpper(strings.TrimSpace(base)) + "/" + strings.ToUpper(strings.TrimSpace(quote))
+}
@@ -0,0 +1,68 @@
+package fx
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		rate    string
+		mode    money.RoundingMode
+		from    currency.Value
+		to      currency.Value
+		want    int64
+		wantErr error
+	}{
+		{name: "sgd cents to jpy rounded down", amount: 1001, rate: "110.255", mode: money.RoundDown, from: currency.SGD, to: currency.JPY, want: 1103},
+		{name: "sgd cents to jpy exact", amount: 1000, rate: "110", mode: money.RoundDown, from: currency.SGD, to: currency.JPY, want: 1100},
+		{name: "jpy to sgd cents", amount: 1000, rate: "0.009", mode: money.RoundDown, from: currency.JPY, to: currency.SGD, want: 900},
+		{name: "jpy to sgd cents rounded down", amount: 111, rate: "0.00907", mode: money.RoundDown, from: currency.JPY, to: currency.SGD, want: 100},
+		{name: "sgd cents to jpy rounded half up", amount: 1001, rate: "110.255", mode: money.RoundHalfUp, from: currency.SGD, to: currency.JPY, want: 1104},
+		{name: "too small to convert", amount: 1, rate: "0.009", mode: money.RoundDown, from: currency.JPY, to: currency.SGD, wantErr: ErrAmountTooSmall},
+		{name: "rounded up is not too small", amount: 1, rate: "0.009", mode: money.RoundUp, from: currency.JPY, to: currency.SGD, want: 1},
+		{name: "overflow", amount: math.MaxInt64, rate: "110", mode: money.RoundDown, from: currency.JPY, to: currency.SGD, wantErr: money.ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := ParseRate(tt.rate)
+			assert.NoError(t, err)
+			got, err := Convert(money.New(tt.amount, tt.from), rate, tt.to, tt.mode)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, money.New(tt.want, tt.to), got)
+		})
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	for _, v := range []string{"", "abc", "0", "-1.5"} {
+		_, err := ParseRate(v)
+		assert.ErrorIs(t, err, ErrInvalidRate, v)
+	}
+	rate, err := ParseRate(" 110.25 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "110.250000000000", FormatRate(rate))
+}
+
+// stubRates is the RateProvider returning the same rate, the mocks package can't be imported here as it imports fx.
+type stubRates struct {
+	rate  *Rate
+	err   error
+	calls int
+}
+
+func (s *stubRates) Rate(_ context.Context, _, _ string) (*Rate, error) {
+	s.calls++
+	return s.rate, s.err
+}
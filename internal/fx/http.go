@@ -0,0 +1,83 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+// HTTPRates fetches the latest rate from a provider which serves
+// GET <url>?base=SGD&symbols=JPY with {"base": "SGD", "timestamp": 1760659200, "rates": {"JPY": 110.25}}.
+type HTTPRates struct {
+	client *http.Client
+	url    string
+}
+
+func NewHTTPRates(client *http.Client, url string) *HTTPRates {
+	return &HTTPRates{
+		client: client,
+		url:    url,
+	}
+}
+
+type latestResponse struct {
+	Base string `json:"base"`
+	// Timestamp is the unix seconds when the rates are published
+	Timestamp int64                  `json:"timestamp"`
+	Rates     map[string]json.Number `json:"rates"`
+}
+
+func (h *HTTPRates) Rate(ctx context.Context, base, quote string) (*Rate, error) {
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	u, err := url.Parse(h.url)
+	if err != nil {
+		return nil, fmt.Errorf("parse fx provider url: %w", err)
+	}
+	q := u.Query()
+	q.Set("base", base)
+	q.Set("symbols", quote)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build fx provider request: %w", err)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		log.Error(ctx, "failed to request fx provider: %v", err)
+		return nil, fmt.Errorf("request fx provider: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Error(ctx, "fx provider responded with status %d for %s/%s", resp.StatusCode, base, quote)
+		return nil, fmt.Errorf("fx provider responded with status %d", resp.StatusCode)
+	}
+	latest := latestResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&latest)
+	if err != nil {
+		log.Error(ctx, "failed to decode fx provider response: %v", err)
+		return nil, fmt.Errorf("decode fx provider response: %w", err)
+	}
+	if !strings.EqualFold(latest.Base, base) {
+		return nil, fmt.Errorf("fx provider responded with base %q instead of %q", latest.Base, base)
+	}
+	// the timestamp is mandatory, otherwise the staleness of the rate is unknown
+	if latest.Timestamp <= 0 {
+		return nil, fmt.Errorf("fx provider responded without timestamp")
+	}
+	value, ok := latest.Rates[quote]
+	if !ok {
+		return nil, ErrRateNotFound
+	}
+	rate, err := ParseRate(value.String())
+	if err != nil {
+		return nil, fmt.Errorf("fx provider rate of %s/%s: %w", base, quote, err)
+	}
+	return &Rate{Value: rate, AsOf: time.Unix(latest.Timestamp, 0).UTC()}, nil
+}
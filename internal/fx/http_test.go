@@ -0,0 +1,57 @@
+package fx
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHTTPRates(t *testing.T, status int, body string) *HTTPRates {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/latest", r.URL.Path)
+		assert.Equal(t, "SGD", r.URL.Query().Get("base"))
+		assert.Equal(t, "JPY", r.URL.Query().Get("symbols"))
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return NewHTTPRates(server.Client(), server.URL+"/latest")
+}
+
+func TestHTTPRates(t *testing.T) {
+	t.Run("ok rate", func(t *testing.T) {
+		rates := newTestHTTPRates(t, http.StatusOK, `{"base": "SGD", "timestamp": 1760659200, "rates": {"JPY": 110.25}}`)
+		rate, err := rates.Rate(t.Context(), "sgd", "jpy")
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewRat(11025, 100), rate.Value)
+		assert.Equal(t, time.Unix(1760659200, 0).UTC(), rate.AsOf)
+	})
+
+	t.Run("rate not found", func(t *testing.T) {
+		rates := newTestHTTPRates(t, http.StatusOK, `{"base": "SGD", "timestamp": 1760659200, "rates": {}}`)
+		_, err := rates.Rate(t.Context(), "SGD", "JPY")
+		assert.ErrorIs(t, err, ErrRateNotFound)
+	})
+
+	t.Run("missing timestamp", func(t *testing.T) {
+		rates := newTestHTTPRates(t, http.StatusOK, `{"base": "SGD", "rates": {"JPY": 110.25}}`)
+		_, err := rates.Rate(t.Context(), "SGD", "JPY")
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid rate", func(t *testing.T) {
+		rates := newTestHTTPRates(t, http.StatusOK, `{"base": "SGD", "timestamp": 1760659200, "rates": {"JPY": 0}}`)
+		_, err := rates.Rate(t.Context(), "SGD", "JPY")
+		assert.ErrorIs(t, err, ErrInvalidRate)
+	})
+
+	t.Run("unexpected status", func(t *testing.T) {
+		rates := newTestHTTPRates(t, http.StatusServiceUnavailable, ``)
+		_, err := rates.Rate(t.Context(), "SGD", "JPY")
+		assert.Error(t, err)
+	})
+}
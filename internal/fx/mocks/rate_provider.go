@@ -0,0 +1,59 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	fx "github.com/lengzuo/fundflow/internal/fx"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// RateProvider is an autogenerated mock type for the RateProvider type
+type RateProvider struct {
+	mock.Mock
+}
+
+// Rate provides a mock function with given fields: ctx, base, quote
+func (_m *RateProvider) Rate(ctx context.Context, base string, quote string) (*fx.Rate, error) {
+	ret := _m.Called(ctx, base, quote)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rate")
+	}
+
+	var r0 *fx.Rate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*fx.Rate, error)); ok {
+		return rf(ctx, base, quote)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *fx.Rate); ok {
+		r0 = rf(ctx, base, quote)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*fx.Rate)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, base, quote)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewRateProvider creates a new instance of RateProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewRateProvider(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *RateProvider {
+	mock := &RateProvider{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
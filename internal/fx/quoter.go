@@ -0,0 +1,72 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+)
+
+const maxSpreadBps = 10000
+
+// Quoter prices the conversion from the mid rate of source. Only the registered currencies are quoted,
+// the rate older than maxAge is refused and the spread of the pair is taken from the rate.
+type Quoter struct {
+	source RateProvider
+	// spreads is in basis points keyed by pair, it applies to both direction of the pair
+	spreads map[string]int64
+	// maxAge is disabled when it is zero
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// NewQuoter parses spreads in basis points such as {"SGD/JPY": "50"} for 0.5%.
+func NewQuoter(source RateProvider, spreads map[string]string, maxAge time.Duration) (*Quoter, error) {
+	q := &Quoter{
+		source:  source,
+		spreads: make(map[string]int64, len(spreads)),
+		maxAge:  maxAge,
+		now:     time.Now,
+	}
+	for pair, value := range spreads {
+		base, quote, err := parsePair(pair)
+		if err != nil {
+			return nil, err
+		}
+		bps, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || bps < 0 || bps >= maxSpreadBps {
+			return nil, fmt.Errorf("invalid spread of %s, it must be basis points in [0, %d)", pair, maxSpreadBps)
+		}
+		q.spreads[pairKey(base, quote)] = bps
+	}
+	return q, nil
+}
+
+func (q *Quoter) spread(base, quote string) int64 {
+	if bps, ok := q.spreads[pairKey(base, quote)]; ok {
+		return bps
+	}
+	return q.spreads[pairKey(quote, base)]
+}
+
+func (q *Quoter) Rate(ctx context.Context, base, quote string) (*Rate, error) {
+	for _, code := range []string{base, quote} {
+		if err := currency.Supported(code); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, code)
+		}
+	}
+	mid, err := q.source.Rate(ctx, base, quote)
+	if err != nil {
+		return nil, err
+	}
+	if q.maxAge > 0 && !mid.AsOf.IsZero() && q.now().Sub(mid.AsOf) > q.maxAge {
+		return nil, fmt.Errorf("%w: %s/%s as of %s", ErrStaleRate, base, quote, mid.AsOf.Format(time.RFC3339))
+	}
+	// the customer always receives less than the mid rate, e.g. 110 at 50 bps is 109.45
+	value := new(big.Rat).Mul(mid.Value, big.NewRat(maxSpreadBps-q.spread(base, quote), maxSpreadBps))
+	return &Rate{Value: value, AsOf: mid.AsOf}, nil
+}
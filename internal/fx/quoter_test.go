@@ -0,0 +1,63 @@
+package fx
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoter(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	newQuoter := func(t *testing.T, source RateProvider) *Quoter {
+		q, err := NewQuoter(source, map[string]string{"SGD/JPY": "50"}, 10*time.Minute)
+		assert.NoError(t, err)
+		q.now = func() time.Time { return now }
+		return q
+	}
+
+	t.Run("apply spread of the pair", func(t *testing.T) {
+		source := &stubRates{rate: &Rate{Value: big.NewRat(110, 1), AsOf: now.Add(-time.Minute)}}
+		rate, err := newQuoter(t, source).Rate(t.Context(), "SGD", "JPY")
+		assert.NoError(t, err)
+		assert.Equal(t, "109.450000000000", FormatRate(rate.Value))
+	})
+
+	t.Run("apply spread to inverse pair", func(t *testing.T) {
+		source := &stubRates{rate: &Rate{Value: big.NewRat(1, 100), AsOf: now}}
+		rate, err := newQuoter(t, source).Rate(t.Context(), "JPY", "SGD")
+		assert.NoError(t, err)
+		assert.Equal(t, "0.009950000000", FormatRate(rate.Value))
+	})
+
+	t.Run("refuse stale rate", func(t *testing.T) {
+		source := &stubRates{rate: &Rate{Value: big.NewRat(110, 1), AsOf: now.Add(-11 * time.Minute)}}
+		_, err := newQuoter(t, source).Rate(t.Context(), "SGD", "JPY")
+		assert.ErrorIs(t, err, ErrStaleRate)
+	})
+
+	t.Run("rate without as of never stale", func(t *testing.T) {
+		source := &stubRates{rate: &Rate{Value: big.NewRat(110, 1)}}
+		_, err := newQuoter(t, source).Rate(t.Context(), "SGD", "JPY")
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuse unregistered currency", func(t *testing.T) {
+		_, err := newQuoter(t, &stubRates{}).Rate(t.Context(), "SGD", "THB")
+		assert.ErrorIs(t, err, currency.ErrUnSupportedCurrency)
+	})
+
+	t.Run("invalid spreads", func(t *testing.T) {
+		for _, spreads := range []map[string]string{
+			{"SGD/JPY": "-1"},
+			{"SGD/JPY": "10000"},
+			{"SGD/JPY": "0.5"},
+			{"SGD/THB": "50"},
+		} {
+			_, err := NewQuoter(&stubRates{}, spreads, time.Minute)
+			assert.Error(t, err, spreads)
+		}
+	})
+}
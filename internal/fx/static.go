@@ -0,0 +1,65 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+)
+
+// StaticRates serves fixed rates keyed by "BASE/QUOTE", the inverse pair is derived when it isn't configured.
+type StaticRates struct {
+	rates map[string]*big.Rat
+	asOf  time.Time
+}
+
+// NewStaticRates parses rates such as {"SGD/JPY": "110.25"} published at asOf,
+// both currencies of the pair must be registered in the currency package.
+func NewStaticRates(rates map[string]string, asOf time.Time) (*StaticRates, error) {
+	s := &StaticRates{rates: make(map[string]*big.Rat, len(rates)), asOf: asOf}
+	for pair, value := range rates {
+		base, quote, err := parsePair(pair)
+		if err != nil {
+			return nil, err
+		}
+		rate, err := ParseRate(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate of %s: %w", pair, err)
+		}
+		s.rates[pairKey(base, quote)] = rate
+	}
+	return s, nil
+}
+
+type ratesFile struct {
+	// AsOf is optional, the rates never go stale without it
+	AsOf  time.Time         `json:"as_of"`
+	Rates map[string]string `json:"rates"`
+}
+
+// LoadStaticRates reads the rates from a json file such as
+// {"as_of": "2026-10-17T00:00:00Z", "rates": {"SGD/JPY": "110.25"}}.
+func LoadStaticRates(path string) (*StaticRates, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fx rates file: %w", err)
+	}
+	file := ratesFile{}
+	err = json.Unmarshal(b, &file)
+	if err != nil {
+		return nil, fmt.Errorf("parse fx rates file: %w", err)
+	}
+	return NewStaticRates(file.Rates, file.AsOf)
+}
+
+func (s *StaticRates) Rate(_ context.Context, base, quote string) (*Rate, error) {
+	if rate, ok := s.rates[pairKey(base, quote)]; ok {
+		return &Rate{Value: rate, AsOf: s.asOf}, nil
+	}
+	if rate, ok := s.rates[pairKey(quote, base)]; ok {
+		return &Rate{Value: new(big.Rat).Inv(rate), AsOf: s.asOf}, nil
+	}
+	return nil, ErrRateNotFound
+}
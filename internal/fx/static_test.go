@@ -0,0 +1,74 @@
+package fx
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticRates(t *testing.T) {
+	asOf := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	rates, err := NewStaticRates(map[string]string{"SGD/JPY": "110"}, asOf)
+	assert.NoError(t, err)
+
+	t.Run("configured pair", func(t *testing.T) {
+		rate, err := rates.Rate(t.Context(), "sgd", "JPY")
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewRat(110, 1), rate.Value)
+		assert.Equal(t, asOf, rate.AsOf)
+	})
+
+	t.Run("inverse pair", func(t *testing.T) {
+		rate, err := rates.Rate(t.Context(), "JPY", "SGD")
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewRat(1, 110), rate.Value)
+	})
+
+	t.Run("unknown pair", func(t *testing.T) {
+		_, err := rates.Rate(t.Context(), "SGD", "THB")
+		assert.ErrorIs(t, err, ErrRateNotFound)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := NewStaticRates(map[string]string{"SGDJPY": "110"}, asOf)
+		assert.Error(t, err)
+		_, err = NewStaticRates(map[string]string{"SGD/JPY": "x"}, asOf)
+		assert.ErrorIs(t, err, ErrInvalidRate)
+	})
+
+	t.Run("unregistered currency", func(t *testing.T) {
+		_, err := NewStaticRates(map[string]string{"SGD/THB": "26"}, asOf)
+		assert.ErrorIs(t, err, currency.ErrUnSupportedCurrency)
+	})
+}
+
+func TestLoadStaticRates(t *testing.T) {
+	t.Run("ok file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rates.json")
+		err := os.WriteFile(path, []byte(`{"as_of": "2026-10-17T00:00:00Z", "rates": {"SGD/JPY": "110.25"}}`), 0o600)
+		assert.NoError(t, err)
+		rates, err := LoadStaticRates(path)
+		assert.NoError(t, err)
+		rate, err := rates.Rate(t.Context(), "SGD", "JPY")
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewRat(11025, 100), rate.Value)
+		assert.Equal(t, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), rate.AsOf)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadStaticRates(filepath.Join(t.TempDir(), "missing.json"))
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rates.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{`), 0o600))
+		_, err := LoadStaticRates(path)
+		assert.Error(t, err)
+	})
+}
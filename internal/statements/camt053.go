@@ -0,0 +1,169 @@
+package statements
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+	camtDateLayout   = "2006-01-02"
+	camtCredit       = "CRDT"
+	camtDebit        = "DBIT"
+	// camtBooked is the status of an entry booked on the account
+	camtBooked = "BOOK"
+	// camtOpeningBooked and camtClosingBooked are the balance types of the period
+	camtOpeningBooked = "OPBD"
+	camtClosingBooked = "CLBD"
+)
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtGroupHeader struct {
+	MsgID    string `xml:"MsgId"`
+	CreDtTm  string `xml:"CreDtTm"`
+	MsgPgntn struct {
+		PgNb      int  `xml:"PgNb"`
+		LastPgInd bool `xml:"LastPgInd"`
+	} `xml:"MsgPgntn"`
+}
+
+type camtAccount struct {
+	ID       string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+	Owner    string `xml:"Ownr>Nm"`
+}
+
+type camtBalance struct {
+	Type      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Date      string     `xml:"Dt>Dt"`
+}
+
+type camtSum struct {
+	Count int    `xml:"NbOfNtries"`
+	Sum   string `xml:"Sum"`
+}
+
+type camtSummary struct {
+	Total  camtSum `xml:"TtlNtries"`
+	Credit camtSum `xml:"TtlCdtNtries"`
+	Debit  camtSum `xml:"TtlDbtNtries"`
+}
+
+type camtEntry struct {
+	NtryRef     string     `xml:"NtryRef"`
+	Amount      camtAmount `xml:"Amt"`
+	CdtDbtInd   string     `xml:"CdtDbtInd"`
+	Status      string     `xml:"Sts"`
+	BookingDate string     `xml:"BookgDt>DtTm"`
+	ValueDate   string     `xml:"ValDt>DtTm"`
+	AcctSvcrRef string     `xml:"AcctSvcrRef"`
+	TxType      string     `xml:"BkTxCd>Prtry>Cd"`
+	EndToEndID  string     `xml:"NtryDtls>TxDtls>Refs>EndToEndId"`
+	Remittance  string     `xml:"NtryDtls>TxDtls>RmtInf>Ustrd,omitempty"`
+}
+
+// camt053Writer writes the ISO 20022 camt.053.001.02 statement in a single page. The opening and closing booked
+// balances and the totals are part of the header, the schema requires them before the entries.
+type camt053Writer struct {
+	enc       *xml.Encoder
+	statement Statement
+}
+
+func newCAMT053Writer(w io.Writer, statement Statement) (*camt053Writer, error) {
+	writer := &camt053Writer{enc: xml.NewEncoder(w), statement: statement}
+	writer.enc.Indent("", "  ")
+	document := start("Document")
+	document.Attr = []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: camt053Namespace}}
+	tokens := []xml.Token{
+		xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)},
+		document,
+		start("BkToCstmrStmt"),
+	}
+	if err := encodeTokens(writer.enc, tokens...); err != nil {
+		return nil, err
+	}
+	createdAt := statement.GeneratedAt.UTC().Format(time.RFC3339)
+	header := camtGroupHeader{MsgID: statement.ID, CreDtTm: createdAt}
+	header.MsgPgntn.PgNb = 1
+	header.MsgPgntn.LastPgInd = true
+	if err := writer.enc.EncodeElement(header, start("GrpHdr")); err != nil {
+		return nil, err
+	}
+	if err := encodeTokens(writer.enc, start("Stmt")); err != nil {
+		return nil, err
+	}
+	elements := []struct {
+		value any
+		name  string
+	}{
+		{value: statement.ID, name: "Id"},
+		{value: createdAt, name: "CreDtTm"},
+		{value: struct {
+			From string `xml:"FrDtTm"`
+			To   string `xml:"ToDtTm"`
+		}{
+			From: statement.From.UTC().Format(time.RFC3339),
+			// The period ends right before To
+			To: statement.To.Add(-time.Second).UTC().Format(time.RFC3339),
+		}, name: "FrToDt"},
+		{value: camtAccount{ID: accountID(statement), Currency: statement.Currency, Owner: statement.Username}, name: "Acct"},
+		{value: writer.balance(camtOpeningBooked, statement.OpeningBalance, statement.From), name: "Bal"},
+		{value: writer.balance(camtClosingBooked, statement.ClosingBalance, statement.To.Add(-time.Second)), name: "Bal"},
+		{value: camtSummary{
+			Total:  camtSum{Count: statement.CreditCount + statement.DebitCount, Sum: decimal(statement.TotalCredit+statement.TotalDebit, statement.Currency)},
+			Credit: camtSum{Count: statement.CreditCount, Sum: decimal(statement.TotalCredit, statement.Currency)},
+			Debit:  camtSum{Count: statement.DebitCount, Sum: decimal(statement.TotalDebit, statement.Currency)},
+		}, name: "TxsSummry"},
+	}
+	for _, element := range elements {
+		if err := writer.enc.EncodeElement(element.value, start(element.name)); err != nil {
+			return nil, err
+		}
+	}
+	return writer, nil
+}
+
+func (c *camt053Writer) balance(balanceType string, amount int64, at time.Time) camtBalance {
+	indicator := camtCredit
+	if amount < 0 {
+		indicator = camtDebit
+	}
+	return camtBalance{
+		Type:      balanceType,
+		Amount:    camtAmount{Currency: c.statement.Currency, Value: absDecimal(amount, c.statement.Currency)},
+		CdtDbtInd: indicator,
+		Date:      at.UTC().Format(camtDateLayout),
+	}
+}
+
+func (c *camt053Writer) WriteEntry(entry Entry) error {
+	indicator := camtDebit
+	if entry.Credit {
+		indicator = camtCredit
+	}
+	bookedAt := entry.BookedAt.UTC().Format(time.RFC3339)
+	return c.enc.EncodeElement(camtEntry{
+		NtryRef:     strconv.Itoa(entry.LedgerID),
+		Amount:      camtAmount{Currency: c.statement.Currency, Value: decimal(entry.Amount, c.statement.Currency)},
+		CdtDbtInd:   indicator,
+		Status:      camtBooked,
+		BookingDate: bookedAt,
+		ValueDate:   bookedAt,
+		AcctSvcrRef: entry.TxUID,
+		TxType:      entry.TxType,
+		EndToEndID:  entry.TxUID,
+		Remittance:  entry.Reference,
+	}, start("Ntry"))
+}
+
+func (c *camt053Writer) Close() error {
+	return encodeTokens(c.enc, end("Stmt"), end("BkToCstmrStmt"), end("Document"))
+}
@@ -0,0 +1,81 @@
+package statements
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	csvOpeningBalance = "opening_balance"
+	csvClosingBalance = "closing_balance"
+)
+
+var csvHeader = []string{"booked_at", "ledger_id", "tx_uid", "type", "reference", "debit", "credit", "balance", "currency"}
+
+// csvWriter writes a row per entry between the opening balance and closing balance rows,
+// the amounts are decimals in the precision of the currency.
+type csvWriter struct {
+	w         *csv.Writer
+	statement Statement
+}
+
+func newCSVWriter(w io.Writer, statement Statement) (*csvWriter, error) {
+	writer := &csvWriter{w: csv.NewWriter(w), statement: statement}
+	err := writer.w.Write(csvHeader)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.writeBalance(statement.From, csvOpeningBalance, statement.OpeningBalance)
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (c *csvWriter) WriteEntry(entry Entry) error {
+	debit, credit := decimal(entry.Amount, c.statement.Currency), ""
+	if entry.Credit {
+		debit, credit = "", debit
+	}
+	err := c.w.Write([]string{
+		entry.BookedAt.UTC().Format(time.RFC3339),
+		strconv.Itoa(entry.LedgerID),
+		entry.TxUID,
+		entry.TxType,
+		csvSafe(entry.Reference),
+		debit,
+		credit,
+		decimal(entry.Balance, c.statement.Currency),
+		c.statement.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	// Flush every row so the rows are streamed rather than buffered until Close
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	return c.writeBalance(c.statement.To, csvClosingBalance, c.statement.ClosingBalance)
+}
+
+func (c *csvWriter) writeBalance(at time.Time, balanceType string, balance int64) error {
+	err := c.w.Write([]string{at.UTC().Format(time.RFC3339), "", "", balanceType, "", "", "", decimal(balance, c.statement.Currency), c.statement.Currency})
+	if err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// csvSafe prevents the reference chosen by the user from being evaluated as a formula by the spreadsheet.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,150 @@
+package statements
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// ofxTimeLayout is the OFX datetime with milliseconds and the UTC offset
+const ofxTimeLayout = "20060102150405.000[0:UTC]"
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxSignOn struct {
+	XMLName  xml.Name  `xml:"SIGNONMSGSRSV1"`
+	Status   ofxStatus `xml:"SONRS>STATUS"`
+	DTServer string    `xml:"SONRS>DTSERVER"`
+	Language string    `xml:"SONRS>LANGUAGE"`
+}
+
+type ofxAccount struct {
+	XMLName  xml.Name `xml:"BANKACCTFROM"`
+	BankID   string   `xml:"BANKID"`
+	AcctID   string   `xml:"ACCTID"`
+	AcctType string   `xml:"ACCTTYPE"`
+}
+
+type ofxTransaction struct {
+	XMLName  xml.Name `xml:"STMTTRN"`
+	TrnType  string   `xml:"TRNTYPE"`
+	DTPosted string   `xml:"DTPOSTED"`
+	TrnAmt   string   `xml:"TRNAMT"`
+	FITID    string   `xml:"FITID"`
+	Name     string   `xml:"NAME"`
+	Memo     string   `xml:"MEMO,omitempty"`
+}
+
+type ofxBalance struct {
+	XMLName xml.Name `xml:"LEDGERBAL"`
+	BalAmt  string   `xml:"BALAMT"`
+	DTAsOf  string   `xml:"DTASOF"`
+}
+
+// ofxWriter writes OFX 2.2 bank statement response. OFX has no opening balance, the closing balance is
+// the ledger balance as of the end of the period.
+type ofxWriter struct {
+	enc       *xml.Encoder
+	statement Statement
+}
+
+func newOFXWriter(w io.Writer, statement Statement) (*ofxWriter, error) {
+	writer := &ofxWriter{enc: xml.NewEncoder(w), statement: statement}
+	writer.enc.Indent("", "  ")
+	tokens := []xml.Token{
+		xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8" standalone="no"`)},
+		xml.ProcInst{Target: "OFX", Inst: []byte(`OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"`)},
+		start("OFX"),
+	}
+	if err := encodeTokens(writer.enc, tokens...); err != nil {
+		return nil, err
+	}
+	err := writer.enc.EncodeElement(ofxSignOn{
+		Status:   ofxStatus{Code: 0, Severity: "INFO"},
+		DTServer: statement.GeneratedAt.UTC().Format(ofxTimeLayout),
+		Language: "ENG",
+	}, start("SIGNONMSGSRSV1"))
+	if err != nil {
+		return nil, err
+	}
+	if err = encodeTokens(writer.enc, start("BANKMSGSRSV1"), start("STMTTRNRS")); err != nil {
+		return nil, err
+	}
+	if err = writer.enc.EncodeElement(statement.ID, start("TRNUID")); err != nil {
+		return nil, err
+	}
+	if err = writer.enc.EncodeElement(ofxStatus{Code: 0, Severity: "INFO"}, start("STATUS")); err != nil {
+		return nil, err
+	}
+	if err = encodeTokens(writer.enc, start("STMTRS")); err != nil {
+		return nil, err
+	}
+	if err = writer.enc.EncodeElement(statement.Currency, start("CURDEF")); err != nil {
+		return nil, err
+	}
+	err = writer.enc.EncodeElement(ofxAccount{BankID: "FUNDFLOW", AcctID: accountID(statement), AcctType: "CHECKING"}, start("BANKACCTFROM"))
+	if err != nil {
+		return nil, err
+	}
+	if err = encodeTokens(writer.enc, start("BANKTRANLIST")); err != nil {
+		return nil, err
+	}
+	if err = writer.enc.EncodeElement(statement.From.UTC().Format(ofxTimeLayout), start("DTSTART")); err != nil {
+		return nil, err
+	}
+	// DTEND is exclusive in OFX as well, it is the DTSTART of the next statement
+	if err = writer.enc.EncodeElement(statement.To.UTC().Format(ofxTimeLayout), start("DTEND")); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (o *ofxWriter) WriteEntry(entry Entry) error {
+	trnType, amount := "DEBIT", -entry.Amount
+	if entry.Credit {
+		trnType, amount = "CREDIT", entry.Amount
+	}
+	return o.enc.EncodeElement(ofxTransaction{
+		TrnType:  trnType,
+		DTPosted: entry.BookedAt.UTC().Format(ofxTimeLayout),
+		TrnAmt:   decimal(amount, o.statement.Currency),
+		FITID:    strconv.Itoa(entry.LedgerID),
+		Name:     entry.TxType,
+		Memo:     entry.Reference,
+	}, start("STMTTRN"))
+}
+
+func (o *ofxWriter) Close() error {
+	if err := encodeTokens(o.enc, end("BANKTRANLIST")); err != nil {
+		return err
+	}
+	err := o.enc.EncodeElement(ofxBalance{
+		BalAmt: decimal(o.statement.ClosingBalance, o.statement.Currency),
+		DTAsOf: o.statement.To.UTC().Format(ofxTimeLayout),
+	}, start("LEDGERBAL"))
+	if err != nil {
+		return err
+	}
+	return encodeTokens(o.enc, end("STMTRS"), end("STMTTRNRS"), end("BANKMSGSRSV1"), end("OFX"))
+}
+
+func start(name string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: name}}
+}
+
+func end(name string) xml.EndElement {
+	return xml.EndElement{Name: xml.Name{Local: name}}
+}
+
+// encodeTokens writes the tokens and flushes them, unlike EncodeElement the tokens are buffered by the encoder.
+func encodeTokens(enc *xml.Encoder, tokens ...xml.Token) error {
+	for _, token := range tokens {
+		if err := enc.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+	return enc.Flush()
+}
@@ -0,0 +1,115 @@
+package statements
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+type Format string
+
+const (
+	FormatCSV Format = "csv"
+	FormatOFX Format = "ofx"
+	// FormatCAMT053 is the ISO 20022 bank to customer statement, camt.053.001.02
+	FormatCAMT053 Format = "camt053"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported statement format")
+
+// Statement is the header of a wallet statement, it is written before any entry.
+type Statement struct {
+	ID       string
+	Username string
+	Currency string
+	// From is the start of the period and To is the exclusive end of it
+	From           time.Time
+	To             time.Time
+	GeneratedAt    time.Time
+	OpeningBalance int64
+	ClosingBalance int64
+	TotalCredit    int64
+	TotalDebit     int64
+	CreditCount    int
+	DebitCount     int
+}
+
+// Entry is a ledger of the wallet within the period of the statement.
+type Entry struct {
+	LedgerID  int
+	TxUID     string
+	TxType    string
+	Reference string
+	Credit    bool
+	Amount    int64
+	// Balance is the balance of the wallet after the entry
+	Balance  int64
+	BookedAt time.Time
+}
+
+// Writer streams a statement, the header is written when the writer is created, then every entry in booking
+// order and the trailer on Close. Nothing is kept other than the header, so the statement may be of any size.
+type Writer interface {
+	WriteEntry(entry Entry) error
+	Close() error
+}
+
+// NewWriter writes the header of the statement to w in the format.
+func NewWriter(format Format, w io.Writer, statement Statement) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, statement)
+	case FormatOFX:
+		return newOFXWriter(w, statement)
+	case FormatCAMT053:
+		return newCAMT053Writer(w, statement)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// ContentType is the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatCAMT053:
+		return "application/xml"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Extension is the file extension of the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatOFX:
+		return "ofx"
+	case FormatCAMT053:
+		return "xml"
+	}
+	return "csv"
+}
+
+// Valid reports whether the format is supported.
+func (f Format) Valid() bool {
+	return f == FormatCSV || f == FormatOFX || f == FormatCAMT053
+}
+
+// accountID identifies the wallet in the formats which require an account, a wallet is a username and currency.
+func accountID(statement Statement) string {
+	return statement.Username + "-" + statement.Currency
+}
+
+func decimal(amount int64, currency string) string {
+	return money.FormatDecimal(amount, currency)
+}
+
+// absDecimal is the unsigned amount for the formats which carry the sign as a credit or debit indicator.
+func absDecimal(amount int64, currency string) string {
+	if amount < 0 {
+		return money.FormatDecimal(-amount, currency)
+	}
+	return money.FormatDecimal(amount, currency)
+}
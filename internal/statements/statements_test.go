@@ -0,0 +1,108 @@
+package statements
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testFrom      = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	testStatement = Statement{
+		ID:             "stmt1",
+		Username:       "user1",
+		Currency:       "SGD",
+		From:           testFrom,
+		To:             testFrom.AddDate(0, 1, 0),
+		GeneratedAt:    testFrom.AddDate(0, 1, 1),
+		OpeningBalance: 1000,
+		ClosingBalance: 1250,
+		TotalCredit:    500,
+		TotalDebit:     250,
+		CreditCount:    1,
+		DebitCount:     1,
+	}
+	testEntries = []Entry{
+		{LedgerID: 1, TxUID: "tx1", TxType: "deposit", Reference: "salary", Credit: true, Amount: 500, Balance: 1500, BookedAt: testFrom.Add(time.Hour)},
+		{LedgerID: 2, TxUID: "tx2", TxType: "transfer", Reference: "=cmd()", Amount: 250, Balance: 1250, BookedAt: testFrom.Add(2 * time.Hour)},
+	}
+)
+
+func writeStatement(t *testing.T, format Format) string {
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf, testStatement)
+	require.NoError(t, err)
+	for _, entry := range testEntries {
+		require.NoError(t, writer.WriteEntry(entry))
+	}
+	require.NoError(t, writer.Close())
+	return buf.String()
+}
+
+// wellFormed decodes every token of the document and fails on the first syntax error.
+func wellFormed(t *testing.T, document string) {
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	for {
+		_, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		require.NoError(t, err)
+	}
+}
+
+func TestNewWriter_CSV(t *testing.T) {
+	rows, err := csv.NewReader(strings.NewReader(writeStatement(t, FormatCSV))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		csvHeader,
+		{"2026-01-01T00:00:00Z", "", "", "opening_balance", "", "", "", "10.00", "SGD"},
+		{"2026-01-01T01:00:00Z", "1", "tx1", "deposit", "salary", "", "5.00", "15.00", "SGD"},
+		{"2026-01-01T02:00:00Z", "2", "tx2", "transfer", "'=cmd()", "2.50", "", "12.50", "SGD"},
+		{"2026-02-01T00:00:00Z", "", "", "closing_balance", "", "", "", "12.50", "SGD"},
+	}, rows)
+}
+
+func TestNewWriter_OFX(t *testing.T) {
+	document := writeStatement(t, FormatOFX)
+	wellFormed(t, document)
+	assert.True(t, strings.HasPrefix(document, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`))
+	assert.Contains(t, document, `<?OFX OFXHEADER="200" VERSION="220"`)
+	assert.Contains(t, document, "<ACCTID>user1-SGD</ACCTID>")
+	assert.Contains(t, document, "<DTSTART>20260101000000.000[0:UTC]</DTSTART>")
+	assert.Contains(t, document, "<DTEND>20260201000000.000[0:UTC]</DTEND>")
+	assert.Contains(t, document, "<TRNTYPE>CREDIT</TRNTYPE>")
+	assert.Contains(t, document, "<TRNAMT>5.00</TRNAMT>")
+	assert.Contains(t, document, "<TRNAMT>-2.50</TRNAMT>")
+	assert.Contains(t, document, "<BALAMT>12.50</BALAMT>")
+	assert.Equal(t, 2, strings.Count(document, "<STMTTRN>"))
+}
+
+func TestNewWriter_CAMT053(t *testing.T) {
+	document := writeStatement(t, FormatCAMT053)
+	wellFormed(t, document)
+	assert.Contains(t, document, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">`)
+	assert.Contains(t, document, "<ToDtTm>2026-01-31T23:59:59Z</ToDtTm>")
+	assert.Contains(t, document, "<Cd>OPBD</Cd>")
+	assert.Contains(t, document, `<Amt Ccy="SGD">10.00</Amt>`)
+	assert.Contains(t, document, "<Cd>CLBD</Cd>")
+	assert.Contains(t, document, `<Amt Ccy="SGD">12.50</Amt>`)
+	assert.Contains(t, document, "<EndToEndId>tx2</EndToEndId>")
+	assert.Equal(t, 2, strings.Count(document, "<Ntry>"))
+	// The balances and summary are required before the entries
+	assert.Less(t, strings.Index(document, "<TxsSummry>"), strings.Index(document, "<Ntry>"))
+}
+
+func TestNewWriter_UnsupportedFormat(t *testing.T) {
+	writer, err := NewWriter("pdf", io.Discard, testStatement)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	assert.Nil(t, writer)
+}
@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned for the url of the internal network, so a webhook can't be used to reach the
+// services behind the deployment such as the cloud metadata endpoint.
+var ErrForbiddenAddress = errors.New("webhook url must resolve to a public address")
+
+// forbiddenPrefixes are refused on top of the loopback, link local, private, multicast and unspecified addresses.
+var forbiddenPrefixes = []netip.Prefix{
+	// "this network" of RFC 1122
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// shared address space of carrier grade NAT, RFC 6598
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// publicAddr tells whether a webhook is allowed to be posted to the address.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsPrivate() || addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkURL refuses the url whose host is, or resolves to, an address which isn't allowed.
+func checkURL(ctx context.Context, resolver *net.Resolver, allowed func(netip.Addr) bool, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parse webhook url: %w", err)
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !allowed(addr) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve webhook host: %w", err)
+	}
+	for _, addr := range addrs {
+		if !allowed(addr) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// dialControl checks the address actually dialed, the host could resolve to another address since it was
+// checked at registration, e.g. DNS rebinding, and a redirect is dialed through it as well.
+func dialControl(allowed func(netip.Addr) bool) func(network, address string, _ syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return ErrForbiddenAddress
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !allowed(addr) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+}
@@ -0,0 +1,75 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	webhooks "github.com/lengzuo/fundflow/internal/webhooks"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Sender is an autogenerated mock type for the Sender type
+type Sender struct {
+	mock.Mock
+}
+
+// CheckURL provides a mock function with given fields: ctx, url
+func (_m *Sender) CheckURL(ctx context.Context, url string) error {
+	ret := _m.Called(ctx, url)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CheckURL")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, url)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Send provides a mock function with given fields: ctx, url, secret, msg
+func (_m *Sender) Send(ctx context.Context, url string, secret string, msg webhooks.Message) (int, error) {
+	ret := _m.Called(ctx, url, secret, msg)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Send")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, webhooks.Message) (int, error)); ok {
+		return rf(ctx, url, secret, msg)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, webhooks.Message) int); ok {
+		r0 = rf(ctx, url, secret, msg)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, webhooks.Message) error); ok {
+		r1 = rf(ctx, url, secret, msg)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewSender creates a new instance of Sender. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSender(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Sender {
+	mock := &Sender{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,85 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+// maxResponseBody is drained from the response of the endpoint so the connection can be reused, it is never kept
+// as the response of an internal service could leak through the delivery log.
+const maxResponseBody = 4 << 10
+
+// Message is a single delivery attempt of an event.
+type Message struct {
+	// ID is the uid of the delivery, it is the same across the attempts so the receiver is able to deduplicate
+	ID    string
+	Event string
+	Body  []byte
+}
+
+//go:generate mockery --name Sender --output ./mocks --outpkg mocks --case=underscore
+type Sender interface {
+	// CheckURL refuses the url which the messages are not allowed to be posted to with ErrForbiddenAddress
+	CheckURL(ctx context.Context, url string) error
+	// Send posts the message to url and returns the status code of the response, a non 2xx response is an error
+	Send(ctx context.Context, url, secret string, msg Message) (int, error)
+}
+
+type httpSender struct {
+	client   *http.Client
+	resolver *net.Resolver
+	allowed  func(netip.Addr) bool
+}
+
+// NewHTTPSender returns the sender which only posts to the public addresses.
+func NewHTTPSender(timeout time.Duration) Sender {
+	return newHTTPSender(timeout, publicAddr)
+}
+
+func newHTTPSender(timeout time.Duration, allowed func(netip.Addr) bool) *httpSender {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialControl(allowed)}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialed instead of the endpoint and bypass the check of its address
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &httpSender{
+		client:   &http.Client{Timeout: timeout, Transport: transport},
+		resolver: net.DefaultResolver,
+		allowed:  allowed,
+	}
+}
+
+func (s *httpSender) CheckURL(ctx context.Context, url string) error {
+	return checkURL(ctx, s.resolver, s.allowed, url)
+}
+
+func (s *httpSender) Send(ctx context.Context, url, secret string, msg Message) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(msg.Body))
+	if err != nil {
+		return 0, fmt.Errorf("build webhook request: %w", err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, msg.ID)
+	req.Header.Set(HeaderEvent, msg.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, msg.Body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
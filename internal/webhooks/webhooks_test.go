@@ -0,0 +1,137 @@
+package webhooks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"amount":100}`)
+	signature := Sign("secret", now.Unix(), body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		wantErr   bool
+	}{
+		{name: "ok", secret: "secret", timestamp: "1700000000", signature: signature, body: body},
+		{name: "wrong secret", secret: "other", timestamp: "1700000000", signature: signature, body: body, wantErr: true},
+		{name: "tampered body", secret: "secret", timestamp: "1700000000", signature: signature, body: []byte(`{"amount":999}`), wantErr: true},
+		{name: "replayed with another timestamp", secret: "secret", timestamp: "1700000001", signature: signature, body: body, wantErr: true},
+		{name: "stale timestamp", secret: "secret", timestamp: "1699999000", signature: Sign("secret", 1699999000, body), body: body, wantErr: true},
+		{name: "invalid timestamp", secret: "secret", timestamp: "now", signature: signature, body: body, wantErr: true},
+		{name: "missing prefix", secret: "secret", timestamp: "1700000000", signature: signature[len(signaturePrefix):], body: body, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.signature, tt.body, 5*time.Minute, now)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSignature)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// allowAll lets the sender post to the loopback receiver of httptest.
+func allowAll(netip.Addr) bool { return true }
+
+func Test_httpSender_Send(t *testing.T) {
+	msg := Message{ID: "dlv1", Event: "wallet.credited", Body: []byte(`{"amount":100}`)}
+
+	t.Run("ok signed delivery", func(t *testing.T) {
+		var received *http.Request
+		var receivedBody []byte
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			receivedBody, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer receiver.Close()
+
+		status, err := newHTTPSender(time.Second, allowAll).Send(t.Context(), receiver.URL, "secret", msg)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, status)
+		assert.Equal(t, http.MethodPost, received.Method)
+		assert.Equal(t, "dlv1", received.Header.Get(HeaderID))
+		assert.Equal(t, "wallet.credited", received.Header.Get(HeaderEvent))
+		assert.Equal(t, msg.Body, receivedBody)
+		assert.NoError(t, Verify("secret", received.Header.Get(HeaderTimestamp), received.Header.Get(HeaderSignature), receivedBody, time.Minute, time.Now()))
+	})
+
+	t.Run("non 2xx response", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		}))
+		defer receiver.Close()
+
+		status, err := newHTTPSender(time.Second, allowAll).Send(t.Context(), receiver.URL, "secret", msg)
+		assert.EqualError(t, err, "webhook responded 503")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+	})
+
+	t.Run("receiver timeout", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+		}))
+		defer receiver.Close()
+
+		status, err := newHTTPSender(10*time.Millisecond, allowAll).Send(t.Context(), receiver.URL, "secret", msg)
+		assert.Error(t, err)
+		assert.Zero(t, status)
+	})
+
+	t.Run("internal address is refused at dial", func(t *testing.T) {
+		var called atomic.Bool
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called.Store(true)
+		}))
+		defer receiver.Close()
+
+		status, err := NewHTTPSender(time.Second).Send(t.Context(), receiver.URL, "secret", msg)
+		assert.ErrorIs(t, err, ErrForbiddenAddress)
+		assert.Zero(t, status)
+		assert.False(t, called.Load())
+	})
+}
+
+func Test_httpSender_CheckURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{name: "public address", url: "https://93.184.215.14/hook"},
+		{name: "loopback", url: "http://127.0.0.1:8080/hook", wantErr: ErrForbiddenAddress},
+		{name: "loopback v6", url: "http://[::1]/hook", wantErr: ErrForbiddenAddress},
+		{name: "mapped loopback", url: "http://[::ffff:127.0.0.1]/hook", wantErr: ErrForbiddenAddress},
+		{name: "metadata endpoint", url: "http://169.254.169.254/latest/meta-data", wantErr: ErrForbiddenAddress},
+		{name: "private", url: "https://10.0.0.5/hook", wantErr: ErrForbiddenAddress},
+		{name: "private v6", url: "https://[fd00::1]/hook", wantErr: ErrForbiddenAddress},
+		{name: "unspecified", url: "http://0.0.0.0:8080/hook", wantErr: ErrForbiddenAddress},
+		{name: "carrier grade nat", url: "http://100.64.0.1/hook", wantErr: ErrForbiddenAddress},
+		{name: "localhost", url: "http://localhost:8080/hook", wantErr: ErrForbiddenAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewHTTPSender(time.Second).CheckURL(t.Context(), tt.url)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
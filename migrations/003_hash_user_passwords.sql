@@ -0,0 +1,10 @@
+-- Passwords are hashed with bcrypt by the application, rehash the plaintext seed rows with
+-- pgcrypto so the existing users are able to login with the same password.
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+UPDATE users
+SET password = crypt(password, gen_salt('bf', 10)),
+    updated_at = (now() AT TIME ZONE 'utc')
+WHERE password NOT LIKE '$2a$%' AND password NOT LIKE '$2b$%';
+
+COMMENT ON COLUMN users.password IS 'bcrypt hash of the user''s password';
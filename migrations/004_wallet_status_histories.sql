@@ -0,0 +1,25 @@
+ALTER TABLE wallets ADD CONSTRAINT chk_wallet_status CHECK (status IN ('active', 'frozen', 'closed'));
+COMMENT ON COLUMN wallets.status IS 'The current status of the wallet, can be active, frozen or closed. Only active wallet is able to move fund';
+
+CREATE TABLE IF NOT EXISTS wallet_status_histories (
+    id SERIAL PRIMARY KEY,
+    wallet_id INTEGER NOT NULL REFERENCES wallets(id),
+    username VARCHAR(100) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    from_status VARCHAR(16) NOT NULL,
+    to_status VARCHAR(16) NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    changed_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+CREATE INDEX idx_wallet_status_histories_wallet ON wallet_status_histories(username, currency, id DESC);
+
+COMMENT ON COLUMN wallet_status_histories.id IS 'Unique status history ID (auto-incremented)';
+COMMENT ON COLUMN wallet_status_histories.wallet_id IS 'Wallet ID which the status is changed';
+COMMENT ON COLUMN wallet_status_histories.username IS 'Username of the user who owns the wallet';
+COMMENT ON COLUMN wallet_status_histories.currency IS 'Currency of the wallet';
+COMMENT ON COLUMN wallet_status_histories.from_status IS 'Wallet status before the change';
+COMMENT ON COLUMN wallet_status_histories.to_status IS 'Wallet status after the change';
+COMMENT ON COLUMN wallet_status_histories.reason IS 'Reason given by the operator for compliance';
+COMMENT ON COLUMN wallet_status_histories.changed_by IS 'Username of the admin who changed the status';
+COMMENT ON COLUMN wallet_status_histories.created_at IS 'Timestamp when the status was changed';
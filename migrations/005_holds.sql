@@ -0,0 +1,31 @@
+ALTER TABLE wallets ADD COLUMN held_amount INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD CONSTRAINT chk_wallet_held_amount CHECK (held_amount >= 0 AND held_amount <= amount);
+COMMENT ON COLUMN wallets.held_amount IS 'Amount reserved by authorized holds, available balance is amount - held_amount';
+
+CREATE TABLE IF NOT EXISTS holds (
+    id SERIAL PRIMARY KEY,
+    tx_uid CHAR(20) NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    receiver VARCHAR(100) NOT NULL DEFAULT '',
+    currency CHAR(3) NOT NULL,
+    amount INTEGER NOT NULL,
+    captured_amount INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL,
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+CREATE UNIQUE INDEX uk_holds_tx_uid ON holds(tx_uid);
+CREATE INDEX idx_holds_authorized_expires_at ON holds(expires_at) WHERE status = 'authorized';
+
+COMMENT ON COLUMN holds.id IS 'Unique hold ID (auto-incremented)';
+COMMENT ON COLUMN holds.tx_uid IS 'Transaction uid of the pending hold transaction, it is the hold ID exposed in API';
+COMMENT ON COLUMN holds.username IS 'Username of the wallet which the fund is held';
+COMMENT ON COLUMN holds.receiver IS 'Optional username of the wallet credited when the hold is captured';
+COMMENT ON COLUMN holds.currency IS 'Currency of the held amount';
+COMMENT ON COLUMN holds.amount IS 'The authorized amount (integer, smallest unit of currency)';
+COMMENT ON COLUMN holds.captured_amount IS 'The captured amount, can be lower than authorized amount for partial capture';
+COMMENT ON COLUMN holds.status IS 'The status of the hold (e.g., ''authorized'', ''captured'', ''voided'', ''expired'')';
+COMMENT ON COLUMN holds.expires_at IS 'The held amount is released automatically after this timestamp';
+COMMENT ON COLUMN holds.created_at IS 'Timestamp when the hold was authorized';
+COMMENT ON COLUMN holds.updated_at IS 'Timestamp when the hold was last updated';
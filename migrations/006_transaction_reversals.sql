@@ -0,0 +1,7 @@
+ALTER TABLE transactions ADD COLUMN original_uid VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN refunded_amount INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD CONSTRAINT chk_transactions_refunded_amount CHECK (refunded_amount >= 0 AND refunded_amount <= amount);
+CREATE INDEX idx_original_uid ON transactions(original_uid) WHERE original_uid <> '';
+
+COMMENT ON COLUMN transactions.original_uid IS 'The uid of the transaction reversed or refunded by this transaction, empty for other types';
+COMMENT ON COLUMN transactions.refunded_amount IS 'Sum of the amount reversed or refunded from this transaction, it can never exceed the amount';
@@ -0,0 +1,4 @@
+CREATE INDEX idx_transactions_metadata ON transactions USING GIN (metadata);
+ALTER TABLE transactions ADD CONSTRAINT chk_transactions_metadata_size CHECK (metadata IS NULL OR octet_length(metadata::text) <= 4096);
+
+COMMENT ON COLUMN transactions.metadata IS 'Optional key-value pair (string values, up to 20 keys and 4KB) to store additional information related to this transactions, such as order_id';
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id SERIAL PRIMARY KEY,
+    trigger VARCHAR(16) NOT NULL,
+    triggered_by VARCHAR(100) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL,
+    wallets_checked INTEGER NOT NULL DEFAULT 0,
+    discrepancies INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL,
+    finished_at TIMESTAMP WITHOUT TIME ZONE
+);
+CREATE INDEX idx_reconciliation_runs_started_at ON reconciliation_runs(started_at DESC);
+
+COMMENT ON COLUMN reconciliation_runs.id IS 'Unique reconciliation run ID (auto-incremented)';
+COMMENT ON COLUMN reconciliation_runs.trigger IS 'How the run was started (e.g., ''scheduled'', ''manual'')';
+COMMENT ON COLUMN reconciliation_runs.triggered_by IS 'The admin username who started a manual run, empty for scheduled run';
+COMMENT ON COLUMN reconciliation_runs.status IS 'The status of the run (e.g., ''running'', ''completed'', ''failed'')';
+COMMENT ON COLUMN reconciliation_runs.wallets_checked IS 'Number of wallets compared against the ledgers';
+COMMENT ON COLUMN reconciliation_runs.discrepancies IS 'Number of wallets which balance doesn''t match the sum of its ledgers';
+COMMENT ON COLUMN reconciliation_runs.error IS 'The error message when the run failed';
+COMMENT ON COLUMN reconciliation_runs.started_at IS 'Timestamp when the run was started';
+COMMENT ON COLUMN reconciliation_runs.finished_at IS 'Timestamp when the run was completed or failed';
+
+
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES reconciliation_runs(id),
+    wallet_id INTEGER NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    wallet_amount INTEGER NOT NULL,
+    ledger_amount INTEGER NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+CREATE INDEX idx_reconciliation_discrepancies_run_id ON reconciliation_discrepancies(run_id);
+
+COMMENT ON COLUMN reconciliation_discrepancies.id IS 'Unique discrepancy ID (auto-incremented)';
+COMMENT ON COLUMN reconciliation_discrepancies.run_id IS 'The reconciliation run which found this discrepancy';
+COMMENT ON COLUMN reconciliation_discrepancies.wallet_id IS 'The wallet which balance doesn''t match its ledgers';
+COMMENT ON COLUMN reconciliation_discrepancies.username IS 'Username of the wallet owner';
+COMMENT ON COLUMN reconciliation_discrepancies.currency IS 'Currency of the wallet';
+COMMENT ON COLUMN reconciliation_discrepancies.wallet_amount IS 'The wallets.amount at the time of the run';
+COMMENT ON COLUMN reconciliation_discrepancies.ledger_amount IS 'The sum of credit minus debit ledgers of the wallet at the time of the run';
+COMMENT ON COLUMN reconciliation_discrepancies.created_at IS 'Timestamp when the discrepancy was recorded';
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id SERIAL PRIMARY KEY,
+    uid CHAR(20) NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    rate NUMERIC(24,12) NOT NULL,
+    from_amount INTEGER NOT NULL,
+    to_amount INTEGER NOT NULL,
+    tx_uid VARCHAR(20) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+CREATE UNIQUE INDEX uk_fx_quotes_uid ON fx_quotes(uid);
+
+COMMENT ON COLUMN fx_quotes.id IS 'Unique quote ID (auto-incremented)';
+COMMENT ON COLUMN fx_quotes.uid IS 'Unique quote ID exposed in API';
+COMMENT ON COLUMN fx_quotes.username IS 'Username of the user who requested the quote, only this user can consume it';
+COMMENT ON COLUMN fx_quotes.from_currency IS 'Currency debited when the quote is consumed';
+COMMENT ON COLUMN fx_quotes.to_currency IS 'Currency credited when the quote is consumed';
+COMMENT ON COLUMN fx_quotes.rate IS 'Units of to_currency bought by one unit of from_currency, locked at quote time';
+COMMENT ON COLUMN fx_quotes.from_amount IS 'Amount debited (integer, smallest unit of from_currency)';
+COMMENT ON COLUMN fx_quotes.to_amount IS 'Amount credited (integer, smallest unit of to_currency), rounded down from from_amount * rate';
+COMMENT ON COLUMN fx_quotes.tx_uid IS 'The exchange transaction which consumed the quote, empty until it is used';
+COMMENT ON COLUMN fx_quotes.expires_at IS 'The quote can''t be consumed after this timestamp';
+COMMENT ON COLUMN fx_quotes.used_at IS 'Timestamp when the quote was consumed, a quote can only be used once';
+COMMENT ON COLUMN fx_quotes.created_at IS 'Timestamp when the quote was created';
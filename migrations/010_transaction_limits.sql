@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS transaction_limits (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL DEFAULT '',
+    currency CHAR(3) NOT NULL,
+    per_transaction INTEGER NOT NULL DEFAULT 0 CHECK (per_transaction >= 0),
+    daily_withdraw INTEGER NOT NULL DEFAULT 0 CHECK (daily_withdraw >= 0),
+    monthly_withdraw INTEGER NOT NULL DEFAULT 0 CHECK (monthly_withdraw >= 0),
+    daily_transfer INTEGER NOT NULL DEFAULT 0 CHECK (daily_transfer >= 0),
+    monthly_transfer INTEGER NOT NULL DEFAULT 0 CHECK (monthly_transfer >= 0),
+    max_balance INTEGER NOT NULL DEFAULT 0 CHECK (max_balance >= 0),
+    updated_by VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+CREATE UNIQUE INDEX uk_transaction_limits ON transaction_limits(username, currency);
+-- Serve the daily and monthly usage of the cumulative limits
+CREATE INDEX idx_initiated_by_currency ON transactions(initiated_by, currency, created_at);
+
+COMMENT ON COLUMN transaction_limits.id IS 'Unique limit ID (auto-incremented)';
+COMMENT ON COLUMN transaction_limits.username IS 'The user the limits apply to, empty is the default of every user without own limits';
+COMMENT ON COLUMN transaction_limits.currency IS 'The currency of the wallet the limits apply to';
+COMMENT ON COLUMN transaction_limits.per_transaction IS 'Maximum amount of a single deposit, withdraw or transfer, 0 is unlimited';
+COMMENT ON COLUMN transaction_limits.daily_withdraw IS 'Maximum completed withdraw amount within a UTC day, 0 is unlimited';
+COMMENT ON COLUMN transaction_limits.monthly_withdraw IS 'Maximum completed withdraw amount within a UTC month, 0 is unlimited';
+COMMENT ON COLUMN transaction_limits.daily_transfer IS 'Maximum completed transfer amount sent within a UTC day, 0 is unlimited';
+COMMENT ON COLUMN transaction_limits.monthly_transfer IS 'Maximum completed transfer amount sent within a UTC month, 0 is unlimited';
+COMMENT ON COLUMN transaction_limits.max_balance IS 'Maximum wallet balance after a deposit or received transfer, 0 is unlimited';
+COMMENT ON COLUMN transaction_limits.updated_by IS 'The admin username who last changed the limits';
+COMMENT ON COLUMN transaction_limits.created_at IS 'Timestamp when the limits were created';
+COMMENT ON COLUMN transaction_limits.updated_at IS 'Timestamp when the limits were last changed';
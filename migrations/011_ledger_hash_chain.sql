@@ -0,0 +1,10 @@
+ALTER TABLE ledgers
+    ADD COLUMN prev_hash VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN hash VARCHAR(64) NOT NULL DEFAULT '';
+-- Walk the chain of a wallet in the inserted order
+CREATE INDEX idx_ledgers_wallet_id ON ledgers(username, currency, id);
+-- A chained entry has only one successor, so concurrent inserts can't fork the chain of a wallet
+CREATE UNIQUE INDEX uk_ledgers_prev_hash ON ledgers(username, currency, prev_hash) WHERE hash <> '';
+
+COMMENT ON COLUMN ledgers.prev_hash IS 'The hash of the previous ledger entry of the same wallet, empty for the first chained entry';
+COMMENT ON COLUMN ledgers.hash IS 'sha256 hex of the entry content and prev_hash, empty for the entries inserted before the chain was introduced';
@@ -0,0 +1,19 @@
+-- INTEGER caps an amount at 2147483647 of the smallest unit (about 21M SGD), widen every amount to BIGINT.
+-- The existing CHECK constraints are kept by ALTER COLUMN TYPE.
+ALTER TABLE wallets ALTER COLUMN amount TYPE BIGINT, ALTER COLUMN held_amount TYPE BIGINT;
+ALTER TABLE transactions ALTER COLUMN amount TYPE BIGINT, ALTER COLUMN refunded_amount TYPE BIGINT;
+ALTER TABLE ledgers ALTER COLUMN amount TYPE BIGINT;
+ALTER TABLE holds ALTER COLUMN amount TYPE BIGINT, ALTER COLUMN captured_amount TYPE BIGINT;
+ALTER TABLE fx_quotes ALTER COLUMN from_amount TYPE BIGINT, ALTER COLUMN to_amount TYPE BIGINT;
+ALTER TABLE reconciliation_discrepancies ALTER COLUMN wallet_amount TYPE BIGINT, ALTER COLUMN ledger_amount TYPE BIGINT;
+ALTER TABLE transaction_limits
+    ALTER COLUMN per_transaction TYPE BIGINT,
+    ALTER COLUMN daily_withdraw TYPE BIGINT,
+    ALTER COLUMN monthly_withdraw TYPE BIGINT,
+    ALTER COLUMN daily_transfer TYPE BIGINT,
+    ALTER COLUMN monthly_transfer TYPE BIGINT,
+    ALTER COLUMN max_balance TYPE BIGINT;
+
+COMMENT ON COLUMN wallets.amount IS 'Wallet balance stored as a 64-bit integer (smallest unit of currency)';
+COMMENT ON COLUMN transactions.amount IS 'The amount involved in the transaction (64-bit integer, smallest unit of currency)';
+COMMENT ON COLUMN ledgers.amount IS 'The amount of the transaction affecting the wallet balance (64-bit integer, smallest unit of currency)';
@@ -0,0 +1,70 @@
+CREATE TABLE IF NOT EXISTS transfer_schedules (
+    id SERIAL PRIMARY KEY,
+    uid CHAR(20) NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    receiver VARCHAR(100) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    reference VARCHAR(64) NOT NULL,
+    frequency VARCHAR(16) NOT NULL,
+    start_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    end_at TIMESTAMP WITHOUT TIME ZONE,
+    occurrence INTEGER NOT NULL DEFAULT 0,
+    due_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    next_run_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    on_insufficient_fund VARCHAR(16) NOT NULL,
+    max_retries INTEGER NOT NULL DEFAULT 0 CHECK (max_retries >= 0),
+    status VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+CREATE UNIQUE INDEX uk_transfer_schedules_uid ON transfer_schedules(uid);
+CREATE INDEX idx_transfer_schedules_username ON transfer_schedules(username);
+CREATE INDEX idx_transfer_schedules_active_next_run_at ON transfer_schedules(next_run_at) WHERE status = 'active';
+
+COMMENT ON COLUMN transfer_schedules.id IS 'Unique schedule ID (auto-incremented)';
+COMMENT ON COLUMN transfer_schedules.uid IS 'Unique schedule ID exposed in API';
+COMMENT ON COLUMN transfer_schedules.username IS 'Username of the sender who created the schedule';
+COMMENT ON COLUMN transfer_schedules.receiver IS 'Username of the wallet credited by every run';
+COMMENT ON COLUMN transfer_schedules.currency IS 'Currency of the transfer';
+COMMENT ON COLUMN transfer_schedules.amount IS 'Amount of every transfer (integer, smallest unit of currency)';
+COMMENT ON COLUMN transfer_schedules.reference IS 'Reference of the transfers made by the schedule';
+COMMENT ON COLUMN transfer_schedules.frequency IS 'How often the transfer repeats (e.g., ''once'', ''weekly'', ''monthly'')';
+COMMENT ON COLUMN transfer_schedules.start_at IS 'The first occurrence, the later occurrences are counted from it';
+COMMENT ON COLUMN transfer_schedules.end_at IS 'Optional timestamp after which no occurrence is due';
+COMMENT ON COLUMN transfer_schedules.occurrence IS 'Number of occurrences already completed or skipped';
+COMMENT ON COLUMN transfer_schedules.due_at IS 'The current occurrence';
+COMMENT ON COLUMN transfer_schedules.next_run_at IS 'When the current occurrence is attempted next, later than due_at after a retry';
+COMMENT ON COLUMN transfer_schedules.attempts IS 'Number of failed attempts of the current occurrence';
+COMMENT ON COLUMN transfer_schedules.on_insufficient_fund IS 'Policy when the sender can''t afford the transfer (e.g., ''retry'', ''skip'')';
+COMMENT ON COLUMN transfer_schedules.max_retries IS 'Number of retries of an occurrence before it is skipped with the retry policy';
+COMMENT ON COLUMN transfer_schedules.status IS 'The status of the schedule (e.g., ''active'', ''completed'', ''cancelled'')';
+COMMENT ON COLUMN transfer_schedules.created_at IS 'Timestamp when the schedule was created';
+COMMENT ON COLUMN transfer_schedules.updated_at IS 'Timestamp when the schedule was last updated';
+
+
+CREATE TABLE IF NOT EXISTS transfer_schedule_runs (
+    id SERIAL PRIMARY KEY,
+    schedule_id INTEGER NOT NULL REFERENCES transfer_schedules(id),
+    execution_key VARCHAR(64) NOT NULL,
+    due_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    attempt INTEGER NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    tx_uid VARCHAR(20) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+-- An occurrence is never paid twice
+CREATE UNIQUE INDEX uk_transfer_schedule_runs_completed ON transfer_schedule_runs(execution_key) WHERE status = 'completed';
+CREATE INDEX idx_transfer_schedule_runs_schedule_id ON transfer_schedule_runs(schedule_id, id DESC);
+
+COMMENT ON COLUMN transfer_schedule_runs.id IS 'Unique run ID (auto-incremented)';
+COMMENT ON COLUMN transfer_schedule_runs.schedule_id IS 'The schedule which made this run';
+COMMENT ON COLUMN transfer_schedule_runs.execution_key IS 'Schedule uid and occurrence number, shared by every attempt of the occurrence';
+COMMENT ON COLUMN transfer_schedule_runs.due_at IS 'The occurrence attempted by this run';
+COMMENT ON COLUMN transfer_schedule_runs.attempt IS 'Attempt number of the occurrence, starting from 1';
+COMMENT ON COLUMN transfer_schedule_runs.status IS 'The outcome of the run (e.g., ''completed'', ''failed'' to be retried, ''skipped'')';
+COMMENT ON COLUMN transfer_schedule_runs.tx_uid IS 'The transfer transaction of the completed run';
+COMMENT ON COLUMN transfer_schedule_runs.error IS 'Why the transfer was refused';
+COMMENT ON COLUMN transfer_schedule_runs.created_at IS 'Timestamp when the run was made';
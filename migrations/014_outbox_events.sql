@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    uid CHAR(20) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL,
+    published_at TIMESTAMP WITHOUT TIME ZONE
+);
+CREATE UNIQUE INDEX uk_outbox_events_uid ON outbox_events(uid);
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events(id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_events_published_at ON outbox_events(published_at) WHERE published_at IS NOT NULL;
+
+COMMENT ON COLUMN outbox_events.id IS 'Unique event ID (auto-incremented), the events are published in this order';
+COMMENT ON COLUMN outbox_events.uid IS 'Unique event ID exposed to the consumers for deduplication';
+COMMENT ON COLUMN outbox_events.type IS 'The type of the event (e.g., ''wallet.credited'', ''wallet.debited'')';
+COMMENT ON COLUMN outbox_events.username IS 'Username of the wallet the event belongs to';
+COMMENT ON COLUMN outbox_events.currency IS 'Currency of the wallet the event belongs to';
+COMMENT ON COLUMN outbox_events.payload IS 'The event body in JSON format';
+COMMENT ON COLUMN outbox_events.created_at IS 'Timestamp when the event was written with its transaction';
+COMMENT ON COLUMN outbox_events.published_at IS 'Timestamp when the event was published, NULL until then';
@@ -0,0 +1,74 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id SERIAL PRIMARY KEY,
+    uid CHAR(20) NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+CREATE UNIQUE INDEX uk_webhook_endpoints_uid ON webhook_endpoints(uid);
+CREATE INDEX idx_webhook_endpoints_username ON webhook_endpoints(username) WHERE status = 'active';
+
+COMMENT ON COLUMN webhook_endpoints.id IS 'Unique endpoint ID (auto-incremented)';
+COMMENT ON COLUMN webhook_endpoints.uid IS 'Unique endpoint ID exposed in API';
+COMMENT ON COLUMN webhook_endpoints.username IS 'Username of the client, the events of its wallets are delivered to the endpoint';
+COMMENT ON COLUMN webhook_endpoints.url IS 'The http(s) URL the events are posted to';
+COMMENT ON COLUMN webhook_endpoints.secret IS 'The HMAC-SHA256 key of the signature, only returned when the endpoint is registered';
+COMMENT ON COLUMN webhook_endpoints.status IS 'The status of the endpoint (e.g., ''active'', ''disabled'')';
+COMMENT ON COLUMN webhook_endpoints.created_at IS 'Timestamp when the endpoint was registered';
+COMMENT ON COLUMN webhook_endpoints.updated_at IS 'Timestamp when the endpoint was last updated';
+
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    uid CHAR(20) NOT NULL,
+    endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints(id),
+    event_uid CHAR(20) NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    delivered_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+CREATE UNIQUE INDEX uk_webhook_deliveries_uid ON webhook_deliveries(uid);
+-- An event is delivered to an endpoint once, the redelivered stream entry is ignored
+CREATE UNIQUE INDEX uk_webhook_deliveries_endpoint_event ON webhook_deliveries(endpoint_id, event_uid);
+CREATE INDEX idx_webhook_deliveries_pending_next_attempt_at ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+COMMENT ON COLUMN webhook_deliveries.id IS 'Unique delivery ID (auto-incremented)';
+COMMENT ON COLUMN webhook_deliveries.uid IS 'Unique delivery ID exposed in API and sent in the X-Webhook-Id header';
+COMMENT ON COLUMN webhook_deliveries.endpoint_id IS 'The endpoint the event is delivered to';
+COMMENT ON COLUMN webhook_deliveries.event_uid IS 'The outbox event which is delivered';
+COMMENT ON COLUMN webhook_deliveries.event_type IS 'The type of the event (e.g., ''wallet.credited'', ''wallet.debited'')';
+COMMENT ON COLUMN webhook_deliveries.payload IS 'The event body in JSON format';
+COMMENT ON COLUMN webhook_deliveries.status IS 'The status of the delivery (e.g., ''pending'', ''succeeded'', ''dead'' after the last attempt failed)';
+COMMENT ON COLUMN webhook_deliveries.attempts IS 'Number of attempts made so far';
+COMMENT ON COLUMN webhook_deliveries.next_attempt_at IS 'When the pending delivery is attempted next';
+COMMENT ON COLUMN webhook_deliveries.delivered_at IS 'Timestamp when the endpoint acknowledged the delivery with a 2xx response';
+COMMENT ON COLUMN webhook_deliveries.created_at IS 'Timestamp when the delivery was created';
+COMMENT ON COLUMN webhook_deliveries.updated_at IS 'Timestamp when the delivery was last updated';
+
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id),
+    attempt INTEGER NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+CREATE INDEX idx_webhook_attempts_delivery_id ON webhook_attempts(delivery_id, id DESC);
+
+COMMENT ON COLUMN webhook_attempts.id IS 'Unique attempt ID (auto-incremented)';
+COMMENT ON COLUMN webhook_attempts.delivery_id IS 'The delivery which made this attempt';
+COMMENT ON COLUMN webhook_attempts.attempt IS 'Attempt number of the delivery, starting from 1';
+COMMENT ON COLUMN webhook_attempts.status_code IS 'HTTP status code of the response, 0 when no response was received';
+COMMENT ON COLUMN webhook_attempts.error IS 'Why the attempt failed, empty when it succeeded';
+COMMENT ON COLUMN webhook_attempts.duration_ms IS 'How long the request took in milliseconds';
+COMMENT ON COLUMN webhook_attempts.created_at IS 'Timestamp when the attempt was made';
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS transaction_recoveries (
+    id BIGSERIAL PRIMARY KEY,
+    tx_uid CHAR(20) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    recovered_by VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+CREATE INDEX idx_transaction_recoveries_tx_uid ON transaction_recoveries(tx_uid);
+CREATE INDEX idx_transactions_pending_updated_at ON transactions(updated_at) WHERE status = 'pending';
+
+COMMENT ON COLUMN transaction_recoveries.id IS 'Unique recovery ID (auto-incremented)';
+COMMENT ON COLUMN transaction_recoveries.tx_uid IS 'The pending hold transaction which was recovered';
+COMMENT ON COLUMN transaction_recoveries.action IS 'The requested recovery (e.g., ''retry'' to follow the hold, ''fail'' to release it)';
+COMMENT ON COLUMN transaction_recoveries.status IS 'The final status of the transaction (e.g., ''completed'', ''failed'', ''cancelled'')';
+COMMENT ON COLUMN transaction_recoveries.reason IS 'Why the transaction was recovered';
+COMMENT ON COLUMN transaction_recoveries.recovered_by IS 'Username of the admin who recovered the transaction, empty for the recovery worker';
+COMMENT ON COLUMN transaction_recoveries.created_at IS 'Timestamp when the transaction was recovered';
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS wallet_daily_balances (
+    id BIGSERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    balance_date DATE NOT NULL,
+    balance BIGINT NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+-- Also serves the lookup of the latest checkpoint of a wallet before a timestamp
+CREATE UNIQUE INDEX uk_wallet_daily_balances ON wallet_daily_balances(username, currency, balance_date);
+CREATE INDEX idx_wallet_daily_balances_balance_date ON wallet_daily_balances(balance_date);
+
+COMMENT ON COLUMN wallet_daily_balances.id IS 'Unique snapshot ID (auto-incremented)';
+COMMENT ON COLUMN wallet_daily_balances.username IS 'Username of the wallet';
+COMMENT ON COLUMN wallet_daily_balances.currency IS 'Currency of the wallet';
+COMMENT ON COLUMN wallet_daily_balances.balance_date IS 'The UTC day closed by the snapshot';
+COMMENT ON COLUMN wallet_daily_balances.balance IS 'Sum of the credit minus debit ledgers created before the end of balance_date (integer, smallest unit of currency)';
+COMMENT ON COLUMN wallet_daily_balances.created_at IS 'Timestamp when the snapshot was taken';
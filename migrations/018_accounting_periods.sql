@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS accounting_periods (
+    id BIGSERIAL PRIMARY KEY,
+    period_date DATE NOT NULL,
+    wallets INTEGER NOT NULL,
+    closed_at TIMESTAMP WITHOUT TIME ZONE DEFAULT (now() AT TIME ZONE 'utc') NOT NULL
+);
+-- A day is closed once, ledgers are checked against the latest closed day before they are inserted
+CREATE UNIQUE INDEX uk_accounting_periods_period_date ON accounting_periods(period_date);
+
+COMMENT ON COLUMN accounting_periods.id IS 'Unique accounting period ID (auto-incremented)';
+COMMENT ON COLUMN accounting_periods.period_date IS 'The UTC day closed, no ledger can be created within it afterward';
+COMMENT ON COLUMN accounting_periods.wallets IS 'Number of daily balances snapshotted by the close';
+COMMENT ON COLUMN accounting_periods.closed_at IS 'Timestamp when the period was closed';
+
+-- The days snapshotted before the period close are closed as they are
+INSERT INTO accounting_periods (period_date, wallets, closed_at)
+SELECT balance_date, COUNT(*), MAX(created_at) FROM wallet_daily_balances GROUP BY balance_date;
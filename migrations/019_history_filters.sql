@@ -0,0 +1,5 @@
+-- The history of a wallet is read newest first by idx_wallet, these serve its selective filters
+CREATE INDEX idx_ledgers_wallet_direction ON ledgers(username, currency, direction, created_at DESC);
+CREATE INDEX idx_ledgers_wallet_amount ON ledgers(username, currency, amount);
+-- Most transactions are completed, the other statuses are few enough to be looked up first and joined to the ledgers
+CREATE INDEX idx_transactions_not_completed ON transactions(status, type, created_at DESC) WHERE status <> 'completed';
@@ -0,0 +1,3 @@
+-- The history is paged by the (created_at, id) keyset, the id breaks the tie of the legs created at the same time
+CREATE INDEX idx_ledgers_wallet_keyset ON ledgers(username, currency, created_at DESC, id DESC);
+DROP INDEX IF EXISTS idx_wallet;
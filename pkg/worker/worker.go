@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+// Job is a unit of background work executed on every tick of a worker.
+type Job func(ctx context.Context) error
+
+// Run executes job every interval until ctx is cancelled. A failed run is logged
+// and retried on the next tick, so a single bad run never stops the worker.
+func Run(ctx context.Context, name string, interval time.Duration, job Job) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Info(ctx, "starting worker [%s] with interval %s", name, interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info(ctx, "stopping worker [%s]", name)
+			return
+		case <-ticker.C:
+			if err := job(ctx); err != nil {
+				log.Error(ctx, "failed in running worker [%s] with err: %s", name, err)
+			}
+		}
+	}
+}
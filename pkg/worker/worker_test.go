@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("run job until context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		var calls atomic.Int32
+		done := make(chan struct{})
+		go func() {
+			Run(ctx, "test", time.Millisecond, func(ctx context.Context) error {
+				if calls.Add(1) == 3 {
+					cancel()
+				}
+				return errors.New("keep running on error")
+			})
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("worker did not stop after context cancelled")
+		}
+		assert.GreaterOrEqual(t, calls.Load(), int32(3))
+	})
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/render"
 	"github.com/lengzuo/fundflow/internal/apierr"
@@ -22,6 +25,15 @@ type Responder interface {
 	StatusCode() int
 }
 
+// Streamer is the Responder which writes its own body instead of being encoded as json, such as a file download.
+type Streamer interface {
+	Responder
+	ContentType() string
+	// Filename is sent as attachment in Content-Disposition when it isn't empty
+	Filename() string
+	Stream(ctx context.Context, w io.Writer) error
+}
+
 type RestfulFunc[In Params, Out Responder] func(context.Context, In) (Out, apierr.JSON)
 
 func Handle[In Params, Out Responder](f RestfulFunc[In, Out]) http.HandlerFunc {
@@ -69,6 +81,11 @@ func Handle[In Params, Out Responder](f RestfulFunc[In, Out]) http.HandlerFunc {
 			return
 		}
 
+		if streamer, ok := any(out).(Streamer); ok {
+			stream(w, r, streamer)
+			return
+		}
+
 		// Format and write response
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(out.StatusCode())
@@ -80,3 +97,20 @@ func Handle[In Params, Out Responder](f RestfulFunc[In, Out]) http.HandlerFunc {
 		}
 	})
 }
+
+// stream writes the body of the streamer as it is produced. The status is sent before the body, so an error in
+// the middle of the stream can only be logged and the response is cut short.
+func stream(w http.ResponseWriter, r *http.Request, streamer Streamer) {
+	w.Header().Set("Content-Type", streamer.ContentType())
+	if filename := streamer.Filename(); filename != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+	// The server write timeout is meant for json responses, a long download is bounded by the request timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Error(r.Context(), "failed to clear write deadline: %v", err)
+	}
+	w.WriteHeader(streamer.StatusCode())
+	if err := streamer.Stream(r.Context(), w); err != nil {
+		log.Error(r.Context(), "failed to stream response: %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -45,6 +47,32 @@ type mockResponse struct {
 func (m mockResponse) StatusCode() int {
 	return 200
 }
+
+type mockStreamResponse struct {
+	body string
+	err  error
+}
+
+func (m mockStreamResponse) StatusCode() int {
+	return 200
+}
+
+func (m mockStreamResponse) ContentType() string {
+	return "text/csv"
+}
+
+func (m mockStreamResponse) Filename() string {
+	return "statement.csv"
+}
+
+func (m mockStreamResponse) Stream(ctx context.Context, w io.Writer) error {
+	_, err := io.WriteString(w, m.body)
+	if err != nil {
+		return err
+	}
+	return m.err
+}
+
 func TestHandle(t *testing.T) {
 	t.Run("successful requesa in handle", func(t *testing.T) {
 		mockFunc := func(ctx context.Context, in mockParams) (*mockResponse, apierr.JSON) {
@@ -152,4 +180,28 @@ func TestHandle(t *testing.T) {
 		}, responseBody)
 		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
 	})
+
+	t.Run("successful stream in handle", func(t *testing.T) {
+		mockFunc := func(ctx context.Context, in mockParams) (*mockStreamResponse, apierr.JSON) {
+			return &mockStreamResponse{body: "a,b\n1,2\n"}, nil
+		}
+		req, _ := http.NewRequest("GET", "/test", nil)
+		rr := httptest.NewRecorder()
+		Handle(mockFunc).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "a,b\n1,2\n", rr.Body.String())
+		assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="statement.csv"`, rr.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("stream error after the body started", func(t *testing.T) {
+		mockFunc := func(ctx context.Context, in mockParams) (*mockStreamResponse, apierr.JSON) {
+			return &mockStreamResponse{body: "a,b\n", err: errors.New("db error")}, nil
+		}
+		req, _ := http.NewRequest("GET", "/test", nil)
+		rr := httptest.NewRecorder()
+		Handle(mockFunc).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "a,b\n", rr.Body.String())
+	})
 }
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/render"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+// Admin only allows the configured admin usernames, it must run after Auth.
+func Admin(adminUsernames []string) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, _ := r.Context().Value(log.UsernameKey).(string)
+			if username == "" || !slices.Contains(adminUsernames, username) {
+				err := apierr.Forbidden("admin only")
+				render.Status(r, err.HTTPStatusCode())
+				render.JSON(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmin(t *testing.T) {
+	tests := []struct {
+		name       string
+		username   string
+		wantStatus int
+	}{
+		{name: "admin user", username: "admin", wantStatus: http.StatusOK},
+		{name: "normal user", username: "user2", wantStatus: http.StatusForbidden},
+		{name: "no user in context", username: "", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Admin([]string{"admin"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/wallets/freeze", nil)
+			if tt.username != "" {
+				req = req.WithContext(context.WithValue(req.Context(), log.UsernameKey, tt.username))
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
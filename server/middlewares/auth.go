@@ -2,25 +2,54 @@ package middlewares
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/go-chi/render"
 	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/auth"
 	"github.com/lengzuo/fundflow/pkg/log"
 )
 
-func Auth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		authUsername := r.Header.Get("Authorization")
-		if strings.TrimSpace(authUsername) == "" {
-			err := apierr.Unauthenticated()
-			render.Status(r, err.HTTPStatusCode())
-			render.JSON(w, r, err)
-			return
-		}
-		r = r.WithContext(context.WithValue(ctx, log.UsernameKey, authUsername))
-		next.ServeHTTP(w, r)
-	})
+const bearerPrefix = "Bearer "
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(bearerPrefix):])
+}
+
+// Auth verifies the bearer token issued at login and places the verified username into context.
+func Auth(tokens auth.Tokens) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			token := bearerToken(r)
+			if token == "" {
+				err := apierr.Unauthenticated()
+				render.Status(r, err.HTTPStatusCode())
+				render.JSON(w, r, err)
+				return
+			}
+			username, err := tokens.Verify(ctx, token)
+			if err != nil {
+				var apiErr apierr.JSON
+				if errors.Is(err, auth.ErrInvalidToken) {
+					apiErr = apierr.Unauthenticated()
+				} else {
+					log.Error(ctx, "failed in verify token with err: %s", err)
+					apiErr = apierr.ServiceUnavailable("auth service down")
+				}
+				render.Status(r, apiErr.HTTPStatusCode())
+				render.JSON(w, r, apiErr)
+				return
+			}
+			ctx = context.WithValue(ctx, log.UsernameKey, username)
+			ctx = auth.WithToken(ctx, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
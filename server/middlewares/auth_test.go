@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lengzuo/fundflow/internal/auth"
+	"github.com/lengzuo/fundflow/internal/auth/mocks"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		verifyErr  error
+		wantVerify bool
+		wantStatus int
+	}{
+		{name: "valid bearer token", header: "Bearer token", wantVerify: true, wantStatus: http.StatusOK},
+		{name: "lowercase bearer scheme", header: "bearer token", wantVerify: true, wantStatus: http.StatusOK},
+		{name: "missing header", header: "", wantStatus: http.StatusUnauthorized},
+		{name: "username as header", header: "user1", wantStatus: http.StatusUnauthorized},
+		{name: "empty bearer token", header: "Bearer  ", wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", header: "Bearer token", verifyErr: auth.ErrInvalidToken, wantVerify: true, wantStatus: http.StatusUnauthorized},
+		{name: "token store down", header: "Bearer token", verifyErr: errors.New("err"), wantVerify: true, wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := mocks.NewTokens(t)
+			if tt.wantVerify {
+				username := "user1"
+				if tt.verifyErr != nil {
+					username = ""
+				}
+				tokens.On("Verify", mock.Anything, "token").Return(username, tt.verifyErr)
+			}
+			handler := Auth(tokens)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				username, _ := r.Context().Value(log.UsernameKey).(string)
+				token, _ := auth.TokenFromContext(r.Context())
+				assert.Equal(t, "user1", username)
+				assert.Equal(t, "token", token)
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/wallets/balance", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
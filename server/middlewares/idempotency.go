@@ -118,7 +118,8 @@ func Idempotency(redisClient *redis.Client) MiddlewareFunc {
 				renderInternalErr(w, r)
 				return
 			}
-			username := r.Header.Get("Authorization")
+			// Idempotency must run after Auth, so the key is scoped to the verified username
+			username, _ := ctx.Value(log.UsernameKey).(string)
 			apiName := getAPIName(r.URL.Path)
 			// The key should be a combination of idempotency key and username
 			redisKey := username + idempotencyKey + apiName
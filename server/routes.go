@@ -4,11 +4,155 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/lengzuo/fundflow/usecases/balances"
+	"github.com/lengzuo/fundflow/usecases/currencies"
+	"github.com/lengzuo/fundflow/usecases/exchanges"
+	"github.com/lengzuo/fundflow/usecases/holds"
+	"github.com/lengzuo/fundflow/usecases/ledgers"
+	"github.com/lengzuo/fundflow/usecases/limits"
+	"github.com/lengzuo/fundflow/usecases/reconciliations"
+	"github.com/lengzuo/fundflow/usecases/recoveries"
+	"github.com/lengzuo/fundflow/usecases/schedules"
+	"github.com/lengzuo/fundflow/usecases/transactions"
 	"github.com/lengzuo/fundflow/usecases/users"
+	"github.com/lengzuo/fundflow/usecases/wallets"
+	"github.com/lengzuo/fundflow/usecases/webhooks"
 )
 
 func usersRouter(users users.Service) http.Handler {
 	r := chi.NewRouter()
 	r.Post("/signup", Handle(users.SignUp))
+	r.Post("/login", Handle(users.Login))
+	return r
+}
+
+func currenciesRouter(currencies currencies.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", Handle(currencies.List))
+	return r
+}
+
+func sessionsRouter(users users.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/logout", Handle(users.Logout))
+	return r
+}
+
+func walletsRouter(wallets wallets.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/balance", Handle(wallets.Balance))
+	r.Get("/history", Handle(wallets.History))
+	r.Get("/statement", Handle(wallets.Statement))
+	r.Post("/deposit", Handle(wallets.Deposit))
+	r.Post("/withdraw", Handle(wallets.Withdraw))
+	r.Post("/transfer", Handle(wallets.Transfer))
+	r.Post("/bulk-transfer", Handle(wallets.BulkTransfer))
+	return r
+}
+
+func adminWalletsRouter(wallets wallets.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/status-histories", Handle(wallets.StatusHistories))
+	r.Post("/freeze", Handle(wallets.Freeze))
+	r.Post("/unfreeze", Handle(wallets.Unfreeze))
+	r.Post("/close", Handle(wallets.Close))
+	return r
+}
+
+func balancesRouter(balances balances.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/as-of", Handle(balances.AsOf))
+	return r
+}
+
+func adminBalancesRouter(balances balances.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", Handle(balances.ListAsOf))
+	r.Get("/as-of", Handle(balances.AdminAsOf))
+	return r
+}
+
+func holdsRouter(holds holds.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", Handle(holds.Get))
+	r.Post("/authorize", Handle(holds.Authorize))
+	r.Post("/capture", Handle(holds.Capture))
+	r.Post("/void", Handle(holds.Void))
+	return r
+}
+
+func schedulesRouter(schedules schedules.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", Handle(schedules.Get))
+	r.Get("/list", Handle(schedules.List))
+	r.Post("/", Handle(schedules.Create))
+	r.Post("/update", Handle(schedules.Update))
+	r.Post("/cancel", Handle(schedules.Cancel))
+	return r
+}
+
+func webhooksRouter(webhooks webhooks.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/", Handle(webhooks.Register))
+	r.Get("/list", Handle(webhooks.ListEndpoints))
+	r.Post("/disable", Handle(webhooks.Disable))
+	r.Get("/deliveries", Handle(webhooks.ListDeliveries))
+	r.Get("/delivery", Handle(webhooks.GetDelivery))
+	r.Post("/redeliver", Handle(webhooks.Redeliver))
+	return r
+}
+
+func transactionsRouter(transactions transactions.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", Handle(transactions.Get))
+	r.Get("/search", Handle(transactions.Search))
+	r.Post("/refund", Handle(transactions.Refund))
+	return r
+}
+
+func exchangesRouter(exchanges exchanges.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/", Handle(exchanges.Exchange))
+	r.Post("/quote", Handle(exchanges.Quote))
+	return r
+}
+
+func limitsRouter(limits limits.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", Handle(limits.Usage))
+	return r
+}
+
+func adminLimitsRouter(limits limits.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/", Handle(limits.Set))
+	return r
+}
+
+func adminTransactionsRouter(transactions transactions.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/reverse", Handle(transactions.Reverse))
+	return r
+}
+
+func adminRecoveriesRouter(recoveries recoveries.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", Handle(recoveries.List))
+	r.Post("/retry", Handle(recoveries.Retry))
+	r.Post("/fail", Handle(recoveries.Fail))
+	return r
+}
+
+func adminLedgersRouter(ledgers ledgers.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/verify", Handle(ledgers.Verify))
+	return r
+}
+
+func adminReconciliationsRouter(reconciliations reconciliations.Service) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", Handle(reconciliations.ListRuns))
+	r.Get("/discrepancies", Handle(reconciliations.Discrepancies))
+	r.Post("/run", Handle(reconciliations.Run))
 	return r
 }
@@ -13,11 +13,31 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/lengzuo/fundflow/configs"
 	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/auth"
+	"github.com/lengzuo/fundflow/internal/events"
+	"github.com/lengzuo/fundflow/internal/fx"
+	internalwebhooks "github.com/lengzuo/fundflow/internal/webhooks"
 	"github.com/lengzuo/fundflow/pkg/log"
 	pkgredis "github.com/lengzuo/fundflow/pkg/redis"
+	"github.com/lengzuo/fundflow/pkg/worker"
 	"github.com/lengzuo/fundflow/server/middlewares"
+	"github.com/lengzuo/fundflow/usecases/balances"
+	"github.com/lengzuo/fundflow/usecases/currencies"
+	"github.com/lengzuo/fundflow/usecases/exchanges"
+	"github.com/lengzuo/fundflow/usecases/holds"
+	"github.com/lengzuo/fundflow/usecases/ledgers"
+	"github.com/lengzuo/fundflow/usecases/limits"
+	"github.com/lengzuo/fundflow/usecases/outbox"
+	"github.com/lengzuo/fundflow/usecases/reconciliations"
+	"github.com/lengzuo/fundflow/usecases/recoveries"
+	"github.com/lengzuo/fundflow/usecases/schedules"
+	"github.com/lengzuo/fundflow/usecases/transactions"
 	"github.com/lengzuo/fundflow/usecases/users"
+	"github.com/lengzuo/fundflow/usecases/wallets"
+	"github.com/lengzuo/fundflow/usecases/webhooks"
 	"github.com/lengzuo/fundflow/utils"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -28,6 +48,11 @@ func Serve() {
 	}
 	log.New(config.Mode)
 
+	// Enable the currencies of the deployment before anything validates a currency
+	if err := configureCurrencies(config.CurrencyConfig); err != nil {
+		panic(fmt.Sprintf("failed to configure currencies: %v", err))
+	}
+
 	serverCtx, serverStopCtx := context.WithTimeout(context.Background(), 60*time.Second)
 	defer serverStopCtx()
 
@@ -42,19 +67,87 @@ func Serve() {
 
 	// Initialize DAOs from database client above
 	userDAO := dao.NewUsers(db)
-	_ = dao.NewTransactions(db)
-	_ = dao.NewWallets(db)
-	_ = dao.NewLedgers(db)
+	transactionDAO := dao.NewTransactions(db)
+	walletDAO := dao.NewWallets(db)
+	ledgerDAO := dao.NewLedgers(db)
+	holdDAO := dao.NewHolds(db)
+	reconciliationDAO := dao.NewReconciliations(db)
+	exchangeDAO := dao.NewExchanges(db)
+	limitDAO := dao.NewLimits(db)
+	scheduleDAO := dao.NewSchedules(db)
+	outboxDAO := dao.NewOutbox(db)
+	webhookDAO := dao.NewWebhooks(db)
+	recoveryDAO := dao.NewRecoveries(db)
+	balanceDAO := dao.NewBalances(db)
+
+	// Initialize session tokens issued at login and verified by auth middleware
+	tokens := auth.NewTokens(redisClient, utils.SessionTokenTTL)
+
+	// Initialize fx rates used to quote the currency exchange
+	rates, err := newRateProvider(config.FXConfig, redisClient)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load fx rates: %v", err))
+	}
+	fxRounding, err := money.ParseRoundingMode(config.FXConfig.Rounding)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load fx rounding: %v", err))
+	}
 
 	// Initialize usecases
-	userServices := users.New(userDAO)
+	userServices := users.New(userDAO, tokens)
+	walletServices := wallets.New(walletDAO, ledgerDAO)
+	holdServices := holds.New(holdDAO)
+	transactionServices := transactions.New(transactionDAO)
+	reconciliationServices := reconciliations.New(reconciliationDAO)
+	exchangeServices := exchanges.New(exchangeDAO, rates, fxRounding)
+	limitServices := limits.New(limitDAO)
+	ledgerServices := ledgers.New(ledgerDAO)
+	currencyServices := currencies.New()
+	scheduleServices := schedules.New(scheduleDAO)
+	recoveryServices := recoveries.New(recoveryDAO, config.RecoveryConfig.PendingTimeout)
+	balanceServices := balances.New(balanceDAO, utils.BalanceSettleDelay)
+	// Every instance reads the wallet events as its own consumer of the webhooks group
+	consumerName, err := os.Hostname()
+	if err != nil {
+		panic(fmt.Sprintf("failed to get hostname: %v", err))
+	}
+	webhookEvents := events.NewRedisStreamConsumer(redisClient, config.OutboxConfig.Stream, utils.WebhookConsumerGroup, consumerName)
+	webhookServices := webhooks.New(webhookDAO, webhookEvents, internalwebhooks.NewHTTPSender(utils.WebhookTimeout))
+	outboxServices := outbox.New(outboxDAO, events.NewRedisStream(redisClient, config.OutboxConfig.Stream, config.OutboxConfig.StreamMaxLen), config.OutboxConfig.Retention)
+
+	// Background workers live until shutdown, unlike serverCtx which has a deadline
+	workerCtx, workerStopCtx := context.WithCancel(context.Background())
+	defer workerStopCtx()
+	go worker.Run(workerCtx, "expire-holds", utils.HoldExpiryInterval, holdServices.ExpireHolds)
+	go worker.Run(workerCtx, "reconcile-wallets", utils.ReconciliationInterval, reconciliationServices.Reconcile)
+	go worker.Run(workerCtx, "execute-schedules", utils.ScheduleInterval, scheduleServices.RunDue)
+	go worker.Run(workerCtx, "dispatch-outbox", utils.OutboxDispatchInterval, outboxServices.Dispatch)
+	go worker.Run(workerCtx, "cleanup-outbox", utils.OutboxCleanupInterval, outboxServices.Cleanup)
+	go worker.Run(workerCtx, "enqueue-webhooks", utils.WebhookEnqueueInterval, webhookServices.Enqueue)
+	go worker.Run(workerCtx, "deliver-webhooks", utils.WebhookDeliveryInterval, webhookServices.Deliver)
+	go worker.Run(workerCtx, "recover-transactions", utils.RecoveryInterval, recoveryServices.Recover)
+	go worker.Run(workerCtx, "close-periods", utils.PeriodCloseInterval, balanceServices.ClosePeriods)
 
 	// The HTTP Server
 	server := &http.Server{
 		Addr: "0.0.0.0:8080",
 		Handler: router(
 			redisClient,
+			tokens,
+			config.AuthConfig,
 			userServices,
+			walletServices,
+			holdServices,
+			transactionServices,
+			reconciliationServices,
+			exchangeServices,
+			limitServices,
+			ledgerServices,
+			currencyServices,
+			scheduleServices,
+			webhookServices,
+			recoveryServices,
+			balanceServices,
 		),
 		ReadTimeout:  3 * time.Second,
 		WriteTimeout: 3 * time.Second,
@@ -74,6 +167,7 @@ func Serve() {
 	quit := <-sig
 
 	log.Info(serverCtx, "starting graceful shutdown for server")
+	workerStopCtx()
 	// Server run context
 	err = server.Shutdown(serverCtx)
 	if err != nil {
@@ -82,9 +176,56 @@ func Serve() {
 	log.Info(serverCtx, "server shutdown successfully, quit signal: %s", quit.String())
 }
 
+// configureCurrencies enables the ISO 4217 currencies together with the custom units of the optional file.
+func configureCurrencies(config *configs.CurrencyConfig) error {
+	var custom []currency.Value
+	if config.CustomUnitsFile != "" {
+		units, err := currency.LoadCustomUnits(config.CustomUnitsFile)
+		if err != nil {
+			return err
+		}
+		custom = units
+	}
+	return currency.Configure(config.Enabled, custom)
+}
+
+// newRateProvider builds the source of mid rates from config, the http provider is cached in redis,
+// then wraps it with the spreads and staleness check.
+func newRateProvider(config *configs.FXConfig, redisClient *redis.Client) (fx.RateProvider, error) {
+	var source fx.RateProvider
+	switch config.Provider {
+	case "", "static":
+		static, err := fx.LoadStaticRates(config.RatesFile)
+		if err != nil {
+			return nil, err
+		}
+		source = static
+	case "http":
+		client := &http.Client{Timeout: utils.FXProviderTimeout}
+		source = fx.NewCachedRates(fx.NewHTTPRates(client, config.URL), redisClient, config.CacheTTL)
+	default:
+		return nil, fmt.Errorf("unknown fx provider %q", config.Provider)
+	}
+	return fx.NewQuoter(source, config.Spreads, config.MaxRateAge)
+}
+
 func router(
 	redisClient *redis.Client,
+	tokens auth.Tokens,
+	authConfig *configs.AuthConfig,
 	userServices users.Service,
+	walletServices wallets.Service,
+	holdServices holds.Service,
+	transactionServices transactions.Service,
+	reconciliationServices reconciliations.Service,
+	exchangeServices exchanges.Service,
+	limitServices limits.Service,
+	ledgerServices ledgers.Service,
+	currencyServices currencies.Service,
+	scheduleServices schedules.Service,
+	webhookServices webhooks.Service,
+	recoveryServices recoveries.Service,
+	balanceServices balances.Service,
 ) http.Handler {
 	r := chi.NewRouter()
 
@@ -92,7 +233,6 @@ func router(
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(utils.APIRequestTimeout))
-	r.Use(middlewares.Idempotency(redisClient))
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("hi"))
@@ -107,8 +247,32 @@ func router(
 	r.Route("/api", func(apiRouter chi.Router) {
 		// No Auth API
 		apiRouter.Mount("/public/users", usersRouter(userServices))
+		apiRouter.Mount("/currencies", currenciesRouter(currencyServices))
 		// Auth API
-
+		apiRouter.Group(func(authRouter chi.Router) {
+			authRouter.Use(middlewares.Auth(tokens))
+			authRouter.Use(middlewares.Idempotency(redisClient))
+			authRouter.Mount("/users", sessionsRouter(userServices))
+			authRouter.Mount("/wallets", walletsRouter(walletServices))
+			authRouter.Mount("/holds", holdsRouter(holdServices))
+			authRouter.Mount("/transactions", transactionsRouter(transactionServices))
+			authRouter.Mount("/exchanges", exchangesRouter(exchangeServices))
+			authRouter.Mount("/limits", limitsRouter(limitServices))
+			authRouter.Mount("/schedules", schedulesRouter(scheduleServices))
+			authRouter.Mount("/webhooks", webhooksRouter(webhookServices))
+			authRouter.Mount("/balances", balancesRouter(balanceServices))
+			// Admin API
+			authRouter.Route("/admin", func(adminRouter chi.Router) {
+				adminRouter.Use(middlewares.Admin(authConfig.AdminUsernames))
+				adminRouter.Mount("/wallets", adminWalletsRouter(walletServices))
+				adminRouter.Mount("/transactions", adminTransactionsRouter(transactionServices))
+				adminRouter.Mount("/reconciliations", adminReconciliationsRouter(reconciliationServices))
+				adminRouter.Mount("/limits", adminLimitsRouter(limitServices))
+				adminRouter.Mount("/ledgers", adminLedgersRouter(ledgerServices))
+				adminRouter.Mount("/recoveries", adminRecoveriesRouter(recoveryServices))
+				adminRouter.Mount("/balances", adminBalancesRouter(balanceServices))
+			})
+		})
 	})
 
 	return r
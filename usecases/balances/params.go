@@ -0,0 +1,81 @@
+package balances
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+	maxUsernames     = 1000
+	dateLayout       = "2006-01-02"
+)
+
+// parseAt parses the point in time of the balance, either an RFC 3339 timestamp or a UTC date such as 2026-01-31
+// which is its end of day. Ledgers created at the instant returned are excluded from the balance.
+func parseAt(at string) (time.Time, apierr.JSON) {
+	instant, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		day, err := time.Parse(dateLayout, at)
+		if err != nil {
+			return time.Time{}, apierr.BadRequest("at must be a timestamp such as 2026-01-31T12:00:00Z or a date such as 2026-01-31")
+		}
+		instant = day.AddDate(0, 0, 1)
+	}
+	if instant.After(time.Now()) {
+		return time.Time{}, apierr.BadRequest("at must not be in the future")
+	}
+	return instant.UTC(), nil
+}
+
+type AsOfParams struct {
+	Currency string `schema:"currency"`
+	At       string `schema:"at"`
+}
+
+func (p AsOfParams) Validate() apierr.JSON {
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	_, jsonErr := parseAt(p.At)
+	return jsonErr
+}
+
+type AdminAsOfParams struct {
+	Username string `schema:"username"`
+	Currency string `schema:"currency"`
+	At       string `schema:"at"`
+}
+
+func (p AdminAsOfParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.Username) == "" {
+		return apierr.BadRequest("username is mandatory")
+	}
+	return AsOfParams{Currency: p.Currency, At: p.At}.Validate()
+}
+
+// ListAsOfParams lists the balances of every wallet of the currency unless the usernames are given.
+type ListAsOfParams struct {
+	Currency      string   `schema:"currency"`
+	At            string   `schema:"at"`
+	Usernames     []string `schema:"username"`
+	Limit         int      `schema:"limit"`
+	StartingAfter string   `schema:"starting_after"`
+}
+
+func (p ListAsOfParams) Validate() apierr.JSON {
+	if jsonErr := (AsOfParams{Currency: p.Currency, At: p.At}).Validate(); jsonErr != nil {
+		return jsonErr
+	}
+	if len(p.Usernames) > maxUsernames {
+		return apierr.BadRequest("too many usernames")
+	}
+	if p.Limit < 0 || p.Limit > maxListLimit {
+		return apierr.BadRequest("limit must be between 1 and 1000")
+	}
+	return nil
+}
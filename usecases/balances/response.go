@@ -0,0 +1,35 @@
+package balances
+
+import (
+	"net/http"
+	"time"
+)
+
+type BalanceAsOf struct {
+	Username string `json:"username"`
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+	// AmountDecimal is Amount in the precision of the currency
+	AmountDecimal string `json:"amount_decimal"`
+	// AsOf is the exclusive instant of the balance, ledgers created at or after it are not included
+	AsOf time.Time `json:"as_of"`
+	// CheckpointDate is the daily balance which the ledgers after it were summed on top of
+	CheckpointDate string `json:"checkpoint_date,omitempty"`
+}
+
+type BalanceAsOfResponse struct {
+	BalanceAsOf
+}
+
+func (r BalanceAsOfResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type ListAsOfResponse struct {
+	Data    []BalanceAsOf `json:"data"`
+	HasMore bool          `json:"has_more"`
+}
+
+func (r ListAsOfResponse) StatusCode() int {
+	return http.StatusOK
+}
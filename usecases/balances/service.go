@@ -0,0 +1,147 @@
+package balances
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+// maxCloseDays bounds the days closed by a run, the backlog after a downtime catches up over the next runs
+const maxCloseDays = 31
+
+type Service interface {
+	AsOf(ctx context.Context, params AsOfParams) (*BalanceAsOfResponse, apierr.JSON)
+	AdminAsOf(ctx context.Context, params AdminAsOfParams) (*BalanceAsOfResponse, apierr.JSON)
+	ListAsOf(ctx context.Context, params ListAsOfParams) (*ListAsOfResponse, apierr.JSON)
+	ClosePeriods(ctx context.Context) error
+}
+
+type service struct {
+	balances    dao.BalancesRepository
+	settleDelay time.Duration
+}
+
+// New returns the balances service, a day is closed once settleDelay has passed since its end so the
+// transactions committing across midnight are included rather than refused.
+func New(balancesDAO dao.BalancesRepository, settleDelay time.Duration) Service {
+	return &service{
+		balances:    balancesDAO,
+		settleDelay: settleDelay,
+	}
+}
+
+func authUsername(ctx context.Context) (string, apierr.JSON) {
+	username, ok := ctx.Value(log.UsernameKey).(string)
+	if !ok || username == "" {
+		return "", apierr.Unauthenticated()
+	}
+	return username, nil
+}
+
+// toAPIErr maps the errors returned from dao into the error response of the API.
+func toAPIErr(ctx context.Context, err error) apierr.JSON {
+	if errors.Is(err, apierr.NotFound) {
+		return apierr.ResourceNotFound("wallet not found")
+	}
+	log.Error(ctx, "failed in balances service with err: %s", err)
+	return apierr.InternalServer("please try again")
+}
+
+func toBalanceAsOf(balance dao.BalanceAsOfModel, asOf time.Time) BalanceAsOf {
+	b := BalanceAsOf{
+		Username:      balance.Username,
+		Currency:      balance.Currency,
+		Amount:        balance.Balance,
+		AmountDecimal: money.FormatDecimal(balance.Balance, balance.Currency),
+		AsOf:          asOf,
+	}
+	if balance.CheckpointDate != nil {
+		b.CheckpointDate = balance.CheckpointDate.Format(dateLayout)
+	}
+	return b
+}
+
+func (s *service) asOf(ctx context.Context, username, currency, at string) (*BalanceAsOfResponse, apierr.JSON) {
+	before, jsonErr := parseAt(at)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	balance, err := s.balances.AsOf(ctx, username, currency, before)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &BalanceAsOfResponse{BalanceAsOf: toBalanceAsOf(*balance, before)}, nil
+}
+
+// AsOf returns the balance of the wallet of the user at the point in time.
+func (s *service) AsOf(ctx context.Context, params AsOfParams) (*BalanceAsOfResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	return s.asOf(ctx, username, params.Currency, params.At)
+}
+
+// AdminAsOf returns the balance of any wallet at the point in time.
+func (s *service) AdminAsOf(ctx context.Context, params AdminAsOfParams) (*BalanceAsOfResponse, apierr.JSON) {
+	return s.asOf(ctx, params.Username, params.Currency, params.At)
+}
+
+// ListAsOf returns the balances of the wallets of the currency at the point in time for reporting.
+func (s *service) ListAsOf(ctx context.Context, params ListAsOfParams) (*ListAsOfResponse, apierr.JSON) {
+	before, jsonErr := parseAt(params.At)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	balances, hasMore, err := s.balances.ListAsOf(ctx, before, dao.BalanceFilter{
+		Currency:      params.Currency,
+		Usernames:     params.Usernames,
+		Limit:         limit,
+		StartingAfter: params.StartingAfter,
+	})
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	data := make([]BalanceAsOf, 0, len(balances))
+	for _, balance := range balances {
+		data = append(data, toBalanceAsOf(balance, before))
+	}
+	return &ListAsOfResponse{Data: data, HasMore: hasMore}, nil
+}
+
+// ClosePeriods closes the settled days after the latest closed period one by one, it is meant to be run periodically
+// by a worker. Each close snapshots the daily balances of the day, after which no ledger can be created within it.
+// The first run only closes the last settled day, which is summed from the first ledger.
+func (s *service) ClosePeriods(ctx context.Context) error {
+	lastSettled := time.Now().UTC().Add(-s.settleDelay).Truncate(24*time.Hour).AddDate(0, 0, -1)
+	day := lastSettled
+	latest, err := s.balances.LatestClosedPeriod(ctx)
+	if err != nil {
+		return err
+	}
+	if latest != nil {
+		day = latest.PeriodDate.UTC().AddDate(0, 0, 1)
+	}
+	for i := 0; i < maxCloseDays && !day.After(lastSettled); i++ {
+		period, err := s.balances.ClosePeriod(ctx, day)
+		if err != nil {
+			// Closed by another instance meanwhile, the next run continues after it
+			if errors.Is(err, apierr.PeriodClosed) {
+				return nil
+			}
+			return err
+		}
+		log.Info(ctx, "closed period %s with %d daily balances", day.Format(dateLayout), period.Wallets)
+		day = day.AddDate(0, 0, 1)
+	}
+	return nil
+}
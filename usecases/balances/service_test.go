@@ -0,0 +1,157 @@
+package balances
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func authCtx(t *testing.T, username string) context.Context {
+	return context.WithValue(t.Context(), log.UsernameKey, username)
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "as of timestamp", params: AsOfParams{Currency: "SGD", At: "2026-01-31T12:00:00+08:00"}},
+		{name: "as of date", params: AsOfParams{Currency: "SGD", At: "2026-01-31"}},
+		{name: "as of invalid at", params: AsOfParams{Currency: "SGD", At: "31/01/2026"}, wantErr: true},
+		{name: "as of future", params: AsOfParams{Currency: "SGD", At: time.Now().Add(time.Hour).Format(time.RFC3339)}, wantErr: true},
+		{name: "as of unsupported currency", params: AsOfParams{Currency: "XYZ", At: "2026-01-31"}, wantErr: true},
+		{name: "admin as of ok", params: AdminAsOfParams{Username: "user1", Currency: "SGD", At: "2026-01-31"}},
+		{name: "admin as of missing username", params: AdminAsOfParams{Currency: "SGD", At: "2026-01-31"}, wantErr: true},
+		{name: "list ok", params: ListAsOfParams{Currency: "SGD", At: "2026-01-31", Usernames: []string{"user1"}}},
+		{name: "list limit too large", params: ListAsOfParams{Currency: "SGD", At: "2026-01-31", Limit: maxListLimit + 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_AsOf(t *testing.T) {
+	before := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	checkpoint := time.Date(2026, 1, 30, 0, 0, 0, 0, time.UTC)
+
+	t.Run("ok end of date", func(t *testing.T) {
+		repo := mocks.NewBalancesRepository(t)
+		repo.On("AsOf", mock.Anything, "user1", "SGD", before).Return(
+			&dao.BalanceAsOfModel{Username: "user1", Currency: "SGD", Balance: 12345, CheckpointDate: &checkpoint}, nil,
+		)
+		s := New(repo, time.Minute)
+		resp, err := s.AsOf(authCtx(t, "user1"), AsOfParams{Currency: "SGD", At: "2026-01-31"})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(12345), resp.Amount)
+		assert.Equal(t, "123.45", resp.AmountDecimal)
+		assert.Equal(t, before, resp.AsOf)
+		assert.Equal(t, "2026-01-30", resp.CheckpointDate)
+	})
+
+	t.Run("wallet not found", func(t *testing.T) {
+		repo := mocks.NewBalancesRepository(t)
+		repo.On("AsOf", mock.Anything, "user1", "SGD", before).Return(nil, apierr.NotFound)
+		s := New(repo, time.Minute)
+		resp, err := s.AsOf(authCtx(t, "user1"), AsOfParams{Currency: "SGD", At: "2026-02-01T08:00:00+08:00"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	})
+
+	t.Run("without auth", func(t *testing.T) {
+		s := New(mocks.NewBalancesRepository(t), time.Minute)
+		resp, err := s.AsOf(t.Context(), AsOfParams{Currency: "SGD", At: "2026-01-31"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_ListAsOf(t *testing.T) {
+	before := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("ok default limit", func(t *testing.T) {
+		repo := mocks.NewBalancesRepository(t)
+		repo.On("ListAsOf", mock.Anything, before, dao.BalanceFilter{Currency: "SGD", Limit: defaultListLimit, StartingAfter: "user0"}).Return(
+			[]dao.BalanceAsOfModel{{Username: "user1", Currency: "SGD", Balance: 100}}, true, nil,
+		)
+		s := New(repo, time.Minute)
+		resp, err := s.ListAsOf(t.Context(), ListAsOfParams{Currency: "SGD", At: "2026-01-31", StartingAfter: "user0"})
+		assert.Nil(t, err)
+		assert.True(t, resp.HasMore)
+		assert.Equal(t, "user1", resp.Data[0].Username)
+		assert.Empty(t, resp.Data[0].CheckpointDate)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		repo := mocks.NewBalancesRepository(t)
+		repo.On("ListAsOf", mock.Anything, before, mock.Anything).Return(nil, false, errors.New("db down"))
+		s := New(repo, time.Minute)
+		resp, err := s.ListAsOf(t.Context(), ListAsOfParams{Currency: "SGD", At: "2026-01-31"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_ClosePeriods(t *testing.T) {
+	lastSettled := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	closed := func(day time.Time) *dao.AccountingPeriodsModel {
+		return &dao.AccountingPeriodsModel{PeriodDate: day, Wallets: 2}
+	}
+
+	t.Run("first run closes the last settled day", func(t *testing.T) {
+		repo := mocks.NewBalancesRepository(t)
+		repo.On("LatestClosedPeriod", mock.Anything).Return(nil, nil)
+		repo.On("ClosePeriod", mock.Anything, lastSettled).Return(closed(lastSettled), nil)
+		s := New(repo, 0)
+		assert.NoError(t, s.ClosePeriods(t.Context()))
+	})
+
+	t.Run("catch up the days after the latest closed period", func(t *testing.T) {
+		repo := mocks.NewBalancesRepository(t)
+		repo.On("LatestClosedPeriod", mock.Anything).Return(closed(lastSettled.AddDate(0, 0, -2)), nil)
+		repo.On("ClosePeriod", mock.Anything, lastSettled.AddDate(0, 0, -1)).Return(closed(lastSettled.AddDate(0, 0, -1)), nil).Once()
+		repo.On("ClosePeriod", mock.Anything, lastSettled).Return(closed(lastSettled), nil).Once()
+		s := New(repo, 0)
+		assert.NoError(t, s.ClosePeriods(t.Context()))
+	})
+
+	t.Run("nothing to close", func(t *testing.T) {
+		repo := mocks.NewBalancesRepository(t)
+		repo.On("LatestClosedPeriod", mock.Anything).Return(closed(lastSettled), nil)
+		s := New(repo, 0)
+		assert.NoError(t, s.ClosePeriods(t.Context()))
+	})
+
+	t.Run("closed by another instance", func(t *testing.T) {
+		repo := mocks.NewBalancesRepository(t)
+		repo.On("LatestClosedPeriod", mock.Anything).Return(closed(lastSettled.AddDate(0, 0, -2)), nil)
+		repo.On("ClosePeriod", mock.Anything, lastSettled.AddDate(0, 0, -1)).Return(nil, apierr.PeriodClosed)
+		s := New(repo, 0)
+		assert.NoError(t, s.ClosePeriods(t.Context()))
+	})
+
+	t.Run("stop at the failed day", func(t *testing.T) {
+		repo := mocks.NewBalancesRepository(t)
+		repo.On("LatestClosedPeriod", mock.Anything).Return(closed(lastSettled.AddDate(0, 0, -2)), nil)
+		repo.On("ClosePeriod", mock.Anything, lastSettled.AddDate(0, 0, -1)).Return(nil, errors.New("db down"))
+		s := New(repo, 0)
+		assert.Error(t, s.ClosePeriods(t.Context()))
+	})
+}
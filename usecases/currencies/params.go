@@ -0,0 +1,9 @@
+package currencies
+
+import "github.com/lengzuo/fundflow/internal/apierr"
+
+type ListParams struct{}
+
+func (p ListParams) Validate() apierr.JSON {
+	return nil
+}
@@ -0,0 +1,20 @@
+package currencies
+
+import "net/http"
+
+type Currency struct {
+	Code string `json:"code"`
+	// Numeric is the ISO 4217 numeric code, it is omitted for the custom unit
+	Numeric    string `json:"numeric_code,omitempty"`
+	MinorUnits int    `json:"minor_units"`
+	Name       string `json:"name"`
+	Custom     bool   `json:"custom"`
+}
+
+type ListResponse struct {
+	Data []Currency `json:"data"`
+}
+
+func (r ListResponse) StatusCode() int {
+	return http.StatusOK
+}
@@ -0,0 +1,34 @@
+package currencies
+
+import (
+	"context"
+
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+)
+
+type Service interface {
+	List(ctx context.Context, params ListParams) (*ListResponse, apierr.JSON)
+}
+
+type service struct{}
+
+func New() Service {
+	return &service{}
+}
+
+// List returns the currencies enabled in the deployment, including the custom units.
+func (s *service) List(ctx context.Context, params ListParams) (*ListResponse, apierr.JSON) {
+	values := currency.All()
+	resp := &ListResponse{Data: make([]Currency, 0, len(values))}
+	for _, v := range values {
+		resp.Data = append(resp.Data, Currency{
+			Code:       v.Code,
+			Numeric:    v.Numeric,
+			MinorUnits: v.Precise,
+			Name:       v.Name,
+			Custom:     v.Custom,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,24 @@
+package currencies
+
+import (
+	"testing"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_service_List(t *testing.T) {
+	t.Cleanup(func() {
+		assert.NoError(t, currency.Configure([]string{"SGD", "JPY"}, nil))
+	})
+	err := currency.Configure([]string{"SGD", "USD"}, []currency.Value{{Code: "PTS", Name: "Loyalty points"}})
+	assert.NoError(t, err)
+
+	resp, apiErr := New().List(t.Context(), ListParams{})
+	assert.Nil(t, apiErr)
+	assert.Equal(t, []Currency{
+		{Code: "PTS", MinorUnits: 0, Name: "Loyalty points", Custom: true},
+		{Code: "SGD", Numeric: "702", MinorUnits: 2, Name: "Singapore Dollar"},
+		{Code: "USD", Numeric: "840", MinorUnits: 2, Name: "US Dollar"},
+	}, resp.Data)
+}
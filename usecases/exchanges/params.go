@@ -0,0 +1,58 @@
+package exchanges
+
+import (
+	"strings"
+
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+const maxReferenceLength = 64
+
+type QuoteParams struct {
+	FromCurrency string `json:"from_currency"`
+	ToCurrency   string `json:"to_currency"`
+	// Amount is in from currency
+	Amount money.Amount `json:"amount"`
+}
+
+func (p QuoteParams) Validate() apierr.JSON {
+	if err := currency.Supported(p.FromCurrency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if err := currency.Supported(p.ToCurrency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if p.FromCurrency == p.ToCurrency {
+		return apierr.BadRequest("from_currency and to_currency must be different")
+	}
+	amount, err := p.Amount.Of(p.FromCurrency)
+	if err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if !amount.IsPositive() {
+		return apierr.BadRequest("amount must be greater than zero")
+	}
+	return nil
+}
+
+type ExchangeParams struct {
+	QuoteUID string `json:"quote_uid"`
+	// Receiver is optional, the fund is exchanged into own wallet when it is empty.
+	Receiver  string `json:"receiver"`
+	Reference string `json:"reference"`
+}
+
+func (p ExchangeParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.QuoteUID) == "" {
+		return apierr.BadRequest("quote_uid is mandatory")
+	}
+	if strings.TrimSpace(p.Reference) == "" {
+		return apierr.BadRequest("reference is mandatory")
+	}
+	if len(p.Reference) > maxReferenceLength {
+		return apierr.BadRequest("reference is too long")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package exchanges
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+)
+
+type QuoteResponse struct {
+	UID          string `json:"uid"`
+	FromCurrency string `json:"from_currency"`
+	ToCurrency   string `json:"to_currency"`
+	Rate         string `json:"rate"`
+	FromAmount   int64  `json:"from_amount"`
+	ToAmount     int64  `json:"to_amount"`
+	// FromAmountDecimal and ToAmountDecimal are the amounts in the precision of their currency
+	FromAmountDecimal string    `json:"from_amount_decimal"`
+	ToAmountDecimal   string    `json:"to_amount_decimal"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+func (r QuoteResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+type ExchangeResponse struct {
+	UID          string       `json:"uid"`
+	QuoteUID     string       `json:"quote_uid"`
+	Type         dao.TxType   `json:"type"`
+	Status       dao.TxStatus `json:"status"`
+	Reference    string       `json:"reference"`
+	FromCurrency string       `json:"from_currency"`
+	ToCurrency   string       `json:"to_currency"`
+	Rate         string       `json:"rate"`
+	FromAmount   int64        `json:"from_amount"`
+	ToAmount     int64        `json:"to_amount"`
+	// FromAmountDecimal and ToAmountDecimal are the amounts in the precision of their currency
+	FromAmountDecimal string `json:"from_amount_decimal"`
+	ToAmountDecimal   string `json:"to_amount_decimal"`
+}
+
+func (r ExchangeResponse) StatusCode() int {
+	return http.StatusCreated
+}
@@ -0,0 +1,153 @@
+package exchanges
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/fx"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+type Service interface {
+	Quote(ctx context.Context, params QuoteParams) (*QuoteResponse, apierr.JSON)
+	Exchange(ctx context.Context, params ExchangeParams) (*ExchangeResponse, apierr.JSON)
+}
+
+type service struct {
+	exchanges dao.ExchangesRepository
+	rates     fx.RateProvider
+	// rounding rounds the converted amount into the smallest unit of the target currency
+	rounding money.RoundingMode
+}
+
+func New(exchangesDAO dao.ExchangesRepository, rates fx.RateProvider, rounding money.RoundingMode) Service {
+	return &service{
+		exchanges: exchangesDAO,
+		rates:     rates,
+		rounding:  rounding,
+	}
+}
+
+func authUsername(ctx context.Context) (string, apierr.JSON) {
+	username, ok := ctx.Value(log.UsernameKey).(string)
+	if !ok || username == "" {
+		return "", apierr.Unauthenticated()
+	}
+	return username, nil
+}
+
+// toAPIErr maps the errors returned from dao and fx into the error response of the API.
+func toAPIErr(ctx context.Context, err error) apierr.JSON {
+	switch {
+	case errors.Is(err, apierr.NotFound):
+		return apierr.ResourceNotFound("quote or wallet not found")
+	case errors.Is(err, apierr.InsufficientFund):
+		return apierr.Unprocessable("insufficient fund")
+	case errors.Is(err, apierr.WalletFrozen):
+		return apierr.NewJSON(http.StatusForbidden, apierr.CodeWalletFrozen, "wallet is frozen", err)
+	case errors.Is(err, apierr.WalletClosed):
+		return apierr.NewJSON(http.StatusForbidden, apierr.CodeWalletClosed, "wallet is closed", err)
+	case errors.Is(err, apierr.PeriodClosed):
+		return apierr.NewJSON(http.StatusConflict, apierr.CodePeriodClosed, "accounting period is closed, please try again", err)
+	case errors.Is(err, apierr.LimitExceeded):
+		return apierr.NewJSON(http.StatusUnprocessableEntity, apierr.CodeLimitExceeded, err.Error(), err)
+	case errors.Is(err, dao.ErrQuoteUsed):
+		return apierr.Conflict("quote is already used")
+	case errors.Is(err, dao.ErrQuoteExpired):
+		return apierr.Unprocessable("quote is expired, please request a new quote")
+	case errors.Is(err, fx.ErrRateNotFound):
+		return apierr.Unprocessable("exchange between the currencies is not available")
+	case errors.Is(err, fx.ErrStaleRate):
+		return apierr.Unprocessable("exchange rate is temporarily unavailable, please try again later")
+	case errors.Is(err, fx.ErrAmountTooSmall):
+		return apierr.Unprocessable("amount is too small to be exchanged")
+	case errors.Is(err, money.ErrOverflow):
+		return apierr.Unprocessable("amount is too large to be exchanged")
+	}
+	log.Error(ctx, "failed in exchanges service with err: %s", err)
+	return apierr.InternalServer("please try again")
+}
+
+func (s *service) Quote(ctx context.Context, params QuoteParams) (*QuoteResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	from, err := currency.Get(params.FromCurrency)
+	if err != nil {
+		return nil, apierr.BadRequest(err.Error())
+	}
+	to, err := currency.Get(params.ToCurrency)
+	if err != nil {
+		return nil, apierr.BadRequest(err.Error())
+	}
+	rate, err := s.rates.Rate(ctx, from.Code, to.Code)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	fromAmount, err := params.Amount.Money(from)
+	if err != nil {
+		return nil, apierr.BadRequest(err.Error())
+	}
+	toAmount, err := fx.Convert(fromAmount, rate.Value, to, s.rounding)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	quote := &dao.FXQuotesModel{
+		UID:          utils.UUID(),
+		Username:     username,
+		FromCurrency: from.Code,
+		ToCurrency:   to.Code,
+		Rate:         fx.FormatRate(rate.Value),
+		FromAmount:   fromAmount.Amount(),
+		ToAmount:     toAmount.Amount(),
+		ExpiresAt:    time.Now().UTC().Add(utils.FXQuoteTTL),
+	}
+	err = s.exchanges.InsertQuote(ctx, quote)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &QuoteResponse{
+		UID:               quote.UID,
+		FromCurrency:      quote.FromCurrency,
+		ToCurrency:        quote.ToCurrency,
+		Rate:              quote.Rate,
+		FromAmount:        quote.FromAmount,
+		ToAmount:          quote.ToAmount,
+		FromAmountDecimal: fromAmount.Decimal(),
+		ToAmountDecimal:   toAmount.Decimal(),
+		ExpiresAt:         quote.ExpiresAt,
+	}, nil
+}
+
+func (s *service) Exchange(ctx context.Context, params ExchangeParams) (*ExchangeResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	tx, quote, err := s.exchanges.Exchange(ctx, params.QuoteUID, username, params.Receiver, params.Reference)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &ExchangeResponse{
+		UID:               tx.UID,
+		QuoteUID:          quote.UID,
+		Type:              tx.Type,
+		Status:            tx.Status,
+		Reference:         tx.Reference,
+		FromCurrency:      quote.FromCurrency,
+		ToCurrency:        quote.ToCurrency,
+		Rate:              quote.Rate,
+		FromAmount:        quote.FromAmount,
+		ToAmount:          quote.ToAmount,
+		FromAmountDecimal: money.FormatDecimal(quote.FromAmount, quote.FromCurrency),
+		ToAmountDecimal:   money.FormatDecimal(quote.ToAmount, quote.ToCurrency),
+	}, nil
+}
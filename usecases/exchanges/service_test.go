@@ -0,0 +1,151 @@
+package exchanges
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/fx"
+	fxmocks "github.com/lengzuo/fundflow/internal/fx/mocks"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func authCtx(t *testing.T, username string) context.Context {
+	return context.WithValue(t.Context(), log.UsernameKey, username)
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "quote ok", params: QuoteParams{FromCurrency: "SGD", ToCurrency: "JPY", Amount: money.MinorUnits(100)}},
+		{name: "quote same currency", params: QuoteParams{FromCurrency: "SGD", ToCurrency: "SGD", Amount: money.MinorUnits(100)}, wantErr: true},
+		{name: "quote unsupported currency", params: QuoteParams{FromCurrency: "SGD", ToCurrency: "USD", Amount: money.MinorUnits(100)}, wantErr: true},
+		{name: "quote decimal amount", params: QuoteParams{FromCurrency: "SGD", ToCurrency: "JPY", Amount: money.DecimalAmount("10.01")}},
+		{name: "quote too many decimals", params: QuoteParams{FromCurrency: "SGD", ToCurrency: "JPY", Amount: money.DecimalAmount("10.001")}, wantErr: true},
+		{name: "quote zero amount", params: QuoteParams{FromCurrency: "SGD", ToCurrency: "JPY"}, wantErr: true},
+		{name: "exchange ok", params: ExchangeParams{QuoteUID: "quote", Reference: "ref"}},
+		{name: "exchange missing quote", params: ExchangeParams{Reference: "ref"}, wantErr: true},
+		{name: "exchange missing reference", params: ExchangeParams{QuoteUID: "quote"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_Quote(t *testing.T) {
+	t.Run("ok quote sgd to jpy", func(t *testing.T) {
+		rates := fxmocks.NewRateProvider(t)
+		rates.On("Rate", mock.Anything, "SGD", "JPY").Return(&fx.Rate{Value: big.NewRat(11025, 100)}, nil)
+		repo := mocks.NewExchangesRepository(t)
+		repo.On("InsertQuote", mock.Anything, mock.MatchedBy(func(q *dao.FXQuotesModel) bool {
+			return q.Username == "user1" && q.FromAmount == 1001 && q.ToAmount == 1103 && q.Rate == "110.250000000000" && q.ExpiresAt.After(time.Now())
+		})).Return(nil)
+		s := New(repo, rates, money.RoundDown)
+		resp, err := s.Quote(authCtx(t, "user1"), QuoteParams{FromCurrency: "SGD", ToCurrency: "JPY", Amount: money.MinorUnits(1001)})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1103), resp.ToAmount)
+		assert.NotEmpty(t, resp.UID)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	})
+
+	t.Run("ok quote decimal amount rounded half up", func(t *testing.T) {
+		rates := fxmocks.NewRateProvider(t)
+		rates.On("Rate", mock.Anything, "SGD", "JPY").Return(&fx.Rate{Value: big.NewRat(11025, 100)}, nil)
+		repo := mocks.NewExchangesRepository(t)
+		repo.On("InsertQuote", mock.Anything, mock.MatchedBy(func(q *dao.FXQuotesModel) bool {
+			return q.FromAmount == 1001 && q.ToAmount == 1104
+		})).Return(nil)
+		s := New(repo, rates, money.RoundHalfUp)
+		resp, err := s.Quote(authCtx(t, "user1"), QuoteParams{FromCurrency: "SGD", ToCurrency: "JPY", Amount: money.DecimalAmount("10.01")})
+		assert.Nil(t, err)
+		assert.Equal(t, "10.01", resp.FromAmountDecimal)
+		assert.Equal(t, "1104", resp.ToAmountDecimal)
+	})
+
+	t.Run("quote rate not available", func(t *testing.T) {
+		rates := fxmocks.NewRateProvider(t)
+		rates.On("Rate", mock.Anything, "SGD", "JPY").Return(nil, fx.ErrRateNotFound)
+		s := New(mocks.NewExchangesRepository(t), rates, money.RoundDown)
+		resp, err := s.Quote(authCtx(t, "user1"), QuoteParams{FromCurrency: "SGD", ToCurrency: "JPY", Amount: money.MinorUnits(1001)})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnprocessableEntity, err.HTTPStatusCode())
+	})
+
+	t.Run("quote stale rate", func(t *testing.T) {
+		rates := fxmocks.NewRateProvider(t)
+		rates.On("Rate", mock.Anything, "SGD", "JPY").Return(nil, fmt.Errorf("%w: SGD/JPY", fx.ErrStaleRate))
+		s := New(mocks.NewExchangesRepository(t), rates, money.RoundDown)
+		resp, err := s.Quote(authCtx(t, "user1"), QuoteParams{FromCurrency: "SGD", ToCurrency: "JPY", Amount: money.MinorUnits(1001)})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnprocessableEntity, err.HTTPStatusCode())
+	})
+
+	t.Run("quote amount too small", func(t *testing.T) {
+		rates := fxmocks.NewRateProvider(t)
+		rates.On("Rate", mock.Anything, "JPY", "SGD").Return(&fx.Rate{Value: big.NewRat(9, 1000)}, nil)
+		s := New(mocks.NewExchangesRepository(t), rates, money.RoundDown)
+		resp, err := s.Quote(authCtx(t, "user1"), QuoteParams{FromCurrency: "JPY", ToCurrency: "SGD", Amount: money.MinorUnits(1)})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnprocessableEntity, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Exchange(t *testing.T) {
+	t.Run("ok exchange", func(t *testing.T) {
+		repo := mocks.NewExchangesRepository(t)
+		repo.On("Exchange", mock.Anything, "quote", "user1", "user2", "ref").Return(
+			&dao.TransactionsModel{UID: "tx", Type: dao.TypeExchange, Status: dao.StatusCompleted, Reference: "ref"},
+			&dao.FXQuotesModel{UID: "quote", FromCurrency: "SGD", ToCurrency: "JPY", Rate: "110.25", FromAmount: 1000, ToAmount: 1102},
+			nil,
+		)
+		s := New(repo, fxmocks.NewRateProvider(t), money.RoundDown)
+		resp, err := s.Exchange(authCtx(t, "user1"), ExchangeParams{QuoteUID: "quote", Receiver: "user2", Reference: "ref"})
+		assert.Nil(t, err)
+		assert.Equal(t, "tx", resp.UID)
+		assert.Equal(t, int64(1102), resp.ToAmount)
+	})
+
+	tests := []struct {
+		name       string
+		daoErr     error
+		wantStatus int
+	}{
+		{name: "exchange used quote", daoErr: dao.ErrQuoteUsed, wantStatus: http.StatusConflict},
+		{name: "exchange expired quote", daoErr: dao.ErrQuoteExpired, wantStatus: http.StatusUnprocessableEntity},
+		{name: "exchange quote not found", daoErr: apierr.NotFound, wantStatus: http.StatusNotFound},
+		{name: "exchange insufficient fund", daoErr: apierr.InsufficientFund, wantStatus: http.StatusUnprocessableEntity},
+		{name: "exchange over max balance", daoErr: apierr.LimitExceeded, wantStatus: http.StatusUnprocessableEntity},
+		{name: "exchange unexpected error", daoErr: errors.New("err"), wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewExchangesRepository(t)
+			repo.On("Exchange", mock.Anything, "quote", "user1", "", "ref").Return(nil, nil, tt.daoErr)
+			s := New(repo, fxmocks.NewRateProvider(t), money.RoundDown)
+			resp, err := s.Exchange(authCtx(t, "user1"), ExchangeParams{QuoteUID: "quote", Reference: "ref"})
+			assert.Nil(t, resp)
+			assert.Equal(t, tt.wantStatus, err.HTTPStatusCode())
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package holds
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+const (
+	defaultExpiresIn   = 15 * time.Minute
+	maxExpiresIn       = 7 * 24 * time.Hour
+	maxReferenceLength = 64
+)
+
+type AuthorizeParams struct {
+	// Receiver is optional, the captured fund is only debited from the holder when it is empty.
+	Receiver  string       `json:"receiver"`
+	Currency  string       `json:"currency"`
+	Amount    money.Amount `json:"amount"`
+	Reference string       `json:"reference"`
+	// ExpiresIn is the number of seconds before the hold is auto voided.
+	ExpiresIn int `json:"expires_in"`
+}
+
+func (p AuthorizeParams) Validate() apierr.JSON {
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	amount, err := p.Amount.Of(p.Currency)
+	if err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if !amount.IsPositive() {
+		return apierr.BadRequest("amount must be greater than zero")
+	}
+	if strings.TrimSpace(p.Reference) == "" {
+		return apierr.BadRequest("reference is mandatory")
+	}
+	if len(p.Reference) > maxReferenceLength {
+		return apierr.BadRequest("reference is too long")
+	}
+	if p.ExpiresIn < 0 || time.Duration(p.ExpiresIn)*time.Second > maxExpiresIn {
+		return apierr.BadRequest("expires_in must be between 1 and 604800 seconds")
+	}
+	return nil
+}
+
+type CaptureParams struct {
+	UID string `json:"uid"`
+	// Amount is optional in the smallest unit or a decimal string of the hold currency, the whole hold is captured when it is zero.
+	Amount money.Amount `json:"amount"`
+}
+
+func (p CaptureParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	if p.Amount.IsNegative() {
+		return apierr.BadRequest("amount must be greater than zero")
+	}
+	return nil
+}
+
+type VoidParams struct {
+	UID string `json:"uid"`
+}
+
+func (p VoidParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	return nil
+}
+
+type GetParams struct {
+	UID string `schema:"uid"`
+}
+
+func (p GetParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	return nil
+}
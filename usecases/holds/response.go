@@ -0,0 +1,34 @@
+package holds
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+)
+
+type HoldResponse struct {
+	UID            string `json:"uid"`
+	Username       string `json:"username"`
+	Receiver       string `json:"receiver,omitempty"`
+	Currency       string `json:"currency"`
+	Amount         int64  `json:"amount"`
+	CapturedAmount int64  `json:"captured_amount"`
+	// AmountDecimal and CapturedAmountDecimal are the amounts in the precision of the currency
+	AmountDecimal         string         `json:"amount_decimal"`
+	CapturedAmountDecimal string         `json:"captured_amount_decimal"`
+	Status                dao.HoldStatus `json:"status"`
+	ExpiresAt             time.Time      `json:"expires_at"`
+}
+
+func (r HoldResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type AuthorizeResponse struct {
+	HoldResponse
+}
+
+func (r AuthorizeResponse) StatusCode() int {
+	return http.StatusCreated
+}
@@ -0,0 +1,174 @@
+package holds
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+// expireBatchSize limits the number of holds released in a single expiry run.
+const expireBatchSize = 100
+
+type Service interface {
+	Authorize(ctx context.Context, params AuthorizeParams) (*AuthorizeResponse, apierr.JSON)
+	Capture(ctx context.Context, params CaptureParams) (*HoldResponse, apierr.JSON)
+	Void(ctx context.Context, params VoidParams) (*HoldResponse, apierr.JSON)
+	Get(ctx context.Context, params GetParams) (*HoldResponse, apierr.JSON)
+	ExpireHolds(ctx context.Context) error
+}
+
+type service struct {
+	holds dao.HoldsRepository
+}
+
+func New(holdsDAO dao.HoldsRepository) Service {
+	return &service{
+		holds: holdsDAO,
+	}
+}
+
+func authUsername(ctx context.Context) (string, apierr.JSON) {
+	username, ok := ctx.Value(log.UsernameKey).(string)
+	if !ok || username == "" {
+		return "", apierr.Unauthenticated()
+	}
+	return username, nil
+}
+
+// toAPIErr maps the errors returned from dao into the error response of the API.
+func toAPIErr(ctx context.Context, err error) apierr.JSON {
+	switch {
+	case errors.Is(err, apierr.NotFound):
+		return apierr.ResourceNotFound("hold or wallet not found")
+	case errors.Is(err, apierr.InsufficientFund):
+		return apierr.Unprocessable("insufficient fund")
+	case errors.Is(err, apierr.WalletFrozen):
+		return apierr.NewJSON(http.StatusForbidden, apierr.CodeWalletFrozen, "wallet is frozen", err)
+	case errors.Is(err, apierr.WalletClosed):
+		return apierr.NewJSON(http.StatusForbidden, apierr.CodeWalletClosed, "wallet is closed", err)
+	case errors.Is(err, apierr.PeriodClosed):
+		return apierr.NewJSON(http.StatusConflict, apierr.CodePeriodClosed, "accounting period is closed, please try again", err)
+	case errors.Is(err, apierr.LimitExceeded):
+		return apierr.NewJSON(http.StatusUnprocessableEntity, apierr.CodeLimitExceeded, err.Error(), err)
+	case errors.Is(err, money.ErrOverflow):
+		return apierr.Unprocessable("amount is too large for the wallet balance")
+	case errors.Is(err, dao.ErrHoldNotAuthorized):
+		return apierr.Conflict("hold is already captured, voided or expired")
+	case errors.Is(err, dao.ErrHoldExpired):
+		return apierr.Conflict("hold is expired")
+	case errors.Is(err, dao.ErrNotHoldReceiver):
+		return apierr.Forbidden("only the receiver is able to capture or void the hold")
+	case errors.Is(err, dao.ErrCaptureExceedHold):
+		return apierr.Unprocessable("capture amount exceed the authorized amount")
+	}
+	log.Error(ctx, "failed in holds service with err: %s", err)
+	return apierr.InternalServer("please try again")
+}
+
+func toHoldResponse(hold *dao.HoldsModel) *HoldResponse {
+	return &HoldResponse{
+		UID:                   hold.TxUID,
+		Username:              hold.Username,
+		Receiver:              hold.Receiver,
+		Currency:              hold.Currency,
+		Amount:                hold.Amount,
+		CapturedAmount:        hold.CapturedAmount,
+		AmountDecimal:         money.FormatDecimal(hold.Amount, hold.Currency),
+		CapturedAmountDecimal: money.FormatDecimal(hold.CapturedAmount, hold.Currency),
+		Status:                hold.Status,
+		ExpiresAt:             hold.ExpiresAt,
+	}
+}
+
+func (s *service) Authorize(ctx context.Context, params AuthorizeParams) (*AuthorizeResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	if username == params.Receiver {
+		return nil, apierr.BadRequest("unable to hold fund for own wallet")
+	}
+	expiresIn := defaultExpiresIn
+	if params.ExpiresIn > 0 {
+		expiresIn = time.Duration(params.ExpiresIn) * time.Second
+	}
+	amount, err := params.Amount.Of(params.Currency)
+	if err != nil {
+		return nil, apierr.BadRequest(err.Error())
+	}
+	hold, err := s.holds.Authorize(ctx, username, params.Receiver, params.Reference, amount, time.Now().UTC().Add(expiresIn))
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &AuthorizeResponse{HoldResponse: *toHoldResponse(hold)}, nil
+}
+
+func (s *service) Capture(ctx context.Context, params CaptureParams) (*HoldResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	var amount int64
+	if !params.Amount.IsZero() {
+		// The amount is in the currency of the hold
+		hold, err := s.holds.Get(ctx, params.UID, username)
+		if err != nil {
+			return nil, toAPIErr(ctx, err)
+		}
+		captured, err := params.Amount.Of(hold.Currency)
+		if err != nil {
+			return nil, apierr.BadRequest(err.Error())
+		}
+		if captured.IsNegative() {
+			return nil, apierr.BadRequest("amount must be greater than zero")
+		}
+		amount = captured.Amount()
+	}
+	hold, err := s.holds.Capture(ctx, params.UID, username, amount)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return toHoldResponse(hold), nil
+}
+
+func (s *service) Void(ctx context.Context, params VoidParams) (*HoldResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	hold, err := s.holds.Void(ctx, params.UID, username)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return toHoldResponse(hold), nil
+}
+
+func (s *service) Get(ctx context.Context, params GetParams) (*HoldResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	hold, err := s.holds.Get(ctx, params.UID, username)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return toHoldResponse(hold), nil
+}
+
+// ExpireHolds releases the stale authorized holds, it is meant to be run periodically by a worker.
+func (s *service) ExpireHolds(ctx context.Context) error {
+	expired, err := s.holds.ExpireDue(ctx, expireBatchSize)
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		log.Info(ctx, "expired %d holds", expired)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package holds
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func authCtx(t *testing.T, username string) context.Context {
+	return context.WithValue(t.Context(), log.UsernameKey, username)
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "authorize ok", params: AuthorizeParams{Receiver: "merchant", Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref", ExpiresIn: 60}},
+		{name: "authorize ok without receiver", params: AuthorizeParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"}},
+		{name: "authorize unsupported currency", params: AuthorizeParams{Currency: "USD", Amount: money.MinorUnits(100), Reference: "ref"}, wantErr: true},
+		{name: "authorize decimal amount", params: AuthorizeParams{Currency: "SGD", Amount: money.DecimalAmount("1.50"), Reference: "ref"}},
+		{name: "authorize too many decimals", params: AuthorizeParams{Currency: "JPY", Amount: money.DecimalAmount("1.50"), Reference: "ref"}, wantErr: true},
+		{name: "authorize zero amount", params: AuthorizeParams{Currency: "SGD", Reference: "ref"}, wantErr: true},
+		{name: "authorize empty reference", params: AuthorizeParams{Currency: "SGD", Amount: money.MinorUnits(100)}, wantErr: true},
+		{name: "authorize expires too late", params: AuthorizeParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref", ExpiresIn: 604801}, wantErr: true},
+		{name: "capture ok", params: CaptureParams{UID: "uid"}},
+		{name: "capture decimal amount", params: CaptureParams{UID: "uid", Amount: money.DecimalAmount("12.34")}},
+		{name: "capture missing uid", params: CaptureParams{Amount: money.MinorUnits(10)}, wantErr: true},
+		{name: "capture negative amount", params: CaptureParams{UID: "uid", Amount: money.MinorUnits(-1)}, wantErr: true},
+		{name: "capture negative decimal amount", params: CaptureParams{UID: "uid", Amount: money.DecimalAmount("-0.01")}, wantErr: true},
+		{name: "void missing uid", params: VoidParams{}, wantErr: true},
+		{name: "get missing uid", params: GetParams{UID: " "}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_Authorize(t *testing.T) {
+	t.Run("ok authorize", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("Authorize", mock.Anything, "user1", "merchant", "ref", money.New(100, currency.SGD), mock.MatchedBy(func(expiresAt time.Time) bool {
+			return expiresAt.After(time.Now().Add(59*time.Second)) && expiresAt.Before(time.Now().Add(61*time.Second))
+		})).Return(&dao.HoldsModel{TxUID: "uid", Username: "user1", Receiver: "merchant", Currency: "SGD", Amount: 100, Status: dao.HoldStatusAuthorized}, nil)
+		s := New(holdsRepo)
+		resp, err := s.Authorize(authCtx(t, "user1"), AuthorizeParams{Receiver: "merchant", Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref", ExpiresIn: 60})
+		assert.Nil(t, err)
+		assert.Equal(t, "uid", resp.UID)
+		assert.Equal(t, dao.HoldStatusAuthorized, resp.Status)
+		assert.Equal(t, "1.00", resp.AmountDecimal)
+		assert.Equal(t, "0.00", resp.CapturedAmountDecimal)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	})
+
+	t.Run("authorize for own wallet", func(t *testing.T) {
+		s := New(mocks.NewHoldsRepository(t))
+		resp, err := s.Authorize(authCtx(t, "user1"), AuthorizeParams{Receiver: "user1", Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+	})
+
+	t.Run("authorize insufficient available balance", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("Authorize", mock.Anything, "user1", "", "ref", money.New(100, currency.SGD), mock.Anything).Return(nil, apierr.InsufficientFund)
+		s := New(holdsRepo)
+		resp, err := s.Authorize(authCtx(t, "user1"), AuthorizeParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnprocessableEntity, err.HTTPStatusCode())
+	})
+
+	t.Run("authorize without auth", func(t *testing.T) {
+		s := New(mocks.NewHoldsRepository(t))
+		resp, err := s.Authorize(t.Context(), AuthorizeParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Capture(t *testing.T) {
+	t.Run("ok capture", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("Get", mock.Anything, "uid", "merchant").Return(&dao.HoldsModel{TxUID: "uid", Currency: "SGD", Amount: 100, Status: dao.HoldStatusAuthorized}, nil)
+		holdsRepo.On("Capture", mock.Anything, "uid", "merchant", int64(80)).Return(&dao.HoldsModel{TxUID: "uid", Amount: 100, CapturedAmount: 80, Status: dao.HoldStatusCaptured}, nil)
+		s := New(holdsRepo)
+		resp, err := s.Capture(authCtx(t, "merchant"), CaptureParams{UID: "uid", Amount: money.MinorUnits(80)})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(80), resp.CapturedAmount)
+		assert.Equal(t, dao.HoldStatusCaptured, resp.Status)
+	})
+
+	t.Run("ok capture decimal amount in hold currency", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("Get", mock.Anything, "uid", "merchant").Return(&dao.HoldsModel{TxUID: "uid", Currency: "SGD", Amount: 2000, Status: dao.HoldStatusAuthorized}, nil)
+		holdsRepo.On("Capture", mock.Anything, "uid", "merchant", int64(1234)).Return(&dao.HoldsModel{TxUID: "uid", Currency: "SGD", Amount: 2000, CapturedAmount: 1234, Status: dao.HoldStatusCaptured}, nil)
+		s := New(holdsRepo)
+		resp, err := s.Capture(authCtx(t, "merchant"), CaptureParams{UID: "uid", Amount: money.DecimalAmount("12.34")})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1234), resp.CapturedAmount)
+	})
+
+	t.Run("capture with too many decimals for hold currency", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("Get", mock.Anything, "uid", "merchant").Return(&dao.HoldsModel{TxUID: "uid", Currency: "JPY", Amount: 2000, Status: dao.HoldStatusAuthorized}, nil)
+		s := New(holdsRepo)
+		resp, err := s.Capture(authCtx(t, "merchant"), CaptureParams{UID: "uid", Amount: money.DecimalAmount("12.34")})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+	})
+
+	t.Run("capture amount of unknown hold", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("Get", mock.Anything, "uid", "merchant").Return(nil, apierr.NotFound)
+		s := New(holdsRepo)
+		resp, err := s.Capture(authCtx(t, "merchant"), CaptureParams{UID: "uid", Amount: money.DecimalAmount("12.34")})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	})
+
+	tests := []struct {
+		name       string
+		daoErr     error
+		wantStatus int
+	}{
+		{name: "capture not found", daoErr: apierr.NotFound, wantStatus: http.StatusNotFound},
+		{name: "capture voided hold", daoErr: dao.ErrHoldNotAuthorized, wantStatus: http.StatusConflict},
+		{name: "capture expired hold", daoErr: dao.ErrHoldExpired, wantStatus: http.StatusConflict},
+		{name: "capture exceed hold", daoErr: dao.ErrCaptureExceedHold, wantStatus: http.StatusUnprocessableEntity},
+		{name: "capture by payer of receiver hold", daoErr: dao.ErrNotHoldReceiver, wantStatus: http.StatusForbidden},
+		{name: "capture into frozen wallet", daoErr: apierr.WalletFrozen, wantStatus: http.StatusForbidden},
+		{name: "capture over max balance of receiver", daoErr: apierr.LimitExceeded, wantStatus: http.StatusUnprocessableEntity},
+		{name: "capture unexpected error", daoErr: errors.New("err"), wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holdsRepo := mocks.NewHoldsRepository(t)
+			holdsRepo.On("Capture", mock.Anything, "uid", "merchant", int64(0)).Return(nil, tt.daoErr)
+			s := New(holdsRepo)
+			resp, err := s.Capture(authCtx(t, "merchant"), CaptureParams{UID: "uid"})
+			assert.Nil(t, resp)
+			assert.Equal(t, tt.wantStatus, err.HTTPStatusCode())
+		})
+	}
+}
+
+func Test_service_Void(t *testing.T) {
+	t.Run("ok void", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("Void", mock.Anything, "uid", "user1").Return(&dao.HoldsModel{TxUID: "uid", Amount: 100, Status: dao.HoldStatusVoided}, nil)
+		s := New(holdsRepo)
+		resp, err := s.Void(authCtx(t, "user1"), VoidParams{UID: "uid"})
+		assert.Nil(t, err)
+		assert.Equal(t, dao.HoldStatusVoided, resp.Status)
+	})
+
+	t.Run("void captured hold", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("Void", mock.Anything, "uid", "user1").Return(nil, dao.ErrHoldNotAuthorized)
+		s := New(holdsRepo)
+		resp, err := s.Void(authCtx(t, "user1"), VoidParams{UID: "uid"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusConflict, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Get(t *testing.T) {
+	t.Run("ok get", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("Get", mock.Anything, "uid", "user1").Return(&dao.HoldsModel{TxUID: "uid", Amount: 100, Status: dao.HoldStatusAuthorized}, nil)
+		s := New(holdsRepo)
+		resp, err := s.Get(authCtx(t, "user1"), GetParams{UID: "uid"})
+		assert.Nil(t, err)
+		assert.Equal(t, "uid", resp.UID)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+	})
+}
+
+func Test_service_ExpireHolds(t *testing.T) {
+	t.Run("ok expire holds", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("ExpireDue", mock.Anything, expireBatchSize).Return(2, nil)
+		s := New(holdsRepo)
+		assert.NoError(t, s.ExpireHolds(t.Context()))
+	})
+
+	t.Run("expire holds error", func(t *testing.T) {
+		holdsRepo := mocks.NewHoldsRepository(t)
+		holdsRepo.On("ExpireDue", mock.Anything, expireBatchSize).Return(0, errors.New("err"))
+		s := New(holdsRepo)
+		assert.Error(t, s.ExpireHolds(t.Context()))
+	})
+}
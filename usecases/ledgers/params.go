@@ -0,0 +1,22 @@
+package ledgers
+
+import (
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+)
+
+// VerifyParams filters the wallets to verify, every wallet is verified when both are empty.
+type VerifyParams struct {
+	Username string `schema:"username"`
+	Currency string `schema:"currency"`
+}
+
+func (p VerifyParams) Validate() apierr.JSON {
+	if p.Currency == "" {
+		return nil
+	}
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package ledgers
+
+import (
+	"net/http"
+
+	"github.com/lengzuo/fundflow/dao"
+)
+
+type BrokenLink struct {
+	LedgerID     int                  `json:"ledger_id"`
+	TxUID        string               `json:"tx_uid"`
+	Reason       dao.BrokenLinkReason `json:"reason"`
+	ExpectedHash string               `json:"expected_hash"`
+	ActualHash   string               `json:"actual_hash"`
+}
+
+type Chain struct {
+	Username string `json:"username"`
+	Currency string `json:"currency"`
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`
+	// Unchained entries were inserted before the hash chain was introduced
+	Unchained  int         `json:"unchained"`
+	BrokenLink *BrokenLink `json:"broken_link,omitempty"`
+}
+
+type VerifyResponse struct {
+	// Valid is false when the chain of any wallet is broken
+	Valid   bool    `json:"valid"`
+	Wallets []Chain `json:"wallets"`
+}
+
+func (r VerifyResponse) StatusCode() int {
+	return http.StatusOK
+}
@@ -0,0 +1,54 @@
+package ledgers
+
+import (
+	"context"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+type Service interface {
+	Verify(ctx context.Context, params VerifyParams) (*VerifyResponse, apierr.JSON)
+}
+
+type service struct {
+	ledgers dao.LedgersRepository
+}
+
+func New(ledgersDAO dao.LedgersRepository) Service {
+	return &service{
+		ledgers: ledgersDAO,
+	}
+}
+
+// Verify walks the ledger hash chain of the wallets and reports the first broken link of each wallet.
+func (s *service) Verify(ctx context.Context, params VerifyParams) (*VerifyResponse, apierr.JSON) {
+	results, err := s.ledgers.VerifyChains(ctx, params.Username, params.Currency)
+	if err != nil {
+		log.Error(ctx, "failed in verify ledger chains with err: %s", err)
+		return nil, apierr.InternalServer("please try again")
+	}
+	resp := &VerifyResponse{Valid: true, Wallets: make([]Chain, 0, len(results))}
+	for _, result := range results {
+		chain := Chain{
+			Username:  result.Username,
+			Currency:  result.Currency,
+			Valid:     result.BrokenLink == nil,
+			Checked:   result.Checked,
+			Unchained: result.Unchained,
+		}
+		if result.BrokenLink != nil {
+			resp.Valid = false
+			chain.BrokenLink = &BrokenLink{
+				LedgerID:     result.BrokenLink.LedgerID,
+				TxUID:        result.BrokenLink.TxUID,
+				Reason:       result.BrokenLink.Reason,
+				ExpectedHash: result.BrokenLink.ExpectedHash,
+				ActualHash:   result.BrokenLink.ActualHash,
+			}
+		}
+		resp.Wallets = append(resp.Wallets, chain)
+	}
+	return resp, nil
+}
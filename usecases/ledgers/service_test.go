@@ -0,0 +1,70 @@
+package ledgers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "verify every wallet", params: VerifyParams{}},
+		{name: "verify wallet", params: VerifyParams{Username: "user1", Currency: "SGD"}},
+		{name: "verify unsupported currency", params: VerifyParams{Currency: "USD"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_Verify(t *testing.T) {
+	t.Run("broken chain", func(t *testing.T) {
+		repo := mocks.NewLedgersRepository(t)
+		repo.On("VerifyChains", mock.Anything, "", "").Return([]dao.ChainVerificationModel{
+			{Username: "user1", Currency: "JPY", Checked: 2},
+			{Username: "user1", Currency: "SGD", Checked: 3, BrokenLink: &dao.BrokenLinkModel{LedgerID: 7, TxUID: "tx", Reason: dao.ReasonHashMismatch, ExpectedHash: "a", ActualHash: "b"}},
+		}, nil)
+		resp, err := New(repo).Verify(t.Context(), VerifyParams{})
+		assert.Nil(t, err)
+		assert.False(t, resp.Valid)
+		assert.True(t, resp.Wallets[0].Valid)
+		assert.Nil(t, resp.Wallets[0].BrokenLink)
+		assert.False(t, resp.Wallets[1].Valid)
+		assert.Equal(t, 7, resp.Wallets[1].BrokenLink.LedgerID)
+		assert.Equal(t, dao.ReasonHashMismatch, resp.Wallets[1].BrokenLink.Reason)
+	})
+
+	t.Run("valid chain", func(t *testing.T) {
+		repo := mocks.NewLedgersRepository(t)
+		repo.On("VerifyChains", mock.Anything, "user1", "SGD").Return([]dao.ChainVerificationModel{{Username: "user1", Currency: "SGD", Checked: 3}}, nil)
+		resp, err := New(repo).Verify(t.Context(), VerifyParams{Username: "user1", Currency: "SGD"})
+		assert.Nil(t, err)
+		assert.True(t, resp.Valid)
+	})
+
+	t.Run("verify error", func(t *testing.T) {
+		repo := mocks.NewLedgersRepository(t)
+		repo.On("VerifyChains", mock.Anything, "", "").Return(nil, errors.New("err"))
+		resp, err := New(repo).Verify(t.Context(), VerifyParams{})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, err.HTTPStatusCode())
+	})
+}
@@ -0,0 +1,53 @@
+package limits
+
+import (
+	"strings"
+
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+)
+
+type UsageParams struct {
+	Currency string `schema:"currency"`
+}
+
+func (p UsageParams) Validate() apierr.JSON {
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	return nil
+}
+
+// SetParams replaces all the limits of the user, the limit is unlimited when it is zero.
+type SetParams struct {
+	// Username is optional, empty username sets the default limits of every user without own limits
+	Username        string `json:"username"`
+	Currency        string `json:"currency"`
+	PerTransaction  int64  `json:"per_transaction"`
+	DailyWithdraw   int64  `json:"daily_withdraw"`
+	MonthlyWithdraw int64  `json:"monthly_withdraw"`
+	DailyTransfer   int64  `json:"daily_transfer"`
+	MonthlyTransfer int64  `json:"monthly_transfer"`
+	MaxBalance      int64  `json:"max_balance"`
+}
+
+func (p SetParams) Validate() apierr.JSON {
+	if p.Username != strings.TrimSpace(p.Username) {
+		return apierr.BadRequest("username must not have leading or trailing spaces")
+	}
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	for _, limit := range []int64{p.PerTransaction, p.DailyWithdraw, p.MonthlyWithdraw, p.DailyTransfer, p.MonthlyTransfer, p.MaxBalance} {
+		if limit < 0 {
+			return apierr.BadRequest("limit must not be negative")
+		}
+	}
+	if p.DailyWithdraw > 0 && p.MonthlyWithdraw > 0 && p.DailyWithdraw > p.MonthlyWithdraw {
+		return apierr.BadRequest("daily_withdraw must not be greater than monthly_withdraw")
+	}
+	if p.DailyTransfer > 0 && p.MonthlyTransfer > 0 && p.DailyTransfer > p.MonthlyTransfer {
+		return apierr.BadRequest("daily_transfer must not be greater than monthly_transfer")
+	}
+	return nil
+}
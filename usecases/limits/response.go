@@ -0,0 +1,54 @@
+package limits
+
+import (
+	"net/http"
+	"time"
+)
+
+// Usage is the amount used against a limit, Remaining is null when the limit is zero (unlimited).
+type Usage struct {
+	Limit     int64  `json:"limit"`
+	Used      int64  `json:"used"`
+	Remaining *int64 `json:"remaining"`
+}
+
+func newUsage(limit, used int64) Usage {
+	usage := Usage{Limit: limit, Used: used}
+	if limit > 0 {
+		remaining := max(limit-used, 0)
+		usage.Remaining = &remaining
+	}
+	return usage
+}
+
+type UsageResponse struct {
+	Currency        string `json:"currency"`
+	PerTransaction  int64  `json:"per_transaction"`
+	DailyWithdraw   Usage  `json:"daily_withdraw"`
+	MonthlyWithdraw Usage  `json:"monthly_withdraw"`
+	DailyTransfer   Usage  `json:"daily_transfer"`
+	MonthlyTransfer Usage  `json:"monthly_transfer"`
+	// MaxBalance is used by the current wallet balance
+	MaxBalance Usage `json:"max_balance"`
+}
+
+func (r UsageResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type LimitsResponse struct {
+	Username        string    `json:"username"`
+	Currency        string    `json:"currency"`
+	PerTransaction  int64     `json:"per_transaction"`
+	DailyWithdraw   int64     `json:"daily_withdraw"`
+	MonthlyWithdraw int64     `json:"monthly_withdraw"`
+	DailyTransfer   int64     `json:"daily_transfer"`
+	MonthlyTransfer int64     `json:"monthly_transfer"`
+	MaxBalance      int64     `json:"max_balance"`
+	UpdatedBy       string    `json:"updated_by"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (r LimitsResponse) StatusCode() int {
+	return http.StatusOK
+}
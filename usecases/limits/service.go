@@ -0,0 +1,95 @@
+package limits
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+type Service interface {
+	Usage(ctx context.Context, params UsageParams) (*UsageResponse, apierr.JSON)
+	Set(ctx context.Context, params SetParams) (*LimitsResponse, apierr.JSON)
+}
+
+type service struct {
+	limits dao.LimitsRepository
+}
+
+func New(limitsDAO dao.LimitsRepository) Service {
+	return &service{
+		limits: limitsDAO,
+	}
+}
+
+func authUsername(ctx context.Context) (string, apierr.JSON) {
+	username, ok := ctx.Value(log.UsernameKey).(string)
+	if !ok || username == "" {
+		return "", apierr.Unauthenticated()
+	}
+	return username, nil
+}
+
+// toAPIErr maps the errors returned from dao into the error response of the API.
+func toAPIErr(ctx context.Context, err error) apierr.JSON {
+	if errors.Is(err, apierr.NotFound) {
+		return apierr.ResourceNotFound("wallet not found")
+	}
+	log.Error(ctx, "failed in limits service with err: %s", err)
+	return apierr.InternalServer("please try again")
+}
+
+func (s *service) Usage(ctx context.Context, params UsageParams) (*UsageResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	limits, usage, err := s.limits.Usage(ctx, username, params.Currency)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &UsageResponse{
+		Currency:        params.Currency,
+		PerTransaction:  limits.PerTransaction,
+		DailyWithdraw:   newUsage(limits.DailyWithdraw, usage.DailyWithdraw),
+		MonthlyWithdraw: newUsage(limits.MonthlyWithdraw, usage.MonthlyWithdraw),
+		DailyTransfer:   newUsage(limits.DailyTransfer, usage.DailyTransfer),
+		MonthlyTransfer: newUsage(limits.MonthlyTransfer, usage.MonthlyTransfer),
+		MaxBalance:      newUsage(limits.MaxBalance, usage.Balance),
+	}, nil
+}
+
+func (s *service) Set(ctx context.Context, params SetParams) (*LimitsResponse, apierr.JSON) {
+	admin, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	limits, err := s.limits.Set(ctx, dao.LimitsModel{
+		Username:        params.Username,
+		Currency:        params.Currency,
+		PerTransaction:  params.PerTransaction,
+		DailyWithdraw:   params.DailyWithdraw,
+		MonthlyWithdraw: params.MonthlyWithdraw,
+		DailyTransfer:   params.DailyTransfer,
+		MonthlyTransfer: params.MonthlyTransfer,
+		MaxBalance:      params.MaxBalance,
+		UpdatedBy:       admin,
+	})
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &LimitsResponse{
+		Username:        limits.Username,
+		Currency:        limits.Currency,
+		PerTransaction:  limits.PerTransaction,
+		DailyWithdraw:   limits.DailyWithdraw,
+		MonthlyWithdraw: limits.MonthlyWithdraw,
+		DailyTransfer:   limits.DailyTransfer,
+		MonthlyTransfer: limits.MonthlyTransfer,
+		MaxBalance:      limits.MaxBalance,
+		UpdatedBy:       limits.UpdatedBy,
+		UpdatedAt:       limits.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func authCtx(t *testing.T, username string) context.Context {
+	return context.WithValue(t.Context(), log.UsernameKey, username)
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "usage ok", params: UsageParams{Currency: "SGD"}},
+		{name: "usage unsupported currency", params: UsageParams{Currency: "USD"}, wantErr: true},
+		{name: "set ok", params: SetParams{Username: "user1", Currency: "SGD", DailyWithdraw: 100, MonthlyWithdraw: 1000}},
+		{name: "set default ok", params: SetParams{Currency: "JPY", MaxBalance: 100}},
+		{name: "set negative limit", params: SetParams{Currency: "SGD", MaxBalance: -1}, wantErr: true},
+		{name: "set daily over monthly", params: SetParams{Currency: "SGD", DailyTransfer: 1000, MonthlyTransfer: 100}, wantErr: true},
+		{name: "set daily with unlimited monthly", params: SetParams{Currency: "SGD", DailyTransfer: 1000}},
+		{name: "set username with spaces", params: SetParams{Username: " ", Currency: "SGD"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_Usage(t *testing.T) {
+	t.Run("ok usage", func(t *testing.T) {
+		repo := mocks.NewLimitsRepository(t)
+		repo.On("Usage", mock.Anything, "user1", "SGD").Return(
+			&dao.LimitsModel{PerTransaction: 100, DailyWithdraw: 500, MaxBalance: 1000},
+			&dao.LimitUsageModel{DailyWithdraw: 600, MonthlyWithdraw: 600, DailyTransfer: 20, Balance: 400},
+			nil,
+		)
+		resp, err := New(repo).Usage(authCtx(t, "user1"), UsageParams{Currency: "SGD"})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(100), resp.PerTransaction)
+		assert.Equal(t, int64(0), *resp.DailyWithdraw.Remaining)
+		assert.Nil(t, resp.MonthlyWithdraw.Remaining)
+		assert.Equal(t, int64(600), resp.MonthlyWithdraw.Used)
+		assert.Equal(t, int64(20), resp.DailyTransfer.Used)
+		assert.Equal(t, int64(600), *resp.MaxBalance.Remaining)
+	})
+
+	t.Run("wallet not found", func(t *testing.T) {
+		repo := mocks.NewLimitsRepository(t)
+		repo.On("Usage", mock.Anything, "user1", "JPY").Return(nil, nil, apierr.NotFound)
+		resp, err := New(repo).Usage(authCtx(t, "user1"), UsageParams{Currency: "JPY"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		resp, err := New(mocks.NewLimitsRepository(t)).Usage(t.Context(), UsageParams{Currency: "SGD"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Set(t *testing.T) {
+	t.Run("ok set by admin", func(t *testing.T) {
+		repo := mocks.NewLimitsRepository(t)
+		repo.On("Set", mock.Anything, dao.LimitsModel{Username: "user1", Currency: "SGD", DailyWithdraw: 500, UpdatedBy: "admin"}).
+			Return(&dao.LimitsModel{ID: 1, Username: "user1", Currency: "SGD", DailyWithdraw: 500, UpdatedBy: "admin"}, nil)
+		resp, err := New(repo).Set(authCtx(t, "admin"), SetParams{Username: "user1", Currency: "SGD", DailyWithdraw: 500})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(500), resp.DailyWithdraw)
+		assert.Equal(t, "admin", resp.UpdatedBy)
+	})
+
+	t.Run("set error", func(t *testing.T) {
+		repo := mocks.NewLimitsRepository(t)
+		repo.On("Set", mock.Anything, mock.Anything).Return(nil, errors.New("err"))
+		resp, err := New(repo).Set(authCtx(t, "admin"), SetParams{Currency: "SGD"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, err.HTTPStatusCode())
+	})
+}
@@ -0,0 +1,73 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/events"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+// dispatchBatchSize limits the number of events published within a single database transaction.
+const dispatchBatchSize = 500
+
+type Service interface {
+	Dispatch(ctx context.Context) error
+	Cleanup(ctx context.Context) error
+}
+
+type service struct {
+	outbox    dao.OutboxRepository
+	publisher events.Publisher
+	retention time.Duration
+}
+
+// New creates the outbox service, the published events are kept for retention before they are removed by Cleanup.
+func New(outboxDAO dao.OutboxRepository, publisher events.Publisher, retention time.Duration) Service {
+	return &service{
+		outbox:    outboxDAO,
+		publisher: publisher,
+		retention: retention,
+	}
+}
+
+func (s *service) publish(ctx context.Context, event dao.OutboxEventsModel) error {
+	return s.publisher.Publish(ctx, events.Event{
+		UID:       event.UID,
+		Type:      string(event.Type),
+		Key:       event.Username + ":" + event.Currency,
+		Payload:   event.Payload,
+		CreatedAt: event.CreatedAt,
+	})
+}
+
+// Dispatch publishes the pending events batch by batch until the outbox is drained, it is meant to be run
+// periodically by a worker. A failed event stops the dispatch so the later events of the wallet aren't
+// published ahead of it, it is retried by the next run.
+func (s *service) Dispatch(ctx context.Context) error {
+	for {
+		published, err := s.outbox.Dispatch(ctx, dispatchBatchSize, s.publish)
+		if published > 0 {
+			log.Debug(ctx, "published %d outbox events", published)
+		}
+		if err != nil {
+			return err
+		}
+		if published < dispatchBatchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Cleanup removes the events published before the retention, it is meant to be run periodically by a worker.
+func (s *service) Cleanup(ctx context.Context) error {
+	deleted, err := s.outbox.DeletePublished(ctx, time.Now().UTC().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Info(ctx, "removed %d published outbox events", deleted)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/events"
+	eventmocks "github.com/lengzuo/fundflow/internal/events/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// dispatchWith makes the mocked outbox publish the events through the publish callback of the service.
+func dispatchWith(outboxRepo *mocks.OutboxRepository, batch []dao.OutboxEventsModel, published int, err error) {
+	outboxRepo.On("Dispatch", mock.Anything, dispatchBatchSize, mock.Anything).
+		Run(func(args mock.Arguments) {
+			publish := args.Get(2).(func(ctx context.Context, event dao.OutboxEventsModel) error)
+			for _, event := range batch {
+				if publish(args.Get(0).(context.Context), event) != nil {
+					return
+				}
+			}
+		}).
+		Return(published, err).Once()
+}
+
+func Test_service_Dispatch(t *testing.T) {
+	t.Run("ok publish event keyed by wallet", func(t *testing.T) {
+		outboxRepo := mocks.NewOutboxRepository(t)
+		publisher := eventmocks.NewPublisher(t)
+		dispatchWith(outboxRepo, []dao.OutboxEventsModel{
+			{UID: "evt1", Type: dao.EventWalletCredited, Username: "user1", Currency: "SGD", Payload: []byte(`{}`)},
+		}, 1, nil)
+		publisher.On("Publish", mock.Anything, mock.MatchedBy(func(event events.Event) bool {
+			return event.UID == "evt1" && event.Type == "wallet.credited" && event.Key == "user1:SGD"
+		})).Return(nil)
+		s := New(outboxRepo, publisher, time.Hour)
+		assert.NoError(t, s.Dispatch(t.Context()))
+	})
+
+	t.Run("drain full batches", func(t *testing.T) {
+		outboxRepo := mocks.NewOutboxRepository(t)
+		dispatchWith(outboxRepo, nil, dispatchBatchSize, nil)
+		dispatchWith(outboxRepo, nil, 0, nil)
+		s := New(outboxRepo, eventmocks.NewPublisher(t), time.Hour)
+		assert.NoError(t, s.Dispatch(t.Context()))
+	})
+
+	t.Run("publish error", func(t *testing.T) {
+		outboxRepo := mocks.NewOutboxRepository(t)
+		publisher := eventmocks.NewPublisher(t)
+		dispatchWith(outboxRepo, []dao.OutboxEventsModel{{UID: "evt1"}}, 0, errors.New("redis down"))
+		publisher.On("Publish", mock.Anything, mock.Anything).Return(errors.New("redis down"))
+		s := New(outboxRepo, publisher, time.Hour)
+		assert.Error(t, s.Dispatch(t.Context()))
+	})
+}
+
+func Test_service_Cleanup(t *testing.T) {
+	t.Run("ok remove events published before retention", func(t *testing.T) {
+		outboxRepo := mocks.NewOutboxRepository(t)
+		outboxRepo.On("DeletePublished", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+			return before.Before(time.Now().Add(-time.Hour+time.Minute)) && before.After(time.Now().Add(-time.Hour-time.Minute))
+		})).Return(3, nil)
+		s := New(outboxRepo, eventmocks.NewPublisher(t), time.Hour)
+		assert.NoError(t, s.Cleanup(t.Context()))
+	})
+
+	t.Run("cleanup error", func(t *testing.T) {
+		outboxRepo := mocks.NewOutboxRepository(t)
+		outboxRepo.On("DeletePublished", mock.Anything, mock.Anything).Return(0, errors.New("err"))
+		s := New(outboxRepo, eventmocks.NewPublisher(t), time.Hour)
+		assert.Error(t, s.Cleanup(t.Context()))
+	})
+}
@@ -0,0 +1,38 @@
+package reconciliations
+
+import (
+	"github.com/lengzuo/fundflow/internal/apierr"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+type RunParams struct{}
+
+func (p RunParams) Validate() apierr.JSON {
+	return nil
+}
+
+type ListRunsParams struct {
+	Limit int `schema:"limit"`
+}
+
+func (p ListRunsParams) Validate() apierr.JSON {
+	if p.Limit < 0 || p.Limit > maxListLimit {
+		return apierr.BadRequest("limit must be between 1 and 100")
+	}
+	return nil
+}
+
+type DiscrepanciesParams struct {
+	RunID int `schema:"run_id"`
+}
+
+func (p DiscrepanciesParams) Validate() apierr.JSON {
+	if p.RunID <= 0 {
+		return apierr.BadRequest("run_id is mandatory")
+	}
+	return nil
+}
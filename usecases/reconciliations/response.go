@@ -0,0 +1,55 @@
+package reconciliations
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+)
+
+type Run struct {
+	ID             int                       `json:"id"`
+	Trigger        dao.ReconciliationTrigger `json:"trigger"`
+	TriggeredBy    string                    `json:"triggered_by,omitempty"`
+	Status         dao.ReconciliationStatus  `json:"status"`
+	WalletsChecked int                       `json:"wallets_checked"`
+	Discrepancies  int                       `json:"discrepancies"`
+	Error          string                    `json:"error,omitempty"`
+	StartedAt      time.Time                 `json:"started_at"`
+	FinishedAt     *time.Time                `json:"finished_at"`
+}
+
+type Discrepancy struct {
+	WalletID     int    `json:"wallet_id"`
+	Username     string `json:"username"`
+	Currency     string `json:"currency"`
+	WalletAmount int64  `json:"wallet_amount"`
+	LedgerAmount int64  `json:"ledger_amount"`
+	// Difference is the wallet amount minus the ledger amount
+	Difference int64 `json:"difference"`
+}
+
+type RunResponse struct {
+	Run           Run           `json:"run"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+func (r RunResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+type ListRunsResponse struct {
+	Data []Run `json:"data"`
+}
+
+func (r ListRunsResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type DiscrepanciesResponse struct {
+	Data []Discrepancy `json:"data"`
+}
+
+func (r DiscrepanciesResponse) StatusCode() int {
+	return http.StatusOK
+}
@@ -0,0 +1,120 @@
+package reconciliations
+
+import (
+	"context"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+type Service interface {
+	Run(ctx context.Context, params RunParams) (*RunResponse, apierr.JSON)
+	ListRuns(ctx context.Context, params ListRunsParams) (*ListRunsResponse, apierr.JSON)
+	Discrepancies(ctx context.Context, params DiscrepanciesParams) (*DiscrepanciesResponse, apierr.JSON)
+	Reconcile(ctx context.Context) error
+}
+
+type service struct {
+	reconciliations dao.ReconciliationsRepository
+}
+
+func New(reconciliationsDAO dao.ReconciliationsRepository) Service {
+	return &service{
+		reconciliations: reconciliationsDAO,
+	}
+}
+
+func authUsername(ctx context.Context) (string, apierr.JSON) {
+	username, ok := ctx.Value(log.UsernameKey).(string)
+	if !ok || username == "" {
+		return "", apierr.Unauthenticated()
+	}
+	return username, nil
+}
+
+func toRun(run dao.ReconciliationRunsModel) Run {
+	return Run{
+		ID:             run.ID,
+		Trigger:        run.Trigger,
+		TriggeredBy:    run.TriggeredBy,
+		Status:         run.Status,
+		WalletsChecked: run.WalletsChecked,
+		Discrepancies:  run.Discrepancies,
+		Error:          run.Error,
+		StartedAt:      run.StartedAt,
+		FinishedAt:     run.FinishedAt,
+	}
+}
+
+func toDiscrepancies(discrepancies []dao.ReconciliationDiscrepanciesModel) []Discrepancy {
+	data := make([]Discrepancy, 0, len(discrepancies))
+	for _, d := range discrepancies {
+		data = append(data, Discrepancy{
+			WalletID:     d.WalletID,
+			Username:     d.Username,
+			Currency:     d.Currency,
+			WalletAmount: d.WalletAmount,
+			LedgerAmount: d.LedgerAmount,
+			Difference:   d.WalletAmount - d.LedgerAmount,
+		})
+	}
+	return data
+}
+
+// Run reconciles on demand, it is meant for admin to verify a fix without waiting for the next scheduled run.
+func (s *service) Run(ctx context.Context, params RunParams) (*RunResponse, apierr.JSON) {
+	admin, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	run, discrepancies, err := s.reconciliations.Run(ctx, dao.ReconciliationManual, admin)
+	if err != nil {
+		log.Error(ctx, "failed in manual reconciliation with err: %s", err)
+		return nil, apierr.InternalServer("please try again")
+	}
+	return &RunResponse{
+		Run:           toRun(*run),
+		Discrepancies: toDiscrepancies(discrepancies),
+	}, nil
+}
+
+func (s *service) ListRuns(ctx context.Context, params ListRunsParams) (*ListRunsResponse, apierr.JSON) {
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	runs, err := s.reconciliations.ListRuns(ctx, limit)
+	if err != nil {
+		log.Error(ctx, "failed in list reconciliation runs with err: %s", err)
+		return nil, apierr.InternalServer("please try again")
+	}
+	data := make([]Run, 0, len(runs))
+	for _, run := range runs {
+		data = append(data, toRun(run))
+	}
+	return &ListRunsResponse{Data: data}, nil
+}
+
+func (s *service) Discrepancies(ctx context.Context, params DiscrepanciesParams) (*DiscrepanciesResponse, apierr.JSON) {
+	discrepancies, err := s.reconciliations.ListDiscrepancies(ctx, params.RunID)
+	if err != nil {
+		log.Error(ctx, "failed in list discrepancies with err: %s", err)
+		return nil, apierr.InternalServer("please try again")
+	}
+	return &DiscrepanciesResponse{Data: toDiscrepancies(discrepancies)}, nil
+}
+
+// Reconcile is the scheduled run, every discrepancy found is alerted as error log.
+func (s *service) Reconcile(ctx context.Context) error {
+	run, _, err := s.reconciliations.Run(ctx, dao.ReconciliationScheduled, "")
+	if err != nil {
+		return err
+	}
+	if run.Discrepancies > 0 {
+		log.Error(ctx, "reconciliation run %d found %d wallets out of %d not matching their ledgers", run.ID, run.Discrepancies, run.WalletsChecked)
+		return nil
+	}
+	log.Info(ctx, "reconciliation run %d checked %d wallets without discrepancy", run.ID, run.WalletsChecked)
+	return nil
+}
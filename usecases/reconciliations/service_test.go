@@ -0,0 +1,106 @@
+package reconciliations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func authCtx(t *testing.T, username string) context.Context {
+	return context.WithValue(t.Context(), log.UsernameKey, username)
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "list runs ok", params: ListRunsParams{}},
+		{name: "list runs limit too large", params: ListRunsParams{Limit: 101}, wantErr: true},
+		{name: "discrepancies ok", params: DiscrepanciesParams{RunID: 1}},
+		{name: "discrepancies missing run id", params: DiscrepanciesParams{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_Run(t *testing.T) {
+	t.Run("ok manual run", func(t *testing.T) {
+		repo := mocks.NewReconciliationsRepository(t)
+		repo.On("Run", mock.Anything, dao.ReconciliationManual, "admin").Return(
+			&dao.ReconciliationRunsModel{ID: 1, Trigger: dao.ReconciliationManual, TriggeredBy: "admin", Status: dao.ReconciliationCompleted, WalletsChecked: 2, Discrepancies: 1},
+			[]dao.ReconciliationDiscrepanciesModel{{RunID: 1, WalletID: 2, Username: "user1", Currency: "JPY", WalletAmount: 50, LedgerAmount: 40}},
+			nil,
+		)
+		s := New(repo)
+		resp, err := s.Run(authCtx(t, "admin"), RunParams{})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, resp.Run.Discrepancies)
+		assert.Equal(t, []Discrepancy{{WalletID: 2, Username: "user1", Currency: "JPY", WalletAmount: 50, LedgerAmount: 40, Difference: 10}}, resp.Discrepancies)
+	})
+
+	t.Run("manual run error", func(t *testing.T) {
+		repo := mocks.NewReconciliationsRepository(t)
+		repo.On("Run", mock.Anything, dao.ReconciliationManual, "admin").Return(nil, nil, errors.New("err"))
+		s := New(repo)
+		resp, err := s.Run(authCtx(t, "admin"), RunParams{})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_ListRuns(t *testing.T) {
+	t.Run("ok list runs with default limit", func(t *testing.T) {
+		repo := mocks.NewReconciliationsRepository(t)
+		repo.On("ListRuns", mock.Anything, defaultListLimit).Return([]dao.ReconciliationRunsModel{{ID: 1}}, nil)
+		s := New(repo)
+		resp, err := s.ListRuns(t.Context(), ListRunsParams{})
+		assert.Nil(t, err)
+		assert.Len(t, resp.Data, 1)
+	})
+}
+
+func Test_service_Discrepancies(t *testing.T) {
+	t.Run("ok list discrepancies", func(t *testing.T) {
+		repo := mocks.NewReconciliationsRepository(t)
+		repo.On("ListDiscrepancies", mock.Anything, 1).Return([]dao.ReconciliationDiscrepanciesModel{{WalletAmount: 10, LedgerAmount: 20}}, nil)
+		s := New(repo)
+		resp, err := s.Discrepancies(t.Context(), DiscrepanciesParams{RunID: 1})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(-10), resp.Data[0].Difference)
+	})
+}
+
+func Test_service_Reconcile(t *testing.T) {
+	t.Run("ok scheduled run", func(t *testing.T) {
+		repo := mocks.NewReconciliationsRepository(t)
+		repo.On("Run", mock.Anything, dao.ReconciliationScheduled, "").Return(&dao.ReconciliationRunsModel{ID: 1, Discrepancies: 1}, nil, nil)
+		s := New(repo)
+		assert.NoError(t, s.Reconcile(t.Context()))
+	})
+
+	t.Run("scheduled run error", func(t *testing.T) {
+		repo := mocks.NewReconciliationsRepository(t)
+		repo.On("Run", mock.Anything, dao.ReconciliationScheduled, "").Return(nil, nil, errors.New("err"))
+		s := New(repo)
+		assert.Error(t, s.Reconcile(t.Context()))
+	})
+}
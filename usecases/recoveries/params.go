@@ -0,0 +1,39 @@
+package recoveries
+
+import (
+	"strings"
+
+	"github.com/lengzuo/fundflow/internal/apierr"
+)
+
+const maxReasonLength = 256
+
+type RecoverParams struct {
+	// UID of the pending transaction to be recovered
+	UID    string `json:"uid"`
+	Reason string `json:"reason"`
+}
+
+func (p RecoverParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	if strings.TrimSpace(p.Reason) == "" {
+		return apierr.BadRequest("reason is mandatory")
+	}
+	if len(p.Reason) > maxReasonLength {
+		return apierr.BadRequest("reason is too long")
+	}
+	return nil
+}
+
+type ListParams struct {
+	UID string `schema:"uid"`
+}
+
+func (p ListParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	return nil
+}
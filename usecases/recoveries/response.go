@@ -0,0 +1,34 @@
+package recoveries
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+)
+
+type Recovery struct {
+	ID          int64              `json:"id"`
+	TxUID       string             `json:"tx_uid"`
+	Action      dao.RecoveryAction `json:"action"`
+	Status      dao.TxStatus       `json:"status"`
+	Reason      string             `json:"reason"`
+	RecoveredBy string             `json:"recovered_by,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+type RecoveryResponse struct {
+	Recovery
+}
+
+func (r RecoveryResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type ListResponse struct {
+	Data []Recovery `json:"data"`
+}
+
+func (r ListResponse) StatusCode() int {
+	return http.StatusOK
+}
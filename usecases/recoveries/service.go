@@ -0,0 +1,129 @@
+package recoveries
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+const (
+	recoverBatchSize = 100
+	timeoutReason    = "pending beyond timeout"
+)
+
+type Service interface {
+	Retry(ctx context.Context, params RecoverParams) (*RecoveryResponse, apierr.JSON)
+	Fail(ctx context.Context, params RecoverParams) (*RecoveryResponse, apierr.JSON)
+	List(ctx context.Context, params ListParams) (*ListResponse, apierr.JSON)
+	Recover(ctx context.Context) error
+}
+
+type service struct {
+	recoveries     dao.RecoveriesRepository
+	pendingTimeout time.Duration
+}
+
+func New(recoveriesDAO dao.RecoveriesRepository, pendingTimeout time.Duration) Service {
+	return &service{
+		recoveries:     recoveriesDAO,
+		pendingTimeout: pendingTimeout,
+	}
+}
+
+func authUsername(ctx context.Context) (string, apierr.JSON) {
+	username, ok := ctx.Value(log.UsernameKey).(string)
+	if !ok || username == "" {
+		return "", apierr.Unauthenticated()
+	}
+	return username, nil
+}
+
+// toAPIErr maps the errors returned from dao into the error response of the API.
+func toAPIErr(ctx context.Context, err error) apierr.JSON {
+	switch {
+	case errors.Is(err, apierr.NotFound):
+		return apierr.ResourceNotFound("transaction or wallet not found")
+	case errors.Is(err, dao.ErrTxNotPending):
+		return apierr.Conflict("transaction is not pending")
+	case errors.Is(err, dao.ErrTxNotStuck):
+		return apierr.Conflict("hold is authorized until its expiry, void it or fail it instead")
+	}
+	log.Error(ctx, "failed in recoveries service with err: %s", err)
+	return apierr.InternalServer("please try again")
+}
+
+func toRecovery(recovery dao.TransactionRecoveriesModel) Recovery {
+	return Recovery{
+		ID:          recovery.ID,
+		TxUID:       recovery.TxUID,
+		Action:      recovery.Action,
+		Status:      recovery.Status,
+		Reason:      recovery.Reason,
+		RecoveredBy: recovery.RecoveredBy,
+		CreatedAt:   recovery.CreatedAt,
+	}
+}
+
+func (s *service) recover(ctx context.Context, params RecoverParams, action dao.RecoveryAction) (*RecoveryResponse, apierr.JSON) {
+	admin, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	recovery, err := s.recoveries.Recover(ctx, params.UID, action, admin, params.Reason)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	log.Info(ctx, "transaction %s is %s by %s on %s", recovery.TxUID, recovery.Status, admin, action)
+	return &RecoveryResponse{Recovery: toRecovery(*recovery)}, nil
+}
+
+// Retry finalizes the pending hold transaction right away without waiting for the timeout, it follows the hold
+// record and the authorized hold is expired once its expiry passed.
+func (s *service) Retry(ctx context.Context, params RecoverParams) (*RecoveryResponse, apierr.JSON) {
+	return s.recover(ctx, params, dao.RecoveryRetry)
+}
+
+// Fail releases the authorized hold of the pending transaction and fails it.
+func (s *service) Fail(ctx context.Context, params RecoverParams) (*RecoveryResponse, apierr.JSON) {
+	return s.recover(ctx, params, dao.RecoveryFail)
+}
+
+func (s *service) List(ctx context.Context, params ListParams) (*ListResponse, apierr.JSON) {
+	recoveries, err := s.recoveries.List(ctx, params.UID)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	data := make([]Recovery, 0, len(recoveries))
+	for _, recovery := range recoveries {
+		data = append(data, toRecovery(recovery))
+	}
+	return &ListResponse{Data: data}, nil
+}
+
+// Recover retries the hold transactions pending beyond the timeout, it is meant to be run periodically by a worker.
+// Every recovered transaction is alerted as error log as it means the hold was left behind. A transaction which
+// can't be recovered stays pending and is tried again next tick.
+func (s *service) Recover(ctx context.Context) error {
+	uids, err := s.recoveries.ListStuck(ctx, time.Now().UTC().Add(-s.pendingTimeout), recoverBatchSize)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, uid := range uids {
+		recovery, err := s.recoveries.Recover(ctx, uid, dao.RecoveryRetry, "", timeoutReason)
+		if err != nil {
+			// Finalized or still within the hold expiry since it was listed
+			if errors.Is(err, dao.ErrTxNotPending) || errors.Is(err, dao.ErrTxNotStuck) {
+				continue
+			}
+			errs = append(errs, err)
+			continue
+		}
+		log.Error(ctx, "transaction %s pending beyond %s is recovered as %s", uid, s.pendingTimeout, recovery.Status)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package recoveries
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func authCtx(t *testing.T, username string) context.Context {
+	return context.WithValue(t.Context(), log.UsernameKey, username)
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "recover ok", params: RecoverParams{UID: "uid", Reason: "stuck"}},
+		{name: "recover missing uid", params: RecoverParams{Reason: "stuck"}, wantErr: true},
+		{name: "recover missing reason", params: RecoverParams{UID: "uid", Reason: " "}, wantErr: true},
+		{name: "list ok", params: ListParams{UID: "uid"}},
+		{name: "list missing uid", params: ListParams{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_Retry(t *testing.T) {
+	t.Run("ok retry", func(t *testing.T) {
+		repo := mocks.NewRecoveriesRepository(t)
+		repo.On("Recover", mock.Anything, "uid", dao.RecoveryRetry, "admin", "stuck").Return(
+			&dao.TransactionRecoveriesModel{ID: 1, TxUID: "uid", Action: dao.RecoveryRetry, Status: dao.StatusCompleted, RecoveredBy: "admin", Reason: "stuck"}, nil,
+		)
+		s := New(repo, time.Minute)
+		resp, err := s.Retry(authCtx(t, "admin"), RecoverParams{UID: "uid", Reason: "stuck"})
+		assert.Nil(t, err)
+		assert.Equal(t, dao.StatusCompleted, resp.Status)
+		assert.Equal(t, "admin", resp.RecoveredBy)
+	})
+
+	t.Run("retry hold within expiry", func(t *testing.T) {
+		repo := mocks.NewRecoveriesRepository(t)
+		repo.On("Recover", mock.Anything, "uid", dao.RecoveryRetry, "admin", "stuck").Return(nil, dao.ErrTxNotStuck)
+		s := New(repo, time.Minute)
+		resp, err := s.Retry(authCtx(t, "admin"), RecoverParams{UID: "uid", Reason: "stuck"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusConflict, err.HTTPStatusCode())
+	})
+
+	t.Run("retry without auth", func(t *testing.T) {
+		s := New(mocks.NewRecoveriesRepository(t), time.Minute)
+		resp, err := s.Retry(t.Context(), RecoverParams{UID: "uid", Reason: "stuck"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Fail(t *testing.T) {
+	t.Run("ok fail", func(t *testing.T) {
+		repo := mocks.NewRecoveriesRepository(t)
+		repo.On("Recover", mock.Anything, "uid", dao.RecoveryFail, "admin", "stuck").Return(
+			&dao.TransactionRecoveriesModel{ID: 1, TxUID: "uid", Action: dao.RecoveryFail, Status: dao.StatusFailed}, nil,
+		)
+		s := New(repo, time.Minute)
+		resp, err := s.Fail(authCtx(t, "admin"), RecoverParams{UID: "uid", Reason: "stuck"})
+		assert.Nil(t, err)
+		assert.Equal(t, dao.StatusFailed, resp.Status)
+	})
+
+	t.Run("fail transaction which is not pending", func(t *testing.T) {
+		repo := mocks.NewRecoveriesRepository(t)
+		repo.On("Recover", mock.Anything, "uid", dao.RecoveryFail, "admin", "stuck").Return(nil, dao.ErrTxNotPending)
+		s := New(repo, time.Minute)
+		resp, err := s.Fail(authCtx(t, "admin"), RecoverParams{UID: "uid", Reason: "stuck"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusConflict, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_List(t *testing.T) {
+	repo := mocks.NewRecoveriesRepository(t)
+	repo.On("List", mock.Anything, "uid").Return([]dao.TransactionRecoveriesModel{{ID: 1, TxUID: "uid", Status: dao.StatusFailed}}, nil)
+	s := New(repo, time.Minute)
+	resp, err := s.List(authCtx(t, "admin"), ListParams{UID: "uid"})
+	assert.Nil(t, err)
+	assert.Len(t, resp.Data, 1)
+}
+
+func Test_service_Recover(t *testing.T) {
+	t.Run("ok recover stuck transactions", func(t *testing.T) {
+		repo := mocks.NewRecoveriesRepository(t)
+		repo.On("ListStuck", mock.Anything, mock.AnythingOfType("time.Time"), recoverBatchSize).Return([]string{"uid1", "uid2", "uid3"}, nil)
+		repo.On("Recover", mock.Anything, "uid1", dao.RecoveryRetry, "", timeoutReason).Return(&dao.TransactionRecoveriesModel{TxUID: "uid1", Status: dao.StatusCompleted}, nil)
+		repo.On("Recover", mock.Anything, "uid2", dao.RecoveryRetry, "", timeoutReason).Return(nil, dao.ErrTxNotPending)
+		repo.On("Recover", mock.Anything, "uid3", dao.RecoveryRetry, "", timeoutReason).Return(&dao.TransactionRecoveriesModel{TxUID: "uid3", Status: dao.StatusFailed}, nil)
+		s := New(repo, time.Minute)
+		assert.NoError(t, s.Recover(t.Context()))
+	})
+
+	t.Run("recover continues after failure", func(t *testing.T) {
+		repo := mocks.NewRecoveriesRepository(t)
+		repo.On("ListStuck", mock.Anything, mock.AnythingOfType("time.Time"), recoverBatchSize).Return([]string{"uid1", "uid2"}, nil)
+		repo.On("Recover", mock.Anything, "uid1", dao.RecoveryRetry, "", timeoutReason).Return(nil, errors.New("db error"))
+		repo.On("Recover", mock.Anything, "uid2", dao.RecoveryRetry, "", timeoutReason).Return(&dao.TransactionRecoveriesModel{TxUID: "uid2", Status: dao.StatusCompleted}, nil)
+		s := New(repo, time.Minute)
+		assert.Error(t, s.Recover(t.Context()))
+	})
+
+	t.Run("recover list error", func(t *testing.T) {
+		repo := mocks.NewRecoveriesRepository(t)
+		repo.On("ListStuck", mock.Anything, mock.AnythingOfType("time.Time"), recoverBatchSize).Return(nil, errors.New("db error"))
+		s := New(repo, time.Minute)
+		assert.Error(t, s.Recover(t.Context()))
+	})
+}
@@ -0,0 +1,146 @@
+package schedules
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+const (
+	maxReferenceLength = 64
+	// defaultMaxRetries applies to the retry policy without max_retries
+	defaultMaxRetries = 3
+	maxRetries        = 10
+)
+
+var (
+	frequencies = []dao.Frequency{dao.FrequencyOnce, dao.FrequencyWeekly, dao.FrequencyMonthly}
+	policies    = []dao.InsufficientFundPolicy{dao.PolicyRetry, dao.PolicySkip}
+)
+
+func validatePolicy(policy dao.InsufficientFundPolicy, retries int) apierr.JSON {
+	if !slices.Contains(policies, policy) {
+		return apierr.BadRequest("on_insufficient_fund must be either retry or skip")
+	}
+	if retries < 0 || retries > maxRetries {
+		return apierr.BadRequest(fmt.Sprintf("max_retries must be between 0 and %d", maxRetries))
+	}
+	return nil
+}
+
+type CreateParams struct {
+	Receiver  string        `json:"receiver"`
+	Currency  string        `json:"currency"`
+	Amount    money.Amount  `json:"amount"`
+	Reference string        `json:"reference"`
+	Frequency dao.Frequency `json:"frequency"`
+	// StartAt is the first occurrence, the weekly and monthly occurrences repeat from it
+	StartAt time.Time `json:"start_at"`
+	// EndAt is optional, no occurrence is due after it
+	EndAt *time.Time `json:"end_at"`
+	// OnInsufficientFund is skip when it is empty
+	OnInsufficientFund dao.InsufficientFundPolicy `json:"on_insufficient_fund"`
+	MaxRetries         int                        `json:"max_retries"`
+}
+
+func (p CreateParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.Receiver) == "" {
+		return apierr.BadRequest("receiver is mandatory")
+	}
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	amount, err := p.Amount.Of(p.Currency)
+	if err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if !amount.IsPositive() {
+		return apierr.BadRequest("amount must be greater than zero")
+	}
+	if strings.TrimSpace(p.Reference) == "" {
+		return apierr.BadRequest("reference is mandatory")
+	}
+	if len(p.Reference) > maxReferenceLength {
+		return apierr.BadRequest("reference is too long")
+	}
+	if !slices.Contains(frequencies, p.Frequency) {
+		return apierr.BadRequest("frequency must be one of once, weekly or monthly")
+	}
+	if !p.StartAt.After(time.Now()) {
+		return apierr.BadRequest("start_at must be in the future")
+	}
+	if p.EndAt != nil && p.EndAt.Before(p.StartAt) {
+		return apierr.BadRequest("end_at must not be before start_at")
+	}
+	if p.OnInsufficientFund == "" {
+		return validatePolicy(dao.PolicySkip, p.MaxRetries)
+	}
+	return validatePolicy(p.OnInsufficientFund, p.MaxRetries)
+}
+
+type GetParams struct {
+	UID string `schema:"uid"`
+}
+
+func (p GetParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	return nil
+}
+
+type ListParams struct{}
+
+func (p ListParams) Validate() apierr.JSON {
+	return nil
+}
+
+// UpdateParams changes the active schedule, the omitted fields are left unchanged.
+type UpdateParams struct {
+	UID string `json:"uid"`
+	// Amount is in the currency of the schedule
+	Amount             *money.Amount               `json:"amount"`
+	EndAt              *time.Time                  `json:"end_at"`
+	OnInsufficientFund *dao.InsufficientFundPolicy `json:"on_insufficient_fund"`
+	MaxRetries         *int                        `json:"max_retries"`
+}
+
+func (p UpdateParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	if p.Amount == nil && p.EndAt == nil && p.OnInsufficientFund == nil && p.MaxRetries == nil {
+		return apierr.BadRequest("nothing to update")
+	}
+	if p.EndAt != nil && !p.EndAt.After(time.Now()) {
+		return apierr.BadRequest("end_at must be in the future")
+	}
+	if p.OnInsufficientFund != nil {
+		if err := validatePolicy(*p.OnInsufficientFund, 0); err != nil {
+			return err
+		}
+	}
+	if p.MaxRetries != nil {
+		if err := validatePolicy(dao.PolicyRetry, *p.MaxRetries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type CancelParams struct {
+	UID string `json:"uid"`
+}
+
+func (p CancelParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	return nil
+}
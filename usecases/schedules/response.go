@@ -0,0 +1,61 @@
+package schedules
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+)
+
+type Run struct {
+	ExecutionKey string                `json:"execution_key"`
+	DueAt        time.Time             `json:"due_at"`
+	Attempt      int                   `json:"attempt"`
+	Status       dao.ScheduleRunStatus `json:"status"`
+	TxUID        string                `json:"tx_uid,omitempty"`
+	Error        string                `json:"error,omitempty"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+type ScheduleResponse struct {
+	UID      string `json:"uid"`
+	Receiver string `json:"receiver"`
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+	// AmountDecimal is Amount in the precision of the currency
+	AmountDecimal string        `json:"amount_decimal"`
+	Reference     string        `json:"reference"`
+	Frequency     dao.Frequency `json:"frequency"`
+	StartAt       time.Time     `json:"start_at"`
+	EndAt         *time.Time    `json:"end_at,omitempty"`
+	// DueAt is the current occurrence, NextRunAt is later than it when the occurrence is being retried
+	DueAt              time.Time                  `json:"due_at"`
+	NextRunAt          time.Time                  `json:"next_run_at"`
+	Attempts           int                        `json:"attempts"`
+	OnInsufficientFund dao.InsufficientFundPolicy `json:"on_insufficient_fund"`
+	MaxRetries         int                        `json:"max_retries"`
+	Status             dao.ScheduleStatus         `json:"status"`
+	CreatedAt          time.Time                  `json:"created_at"`
+	// Runs are the latest runs first, only returned by get
+	Runs []Run `json:"runs,omitempty"`
+}
+
+func (r ScheduleResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type CreateResponse struct {
+	ScheduleResponse
+}
+
+func (r CreateResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+type ListResponse struct {
+	Data []ScheduleResponse `json:"data"`
+}
+
+func (r ListResponse) StatusCode() int {
+	return http.StatusOK
+}
@@ -0,0 +1,242 @@
+package schedules
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+const (
+	// dueBatchSize limits the number of schedules executed in a single run.
+	dueBatchSize = 100
+	// runsLimit is the number of latest runs returned with the schedule.
+	runsLimit = 20
+)
+
+type Service interface {
+	Create(ctx context.Context, params CreateParams) (*CreateResponse, apierr.JSON)
+	Get(ctx context.Context, params GetParams) (*ScheduleResponse, apierr.JSON)
+	List(ctx context.Context, params ListParams) (*ListResponse, apierr.JSON)
+	Update(ctx context.Context, params UpdateParams) (*ScheduleResponse, apierr.JSON)
+	Cancel(ctx context.Context, params CancelParams) (*ScheduleResponse, apierr.JSON)
+	RunDue(ctx context.Context) error
+}
+
+type service struct {
+	schedules dao.SchedulesRepository
+}
+
+func New(schedulesDAO dao.SchedulesRepository) Service {
+	return &service{
+		schedules: schedulesDAO,
+	}
+}
+
+func authUsername(ctx context.Context) (string, apierr.JSON) {
+	username, ok := ctx.Value(log.UsernameKey).(string)
+	if !ok || username == "" {
+		return "", apierr.Unauthenticated()
+	}
+	return username, nil
+}
+
+// toAPIErr maps the errors returned from dao into the error response of the API.
+func toAPIErr(ctx context.Context, err error) apierr.JSON {
+	switch {
+	case errors.Is(err, apierr.NotFound):
+		return apierr.ResourceNotFound("schedule or wallet not found")
+	case errors.Is(err, apierr.WalletFrozen):
+		return apierr.NewJSON(http.StatusForbidden, apierr.CodeWalletFrozen, "wallet is frozen", err)
+	case errors.Is(err, apierr.WalletClosed):
+		return apierr.NewJSON(http.StatusForbidden, apierr.CodeWalletClosed, "wallet is closed", err)
+	case errors.Is(err, dao.ErrScheduleNotActive):
+		return apierr.Conflict("schedule is already completed or cancelled")
+	}
+	log.Error(ctx, "failed in schedules service with err: %s", err)
+	return apierr.InternalServer("please try again")
+}
+
+func toScheduleResponse(schedule *dao.TransferSchedulesModel) *ScheduleResponse {
+	return &ScheduleResponse{
+		UID:                schedule.UID,
+		Receiver:           schedule.Receiver,
+		Currency:           schedule.Currency,
+		Amount:             schedule.Amount,
+		AmountDecimal:      money.FormatDecimal(schedule.Amount, schedule.Currency),
+		Reference:          schedule.Reference,
+		Frequency:          schedule.Frequency,
+		StartAt:            schedule.StartAt,
+		EndAt:              schedule.EndAt,
+		DueAt:              schedule.DueAt,
+		NextRunAt:          schedule.NextRunAt,
+		Attempts:           schedule.Attempts,
+		OnInsufficientFund: schedule.OnInsufficientFund,
+		MaxRetries:         schedule.MaxRetries,
+		Status:             schedule.Status,
+		CreatedAt:          schedule.CreatedAt,
+	}
+}
+
+func (s *service) Create(ctx context.Context, params CreateParams) (*CreateResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	if username == params.Receiver {
+		return nil, apierr.BadRequest("unable to schedule transfer to own wallet")
+	}
+	amount, err := params.Amount.Of(params.Currency)
+	if err != nil {
+		return nil, apierr.BadRequest(err.Error())
+	}
+	policy, maxRetries := params.OnInsufficientFund, params.MaxRetries
+	if policy == "" {
+		policy = dao.PolicySkip
+	}
+	if policy == dao.PolicyRetry && maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+	schedule := dao.TransferSchedulesModel{
+		Username:           username,
+		Receiver:           params.Receiver,
+		Currency:           params.Currency,
+		Amount:             amount.Amount(),
+		Reference:          params.Reference,
+		Frequency:          params.Frequency,
+		StartAt:            params.StartAt.UTC(),
+		OnInsufficientFund: policy,
+		MaxRetries:         maxRetries,
+	}
+	if params.EndAt != nil {
+		endAt := params.EndAt.UTC()
+		schedule.EndAt = &endAt
+	}
+	created, err := s.schedules.Create(ctx, schedule)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &CreateResponse{ScheduleResponse: *toScheduleResponse(created)}, nil
+}
+
+func (s *service) Get(ctx context.Context, params GetParams) (*ScheduleResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	schedule, err := s.schedules.Get(ctx, params.UID, username)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	runs, err := s.schedules.ListRuns(ctx, schedule.ID, runsLimit)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	resp := toScheduleResponse(schedule)
+	for _, run := range runs {
+		resp.Runs = append(resp.Runs, Run{
+			ExecutionKey: run.ExecutionKey,
+			DueAt:        run.DueAt,
+			Attempt:      run.Attempt,
+			Status:       run.Status,
+			TxUID:        run.TxUID,
+			Error:        run.Error,
+			CreatedAt:    run.CreatedAt,
+		})
+	}
+	return resp, nil
+}
+
+func (s *service) List(ctx context.Context, params ListParams) (*ListResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	schedules, err := s.schedules.List(ctx, username)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	resp := &ListResponse{Data: make([]ScheduleResponse, 0, len(schedules))}
+	for i := range schedules {
+		resp.Data = append(resp.Data, *toScheduleResponse(&schedules[i]))
+	}
+	return resp, nil
+}
+
+func (s *service) Update(ctx context.Context, params UpdateParams) (*ScheduleResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	update := dao.ScheduleUpdate{
+		OnInsufficientFund: params.OnInsufficientFund,
+		MaxRetries:         params.MaxRetries,
+	}
+	if params.EndAt != nil {
+		endAt := params.EndAt.UTC()
+		update.EndAt = &endAt
+	}
+	if params.Amount != nil {
+		// The amount is in the currency of the schedule which is only known after it is loaded
+		schedule, err := s.schedules.Get(ctx, params.UID, username)
+		if err != nil {
+			return nil, toAPIErr(ctx, err)
+		}
+		amount, err := params.Amount.Of(schedule.Currency)
+		if err != nil {
+			return nil, apierr.BadRequest(err.Error())
+		}
+		if !amount.IsPositive() {
+			return nil, apierr.BadRequest("amount must be greater than zero")
+		}
+		minor := amount.Amount()
+		update.Amount = &minor
+	}
+	schedule, err := s.schedules.Update(ctx, params.UID, username, update)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return toScheduleResponse(schedule), nil
+}
+
+func (s *service) Cancel(ctx context.Context, params CancelParams) (*ScheduleResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	schedule, err := s.schedules.Cancel(ctx, params.UID, username)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return toScheduleResponse(schedule), nil
+}
+
+// RunDue executes the due schedules one by one, it is meant to be run periodically by a worker. A failed schedule
+// doesn't stop the others, it stays due and is picked up again by the next run.
+func (s *service) RunDue(ctx context.Context) error {
+	now := time.Now().UTC()
+	uids, err := s.schedules.ListDue(ctx, now, dueBatchSize)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, uid := range uids {
+		run, err := s.schedules.Execute(ctx, uid, now)
+		if err != nil {
+			if !errors.Is(err, dao.ErrScheduleNotDue) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		// run is nil when the occurrence was already paid and the schedule only moved on
+		if run != nil {
+			log.Info(ctx, "schedule run %s is %s", run.ExecutionKey, run.Status)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,190 @@
+package schedules
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func authCtx(t *testing.T, username string) context.Context {
+	return context.WithValue(t.Context(), log.UsernameKey, username)
+}
+
+func TestParams_Validate(t *testing.T) {
+	startAt := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	retry := dao.PolicyRetry
+	unknown := dao.InsufficientFundPolicy("wait")
+	tooMany := maxRetries + 1
+	create := func(modify func(p *CreateParams)) CreateParams {
+		p := CreateParams{Receiver: "user2", Currency: "SGD", Amount: money.MinorUnits(100), Reference: "rent", Frequency: dao.FrequencyMonthly, StartAt: startAt}
+		modify(&p)
+		return p
+	}
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "create ok", params: create(func(p *CreateParams) {})},
+		{name: "create ok with retry", params: create(func(p *CreateParams) { p.OnInsufficientFund = dao.PolicyRetry; p.MaxRetries = 5 })},
+		{name: "create decimal amount", params: create(func(p *CreateParams) { p.Amount = money.DecimalAmount("1.50") })},
+		{name: "create missing receiver", params: create(func(p *CreateParams) { p.Receiver = "" }), wantErr: true},
+		{name: "create unsupported currency", params: create(func(p *CreateParams) { p.Currency = "USD" }), wantErr: true},
+		{name: "create zero amount", params: create(func(p *CreateParams) { p.Amount = money.MinorUnits(0) }), wantErr: true},
+		{name: "create empty reference", params: create(func(p *CreateParams) { p.Reference = " " }), wantErr: true},
+		{name: "create unknown frequency", params: create(func(p *CreateParams) { p.Frequency = "daily" }), wantErr: true},
+		{name: "create start in the past", params: create(func(p *CreateParams) { p.StartAt = past }), wantErr: true},
+		{name: "create end before start", params: create(func(p *CreateParams) { p.EndAt = &past }), wantErr: true},
+		{name: "create unknown policy", params: create(func(p *CreateParams) { p.OnInsufficientFund = unknown }), wantErr: true},
+		{name: "create too many retries", params: create(func(p *CreateParams) { p.MaxRetries = tooMany }), wantErr: true},
+		{name: "get missing uid", params: GetParams{}, wantErr: true},
+		{name: "update ok", params: UpdateParams{UID: "uid", OnInsufficientFund: &retry}},
+		{name: "update nothing", params: UpdateParams{UID: "uid"}, wantErr: true},
+		{name: "update end in the past", params: UpdateParams{UID: "uid", EndAt: &past}, wantErr: true},
+		{name: "update unknown policy", params: UpdateParams{UID: "uid", OnInsufficientFund: &unknown}, wantErr: true},
+		{name: "update too many retries", params: UpdateParams{UID: "uid", MaxRetries: &tooMany}, wantErr: true},
+		{name: "cancel missing uid", params: CancelParams{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_Create(t *testing.T) {
+	startAt := time.Now().Add(time.Hour)
+
+	t.Run("ok create with default retries", func(t *testing.T) {
+		schedulesRepo := mocks.NewSchedulesRepository(t)
+		schedulesRepo.On("Create", mock.Anything, mock.MatchedBy(func(schedule dao.TransferSchedulesModel) bool {
+			return schedule.Username == "user1" && schedule.Amount == 150 && schedule.MaxRetries == defaultMaxRetries &&
+				schedule.StartAt.Location() == time.UTC
+		})).Return(&dao.TransferSchedulesModel{UID: "uid", Receiver: "user2", Currency: "SGD", Amount: 150, Status: dao.ScheduleStatusActive}, nil)
+		s := New(schedulesRepo)
+		resp, err := s.Create(authCtx(t, "user1"), CreateParams{Receiver: "user2", Currency: "SGD", Amount: money.DecimalAmount("1.50"), Reference: "rent",
+			Frequency: dao.FrequencyWeekly, StartAt: startAt, OnInsufficientFund: dao.PolicyRetry})
+		assert.Nil(t, err)
+		assert.Equal(t, "uid", resp.UID)
+		assert.Equal(t, "1.50", resp.AmountDecimal)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	})
+
+	t.Run("create to own wallet", func(t *testing.T) {
+		s := New(mocks.NewSchedulesRepository(t))
+		resp, err := s.Create(authCtx(t, "user1"), CreateParams{Receiver: "user1", Currency: "SGD", Amount: money.MinorUnits(100), Reference: "rent",
+			Frequency: dao.FrequencyOnce, StartAt: startAt})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+	})
+
+	t.Run("create without receiver wallet", func(t *testing.T) {
+		schedulesRepo := mocks.NewSchedulesRepository(t)
+		schedulesRepo.On("Create", mock.Anything, mock.Anything).Return(nil, apierr.NotFound)
+		s := New(schedulesRepo)
+		resp, err := s.Create(authCtx(t, "user1"), CreateParams{Receiver: "user2", Currency: "SGD", Amount: money.MinorUnits(100), Reference: "rent",
+			Frequency: dao.FrequencyOnce, StartAt: startAt})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Get(t *testing.T) {
+	t.Run("ok get with runs", func(t *testing.T) {
+		schedulesRepo := mocks.NewSchedulesRepository(t)
+		schedulesRepo.On("Get", mock.Anything, "uid", "user1").Return(&dao.TransferSchedulesModel{ID: 1, UID: "uid", Currency: "SGD", Amount: 100}, nil)
+		schedulesRepo.On("ListRuns", mock.Anything, 1, runsLimit).Return([]dao.TransferScheduleRunsModel{
+			{ExecutionKey: "uid:0", Attempt: 1, Status: dao.ScheduleRunCompleted, TxUID: "tx"},
+		}, nil)
+		s := New(schedulesRepo)
+		resp, err := s.Get(authCtx(t, "user1"), GetParams{UID: "uid"})
+		assert.Nil(t, err)
+		assert.Len(t, resp.Runs, 1)
+		assert.Equal(t, "tx", resp.Runs[0].TxUID)
+	})
+}
+
+func Test_service_Update(t *testing.T) {
+	t.Run("ok update amount in schedule currency", func(t *testing.T) {
+		schedulesRepo := mocks.NewSchedulesRepository(t)
+		schedulesRepo.On("Get", mock.Anything, "uid", "user1").Return(&dao.TransferSchedulesModel{UID: "uid", Currency: "JPY"}, nil)
+		schedulesRepo.On("Update", mock.Anything, "uid", "user1", mock.MatchedBy(func(update dao.ScheduleUpdate) bool {
+			return update.Amount != nil && *update.Amount == 500
+		})).Return(&dao.TransferSchedulesModel{UID: "uid", Currency: "JPY", Amount: 500}, nil)
+		s := New(schedulesRepo)
+		amount := money.DecimalAmount("500")
+		resp, err := s.Update(authCtx(t, "user1"), UpdateParams{UID: "uid", Amount: &amount})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(500), resp.Amount)
+	})
+
+	t.Run("update amount too precise", func(t *testing.T) {
+		schedulesRepo := mocks.NewSchedulesRepository(t)
+		schedulesRepo.On("Get", mock.Anything, "uid", "user1").Return(&dao.TransferSchedulesModel{UID: "uid", Currency: "JPY"}, nil)
+		s := New(schedulesRepo)
+		amount := money.DecimalAmount("1.50")
+		resp, err := s.Update(authCtx(t, "user1"), UpdateParams{UID: "uid", Amount: &amount})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Cancel(t *testing.T) {
+	tests := []struct {
+		name       string
+		daoErr     error
+		wantStatus int
+	}{
+		{name: "cancel not found", daoErr: apierr.NotFound, wantStatus: http.StatusNotFound},
+		{name: "cancel completed schedule", daoErr: dao.ErrScheduleNotActive, wantStatus: http.StatusConflict},
+		{name: "cancel unexpected error", daoErr: errors.New("err"), wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedulesRepo := mocks.NewSchedulesRepository(t)
+			schedulesRepo.On("Cancel", mock.Anything, "uid", "user1").Return(nil, tt.daoErr)
+			s := New(schedulesRepo)
+			resp, err := s.Cancel(authCtx(t, "user1"), CancelParams{UID: "uid"})
+			assert.Nil(t, resp)
+			assert.Equal(t, tt.wantStatus, err.HTTPStatusCode())
+		})
+	}
+}
+
+func Test_service_RunDue(t *testing.T) {
+	t.Run("ok run due schedules", func(t *testing.T) {
+		schedulesRepo := mocks.NewSchedulesRepository(t)
+		schedulesRepo.On("ListDue", mock.Anything, mock.Anything, dueBatchSize).Return([]string{"uid1", "uid2", "uid3"}, nil)
+		schedulesRepo.On("Execute", mock.Anything, "uid1", mock.Anything).Return(&dao.TransferScheduleRunsModel{ExecutionKey: "uid1:0", Status: dao.ScheduleRunCompleted}, nil)
+		schedulesRepo.On("Execute", mock.Anything, "uid2", mock.Anything).Return(nil, dao.ErrScheduleNotDue)
+		schedulesRepo.On("Execute", mock.Anything, "uid3", mock.Anything).Return(nil, nil)
+		s := New(schedulesRepo)
+		assert.NoError(t, s.RunDue(t.Context()))
+	})
+
+	t.Run("failed schedule doesn't stop the others", func(t *testing.T) {
+		schedulesRepo := mocks.NewSchedulesRepository(t)
+		schedulesRepo.On("ListDue", mock.Anything, mock.Anything, dueBatchSize).Return([]string{"uid1", "uid2"}, nil)
+		schedulesRepo.On("Execute", mock.Anything, "uid1", mock.Anything).Return(nil, errors.New("err"))
+		schedulesRepo.On("Execute", mock.Anything, "uid2", mock.Anything).Return(&dao.TransferScheduleRunsModel{ExecutionKey: "uid2:0", Status: dao.ScheduleRunSkipped}, nil)
+		s := New(schedulesRepo)
+		assert.Error(t, s.RunDue(t.Context()))
+	})
+}
@@ -0,0 +1,87 @@
+package transactions
+
+import (
+	"strings"
+
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/utils/metadata"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+const (
+	maxReferenceLength = 64
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+func validateReversal(uid, reference string, amount money.Amount) apierr.JSON {
+	if strings.TrimSpace(uid) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	if amount.IsNegative() {
+		return apierr.BadRequest("amount must be greater than zero")
+	}
+	if strings.TrimSpace(reference) == "" {
+		return apierr.BadRequest("reference is mandatory")
+	}
+	if len(reference) > maxReferenceLength {
+		return apierr.BadRequest("reference is too long")
+	}
+	return nil
+}
+
+type RefundParams struct {
+	// UID of the transfer or captured hold to be refunded
+	UID string `json:"uid"`
+	// Amount is optional in the smallest unit or a decimal string of the transaction currency, the whole remaining refundable amount is refunded when it is zero.
+	Amount    money.Amount `json:"amount"`
+	Reference string       `json:"reference"`
+}
+
+func (p RefundParams) Validate() apierr.JSON {
+	return validateReversal(p.UID, p.Reference, p.Amount)
+}
+
+type ReverseParams struct {
+	// UID of the transaction to be reversed
+	UID string `json:"uid"`
+	// Amount is optional in the smallest unit or a decimal string of the transaction currency, the whole remaining refundable amount is reversed when it is zero.
+	Amount    money.Amount `json:"amount"`
+	Reference string       `json:"reference"`
+}
+
+func (p ReverseParams) Validate() apierr.JSON {
+	return validateReversal(p.UID, p.Reference, p.Amount)
+}
+
+type GetParams struct {
+	UID string `schema:"uid"`
+}
+
+func (p GetParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	return nil
+}
+
+type SearchParams struct {
+	MetadataKey string `schema:"metadata_key"`
+	// MetadataValue is optional, every transaction having the key is matched when it is empty.
+	MetadataValue string `schema:"metadata_value"`
+	Limit         int    `schema:"limit"`
+	StartingAfter string `schema:"starting_after"`
+}
+
+func (p SearchParams) Validate() apierr.JSON {
+	if err := metadata.ValidateKey(p.MetadataKey); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if len(p.MetadataValue) > metadata.MaxValueLength {
+		return apierr.BadRequest(metadata.ErrValueTooLong.Error())
+	}
+	if p.Limit < 0 || p.Limit > maxSearchLimit {
+		return apierr.BadRequest("limit must be between 1 and 100")
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package transactions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/utils/metadata"
+)
+
+type ReversalResponse struct {
+	UID         string       `json:"uid"`
+	OriginalUID string       `json:"original_uid"`
+	Type        dao.TxType   `json:"type"`
+	Status      dao.TxStatus `json:"status"`
+	Reference   string       `json:"reference"`
+	Currency    string       `json:"currency"`
+	Amount      int64        `json:"amount"`
+	// AmountDecimal is Amount in the precision of the currency
+	AmountDecimal string `json:"amount_decimal"`
+}
+
+func (r ReversalResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+type Transaction struct {
+	UID            string       `json:"uid"`
+	OriginalUID    string       `json:"original_uid,omitempty"`
+	Type           dao.TxType   `json:"type"`
+	Status         dao.TxStatus `json:"status"`
+	Reference      string       `json:"reference"`
+	Currency       string       `json:"currency"`
+	Amount         int64        `json:"amount"`
+	RefundedAmount int64        `json:"refunded_amount"`
+	// AmountDecimal and RefundedAmountDecimal are the amounts in the precision of the currency
+	AmountDecimal         string            `json:"amount_decimal"`
+	RefundedAmountDecimal string            `json:"refunded_amount_decimal"`
+	Metadata              metadata.Metadata `json:"metadata,omitempty"`
+	CreatedAt             time.Time         `json:"created_at"`
+}
+
+type TransactionResponse struct {
+	Transaction
+}
+
+func (r TransactionResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type SearchResponse struct {
+	Data    []Transaction `json:"data"`
+	HasMore bool          `json:"has_more"`
+}
+
+func (r SearchResponse) StatusCode() int {
+	return http.StatusOK
+}
@@ -0,0 +1,184 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+type Service interface {
+	Refund(ctx context.Context, params RefundParams) (*ReversalResponse, apierr.JSON)
+	Reverse(ctx context.Context, params ReverseParams) (*ReversalResponse, apierr.JSON)
+	Get(ctx context.Context, params GetParams) (*TransactionResponse, apierr.JSON)
+	Search(ctx context.Context, params SearchParams) (*SearchResponse, apierr.JSON)
+}
+
+type service struct {
+	transactions dao.TransactionsRepository
+}
+
+func New(transactionsDAO dao.TransactionsRepository) Service {
+	return &service{
+		transactions: transactionsDAO,
+	}
+}
+
+func authUsername(ctx context.Context) (string, apierr.JSON) {
+	username, ok := ctx.Value(log.UsernameKey).(string)
+	if !ok || username == "" {
+		return "", apierr.Unauthenticated()
+	}
+	return username, nil
+}
+
+// toAPIErr maps the errors returned from dao into the error response of the API.
+func toAPIErr(ctx context.Context, err error) apierr.JSON {
+	switch {
+	case errors.Is(err, apierr.NotFound):
+		return apierr.ResourceNotFound("transaction or wallet not found")
+	case errors.Is(err, apierr.InsufficientFund):
+		return apierr.Unprocessable("insufficient fund")
+	case errors.Is(err, apierr.WalletFrozen):
+		return apierr.NewJSON(http.StatusForbidden, apierr.CodeWalletFrozen, "wallet is frozen", err)
+	case errors.Is(err, apierr.WalletClosed):
+		return apierr.NewJSON(http.StatusForbidden, apierr.CodeWalletClosed, "wallet is closed", err)
+	case errors.Is(err, apierr.PeriodClosed):
+		return apierr.NewJSON(http.StatusConflict, apierr.CodePeriodClosed, "accounting period is closed, please try again", err)
+	case errors.Is(err, dao.ErrTxNotReversible):
+		return apierr.Unprocessable("transaction is not reversible")
+	case errors.Is(err, dao.ErrTxAlreadyReversed):
+		return apierr.Conflict("transaction is already fully reversed")
+	case errors.Is(err, dao.ErrReverseExceedTxAmt):
+		return apierr.Unprocessable("amount exceed the refundable amount of the transaction")
+	case errors.Is(err, dao.ErrPartialBulkReversal):
+		return apierr.Unprocessable("bulk transfer can only be reversed in full")
+	}
+	log.Error(ctx, "failed in transactions service with err: %s", err)
+	return apierr.InternalServer("please try again")
+}
+
+func toReversalResponse(tx *dao.TransactionsModel) *ReversalResponse {
+	return &ReversalResponse{
+		UID:           tx.UID,
+		OriginalUID:   tx.OriginalUID,
+		Type:          tx.Type,
+		Status:        tx.Status,
+		Reference:     tx.Reference,
+		Currency:      tx.Currency,
+		Amount:        tx.Amount,
+		AmountDecimal: money.FormatDecimal(tx.Amount, tx.Currency),
+	}
+}
+
+func (s *service) Refund(ctx context.Context, params RefundParams) (*ReversalResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	amount, jsonErr := s.reversalAmount(ctx, params.UID, params.Amount)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	tx, err := s.transactions.Refund(ctx, params.UID, username, params.Reference, amount)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return toReversalResponse(tx), nil
+}
+
+func (s *service) Reverse(ctx context.Context, params ReverseParams) (*ReversalResponse, apierr.JSON) {
+	admin, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	amount, jsonErr := s.reversalAmount(ctx, params.UID, params.Amount)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	tx, err := s.transactions.Reverse(ctx, params.UID, admin, params.Reference, amount)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return toReversalResponse(tx), nil
+}
+
+// reversalAmount returns the amount in the smallest unit of the currency of the original transaction,
+// zero is kept as the whole remaining refundable amount.
+func (s *service) reversalAmount(ctx context.Context, uid string, amount money.Amount) (int64, apierr.JSON) {
+	if amount.IsZero() {
+		return 0, nil
+	}
+	original, err := s.transactions.GetByUID(ctx, uid)
+	if err != nil {
+		return 0, toAPIErr(ctx, err)
+	}
+	reversed, err := amount.Of(original.Currency)
+	if err != nil {
+		return 0, apierr.BadRequest(err.Error())
+	}
+	if reversed.IsNegative() {
+		return 0, apierr.BadRequest("amount must be greater than zero")
+	}
+	return reversed.Amount(), nil
+}
+
+func toTransaction(tx dao.TransactionsModel) Transaction {
+	return Transaction{
+		UID:                   tx.UID,
+		OriginalUID:           tx.OriginalUID,
+		Type:                  tx.Type,
+		Status:                tx.Status,
+		Reference:             tx.Reference,
+		Currency:              tx.Currency,
+		Amount:                tx.Amount,
+		RefundedAmount:        tx.RefundedAmount,
+		AmountDecimal:         money.FormatDecimal(tx.Amount, tx.Currency),
+		RefundedAmountDecimal: money.FormatDecimal(tx.RefundedAmount, tx.Currency),
+		Metadata:              tx.Metadata,
+		CreatedAt:             tx.CreatedAt,
+	}
+}
+
+func (s *service) Get(ctx context.Context, params GetParams) (*TransactionResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	tx, err := s.transactions.GetByUID(ctx, params.UID)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	// Hide the transaction initiated by other users
+	if tx.InitiatedBy != username {
+		return nil, apierr.ResourceNotFound("transaction not found")
+	}
+	return &TransactionResponse{Transaction: toTransaction(*tx)}, nil
+}
+
+func (s *service) Search(ctx context.Context, params SearchParams) (*SearchResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	txs, hasMore, err := s.transactions.ListByMetadata(ctx, limit, params.StartingAfter, username, params.MetadataKey, params.MetadataValue)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	data := make([]Transaction, 0, len(txs))
+	for _, tx := range txs {
+		data = append(data, toTransaction(tx))
+	}
+	return &SearchResponse{
+		Data:    data,
+		HasMore: hasMore,
+	}, nil
+}
@@ -0,0 +1,211 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/metadata"
+	"github.com/lengzuo/fundflow/utils/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func authCtx(t *testing.T, username string) context.Context {
+	return context.WithValue(t.Context(), log.UsernameKey, username)
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "refund ok", params: RefundParams{UID: "uid", Reference: "ref"}},
+		{name: "refund partial ok", params: RefundParams{UID: "uid", Amount: money.MinorUnits(10), Reference: "ref"}},
+		{name: "refund partial decimal ok", params: RefundParams{UID: "uid", Amount: money.DecimalAmount("12.34"), Reference: "ref"}},
+		{name: "refund missing uid", params: RefundParams{Reference: "ref"}, wantErr: true},
+		{name: "refund negative amount", params: RefundParams{UID: "uid", Amount: money.MinorUnits(-1), Reference: "ref"}, wantErr: true},
+		{name: "reverse negative decimal amount", params: ReverseParams{UID: "uid", Amount: money.DecimalAmount("-1.00"), Reference: "ref"}, wantErr: true},
+		{name: "reverse missing reference", params: ReverseParams{UID: "uid"}, wantErr: true},
+		{name: "get missing uid", params: GetParams{}, wantErr: true},
+		{name: "search ok", params: SearchParams{MetadataKey: "order_id", MetadataValue: "123"}},
+		{name: "search missing key", params: SearchParams{MetadataValue: "123"}, wantErr: true},
+		{name: "search invalid key", params: SearchParams{MetadataKey: "order id"}, wantErr: true},
+		{name: "search limit too large", params: SearchParams{MetadataKey: "order_id", Limit: 101}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_Refund(t *testing.T) {
+	t.Run("ok refund", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("GetByUID", mock.Anything, "uid").Return(&dao.TransactionsModel{UID: "uid", Type: dao.TypeTransfer, Currency: "SGD", Amount: 100}, nil)
+		txRepo.On("Refund", mock.Anything, "uid", "merchant", "ref", int64(50)).Return(&dao.TransactionsModel{
+			UID: "refund", OriginalUID: "uid", Type: dao.TypeRefund, Status: dao.StatusCompleted, Reference: "ref", Currency: "SGD", Amount: 50,
+		}, nil)
+		s := New(txRepo)
+		resp, err := s.Refund(authCtx(t, "merchant"), RefundParams{UID: "uid", Amount: money.MinorUnits(50), Reference: "ref"})
+		assert.Nil(t, err)
+		assert.Equal(t, "uid", resp.OriginalUID)
+		assert.Equal(t, dao.TypeRefund, resp.Type)
+		assert.Equal(t, int64(50), resp.Amount)
+		assert.Equal(t, "0.50", resp.AmountDecimal)
+	})
+
+	tests := []struct {
+		name       string
+		daoErr     error
+		wantStatus int
+	}{
+		{name: "refund not found", daoErr: apierr.NotFound, wantStatus: http.StatusNotFound},
+		{name: "refund already reversed", daoErr: dao.ErrTxAlreadyReversed, wantStatus: http.StatusConflict},
+		{name: "refund exceed amount", daoErr: dao.ErrReverseExceedTxAmt, wantStatus: http.StatusUnprocessableEntity},
+		{name: "refund not reversible", daoErr: dao.ErrTxNotReversible, wantStatus: http.StatusUnprocessableEntity},
+		{name: "refund insufficient fund", daoErr: apierr.InsufficientFund, wantStatus: http.StatusUnprocessableEntity},
+		{name: "refund unexpected error", daoErr: errors.New("err"), wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txRepo := mocks.NewTransactionsRepository(t)
+			txRepo.On("Refund", mock.Anything, "uid", "merchant", "ref", int64(0)).Return(nil, tt.daoErr)
+			s := New(txRepo)
+			resp, err := s.Refund(authCtx(t, "merchant"), RefundParams{UID: "uid", Reference: "ref"})
+			assert.Nil(t, resp)
+			assert.Equal(t, tt.wantStatus, err.HTTPStatusCode())
+		})
+	}
+
+	t.Run("ok refund decimal amount in transaction currency", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("GetByUID", mock.Anything, "uid").Return(&dao.TransactionsModel{UID: "uid", Type: dao.TypeTransfer, Currency: "SGD", Amount: 2000}, nil)
+		txRepo.On("Refund", mock.Anything, "uid", "merchant", "ref", int64(1234)).Return(&dao.TransactionsModel{
+			UID: "refund", OriginalUID: "uid", Type: dao.TypeRefund, Status: dao.StatusCompleted, Reference: "ref", Currency: "SGD", Amount: 1234,
+		}, nil)
+		s := New(txRepo)
+		resp, err := s.Refund(authCtx(t, "merchant"), RefundParams{UID: "uid", Amount: money.DecimalAmount("12.34"), Reference: "ref"})
+		assert.Nil(t, err)
+		assert.Equal(t, "12.34", resp.AmountDecimal)
+	})
+
+	t.Run("refund with too many decimals for transaction currency", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("GetByUID", mock.Anything, "uid").Return(&dao.TransactionsModel{UID: "uid", Type: dao.TypeTransfer, Currency: "JPY", Amount: 2000}, nil)
+		s := New(txRepo)
+		resp, err := s.Refund(authCtx(t, "merchant"), RefundParams{UID: "uid", Amount: money.DecimalAmount("12.34"), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+	})
+
+	t.Run("refund without auth", func(t *testing.T) {
+		s := New(mocks.NewTransactionsRepository(t))
+		resp, err := s.Refund(t.Context(), RefundParams{UID: "uid", Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Reverse(t *testing.T) {
+	t.Run("ok reverse", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("Reverse", mock.Anything, "uid", "admin", "ref", int64(0)).Return(&dao.TransactionsModel{
+			UID: "reversal", OriginalUID: "uid", Type: dao.TypeReversal, Status: dao.StatusCompleted, Reference: "ref", Currency: "SGD", Amount: 100,
+		}, nil)
+		s := New(txRepo)
+		resp, err := s.Reverse(authCtx(t, "admin"), ReverseParams{UID: "uid", Reference: "ref"})
+		assert.Nil(t, err)
+		assert.Equal(t, dao.TypeReversal, resp.Type)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	})
+
+	t.Run("ok partial reverse decimal amount", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("GetByUID", mock.Anything, "uid").Return(&dao.TransactionsModel{UID: "uid", Type: dao.TypeWithdraw, Currency: "SGD", Amount: 2000}, nil)
+		txRepo.On("Reverse", mock.Anything, "uid", "admin", "ref", int64(1234)).Return(&dao.TransactionsModel{
+			UID: "reversal", OriginalUID: "uid", Type: dao.TypeReversal, Status: dao.StatusCompleted, Reference: "ref", Currency: "SGD", Amount: 1234,
+		}, nil)
+		s := New(txRepo)
+		resp, err := s.Reverse(authCtx(t, "admin"), ReverseParams{UID: "uid", Amount: money.DecimalAmount("12.34"), Reference: "ref"})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1234), resp.Amount)
+	})
+
+	t.Run("reverse amount of unknown transaction", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("GetByUID", mock.Anything, "uid").Return(nil, apierr.NotFound)
+		s := New(txRepo)
+		resp, err := s.Reverse(authCtx(t, "admin"), ReverseParams{UID: "uid", Amount: money.DecimalAmount("12.34"), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Get(t *testing.T) {
+	t.Run("ok get", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("GetByUID", mock.Anything, "uid").Return(&dao.TransactionsModel{
+			UID: "uid", InitiatedBy: "user1", Type: dao.TypeDeposit, Amount: 100, Metadata: metadata.Metadata{"order_id": "123"},
+		}, nil)
+		s := New(txRepo)
+		resp, err := s.Get(authCtx(t, "user1"), GetParams{UID: "uid"})
+		assert.Nil(t, err)
+		assert.Equal(t, metadata.Metadata{"order_id": "123"}, resp.Metadata)
+	})
+
+	t.Run("get transaction of other user", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("GetByUID", mock.Anything, "uid").Return(&dao.TransactionsModel{UID: "uid", InitiatedBy: "user2"}, nil)
+		s := New(txRepo)
+		resp, err := s.Get(authCtx(t, "user1"), GetParams{UID: "uid"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	})
+
+	t.Run("get transaction not found", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("GetByUID", mock.Anything, "uid").Return(nil, apierr.NotFound)
+		s := New(txRepo)
+		resp, err := s.Get(authCtx(t, "user1"), GetParams{UID: "uid"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Search(t *testing.T) {
+	t.Run("ok search with default limit", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("ListByMetadata", mock.Anything, defaultSearchLimit, "", "user1", "order_id", "123").Return([]dao.TransactionsModel{
+			{UID: "uid", Metadata: metadata.Metadata{"order_id": "123"}},
+		}, true, nil)
+		s := New(txRepo)
+		resp, err := s.Search(authCtx(t, "user1"), SearchParams{MetadataKey: "order_id", MetadataValue: "123"})
+		assert.Nil(t, err)
+		assert.True(t, resp.HasMore)
+		assert.Len(t, resp.Data, 1)
+		assert.Equal(t, "uid", resp.Data[0].UID)
+	})
+
+	t.Run("search error", func(t *testing.T) {
+		txRepo := mocks.NewTransactionsRepository(t)
+		txRepo.On("ListByMetadata", mock.Anything, 5, "", "user1", "order_id", "").Return(nil, false, errors.New("err"))
+		s := New(txRepo)
+		resp, err := s.Search(authCtx(t, "user1"), SearchParams{MetadataKey: "order_id", Limit: 5})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, err.HTTPStatusCode())
+	})
+}
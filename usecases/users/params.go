@@ -0,0 +1,48 @@
+package users
+
+import (
+	"regexp"
+
+	"github.com/lengzuo/fundflow/internal/apierr"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores anything after 72 bytes
+	maxPasswordLength = 72
+)
+
+var usernameRegex = regexp.MustCompile(`^[a-z0-9_]{3,32}$`)
+
+type SignUpParams struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (p SignUpParams) Validate() apierr.JSON {
+	if !usernameRegex.MatchString(p.Username) {
+		return apierr.BadRequest("username must be 3 to 32 characters of lowercase letters, digits or underscore")
+	}
+	if len(p.Password) < minPasswordLength || len(p.Password) > maxPasswordLength {
+		return apierr.BadRequest("password must be between 8 and 72 characters")
+	}
+	return nil
+}
+
+type LoginParams struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (p LoginParams) Validate() apierr.JSON {
+	if p.Username == "" || p.Password == "" {
+		return apierr.BadRequest("username and password are mandatory")
+	}
+	return nil
+}
+
+type LogoutParams struct{}
+
+func (p LogoutParams) Validate() apierr.JSON {
+	return nil
+}
@@ -0,0 +1,32 @@
+package users
+
+import (
+	"net/http"
+	"time"
+)
+
+type SignUpResponse struct {
+	Username string `json:"username"`
+}
+
+func (r SignUpResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (r LoginResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type LogoutResponse struct {
+	Revoked bool `json:"revoked"`
+}
+
+func (r LogoutResponse) StatusCode() int {
+	return http.StatusOK
+}
@@ -0,0 +1,96 @@
+package users
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/auth"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/password"
+)
+
+// dummyHash is compared against when the username doesn't exist, so the response time
+// of login doesn't reveal which usernames are registered.
+const dummyHash = "$2a$12$Id/GmMgK7Y14IdEOUd8/G.MA4rG.BLSPC.DS6maPVd/3HRy6UTXZm"
+
+const tokenType = "Bearer"
+
+type Service interface {
+	SignUp(ctx context.Context, params SignUpParams) (*SignUpResponse, apierr.JSON)
+	Login(ctx context.Context, params LoginParams) (*LoginResponse, apierr.JSON)
+	Logout(ctx context.Context, params LogoutParams) (*LogoutResponse, apierr.JSON)
+}
+
+type service struct {
+	users  dao.UserRepository
+	tokens auth.Tokens
+}
+
+func New(usersDAO dao.UserRepository, tokens auth.Tokens) Service {
+	return &service{
+		users:  usersDAO,
+		tokens: tokens,
+	}
+}
+
+func (s *service) SignUp(ctx context.Context, params SignUpParams) (*SignUpResponse, apierr.JSON) {
+	hashed, err := password.Hash(params.Password)
+	if err != nil {
+		log.Error(ctx, "failed to hash password with err: %s", err)
+		return nil, apierr.InternalServer("please try again")
+	}
+	err = s.users.Insert(ctx, &dao.UsersModel{
+		Username: params.Username,
+		Password: hashed,
+	})
+	if err != nil {
+		if errors.Is(err, dao.ErrAlreadyExists) {
+			return nil, apierr.Conflict("username already exists")
+		}
+		return nil, apierr.InternalServer("please try again")
+	}
+	return &SignUpResponse{Username: params.Username}, nil
+}
+
+func (s *service) Login(ctx context.Context, params LoginParams) (*LoginResponse, apierr.JSON) {
+	user, err := s.users.Get(ctx, params.Username)
+	if err != nil && !errors.Is(err, apierr.NotFound) {
+		return nil, apierr.InternalServer("please try again")
+	}
+	if user == nil {
+		_ = password.Verify(dummyHash, params.Password)
+		return nil, apierr.Unauthenticated()
+	}
+	err = password.Verify(user.Password, params.Password)
+	if err != nil {
+		if !errors.Is(err, password.ErrMismatched) {
+			log.Error(ctx, "failed to verify password of %s with err: %s", user.Username, err)
+		}
+		return nil, apierr.Unauthenticated()
+	}
+	if !user.Active {
+		return nil, apierr.Forbidden("user is inactive")
+	}
+	token, expiresAt, err := s.tokens.Issue(ctx, user.Username)
+	if err != nil {
+		return nil, apierr.InternalServer("please try again")
+	}
+	return &LoginResponse{
+		Token:     token,
+		TokenType: tokenType,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *service) Logout(ctx context.Context, params LogoutParams) (*LogoutResponse, apierr.JSON) {
+	token, ok := auth.TokenFromContext(ctx)
+	if !ok {
+		return nil, apierr.Unauthenticated()
+	}
+	if err := s.tokens.Revoke(ctx, token); err != nil {
+		return nil, apierr.InternalServer("please try again")
+	}
+	return &LogoutResponse{Revoked: true}, nil
+}
@@ -0,0 +1,150 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/auth"
+	authmocks "github.com/lengzuo/fundflow/internal/auth/mocks"
+	"github.com/lengzuo/fundflow/utils/password"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "signup ok", params: SignUpParams{Username: "user_11", Password: "password"}},
+		{name: "signup username too short", params: SignUpParams{Username: "ab", Password: "password"}, wantErr: true},
+		{name: "signup username uppercase", params: SignUpParams{Username: "User11", Password: "password"}, wantErr: true},
+		{name: "signup username with space", params: SignUpParams{Username: "user 11", Password: "password"}, wantErr: true},
+		{name: "signup password too short", params: SignUpParams{Username: "user11", Password: "pass"}, wantErr: true},
+		{name: "signup password too long", params: SignUpParams{Username: "user11", Password: string(make([]byte, 73))}, wantErr: true},
+		{name: "login ok", params: LoginParams{Username: "user1", Password: "password"}},
+		{name: "login missing password", params: LoginParams{Username: "user1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_SignUp(t *testing.T) {
+	t.Run("ok signup stores hashed password", func(t *testing.T) {
+		usersRepo := mocks.NewUserRepository(t)
+		usersRepo.On("Insert", mock.Anything, mock.MatchedBy(func(u *dao.UsersModel) bool {
+			return u.Username == "user11" && u.Password != "password" && password.Verify(u.Password, "password") == nil
+		})).Return(nil)
+		s := New(usersRepo, authmocks.NewTokens(t))
+		resp, err := s.SignUp(t.Context(), SignUpParams{Username: "user11", Password: "password"})
+		assert.Nil(t, err)
+		assert.Equal(t, &SignUpResponse{Username: "user11"}, resp)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	})
+
+	t.Run("username already exists", func(t *testing.T) {
+		usersRepo := mocks.NewUserRepository(t)
+		usersRepo.On("Insert", mock.Anything, mock.Anything).Return(dao.ErrAlreadyExists)
+		s := New(usersRepo, authmocks.NewTokens(t))
+		resp, err := s.SignUp(t.Context(), SignUpParams{Username: "user1", Password: "password"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusConflict, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Login(t *testing.T) {
+	hashed, err := password.Hash("password")
+	assert.NoError(t, err)
+
+	t.Run("ok login", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour)
+		usersRepo := mocks.NewUserRepository(t)
+		usersRepo.On("Get", mock.Anything, "user1").Return(&dao.UsersModel{Username: "user1", Password: hashed, Active: true}, nil)
+		tokens := authmocks.NewTokens(t)
+		tokens.On("Issue", mock.Anything, "user1").Return("token", expiresAt, nil)
+		s := New(usersRepo, tokens)
+		resp, jsonErr := s.Login(t.Context(), LoginParams{Username: "user1", Password: "password"})
+		assert.Nil(t, jsonErr)
+		assert.Equal(t, &LoginResponse{Token: "token", TokenType: "Bearer", ExpiresAt: expiresAt}, resp)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		usersRepo := mocks.NewUserRepository(t)
+		usersRepo.On("Get", mock.Anything, "user1").Return(&dao.UsersModel{Username: "user1", Password: hashed, Active: true}, nil)
+		s := New(usersRepo, authmocks.NewTokens(t))
+		resp, jsonErr := s.Login(t.Context(), LoginParams{Username: "user1", Password: "password1"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, jsonErr.HTTPStatusCode())
+	})
+
+	t.Run("unknown username", func(t *testing.T) {
+		usersRepo := mocks.NewUserRepository(t)
+		usersRepo.On("Get", mock.Anything, "nobody").Return(nil, apierr.NotFound)
+		s := New(usersRepo, authmocks.NewTokens(t))
+		resp, jsonErr := s.Login(t.Context(), LoginParams{Username: "nobody", Password: "password"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, jsonErr.HTTPStatusCode())
+	})
+
+	t.Run("inactive user", func(t *testing.T) {
+		usersRepo := mocks.NewUserRepository(t)
+		usersRepo.On("Get", mock.Anything, "user1").Return(&dao.UsersModel{Username: "user1", Password: hashed, Active: false}, nil)
+		s := New(usersRepo, authmocks.NewTokens(t))
+		resp, jsonErr := s.Login(t.Context(), LoginParams{Username: "user1", Password: "password"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusForbidden, jsonErr.HTTPStatusCode())
+	})
+
+	t.Run("issue token error", func(t *testing.T) {
+		usersRepo := mocks.NewUserRepository(t)
+		usersRepo.On("Get", mock.Anything, "user1").Return(&dao.UsersModel{Username: "user1", Password: hashed, Active: true}, nil)
+		tokens := authmocks.NewTokens(t)
+		tokens.On("Issue", mock.Anything, "user1").Return("", time.Time{}, errors.New("err"))
+		s := New(usersRepo, tokens)
+		resp, jsonErr := s.Login(t.Context(), LoginParams{Username: "user1", Password: "password"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, jsonErr.HTTPStatusCode())
+	})
+}
+
+func Test_service_Logout(t *testing.T) {
+	t.Run("ok logout revoke current token", func(t *testing.T) {
+		tokens := authmocks.NewTokens(t)
+		tokens.On("Revoke", mock.Anything, "token").Return(nil)
+		s := New(mocks.NewUserRepository(t), tokens)
+		resp, jsonErr := s.Logout(auth.WithToken(t.Context(), "token"), LogoutParams{})
+		assert.Nil(t, jsonErr)
+		assert.True(t, resp.Revoked)
+	})
+
+	t.Run("logout without token", func(t *testing.T) {
+		s := New(mocks.NewUserRepository(t), authmocks.NewTokens(t))
+		resp, jsonErr := s.Logout(t.Context(), LogoutParams{})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, jsonErr.HTTPStatusCode())
+	})
+
+	t.Run("revoke error", func(t *testing.T) {
+		tokens := authmocks.NewTokens(t)
+		tokens.On("Revoke", mock.Anything, "token").Return(errors.New("err"))
+		s := New(mocks.NewUserRepository(t), tokens)
+		resp, jsonErr := s.Logout(auth.WithToken(t.Context(), "token"), LogoutParams{})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, jsonErr.HTTPStatusCode())
+	})
+}
@@ -0,0 +1,40 @@
+package wallets
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor hides the keyset of the ledger from the client, it is the url safe base64 of
+// created_at in unix microseconds, the precision of postgres, and the ledger id.
+func encodeCursor(createdAt time.Time, ledgerID int) string {
+	raw := strconv.FormatInt(createdAt.UnixMicro(), 10) + ":" + strconv.Itoa(ledgerID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*dao.HistoryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+	createdAt, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	ledgerID, err := strconv.Atoi(id)
+	if err != nil || ledgerID <= 0 {
+		return nil, errInvalidCursor
+	}
+	return &dao.HistoryCursor{CreatedAt: time.UnixMicro(createdAt).UTC(), LedgerID: ledgerID}, nil
+}
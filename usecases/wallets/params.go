@@ -0,0 +1,369 @@
+package wallets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/statements"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/metadata"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+	maxReferenceLength  = 64
+	maxReasonLength     = 255
+	// maxBulkTransferItems bounds the wallets locked by a single bulk transfer
+	maxBulkTransferItems = 500
+	// maxStatementDays bounds the period of a single statement export
+	maxStatementDays    = 366
+	statementDateLayout = "2006-01-02"
+)
+
+func validateAmount(currencyCode, reference string, amount money.Amount, meta metadata.Metadata) apierr.JSON {
+	if err := currency.Supported(currencyCode); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if err := validatePositive(currencyCode, amount); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	return validateReference(reference, meta)
+}
+
+func validatePositive(currencyCode string, amount money.Amount) error {
+	m, err := amount.Of(currencyCode)
+	if err != nil {
+		return err
+	}
+	if !m.IsPositive() {
+		return errors.New("amount must be greater than zero")
+	}
+	return nil
+}
+
+func validateReference(reference string, meta metadata.Metadata) apierr.JSON {
+	if strings.TrimSpace(reference) == "" {
+		return apierr.BadRequest("reference is mandatory")
+	}
+	if len(reference) > maxReferenceLength {
+		return apierr.BadRequest("reference is too long")
+	}
+	if err := meta.Validate(); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	return nil
+}
+
+type DepositParams struct {
+	Currency  string            `json:"currency"`
+	Amount    money.Amount      `json:"amount"`
+	Reference string            `json:"reference"`
+	Metadata  metadata.Metadata `json:"metadata"`
+}
+
+func (p DepositParams) Validate() apierr.JSON {
+	return validateAmount(p.Currency, p.Reference, p.Amount, p.Metadata)
+}
+
+type WithdrawParams struct {
+	Currency  string            `json:"currency"`
+	Amount    money.Amount      `json:"amount"`
+	Reference string            `json:"reference"`
+	Metadata  metadata.Metadata `json:"metadata"`
+}
+
+func (p WithdrawParams) Validate() apierr.JSON {
+	return validateAmount(p.Currency, p.Reference, p.Amount, p.Metadata)
+}
+
+type TransferParams struct {
+	Receiver  string            `json:"receiver"`
+	Currency  string            `json:"currency"`
+	Amount    money.Amount      `json:"amount"`
+	Reference string            `json:"reference"`
+	Metadata  metadata.Metadata `json:"metadata"`
+}
+
+func (p TransferParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.Receiver) == "" {
+		return apierr.BadRequest("receiver is mandatory")
+	}
+	return validateAmount(p.Currency, p.Reference, p.Amount, p.Metadata)
+}
+
+type BulkTransferItem struct {
+	Receiver string       `json:"receiver"`
+	Amount   money.Amount `json:"amount"`
+}
+
+type BulkTransferParams struct {
+	Currency  string             `json:"currency"`
+	Reference string             `json:"reference"`
+	Metadata  metadata.Metadata  `json:"metadata"`
+	Items     []BulkTransferItem `json:"items"`
+}
+
+func (p BulkTransferParams) Validate() apierr.JSON {
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if err := validateReference(p.Reference, p.Metadata); err != nil {
+		return err
+	}
+	if len(p.Items) == 0 {
+		return apierr.BadRequest("items is mandatory")
+	}
+	if len(p.Items) > maxBulkTransferItems {
+		return apierr.BadRequest(fmt.Sprintf("items must not be more than %d", maxBulkTransferItems))
+	}
+	receivers := make(map[string]struct{}, len(p.Items))
+	for i, item := range p.Items {
+		if strings.TrimSpace(item.Receiver) == "" {
+			return apierr.BadRequest(fmt.Sprintf("items[%d]: receiver is mandatory", i))
+		}
+		if _, ok := receivers[item.Receiver]; ok {
+			return apierr.BadRequest(fmt.Sprintf("items[%d]: receiver %s is duplicated", i, item.Receiver))
+		}
+		receivers[item.Receiver] = struct{}{}
+		if err := validatePositive(p.Currency, item.Amount); err != nil {
+			return apierr.BadRequest(fmt.Sprintf("items[%d]: %s", i, err.Error()))
+		}
+	}
+	return nil
+}
+
+type BalanceParams struct {
+	Currencies []string `schema:"currency"`
+}
+
+func (p BalanceParams) Validate() apierr.JSON {
+	for _, c := range p.Currencies {
+		if err := currency.Supported(c); err != nil {
+			return apierr.BadRequest(err.Error())
+		}
+	}
+	return nil
+}
+
+// HistoryParams lists the history of the wallet, every filter is optional. type and status can be repeated,
+// min_amount and max_amount are inclusive decimals in the precision of the currency, and from and to are
+// inclusive UTC dates such as 2026-06-30. starting_after and ending_before are the cursors of the response.
+type HistoryParams struct {
+	Currency      string         `schema:"currency"`
+	Limit         int            `schema:"limit"`
+	StartingAfter string         `schema:"starting_after"`
+	EndingBefore  string         `schema:"ending_before"`
+	Types         []dao.TxType   `schema:"type"`
+	Statuses      []dao.TxStatus `schema:"status"`
+	Direction     dao.Direction  `schema:"direction"`
+	MinAmount     string         `schema:"min_amount"`
+	MaxAmount     string         `schema:"max_amount"`
+	From          string         `schema:"from"`
+	To            string         `schema:"to"`
+	Reference     string         `schema:"reference"`
+}
+
+var (
+	historyTypes = map[dao.TxType]bool{
+		dao.TypeDeposit: true, dao.TypeWithdraw: true, dao.TypeTransfer: true, dao.TypeBulkTransfer: true,
+		dao.TypeHold: true, dao.TypeReversal: true, dao.TypeRefund: true, dao.TypeExchange: true,
+	}
+	historyStatuses = map[dao.TxStatus]bool{
+		dao.StatusPending: true, dao.StatusCompleted: true, dao.StatusFailed: true, dao.StatusCancelled: true,
+	}
+)
+
+func (p HistoryParams) Validate() apierr.JSON {
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if p.Limit < 0 || p.Limit > maxHistoryLimit {
+		return apierr.BadRequest("limit must be between 1 and 100")
+	}
+	if _, jsonErr := p.page(); jsonErr != nil {
+		return jsonErr
+	}
+	_, jsonErr := p.filter()
+	return jsonErr
+}
+
+// page decodes the cursors of the history into the dao page.
+func (p HistoryParams) page() (dao.HistoryPage, apierr.JSON) {
+	page := dao.HistoryPage{Limit: p.Limit}
+	if page.Limit == 0 {
+		page.Limit = defaultHistoryLimit
+	}
+	if p.StartingAfter != "" && p.EndingBefore != "" {
+		return page, apierr.BadRequest("only one of starting_after and ending_before is allowed")
+	}
+	var err error
+	if p.StartingAfter != "" {
+		if page.StartingAfter, err = decodeCursor(p.StartingAfter); err != nil {
+			return page, apierr.BadRequest("starting_after is not a valid cursor")
+		}
+	}
+	if p.EndingBefore != "" {
+		if page.EndingBefore, err = decodeCursor(p.EndingBefore); err != nil {
+			return page, apierr.BadRequest("ending_before is not a valid cursor")
+		}
+	}
+	return page, nil
+}
+
+// filter parses the filters of the history into the dao filter.
+func (p HistoryParams) filter() (dao.HistoryFilter, apierr.JSON) {
+	filter := dao.HistoryFilter{
+		Types:     p.Types,
+		Statuses:  p.Statuses,
+		Direction: p.Direction,
+		Reference: p.Reference,
+	}
+	for _, t := range p.Types {
+		if !historyTypes[t] {
+			return filter, apierr.BadRequest(fmt.Sprintf("type %q is not supported", t))
+		}
+	}
+	for _, status := range p.Statuses {
+		if !historyStatuses[status] {
+			return filter, apierr.BadRequest(fmt.Sprintf("status %q is not supported", status))
+		}
+	}
+	if p.Direction != "" && p.Direction != dao.DirectionCredit && p.Direction != dao.DirectionDebit {
+		return filter, apierr.BadRequest("direction must be c or d")
+	}
+	if len(p.Reference) > maxReferenceLength {
+		return filter, apierr.BadRequest("reference is too long")
+	}
+	var err error
+	if p.MinAmount != "" {
+		if filter.MinAmount, err = parseFilterAmount(p.MinAmount, p.Currency); err != nil {
+			return filter, apierr.BadRequest(fmt.Sprintf("min_amount: %s", err))
+		}
+	}
+	if p.MaxAmount != "" {
+		if filter.MaxAmount, err = parseFilterAmount(p.MaxAmount, p.Currency); err != nil {
+			return filter, apierr.BadRequest(fmt.Sprintf("max_amount: %s", err))
+		}
+	}
+	if filter.MinAmount > 0 && filter.MaxAmount > 0 && filter.MaxAmount < filter.MinAmount {
+		return filter, apierr.BadRequest("max_amount must not be less than min_amount")
+	}
+	if p.From != "" {
+		if filter.CreatedFrom, err = time.Parse(statementDateLayout, p.From); err != nil {
+			return filter, apierr.BadRequest("from must be a date such as 2026-06-01")
+		}
+	}
+	if p.To != "" {
+		to, err := time.Parse(statementDateLayout, p.To)
+		if err != nil {
+			return filter, apierr.BadRequest("to must be a date such as 2026-06-30")
+		}
+		filter.CreatedTo = to.AddDate(0, 0, 1)
+	}
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedTo.After(filter.CreatedFrom) {
+		return filter, apierr.BadRequest("to must not be before from")
+	}
+	return filter, nil
+}
+
+// parseFilterAmount parses the positive decimal amount of a filter into the smallest unit of the currency.
+func parseFilterAmount(amount, currencyCode string) (int64, error) {
+	m, err := money.DecimalAmount(amount).Of(currencyCode)
+	if err != nil {
+		return 0, err
+	}
+	if !m.IsPositive() {
+		return 0, errors.New("amount must be positive")
+	}
+	return m.Amount(), nil
+}
+
+// StatementParams is the period of the statement, from and to are inclusive UTC dates such as 2026-01-31.
+type StatementParams struct {
+	Currency string `schema:"currency"`
+	From     string `schema:"from"`
+	To       string `schema:"to"`
+	// Format is one of csv (default), ofx or camt053
+	Format statements.Format `schema:"format"`
+}
+
+func (p StatementParams) Validate() apierr.JSON {
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if p.Format != "" && !p.Format.Valid() {
+		return apierr.BadRequest("format must be one of csv, ofx or camt053")
+	}
+	from, err := time.Parse(statementDateLayout, p.From)
+	if err != nil {
+		return apierr.BadRequest("from must be a date such as 2026-01-01")
+	}
+	to, err := time.Parse(statementDateLayout, p.To)
+	if err != nil {
+		return apierr.BadRequest("to must be a date such as 2026-01-31")
+	}
+	if to.Before(from) {
+		return apierr.BadRequest("to must not be before from")
+	}
+	if to.Sub(from) >= maxStatementDays*24*time.Hour {
+		return apierr.BadRequest(fmt.Sprintf("period must not be longer than %d days", maxStatementDays))
+	}
+	return nil
+}
+
+// period returns the start and the exclusive end of the statement, the params must be validated.
+func (p StatementParams) period() (time.Time, time.Time) {
+	from, _ := time.Parse(statementDateLayout, p.From)
+	to, _ := time.Parse(statementDateLayout, p.To)
+	return from, to.AddDate(0, 0, 1)
+}
+
+func (p StatementParams) format() statements.Format {
+	if p.Format == "" {
+		return statements.FormatCSV
+	}
+	return p.Format
+}
+
+type UpdateStatusParams struct {
+	Username string `json:"username"`
+	Currency string `json:"currency"`
+	Reason   string `json:"reason"`
+}
+
+func (p UpdateStatusParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.Username) == "" {
+		return apierr.BadRequest("username is mandatory")
+	}
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	if strings.TrimSpace(p.Reason) == "" {
+		return apierr.BadRequest("reason is mandatory")
+	}
+	if len(p.Reason) > maxReasonLength {
+		return apierr.BadRequest("reason is too long")
+	}
+	return nil
+}
+
+type StatusHistoriesParams struct {
+	Username string `schema:"username"`
+	Currency string `schema:"currency"`
+}
+
+func (p StatusHistoriesParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.Username) == "" {
+		return apierr.BadRequest("username is mandatory")
+	}
+	if err := currency.Supported(p.Currency); err != nil {
+		return apierr.BadRequest(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package wallets
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/statements"
+	"github.com/lengzuo/fundflow/utils/metadata"
+)
+
+type TransactionResponse struct {
+	// UID of the created transaction, e.g. to be refunded or reversed later
+	UID       string       `json:"uid"`
+	Type      dao.TxType   `json:"type"`
+	Status    dao.TxStatus `json:"status"`
+	Reference string       `json:"reference"`
+	Currency  string       `json:"currency"`
+	Amount    int64        `json:"amount"`
+	// AmountDecimal is Amount in the precision of the currency
+	AmountDecimal string            `json:"amount_decimal"`
+	Metadata      metadata.Metadata `json:"metadata,omitempty"`
+}
+
+func (r TransactionResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+// BulkTransferResult is the outcome of an item, Error is set on the refused item.
+// Every item is cancelled when any of them is refused.
+type BulkTransferResult struct {
+	Receiver      string       `json:"receiver"`
+	Amount        int64        `json:"amount"`
+	AmountDecimal string       `json:"amount_decimal"`
+	Status        dao.TxStatus `json:"status"`
+	Error         apierr.JSON  `json:"error,omitempty"`
+}
+
+type BulkTransferResponse struct {
+	// UID is the transaction shared by every leg, it is empty when the bulk transfer is refused
+	UID       string       `json:"uid,omitempty"`
+	Type      dao.TxType   `json:"type"`
+	Status    dao.TxStatus `json:"status"`
+	Reference string       `json:"reference"`
+	Currency  string       `json:"currency"`
+	// Amount is the total debited from the sender
+	Amount        int64                `json:"amount"`
+	AmountDecimal string               `json:"amount_decimal"`
+	Metadata      metadata.Metadata    `json:"metadata,omitempty"`
+	Items         []BulkTransferResult `json:"items"`
+}
+
+func (r BulkTransferResponse) StatusCode() int {
+	if r.Status != dao.StatusCompleted {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusCreated
+}
+
+type Balance struct {
+	Currency               string `json:"currency"`
+	Amount                 int64  `json:"amount"`
+	HeldAmount             int64  `json:"held_amount"`
+	AvailableAmount        int64  `json:"available_amount"`
+	AmountDecimal          string `json:"amount_decimal"`
+	HeldAmountDecimal      string `json:"held_amount_decimal"`
+	AvailableAmountDecimal string `json:"available_amount_decimal"`
+}
+
+type BalanceResponse struct {
+	Balances []Balance `json:"balances"`
+}
+
+func (r BalanceResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type History struct {
+	UID       string        `json:"uid"`
+	Type      dao.TxType    `json:"type"`
+	Status    dao.TxStatus  `json:"status"`
+	Direction dao.Direction `json:"direction"`
+	Amount    int64         `json:"amount"`
+	// AmountDecimal is Amount in the precision of the currency
+	AmountDecimal string            `json:"amount_decimal"`
+	Currency      string            `json:"currency"`
+	Metadata      metadata.Metadata `json:"metadata,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// HistoryResponse is a page of the history newest first. HasMore tells whether there are more entries in the
+// direction of the paging, NextCursor is the starting_after of the older page and PreviousCursor is the
+// ending_before of the newer page, each is omitted when there is no such page.
+type HistoryResponse struct {
+	Data           []History `json:"data"`
+	HasMore        bool      `json:"has_more"`
+	NextCursor     string    `json:"next_cursor,omitempty"`
+	PreviousCursor string    `json:"previous_cursor,omitempty"`
+}
+
+func (r HistoryResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+// StatementResponse is streamed as a file in its format rather than encoded as json.
+type StatementResponse struct {
+	format   statements.Format
+	filename string
+	write    func(ctx context.Context, w io.Writer) error
+}
+
+func (r StatementResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+func (r StatementResponse) ContentType() string {
+	return r.format.ContentType()
+}
+
+func (r StatementResponse) Filename() string {
+	return r.filename
+}
+
+func (r StatementResponse) Stream(ctx context.Context, w io.Writer) error {
+	return r.write(ctx, w)
+}
+
+type WalletStatusResponse struct {
+	Username string           `json:"username"`
+	Currency string           `json:"currency"`
+	Status   dao.WalletStatus `json:"status"`
+}
+
+func (r WalletStatusResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type StatusHistory struct {
+	FromStatus dao.WalletStatus `json:"from_status"`
+	ToStatus   dao.WalletStatus `json:"to_status"`
+	Reason     string           `json:"reason"`
+	ChangedBy  string           `json:"changed_by"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+type StatusHistoriesResponse struct {
+	Data []StatusHistory `json:"data"`
+}
+
+func (r StatusHistoriesResponse) StatusCode() int {
+	return http.StatusOK
+}
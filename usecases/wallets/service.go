@@ -0,0 +1,371 @@
+package wallets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/statements"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/money"
+)
+
+type Service interface {
+	Deposit(ctx context.Context, params DepositParams) (*TransactionResponse, apierr.JSON)
+	Withdraw(ctx context.Context, params WithdrawParams) (*TransactionResponse, apierr.JSON)
+	Transfer(ctx context.Context, params TransferParams) (*TransactionResponse, apierr.JSON)
+	BulkTransfer(ctx context.Context, params BulkTransferParams) (*BulkTransferResponse, apierr.JSON)
+	Balance(ctx context.Context, params BalanceParams) (*BalanceResponse, apierr.JSON)
+	History(ctx context.Context, params HistoryParams) (*HistoryResponse, apierr.JSON)
+	Statement(ctx context.Context, params StatementParams) (*StatementResponse, apierr.JSON)
+	Freeze(ctx context.Context, params UpdateStatusParams) (*WalletStatusResponse, apierr.JSON)
+	Unfreeze(ctx context.Context, params UpdateStatusParams) (*WalletStatusResponse, apierr.JSON)
+	Close(ctx context.Context, params UpdateStatusParams) (*WalletStatusResponse, apierr.JSON)
+	StatusHistories(ctx context.Context, params StatusHistoriesParams) (*StatusHistoriesResponse, apierr.JSON)
+}
+
+type service struct {
+	wallets dao.WalletsRepository
+	ledgers dao.LedgersRepository
+}
+
+func New(walletsDAO dao.WalletsRepository, ledgersDAO dao.LedgersRepository) Service {
+	return &service{
+		wallets: walletsDAO,
+		ledgers: ledgersDAO,
+	}
+}
+
+func authUsername(ctx context.Context) (string, apierr.JSON) {
+	username, ok := ctx.Value(log.UsernameKey).(string)
+	if !ok || username == "" {
+		return "", apierr.Unauthenticated()
+	}
+	return username, nil
+}
+
+// toAPIErr maps the errors returned from dao into the error response of the API.
+func toAPIErr(ctx context.Context, err error) apierr.JSON {
+	switch {
+	case errors.Is(err, apierr.NotFound):
+		return apierr.ResourceNotFound("wallet not found")
+	case errors.Is(err, apierr.InsufficientFund):
+		return apierr.Unprocessable("insufficient fund")
+	case errors.Is(err, apierr.WalletFrozen):
+		return apierr.NewJSON(http.StatusForbidden, apierr.CodeWalletFrozen, "wallet is frozen", err)
+	case errors.Is(err, apierr.WalletClosed):
+		return apierr.NewJSON(http.StatusForbidden, apierr.CodeWalletClosed, "wallet is closed", err)
+	case errors.Is(err, apierr.PeriodClosed):
+		return apierr.NewJSON(http.StatusConflict, apierr.CodePeriodClosed, "accounting period is closed, please try again", err)
+	case errors.Is(err, apierr.LimitExceeded):
+		return apierr.NewJSON(http.StatusUnprocessableEntity, apierr.CodeLimitExceeded, err.Error(), err)
+	case errors.Is(err, dao.ErrInvalidStatusTransition):
+		return apierr.Conflict("wallet status can't be changed from its current status")
+	case errors.Is(err, dao.ErrNonZeroBalance):
+		return apierr.Unprocessable("wallet balance must be zero before closing")
+	case errors.Is(err, money.ErrOverflow):
+		return apierr.Unprocessable("amount is too large for the wallet balance")
+	}
+	log.Error(ctx, "failed in wallets service with err: %s", err)
+	return apierr.InternalServer("please try again")
+}
+
+func (s *service) Deposit(ctx context.Context, params DepositParams) (*TransactionResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	amount, err := params.Amount.Of(params.Currency)
+	if err != nil {
+		return nil, apierr.BadRequest(err.Error())
+	}
+	uid, err := s.wallets.Deposit(ctx, username, params.Reference, amount, params.Metadata)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &TransactionResponse{
+		UID:           uid,
+		Type:          dao.TypeDeposit,
+		Status:        dao.StatusCompleted,
+		Reference:     params.Reference,
+		Currency:      params.Currency,
+		Amount:        amount.Amount(),
+		AmountDecimal: amount.Decimal(),
+		Metadata:      params.Metadata,
+	}, nil
+}
+
+func (s *service) Withdraw(ctx context.Context, params WithdrawParams) (*TransactionResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	amount, err := params.Amount.Of(params.Currency)
+	if err != nil {
+		return nil, apierr.BadRequest(err.Error())
+	}
+	uid, err := s.wallets.Withdraw(ctx, username, params.Reference, amount, params.Metadata)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &TransactionResponse{
+		UID:           uid,
+		Type:          dao.TypeWithdraw,
+		Status:        dao.StatusCompleted,
+		Reference:     params.Reference,
+		Currency:      params.Currency,
+		Amount:        amount.Amount(),
+		AmountDecimal: amount.Decimal(),
+		Metadata:      params.Metadata,
+	}, nil
+}
+
+func (s *service) Transfer(ctx context.Context, params TransferParams) (*TransactionResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	if username == params.Receiver {
+		return nil, apierr.BadRequest("unable to transfer to own wallet")
+	}
+	amount, err := params.Amount.Of(params.Currency)
+	if err != nil {
+		return nil, apierr.BadRequest(err.Error())
+	}
+	uid, err := s.wallets.Transfer(ctx, username, params.Receiver, params.Reference, amount, params.Metadata)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &TransactionResponse{
+		UID:           uid,
+		Type:          dao.TypeTransfer,
+		Status:        dao.StatusCompleted,
+		Reference:     params.Reference,
+		Currency:      params.Currency,
+		Amount:        amount.Amount(),
+		AmountDecimal: amount.Decimal(),
+		Metadata:      params.Metadata,
+	}, nil
+}
+
+// BulkTransfer pays every item from the wallet of the user in a single transaction, either all the items are
+// committed or none. The refused items are reported with their error in the response of status 422.
+func (s *service) BulkTransfer(ctx context.Context, params BulkTransferParams) (*BulkTransferResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	total, err := money.Of(0, params.Currency)
+	if err != nil {
+		return nil, apierr.BadRequest(err.Error())
+	}
+	items := make([]dao.BulkTransferItem, 0, len(params.Items))
+	for i, item := range params.Items {
+		if username == item.Receiver {
+			return nil, apierr.BadRequest(fmt.Sprintf("items[%d]: unable to transfer to own wallet", i))
+		}
+		amount, err := item.Amount.Of(params.Currency)
+		if err != nil {
+			return nil, apierr.BadRequest(fmt.Sprintf("items[%d]: %s", i, err.Error()))
+		}
+		total, err = total.Add(amount)
+		if err != nil {
+			return nil, toAPIErr(ctx, err)
+		}
+		items = append(items, dao.BulkTransferItem{Receiver: item.Receiver, Amount: amount})
+	}
+
+	resp := &BulkTransferResponse{
+		Type:          dao.TypeBulkTransfer,
+		Status:        dao.StatusCompleted,
+		Reference:     params.Reference,
+		Currency:      params.Currency,
+		Amount:        total.Amount(),
+		AmountDecimal: total.Decimal(),
+		Metadata:      params.Metadata,
+		Items:         make([]BulkTransferResult, 0, len(items)),
+	}
+	uid, err := s.wallets.BulkTransfer(ctx, username, params.Reference, items, params.Metadata)
+	var bulkErr *dao.BulkTransferError
+	if err != nil && !errors.As(err, &bulkErr) {
+		return nil, toAPIErr(ctx, err)
+	}
+	resp.UID = uid
+	if bulkErr != nil {
+		resp.Status = dao.StatusFailed
+	}
+	for i, item := range items {
+		result := BulkTransferResult{
+			Receiver:      item.Receiver,
+			Amount:        item.Amount.Amount(),
+			AmountDecimal: item.Amount.Decimal(),
+			Status:        dao.StatusCompleted,
+		}
+		if bulkErr != nil {
+			result.Status = dao.StatusCancelled
+			if itemErr, ok := bulkErr.Items[i]; ok {
+				result.Status = dao.StatusFailed
+				result.Error = toAPIErr(ctx, itemErr)
+			}
+		}
+		resp.Items = append(resp.Items, result)
+	}
+	return resp, nil
+}
+
+func (s *service) Balance(ctx context.Context, params BalanceParams) (*BalanceResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	currencies := params.Currencies
+	if len(currencies) == 0 {
+		currencies = currency.Codes()
+	}
+	wallets, err := s.wallets.Balance(ctx, username, currencies)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	if len(wallets) == 0 {
+		return nil, apierr.ResourceNotFound("wallet not found")
+	}
+	balances := make([]Balance, 0, len(wallets))
+	for _, w := range wallets {
+		available := w.Amount - w.HeldAmount
+		balances = append(balances, Balance{
+			Currency:               w.Currency,
+			Amount:                 w.Amount,
+			HeldAmount:             w.HeldAmount,
+			AvailableAmount:        available,
+			AmountDecimal:          money.FormatDecimal(w.Amount, w.Currency),
+			HeldAmountDecimal:      money.FormatDecimal(w.HeldAmount, w.Currency),
+			AvailableAmountDecimal: money.FormatDecimal(available, w.Currency),
+		})
+	}
+	return &BalanceResponse{Balances: balances}, nil
+}
+
+func (s *service) History(ctx context.Context, params HistoryParams) (*HistoryResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	page, jsonErr := params.page()
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	filter, jsonErr := params.filter()
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	txHistories, hasMore, err := s.ledgers.List(ctx, page, params.Currency, username, filter)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	histories := make([]History, 0, len(txHistories))
+	for _, h := range txHistories {
+		histories = append(histories, History{
+			UID:           h.UID,
+			Type:          h.Type,
+			Status:        h.Status,
+			Direction:     h.Direction,
+			Amount:        h.Amount,
+			AmountDecimal: money.FormatDecimal(h.Amount, h.Currency),
+			Currency:      h.Currency,
+			Metadata:      h.Metadata,
+			CreatedAt:     h.CreatedAt,
+		})
+	}
+	resp := &HistoryResponse{
+		Data:    histories,
+		HasMore: hasMore,
+	}
+	if len(txHistories) == 0 {
+		return resp, nil
+	}
+	// The page came from the other side of a cursor, so there are entries beyond it on that side
+	hasOlder, hasNewer := hasMore, page.StartingAfter != nil
+	if page.EndingBefore != nil {
+		hasOlder, hasNewer = true, hasMore
+	}
+	if hasOlder {
+		last := txHistories[len(txHistories)-1]
+		resp.NextCursor = encodeCursor(last.CreatedAt, last.LedgerID)
+	}
+	if hasNewer {
+		first := txHistories[0]
+		resp.PreviousCursor = encodeCursor(first.CreatedAt, first.LedgerID)
+	}
+	return resp, nil
+}
+
+// Statement checks the wallet and returns the statement to be streamed, the ledgers are only read while the
+// response is written so the export never holds the whole period in memory.
+func (s *service) Statement(ctx context.Context, params StatementParams) (*StatementResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	_, err := s.wallets.Get(ctx, username, params.Currency)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	from, to := params.period()
+	format := params.format()
+	statement := statements.Statement{
+		ID:          utils.UUID(),
+		Username:    username,
+		Currency:    params.Currency,
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now().UTC(),
+	}
+	return &StatementResponse{
+		format:   format,
+		filename: fmt.Sprintf("statement-%s-%s-%s.%s", params.Currency, params.From, params.To, format.Extension()),
+		write: func(ctx context.Context, w io.Writer) error {
+			return s.ledgers.Statement(ctx, username, params.Currency, from, to, func(summary dao.StatementSummaryModel, entries dao.StatementEntries) error {
+				statement.OpeningBalance = summary.OpeningBalance
+				statement.ClosingBalance = summary.ClosingBalance()
+				statement.TotalCredit = summary.TotalCredit
+				statement.TotalDebit = summary.TotalDebit
+				statement.CreditCount = summary.CreditCount
+				statement.DebitCount = summary.DebitCount
+				writer, err := statements.NewWriter(format, w, statement)
+				if err != nil {
+					return err
+				}
+				balance := summary.OpeningBalance
+				err = entries(func(entry dao.StatementEntryModel) error {
+					credit := entry.Direction == dao.DirectionCredit
+					if credit {
+						balance += entry.Amount
+					} else {
+						balance -= entry.Amount
+					}
+					return writer.WriteEntry(statements.Entry{
+						LedgerID:  entry.LedgerID,
+						TxUID:     entry.TxUID,
+						TxType:    string(entry.Type),
+						Reference: entry.Reference,
+						Credit:    credit,
+						Amount:    entry.Amount,
+						Balance:   balance,
+						BookedAt:  entry.CreatedAt,
+					})
+				})
+				if err != nil {
+					return err
+				}
+				return writer.Close()
+			})
+		},
+	}, nil
+}
@@ -0,0 +1,525 @@
+package wallets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/statements"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/lengzuo/fundflow/utils/metadata"
+	"github.com/lengzuo/fundflow/utils/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func authCtx(t *testing.T, username string) context.Context {
+	return context.WithValue(t.Context(), log.UsernameKey, username)
+}
+
+func errBody(t *testing.T, err apierr.JSON) string {
+	b, marshalErr := json.Marshal(err)
+	assert.NoError(t, marshalErr)
+	return string(b)
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "deposit ok", params: DepositParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"}},
+		{name: "deposit unsupported currency", params: DepositParams{Currency: "USD", Amount: money.MinorUnits(100), Reference: "ref"}, wantErr: true},
+		{name: "deposit decimal amount", params: DepositParams{Currency: "SGD", Amount: money.DecimalAmount("12.34"), Reference: "ref"}},
+		{name: "deposit too many decimals", params: DepositParams{Currency: "SGD", Amount: money.DecimalAmount("12.345"), Reference: "ref"}, wantErr: true},
+		{name: "transfer jpy decimals", params: TransferParams{Receiver: "user2", Currency: "JPY", Amount: money.DecimalAmount("1.5"), Reference: "ref"}, wantErr: true},
+		{name: "deposit zero amount", params: DepositParams{Currency: "SGD", Amount: money.MinorUnits(0), Reference: "ref"}, wantErr: true},
+		{name: "withdraw negative amount", params: WithdrawParams{Currency: "SGD", Amount: money.MinorUnits(-1), Reference: "ref"}, wantErr: true},
+		{name: "deposit invalid metadata key", params: DepositParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref", Metadata: metadata.Metadata{"order id": "1"}}, wantErr: true},
+		{name: "withdraw empty reference", params: WithdrawParams{Currency: "JPY", Amount: money.MinorUnits(1), Reference: " "}, wantErr: true},
+		{name: "transfer ok", params: TransferParams{Receiver: "user2", Currency: "JPY", Amount: money.MinorUnits(1), Reference: "ref"}},
+		{name: "transfer empty receiver", params: TransferParams{Currency: "JPY", Amount: money.MinorUnits(1), Reference: "ref"}, wantErr: true},
+		{name: "bulk transfer ok", params: BulkTransferParams{Currency: "SGD", Reference: "payroll", Items: []BulkTransferItem{{Receiver: "user2", Amount: money.DecimalAmount("1.50")}, {Receiver: "user3", Amount: money.MinorUnits(1)}}}},
+		{name: "bulk transfer without item", params: BulkTransferParams{Currency: "SGD", Reference: "payroll"}, wantErr: true},
+		{name: "bulk transfer duplicated receiver", params: BulkTransferParams{Currency: "SGD", Reference: "payroll", Items: []BulkTransferItem{{Receiver: "user2", Amount: money.MinorUnits(1)}, {Receiver: "user2", Amount: money.MinorUnits(1)}}}, wantErr: true},
+		{name: "bulk transfer zero amount item", params: BulkTransferParams{Currency: "SGD", Reference: "payroll", Items: []BulkTransferItem{{Receiver: "user2", Amount: money.MinorUnits(0)}}}, wantErr: true},
+		{name: "bulk transfer empty receiver", params: BulkTransferParams{Currency: "SGD", Reference: "payroll", Items: []BulkTransferItem{{Amount: money.MinorUnits(1)}}}, wantErr: true},
+		{name: "bulk transfer empty reference", params: BulkTransferParams{Currency: "SGD", Items: []BulkTransferItem{{Receiver: "user2", Amount: money.MinorUnits(1)}}}, wantErr: true},
+		{name: "bulk transfer too many items", params: BulkTransferParams{Currency: "SGD", Reference: "payroll", Items: make([]BulkTransferItem, maxBulkTransferItems+1)}, wantErr: true},
+		{name: "balance ok without currency", params: BalanceParams{}},
+		{name: "balance unsupported currency", params: BalanceParams{Currencies: []string{"SGD", "USD"}}, wantErr: true},
+		{name: "history ok", params: HistoryParams{Currency: "SGD", Limit: 10}},
+		{name: "history missing currency", params: HistoryParams{}, wantErr: true},
+		{name: "history limit too large", params: HistoryParams{Currency: "SGD", Limit: 101}, wantErr: true},
+		{name: "history filters ok", params: HistoryParams{Currency: "SGD", Types: []dao.TxType{dao.TypeTransfer}, Statuses: []dao.TxStatus{dao.StatusFailed}, MinAmount: "1.50", MaxAmount: "100", From: "2026-06-01", To: "2026-06-30"}},
+		{name: "history unknown type", params: HistoryParams{Currency: "SGD", Types: []dao.TxType{"payout"}}, wantErr: true},
+		{name: "history unknown status", params: HistoryParams{Currency: "SGD", Statuses: []dao.TxStatus{"done"}}, wantErr: true},
+		{name: "history unknown direction", params: HistoryParams{Currency: "SGD", Direction: "x"}, wantErr: true},
+		{name: "history amount too precise", params: HistoryParams{Currency: "SGD", MinAmount: "1.005"}, wantErr: true},
+		{name: "history amount range reversed", params: HistoryParams{Currency: "SGD", MinAmount: "100", MaxAmount: "10"}, wantErr: true},
+		{name: "history date range reversed", params: HistoryParams{Currency: "SGD", From: "2026-06-30", To: "2026-06-01"}, wantErr: true},
+		{name: "history invalid cursor", params: HistoryParams{Currency: "SGD", StartingAfter: "uid1"}, wantErr: true},
+		{name: "history both cursors", params: HistoryParams{Currency: "SGD", StartingAfter: encodeCursor(time.Now(), 1), EndingBefore: encodeCursor(time.Now(), 2)}, wantErr: true},
+		{name: "statement ok", params: StatementParams{Currency: "SGD", From: "2026-01-01", To: "2026-01-31", Format: statements.FormatOFX}},
+		{name: "statement single day", params: StatementParams{Currency: "SGD", From: "2026-01-01", To: "2026-01-01"}},
+		{name: "statement unsupported format", params: StatementParams{Currency: "SGD", From: "2026-01-01", To: "2026-01-31", Format: "pdf"}, wantErr: true},
+		{name: "statement invalid date", params: StatementParams{Currency: "SGD", From: "2026-13-01", To: "2026-01-31"}, wantErr: true},
+		{name: "statement to before from", params: StatementParams{Currency: "SGD", From: "2026-02-01", To: "2026-01-31"}, wantErr: true},
+		{name: "statement period too long", params: StatementParams{Currency: "SGD", From: "2025-01-01", To: "2026-01-31"}, wantErr: true},
+		{name: "update status ok", params: UpdateStatusParams{Username: "user1", Currency: "SGD", Reason: "fraud"}},
+		{name: "update status missing reason", params: UpdateStatusParams{Username: "user1", Currency: "SGD"}, wantErr: true},
+		{name: "update status missing username", params: UpdateStatusParams{Currency: "SGD", Reason: "fraud"}, wantErr: true},
+		{name: "status histories unsupported currency", params: StatusHistoriesParams{Username: "user1", Currency: "USD"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_service_Deposit(t *testing.T) {
+	t.Run("ok deposit", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Deposit", mock.Anything, "user1", "ref", money.New(100, currency.SGD), metadata.Metadata{"order_id": "123"}).Return("uid", nil)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Deposit(authCtx(t, "user1"), DepositParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref", Metadata: metadata.Metadata{"order_id": "123"}})
+		assert.Nil(t, err)
+		assert.Equal(t, &TransactionResponse{
+			UID:           "uid",
+			Type:          dao.TypeDeposit,
+			Status:        dao.StatusCompleted,
+			Reference:     "ref",
+			Currency:      "SGD",
+			Amount:        100,
+			AmountDecimal: "1.00",
+			Metadata:      metadata.Metadata{"order_id": "123"},
+		}, resp)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	})
+
+	t.Run("ok deposit decimal amount", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Deposit", mock.Anything, "user1", "ref", money.New(1234, currency.SGD), metadata.Metadata(nil)).Return("uid", nil)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Deposit(authCtx(t, "user1"), DepositParams{Currency: "SGD", Amount: money.DecimalAmount("12.34"), Reference: "ref"})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1234), resp.Amount)
+		assert.Equal(t, "12.34", resp.AmountDecimal)
+	})
+
+	t.Run("unauthenticated deposit", func(t *testing.T) {
+		s := New(mocks.NewWalletsRepository(t), mocks.NewLedgersRepository(t))
+		resp, err := s.Deposit(t.Context(), DepositParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, err.HTTPStatusCode())
+	})
+
+	t.Run("wallet not found deposit", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Deposit", mock.Anything, "user1", "ref", money.New(100, currency.JPY), metadata.Metadata(nil)).Return("", apierr.NotFound)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Deposit(authCtx(t, "user1"), DepositParams{Currency: "JPY", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	})
+
+	t.Run("deposit within closed period", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Deposit", mock.Anything, "user1", "ref", money.New(100, currency.SGD), metadata.Metadata(nil)).Return("", apierr.PeriodClosed)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Deposit(authCtx(t, "user1"), DepositParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusConflict, err.HTTPStatusCode())
+		assert.Contains(t, errBody(t, err), apierr.CodePeriodClosed)
+	})
+
+	t.Run("deposit overflow balance", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Deposit", mock.Anything, "user1", "ref", money.New(100, currency.JPY), metadata.Metadata(nil)).Return("", money.ErrOverflow)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Deposit(authCtx(t, "user1"), DepositParams{Currency: "JPY", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnprocessableEntity, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Withdraw(t *testing.T) {
+	t.Run("ok withdraw", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Withdraw", mock.Anything, "user1", "ref", money.New(100, currency.SGD), metadata.Metadata(nil)).Return("uid", nil)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Withdraw(authCtx(t, "user1"), WithdrawParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, err)
+		assert.Equal(t, "uid", resp.UID)
+		assert.Equal(t, dao.TypeWithdraw, resp.Type)
+	})
+
+	t.Run("insufficient fund withdraw", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Withdraw", mock.Anything, "user1", "ref", money.New(100, currency.SGD), metadata.Metadata(nil)).Return("", apierr.InsufficientFund)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Withdraw(authCtx(t, "user1"), WithdrawParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnprocessableEntity, err.HTTPStatusCode())
+	})
+
+	t.Run("frozen wallet withdraw", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Withdraw", mock.Anything, "user1", "ref", money.New(100, currency.SGD), metadata.Metadata(nil)).Return("", apierr.WalletFrozen)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Withdraw(authCtx(t, "user1"), WithdrawParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusForbidden, err.HTTPStatusCode())
+		assert.Contains(t, errBody(t, err), apierr.CodeWalletFrozen)
+	})
+
+	t.Run("limit exceeded withdraw", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Withdraw", mock.Anything, "user1", "ref", money.New(100, currency.SGD), metadata.Metadata(nil)).Return("", fmt.Errorf("%w: daily withdraw limit of 50 SGD", apierr.LimitExceeded))
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Withdraw(authCtx(t, "user1"), WithdrawParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnprocessableEntity, err.HTTPStatusCode())
+		assert.Contains(t, errBody(t, err), apierr.CodeLimitExceeded)
+		assert.Contains(t, errBody(t, err), "daily withdraw limit of 50 SGD")
+	})
+
+	t.Run("unexpected error withdraw", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Withdraw", mock.Anything, "user1", "ref", money.New(100, currency.SGD), metadata.Metadata(nil)).Return("", errors.New("err"))
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Withdraw(authCtx(t, "user1"), WithdrawParams{Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Transfer(t *testing.T) {
+	t.Run("ok transfer", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Transfer", mock.Anything, "user1", "user2", "ref", money.New(100, currency.SGD), metadata.Metadata(nil)).Return("uid", nil)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Transfer(authCtx(t, "user1"), TransferParams{Receiver: "user2", Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, err)
+		assert.Equal(t, "uid", resp.UID)
+		assert.Equal(t, dao.TypeTransfer, resp.Type)
+	})
+
+	t.Run("transfer to own wallet", func(t *testing.T) {
+		s := New(mocks.NewWalletsRepository(t), mocks.NewLedgersRepository(t))
+		resp, err := s.Transfer(authCtx(t, "user1"), TransferParams{Receiver: "user1", Currency: "SGD", Amount: money.MinorUnits(100), Reference: "ref"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_BulkTransfer(t *testing.T) {
+	params := BulkTransferParams{Currency: "SGD", Reference: "payroll", Items: []BulkTransferItem{
+		{Receiver: "user2", Amount: money.DecimalAmount("1.50")},
+		{Receiver: "user3", Amount: money.MinorUnits(250)},
+		{Receiver: "user4", Amount: money.MinorUnits(100)},
+	}}
+	items := []dao.BulkTransferItem{
+		{Receiver: "user2", Amount: money.New(150, currency.SGD)},
+		{Receiver: "user3", Amount: money.New(250, currency.SGD)},
+		{Receiver: "user4", Amount: money.New(100, currency.SGD)},
+	}
+
+	t.Run("ok bulk transfer", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("BulkTransfer", mock.Anything, "user1", "payroll", items, metadata.Metadata(nil)).Return("uid", nil)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.BulkTransfer(authCtx(t, "user1"), params)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode())
+		assert.Equal(t, "uid", resp.UID)
+		assert.Equal(t, int64(500), resp.Amount)
+		assert.Equal(t, "5.00", resp.AmountDecimal)
+		assert.Equal(t, BulkTransferResult{Receiver: "user2", Amount: 150, AmountDecimal: "1.50", Status: dao.StatusCompleted}, resp.Items[0])
+	})
+
+	t.Run("refused items are reported", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("BulkTransfer", mock.Anything, "user1", "payroll", items, metadata.Metadata(nil)).Return("", &dao.BulkTransferError{Items: map[int]error{
+			0: apierr.NotFound,
+			2: apierr.WalletFrozen,
+		}})
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.BulkTransfer(authCtx(t, "user1"), params)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode())
+		assert.Empty(t, resp.UID)
+		assert.Equal(t, dao.StatusFailed, resp.Status)
+		assert.Equal(t, []dao.TxStatus{dao.StatusFailed, dao.StatusCancelled, dao.StatusFailed}, []dao.TxStatus{resp.Items[0].Status, resp.Items[1].Status, resp.Items[2].Status})
+		assert.JSONEq(t, `{"code":"NOT_FOUND","message":"wallet not found"}`, errBody(t, resp.Items[0].Error))
+		assert.Nil(t, resp.Items[1].Error)
+		assert.JSONEq(t, `{"code":"WALLET_FROZEN","message":"wallet is frozen"}`, errBody(t, resp.Items[2].Error))
+	})
+
+	t.Run("insufficient fund of sender", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("BulkTransfer", mock.Anything, "user1", "payroll", items, metadata.Metadata(nil)).Return("", apierr.InsufficientFund)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.BulkTransfer(authCtx(t, "user1"), params)
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnprocessableEntity, err.HTTPStatusCode())
+	})
+
+	t.Run("bulk transfer to own wallet", func(t *testing.T) {
+		s := New(mocks.NewWalletsRepository(t), mocks.NewLedgersRepository(t))
+		resp, err := s.BulkTransfer(authCtx(t, "user3"), params)
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Balance(t *testing.T) {
+	t.Run("ok balance default all currencies", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Balance", mock.Anything, "user1", []string{"JPY", "SGD"}).Return([]dao.WalletsModel{
+			{Currency: "SGD", Amount: 20, HeldAmount: 5},
+			{Currency: "JPY", Amount: 10},
+		}, nil)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Balance(authCtx(t, "user1"), BalanceParams{})
+		assert.Nil(t, err)
+		assert.Equal(t, []Balance{
+			{Currency: "SGD", Amount: 20, HeldAmount: 5, AvailableAmount: 15, AmountDecimal: "0.20", HeldAmountDecimal: "0.05", AvailableAmountDecimal: "0.15"},
+			{Currency: "JPY", Amount: 10, AvailableAmount: 10, AmountDecimal: "10", HeldAmountDecimal: "0", AvailableAmountDecimal: "10"},
+		}, resp.Balances)
+	})
+
+	t.Run("no wallet found balance", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Balance", mock.Anything, "user1", []string{"SGD"}).Return([]dao.WalletsModel{}, nil)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Balance(authCtx(t, "user1"), BalanceParams{Currencies: []string{"SGD"}})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_History(t *testing.T) {
+	t.Run("ok history with default limit", func(t *testing.T) {
+		now := time.Now()
+		ledgersRepo := mocks.NewLedgersRepository(t)
+		ledgersRepo.On("List", mock.Anything, dao.HistoryPage{Limit: defaultHistoryLimit}, "SGD", "user1", dao.HistoryFilter{}).Return([]dao.TxHistoryModel{
+			{LedgerID: 3, UID: "uid1", Type: dao.TypeDeposit, Status: dao.StatusCompleted, Direction: dao.DirectionCredit, Amount: 10, Currency: "SGD", CreatedAt: now},
+		}, true, nil)
+		s := New(mocks.NewWalletsRepository(t), ledgersRepo)
+		resp, err := s.History(authCtx(t, "user1"), HistoryParams{Currency: "SGD"})
+		assert.Nil(t, err)
+		assert.True(t, resp.HasMore)
+		assert.Equal(t, []History{
+			{UID: "uid1", Type: dao.TypeDeposit, Status: dao.StatusCompleted, Direction: dao.DirectionCredit, Amount: 10, AmountDecimal: "0.10", Currency: "SGD", CreatedAt: now},
+		}, resp.Data)
+		assert.Equal(t, encodeCursor(now, 3), resp.NextCursor)
+		assert.Empty(t, resp.PreviousCursor)
+	})
+
+	t.Run("ok history pages both ways", func(t *testing.T) {
+		createdAt := time.Date(2026, 6, 15, 8, 0, 0, 123456000, time.UTC)
+		cursor := &dao.HistoryCursor{CreatedAt: createdAt, LedgerID: 7}
+		page := []dao.TxHistoryModel{
+			{LedgerID: 9, UID: "uid2", Type: dao.TypeTransfer, Currency: "SGD", CreatedAt: createdAt.Add(time.Second)},
+			{LedgerID: 8, UID: "uid2", Type: dao.TypeTransfer, Currency: "SGD", CreatedAt: createdAt.Add(time.Second)},
+		}
+		ledgersRepo := mocks.NewLedgersRepository(t)
+		ledgersRepo.On("List", mock.Anything, dao.HistoryPage{Limit: 2, StartingAfter: cursor}, "SGD", "user1", dao.HistoryFilter{}).Return(page, false, nil)
+		ledgersRepo.On("List", mock.Anything, dao.HistoryPage{Limit: 2, EndingBefore: cursor}, "SGD", "user1", dao.HistoryFilter{}).Return(page, false, nil)
+		s := New(mocks.NewWalletsRepository(t), ledgersRepo)
+
+		// The older page after the cursor has newer entries before it only
+		resp, err := s.History(authCtx(t, "user1"), HistoryParams{Currency: "SGD", Limit: 2, StartingAfter: encodeCursor(createdAt, 7)})
+		assert.Nil(t, err)
+		assert.False(t, resp.HasMore)
+		assert.Empty(t, resp.NextCursor)
+		assert.Equal(t, encodeCursor(page[0].CreatedAt, 9), resp.PreviousCursor)
+
+		// The newer page before the cursor has older entries after it only
+		resp, err = s.History(authCtx(t, "user1"), HistoryParams{Currency: "SGD", Limit: 2, EndingBefore: encodeCursor(createdAt, 7)})
+		assert.Nil(t, err)
+		assert.Equal(t, encodeCursor(page[1].CreatedAt, 8), resp.NextCursor)
+		assert.Empty(t, resp.PreviousCursor)
+	})
+
+	t.Run("ok history with filters", func(t *testing.T) {
+		ledgersRepo := mocks.NewLedgersRepository(t)
+		ledgersRepo.On("List", mock.Anything, dao.HistoryPage{Limit: defaultHistoryLimit}, "SGD", "user1", dao.HistoryFilter{
+			Types:       []dao.TxType{dao.TypeWithdraw},
+			Statuses:    []dao.TxStatus{dao.StatusCompleted},
+			Direction:   dao.DirectionDebit,
+			MinAmount:   10000,
+			CreatedFrom: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+			CreatedTo:   time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		}).Return([]dao.TxHistoryModel{}, false, nil)
+		s := New(mocks.NewWalletsRepository(t), ledgersRepo)
+		resp, err := s.History(authCtx(t, "user1"), HistoryParams{
+			Currency: "SGD", Types: []dao.TxType{dao.TypeWithdraw}, Statuses: []dao.TxStatus{dao.StatusCompleted},
+			Direction: dao.DirectionDebit, MinAmount: "100", From: "2026-06-01", To: "2026-06-30",
+		})
+		assert.Nil(t, err)
+		assert.Empty(t, resp.Data)
+	})
+
+	t.Run("error history", func(t *testing.T) {
+		ledgersRepo := mocks.NewLedgersRepository(t)
+		ledgersRepo.On("List", mock.Anything, dao.HistoryPage{Limit: 5}, "SGD", "user1", dao.HistoryFilter{}).Return(nil, false, errors.New("err"))
+		s := New(mocks.NewWalletsRepository(t), ledgersRepo)
+		resp, err := s.History(authCtx(t, "user1"), HistoryParams{Currency: "SGD", Limit: 5})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusInternalServerError, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_Statement(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	params := StatementParams{Currency: "SGD", From: "2026-01-01", To: "2026-01-31"}
+
+	t.Run("ok csv statement with running balance", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Get", mock.Anything, "user1", "SGD").Return(&dao.WalletsModel{Username: "user1"}, nil)
+		ledgersRepo := mocks.NewLedgersRepository(t)
+		ledgersRepo.On("Statement", mock.Anything, "user1", "SGD", from, from.AddDate(0, 1, 0), mock.Anything).
+			Run(func(args mock.Arguments) {
+				write := args.Get(5).(func(dao.StatementSummaryModel, dao.StatementEntries) error)
+				summary := dao.StatementSummaryModel{OpeningBalance: 1000, TotalCredit: 500, TotalDebit: 250, CreditCount: 1, DebitCount: 1}
+				entries := func(yield func(dao.StatementEntryModel) error) error {
+					for _, entry := range []dao.StatementEntryModel{
+						{LedgerID: 1, TxUID: "tx1", Type: dao.TypeDeposit, Reference: "salary", Direction: dao.DirectionCredit, Amount: 500, CreatedAt: from.Add(time.Hour)},
+						{LedgerID: 2, TxUID: "tx2", Type: dao.TypeTransfer, Reference: "rent", Direction: dao.DirectionDebit, Amount: 250, CreatedAt: from.Add(2 * time.Hour)},
+					} {
+						if err := yield(entry); err != nil {
+							return err
+						}
+					}
+					return nil
+				}
+				assert.NoError(t, write(summary, entries))
+			}).
+			Return(nil)
+		s := New(walletsRepo, ledgersRepo)
+		resp, err := s.Statement(authCtx(t, "user1"), params)
+		assert.Nil(t, err)
+		assert.Equal(t, "statement-SGD-2026-01-01-2026-01-31.csv", resp.Filename())
+		assert.Equal(t, "text/csv; charset=utf-8", resp.ContentType())
+
+		var buf bytes.Buffer
+		assert.NoError(t, resp.Stream(t.Context(), &buf))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 5)
+		assert.Equal(t, "2026-01-01T00:00:00Z,,,opening_balance,,,,10.00,SGD", lines[1])
+		assert.Equal(t, "2026-01-01T01:00:00Z,1,tx1,deposit,salary,,5.00,15.00,SGD", lines[2])
+		assert.Equal(t, "2026-01-01T02:00:00Z,2,tx2,transfer,rent,2.50,,12.50,SGD", lines[3])
+		assert.Equal(t, "2026-02-01T00:00:00Z,,,closing_balance,,,,12.50,SGD", lines[4])
+	})
+
+	t.Run("statement wallet not found", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("Get", mock.Anything, "user1", "SGD").Return(nil, apierr.NotFound)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Statement(authCtx(t, "user1"), params)
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_UpdateStatus(t *testing.T) {
+	params := UpdateStatusParams{Username: "user2", Currency: "SGD", Reason: "fraud"}
+
+	t.Run("ok freeze", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("UpdateStatus", mock.Anything, "user2", "SGD", dao.WalletStatusFrozen, "fraud", "admin").
+			Return(&dao.WalletsModel{Status: dao.WalletStatusFrozen}, nil)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Freeze(authCtx(t, "admin"), params)
+		assert.Nil(t, err)
+		assert.Equal(t, &WalletStatusResponse{Username: "user2", Currency: "SGD", Status: dao.WalletStatusFrozen}, resp)
+	})
+
+	t.Run("ok unfreeze", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("UpdateStatus", mock.Anything, "user2", "SGD", dao.WalletStatusActive, "fraud", "admin").
+			Return(&dao.WalletsModel{Status: dao.WalletStatusActive}, nil)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Unfreeze(authCtx(t, "admin"), params)
+		assert.Nil(t, err)
+		assert.Equal(t, dao.WalletStatusActive, resp.Status)
+	})
+
+	t.Run("close with balance", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("UpdateStatus", mock.Anything, "user2", "SGD", dao.WalletStatusClosed, "fraud", "admin").
+			Return(nil, dao.ErrNonZeroBalance)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Close(authCtx(t, "admin"), params)
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnprocessableEntity, err.HTTPStatusCode())
+	})
+
+	t.Run("invalid transition", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("UpdateStatus", mock.Anything, "user2", "SGD", dao.WalletStatusFrozen, "fraud", "admin").
+			Return(nil, dao.ErrInvalidStatusTransition)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Freeze(authCtx(t, "admin"), params)
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusConflict, err.HTTPStatusCode())
+	})
+
+	t.Run("closed wallet", func(t *testing.T) {
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("UpdateStatus", mock.Anything, "user2", "SGD", dao.WalletStatusActive, "fraud", "admin").
+			Return(nil, apierr.WalletClosed)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.Unfreeze(authCtx(t, "admin"), params)
+		assert.Nil(t, resp)
+		assert.Contains(t, errBody(t, err), apierr.CodeWalletClosed)
+	})
+}
+
+func Test_service_StatusHistories(t *testing.T) {
+	t.Run("ok status histories", func(t *testing.T) {
+		now := time.Now()
+		walletsRepo := mocks.NewWalletsRepository(t)
+		walletsRepo.On("ListStatusHistories", mock.Anything, "user2", "SGD").Return([]dao.WalletStatusHistoriesModel{
+			{FromStatus: dao.WalletStatusActive, ToStatus: dao.WalletStatusFrozen, Reason: "fraud", ChangedBy: "admin", CreatedAt: now},
+		}, nil)
+		s := New(walletsRepo, mocks.NewLedgersRepository(t))
+		resp, err := s.StatusHistories(authCtx(t, "admin"), StatusHistoriesParams{Username: "user2", Currency: "SGD"})
+		assert.Nil(t, err)
+		assert.Equal(t, []StatusHistory{
+			{FromStatus: dao.WalletStatusActive, ToStatus: dao.WalletStatusFrozen, Reason: "fraud", ChangedBy: "admin", CreatedAt: now},
+		}, resp.Data)
+	})
+}
+
+func Test_cursor(t *testing.T) {
+	createdAt := time.Date(2026, 6, 15, 8, 0, 0, 123456000, time.UTC)
+	cursor, err := decodeCursor(encodeCursor(createdAt, 42))
+	assert.NoError(t, err)
+	assert.Equal(t, &dao.HistoryCursor{CreatedAt: createdAt, LedgerID: 42}, cursor)
+
+	for _, invalid := range []string{"uid1", encodeCursor(createdAt, 0), "MTIzNDU2", "!!"} {
+		_, err := decodeCursor(invalid)
+		assert.ErrorIs(t, err, errInvalidCursor, invalid)
+	}
+}
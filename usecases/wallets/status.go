@@ -0,0 +1,54 @@
+package wallets
+
+import (
+	"context"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+)
+
+func (s *service) updateStatus(ctx context.Context, params UpdateStatusParams, status dao.WalletStatus) (*WalletStatusResponse, apierr.JSON) {
+	admin, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	wallet, err := s.wallets.UpdateStatus(ctx, params.Username, params.Currency, status, params.Reason, admin)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return &WalletStatusResponse{
+		Username: params.Username,
+		Currency: params.Currency,
+		Status:   wallet.Status,
+	}, nil
+}
+
+func (s *service) Freeze(ctx context.Context, params UpdateStatusParams) (*WalletStatusResponse, apierr.JSON) {
+	return s.updateStatus(ctx, params, dao.WalletStatusFrozen)
+}
+
+func (s *service) Unfreeze(ctx context.Context, params UpdateStatusParams) (*WalletStatusResponse, apierr.JSON) {
+	return s.updateStatus(ctx, params, dao.WalletStatusActive)
+}
+
+func (s *service) Close(ctx context.Context, params UpdateStatusParams) (*WalletStatusResponse, apierr.JSON) {
+	return s.updateStatus(ctx, params, dao.WalletStatusClosed)
+}
+
+func (s *service) StatusHistories(ctx context.Context, params StatusHistoriesParams) (*StatusHistoriesResponse, apierr.JSON) {
+	histories, err := s.wallets.ListStatusHistories(ctx, params.Username, params.Currency)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	data := make([]StatusHistory, 0, len(histories))
+	for _, h := range histories {
+		data = append(data, StatusHistory{
+			FromStatus: h.FromStatus,
+			ToStatus:   h.ToStatus,
+			Reason:     h.Reason,
+			ChangedBy:  h.ChangedBy,
+			CreatedAt:  h.CreatedAt,
+		})
+	}
+	return &StatusHistoriesResponse{Data: data}, nil
+}
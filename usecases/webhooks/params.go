@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+)
+
+const (
+	maxURLLength     = 2048
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+var deliveryStatuses = []dao.WebhookDeliveryStatus{dao.WebhookDeliveryPending, dao.WebhookDeliverySucceeded, dao.WebhookDeliveryDead}
+
+type RegisterParams struct {
+	URL string `json:"url"`
+}
+
+func (p RegisterParams) Validate() apierr.JSON {
+	if len(p.URL) > maxURLLength {
+		return apierr.BadRequest("url is too long")
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return apierr.BadRequest("url must be an absolute http or https url")
+	}
+	return nil
+}
+
+type ListEndpointsParams struct{}
+
+func (p ListEndpointsParams) Validate() apierr.JSON {
+	return nil
+}
+
+type DisableParams struct {
+	UID string `json:"uid"`
+}
+
+func (p DisableParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	return nil
+}
+
+type ListDeliveriesParams struct {
+	// EndpointUID and Status are optional filters
+	EndpointUID string                    `schema:"endpoint_uid"`
+	Status      dao.WebhookDeliveryStatus `schema:"status"`
+	Limit       int                       `schema:"limit"`
+}
+
+func (p ListDeliveriesParams) Validate() apierr.JSON {
+	if p.Status != "" && !slices.Contains(deliveryStatuses, p.Status) {
+		return apierr.BadRequest("status must be one of pending, succeeded or dead")
+	}
+	if p.Limit < 0 || p.Limit > maxListLimit {
+		return apierr.BadRequest("limit must be between 1 and 100")
+	}
+	return nil
+}
+
+type GetDeliveryParams struct {
+	UID string `schema:"uid"`
+}
+
+func (p GetDeliveryParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	return nil
+}
+
+type RedeliverParams struct {
+	UID string `json:"uid"`
+}
+
+func (p RedeliverParams) Validate() apierr.JSON {
+	if strings.TrimSpace(p.UID) == "" {
+		return apierr.BadRequest("uid is mandatory")
+	}
+	return nil
+}
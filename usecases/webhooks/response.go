@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+)
+
+type EndpointResponse struct {
+	UID string `json:"uid"`
+	URL string `json:"url"`
+	// Secret signs the deliveries, it is only returned when the endpoint is registered
+	Secret    string                    `json:"secret,omitempty"`
+	Status    dao.WebhookEndpointStatus `json:"status"`
+	CreatedAt time.Time                 `json:"created_at"`
+}
+
+func (r EndpointResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type RegisterResponse struct {
+	EndpointResponse
+}
+
+func (r RegisterResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+type ListEndpointsResponse struct {
+	Data []EndpointResponse `json:"data"`
+}
+
+func (r ListEndpointsResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type Attempt struct {
+	Attempt int `json:"attempt"`
+	// StatusCode is zero when the endpoint didn't respond
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type DeliveryResponse struct {
+	UID         string                    `json:"uid"`
+	EndpointUID string                    `json:"endpoint_uid"`
+	EventUID    string                    `json:"event_uid"`
+	EventType   string                    `json:"event_type"`
+	Payload     json.RawMessage           `json:"payload"`
+	Status      dao.WebhookDeliveryStatus `json:"status"`
+	Attempts    int                       `json:"attempts"`
+	// NextAttemptAt is only returned for the pending delivery
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	// History is the latest attempts first, only returned by get
+	History []Attempt `json:"history,omitempty"`
+}
+
+func (r DeliveryResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+type ListDeliveriesResponse struct {
+	Data []DeliveryResponse `json:"data"`
+}
+
+func (r ListDeliveriesResponse) StatusCode() int {
+	return http.StatusOK
+}
@@ -0,0 +1,335 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/events"
+	"github.com/lengzuo/fundflow/internal/webhooks"
+	"github.com/lengzuo/fundflow/pkg/log"
+)
+
+const (
+	// enqueueBatchSize limits the number of events read from the stream in a single run.
+	enqueueBatchSize = 100
+	// deliverBatchSize limits the number of deliveries claimed in a single run.
+	deliverBatchSize = 50
+	// deliverConcurrency is the number of deliveries sent at the same time, so a slow endpoint doesn't hold up the others.
+	deliverConcurrency = 10
+	// deliveryLease must outlast a run, a claimed delivery isn't attempted by anyone else until it is over.
+	deliveryLease = 5 * time.Minute
+	// maxAttempts is the number of failed attempts before the delivery is dead.
+	maxAttempts = 10
+	// retryBaseDelay doubles after every failed attempt up to retryMaxDelay.
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 6 * time.Hour
+	historyLimit   = 20
+	secretBytes    = 24
+	secretPrefix   = "whsec_"
+)
+
+type Service interface {
+	Register(ctx context.Context, params RegisterParams) (*RegisterResponse, apierr.JSON)
+	ListEndpoints(ctx context.Context, params ListEndpointsParams) (*ListEndpointsResponse, apierr.JSON)
+	Disable(ctx context.Context, params DisableParams) (*EndpointResponse, apierr.JSON)
+	ListDeliveries(ctx context.Context, params ListDeliveriesParams) (*ListDeliveriesResponse, apierr.JSON)
+	GetDelivery(ctx context.Context, params GetDeliveryParams) (*DeliveryResponse, apierr.JSON)
+	Redeliver(ctx context.Context, params RedeliverParams) (*DeliveryResponse, apierr.JSON)
+	Enqueue(ctx context.Context) error
+	Deliver(ctx context.Context) error
+}
+
+type service struct {
+	webhooks dao.WebhooksRepository
+	consumer events.Consumer
+	sender   webhooks.Sender
+}
+
+// New creates the webhooks service, consumer reads the wallet events published by the outbox and sender posts them to the endpoints.
+func New(webhooksDAO dao.WebhooksRepository, consumer events.Consumer, sender webhooks.Sender) Service {
+	return &service{
+		webhooks: webhooksDAO,
+		consumer: consumer,
+		sender:   sender,
+	}
+}
+
+func authUsername(ctx context.Context) (string, apierr.JSON) {
+	username, ok := ctx.Value(log.UsernameKey).(string)
+	if !ok || username == "" {
+		return "", apierr.Unauthenticated()
+	}
+	return username, nil
+}
+
+// toAPIErr maps the errors returned from dao into the error response of the API.
+func toAPIErr(ctx context.Context, err error) apierr.JSON {
+	switch {
+	case errors.Is(err, apierr.NotFound):
+		return apierr.ResourceNotFound("webhook endpoint or delivery not found")
+	case errors.Is(err, dao.ErrWebhookDeliveryPending):
+		return apierr.Conflict("delivery is still pending")
+	}
+	log.Error(ctx, "failed in webhooks service with err: %s", err)
+	return apierr.InternalServer("please try again")
+}
+
+func toEndpointResponse(endpoint *dao.WebhookEndpointsModel) *EndpointResponse {
+	return &EndpointResponse{
+		UID:       endpoint.UID,
+		URL:       endpoint.URL,
+		Status:    endpoint.Status,
+		CreatedAt: endpoint.CreatedAt,
+	}
+}
+
+func toDeliveryResponse(delivery *dao.WebhookDeliveriesModel) *DeliveryResponse {
+	resp := &DeliveryResponse{
+		UID:         delivery.UID,
+		EndpointUID: delivery.EndpointUID,
+		EventUID:    delivery.EventUID,
+		EventType:   delivery.EventType,
+		Payload:     delivery.Payload,
+		Status:      delivery.Status,
+		Attempts:    delivery.Attempts,
+		DeliveredAt: delivery.DeliveredAt,
+		CreatedAt:   delivery.CreatedAt,
+	}
+	if delivery.Status == dao.WebhookDeliveryPending {
+		resp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return resp
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// backoff is the delay before the next attempt after the number of failed attempts.
+func backoff(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
+
+func (s *service) Register(ctx context.Context, params RegisterParams) (*RegisterResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	if err := s.sender.CheckURL(ctx, params.URL); err != nil {
+		if errors.Is(err, webhooks.ErrForbiddenAddress) {
+			return nil, apierr.BadRequest("url must not point to a loopback, link local or private address")
+		}
+		log.Info(ctx, "refused webhook url %s with err: %s", params.URL, err)
+		return nil, apierr.BadRequest("url host cannot be resolved")
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	endpoint, err := s.webhooks.CreateEndpoint(ctx, dao.WebhookEndpointsModel{
+		Username: username,
+		URL:      params.URL,
+		Secret:   secret,
+	})
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	resp := &RegisterResponse{EndpointResponse: *toEndpointResponse(endpoint)}
+	resp.Secret = endpoint.Secret
+	return resp, nil
+}
+
+func (s *service) ListEndpoints(ctx context.Context, params ListEndpointsParams) (*ListEndpointsResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	endpoints, err := s.webhooks.ListEndpoints(ctx, username)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	resp := &ListEndpointsResponse{Data: make([]EndpointResponse, 0, len(endpoints))}
+	for i := range endpoints {
+		resp.Data = append(resp.Data, *toEndpointResponse(&endpoints[i]))
+	}
+	return resp, nil
+}
+
+func (s *service) Disable(ctx context.Context, params DisableParams) (*EndpointResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	endpoint, err := s.webhooks.DisableEndpoint(ctx, params.UID, username)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return toEndpointResponse(endpoint), nil
+}
+
+func (s *service) ListDeliveries(ctx context.Context, params ListDeliveriesParams) (*ListDeliveriesResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	deliveries, err := s.webhooks.ListDeliveries(ctx, username, dao.WebhookDeliveryFilter{
+		EndpointUID: params.EndpointUID,
+		Status:      params.Status,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	resp := &ListDeliveriesResponse{Data: make([]DeliveryResponse, 0, len(deliveries))}
+	for i := range deliveries {
+		resp.Data = append(resp.Data, *toDeliveryResponse(&deliveries[i]))
+	}
+	return resp, nil
+}
+
+func (s *service) GetDelivery(ctx context.Context, params GetDeliveryParams) (*DeliveryResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	delivery, err := s.webhooks.GetDelivery(ctx, params.UID, username)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	attempts, err := s.webhooks.ListAttempts(ctx, delivery.ID, historyLimit)
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	resp := toDeliveryResponse(delivery)
+	for _, attempt := range attempts {
+		resp.History = append(resp.History, Attempt{
+			Attempt:    attempt.Attempt,
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			DurationMS: attempt.DurationMS,
+			CreatedAt:  attempt.CreatedAt,
+		})
+	}
+	return resp, nil
+}
+
+// Redeliver sends the succeeded or dead delivery again on the next run of the worker.
+func (s *service) Redeliver(ctx context.Context, params RedeliverParams) (*DeliveryResponse, apierr.JSON) {
+	username, jsonErr := authUsername(ctx)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	delivery, err := s.webhooks.Redeliver(ctx, params.UID, username, time.Now().UTC())
+	if err != nil {
+		return nil, toAPIErr(ctx, err)
+	}
+	return toDeliveryResponse(delivery), nil
+}
+
+// Enqueue turns the wallet events read from the stream into the deliveries of the endpoints of the wallet owner,
+// it is meant to be run periodically by a worker. An event is only acknowledged once its deliveries are created,
+// so a failed event is read again by the next run.
+func (s *service) Enqueue(ctx context.Context) error {
+	messages, err := s.consumer.Read(ctx, enqueueBatchSize)
+	if err != nil {
+		return err
+	}
+	handled := make([]string, 0, len(messages))
+	var enqueueErr error
+	for _, msg := range messages {
+		// The key of the wallet event is "username:currency"
+		username, _, _ := strings.Cut(msg.Event.Key, ":")
+		_, enqueueErr = s.webhooks.Enqueue(ctx, dao.WebhookEvent{
+			UID:      msg.Event.UID,
+			Type:     msg.Event.Type,
+			Username: username,
+			Payload:  msg.Event.Payload,
+		})
+		if enqueueErr != nil {
+			break
+		}
+		handled = append(handled, msg.ID)
+	}
+	return errors.Join(enqueueErr, s.consumer.Ack(ctx, handled...))
+}
+
+// Deliver sends the due deliveries and records the outcome of every attempt, it is meant to be run periodically
+// by a worker. A failed attempt is retried with exponential backoff until maxAttempts, then the delivery is dead.
+func (s *service) Deliver(ctx context.Context) error {
+	jobs, err := s.webhooks.ClaimDue(ctx, time.Now().UTC(), deliveryLease, deliverBatchSize)
+	if err != nil {
+		return err
+	}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	sem := make(chan struct{}, deliverConcurrency)
+	for _, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := s.deliver(ctx, job); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *service) deliver(ctx context.Context, job dao.WebhookDeliveryJob) error {
+	started := time.Now()
+	statusCode, sendErr := s.sender.Send(ctx, job.URL, job.Secret, webhooks.Message{
+		ID:    job.UID,
+		Event: job.EventType,
+		Body:  job.Payload,
+	})
+	now := time.Now().UTC()
+	delivery := job.WebhookDeliveriesModel
+	delivery.Attempts++
+	attempt := dao.WebhookAttemptsModel{
+		Attempt:    delivery.Attempts,
+		StatusCode: statusCode,
+		DurationMS: time.Since(started).Milliseconds(),
+	}
+	switch {
+	case sendErr == nil:
+		delivery.Status = dao.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= maxAttempts:
+		attempt.Error = sendErr.Error()
+		delivery.Status = dao.WebhookDeliveryDead
+		log.Error(ctx, "webhook delivery %s is dead after %d attempts with err: %s", delivery.UID, delivery.Attempts, sendErr)
+	default:
+		attempt.Error = sendErr.Error()
+		delivery.NextAttemptAt = now.Add(backoff(delivery.Attempts))
+	}
+	return s.webhooks.RecordAttempt(ctx, delivery, attempt)
+}
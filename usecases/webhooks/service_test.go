@@ -0,0 +1,244 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lengzuo/fundflow/dao"
+	"github.com/lengzuo/fundflow/dao/mocks"
+	"github.com/lengzuo/fundflow/internal/apierr"
+	"github.com/lengzuo/fundflow/internal/events"
+	eventmocks "github.com/lengzuo/fundflow/internal/events/mocks"
+	"github.com/lengzuo/fundflow/internal/webhooks"
+	webhookmocks "github.com/lengzuo/fundflow/internal/webhooks/mocks"
+	"github.com/lengzuo/fundflow/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func authCtx(t *testing.T, username string) context.Context {
+	return context.WithValue(t.Context(), log.UsernameKey, username)
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{ Validate() apierr.JSON }
+		wantErr bool
+	}{
+		{name: "register ok", params: RegisterParams{URL: "https://example.com/hooks"}},
+		{name: "register relative url", params: RegisterParams{URL: "/hooks"}, wantErr: true},
+		{name: "register unsupported scheme", params: RegisterParams{URL: "ftp://example.com/hooks"}, wantErr: true},
+		{name: "register url too long", params: RegisterParams{URL: "https://example.com/" + strings.Repeat("a", maxURLLength)}, wantErr: true},
+		{name: "disable missing uid", params: DisableParams{}, wantErr: true},
+		{name: "list deliveries ok", params: ListDeliveriesParams{Status: dao.WebhookDeliveryDead, Limit: 10}},
+		{name: "list deliveries unknown status", params: ListDeliveriesParams{Status: "failed"}, wantErr: true},
+		{name: "list deliveries limit too large", params: ListDeliveriesParams{Limit: maxListLimit + 1}, wantErr: true},
+		{name: "get delivery missing uid", params: GetDeliveryParams{}, wantErr: true},
+		{name: "redeliver missing uid", params: RedeliverParams{UID: " "}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func Test_backoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, backoff(1))
+	assert.Equal(t, time.Minute, backoff(2))
+	assert.Equal(t, 4*time.Minute, backoff(4))
+	assert.Equal(t, retryMaxDelay, backoff(maxAttempts+20))
+}
+
+func Test_service_Register(t *testing.T) {
+	t.Run("ok register returns the secret once", func(t *testing.T) {
+		webhooksRepo := mocks.NewWebhooksRepository(t)
+		webhooksRepo.On("CreateEndpoint", mock.Anything, mock.MatchedBy(func(endpoint dao.WebhookEndpointsModel) bool {
+			return endpoint.Username == "user1" && strings.HasPrefix(endpoint.Secret, secretPrefix) && len(endpoint.Secret) == len(secretPrefix)+2*secretBytes
+		})).Return(&dao.WebhookEndpointsModel{UID: "ep1", URL: "https://example.com", Secret: "whsec_x", Status: dao.WebhookEndpointActive}, nil)
+		sender := webhookmocks.NewSender(t)
+		sender.On("CheckURL", mock.Anything, "https://example.com").Return(nil)
+		s := New(webhooksRepo, eventmocks.NewConsumer(t), sender)
+		resp, err := s.Register(authCtx(t, "user1"), RegisterParams{URL: "https://example.com"})
+		assert.Nil(t, err)
+		assert.Equal(t, "whsec_x", resp.Secret)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode())
+	})
+
+	t.Run("register internal address", func(t *testing.T) {
+		sender := webhookmocks.NewSender(t)
+		sender.On("CheckURL", mock.Anything, "http://169.254.169.254/latest").Return(webhooks.ErrForbiddenAddress)
+		s := New(mocks.NewWebhooksRepository(t), eventmocks.NewConsumer(t), sender)
+		resp, err := s.Register(authCtx(t, "user1"), RegisterParams{URL: "http://169.254.169.254/latest"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+	})
+
+	t.Run("register unresolvable host", func(t *testing.T) {
+		sender := webhookmocks.NewSender(t)
+		sender.On("CheckURL", mock.Anything, "https://unknown.invalid").Return(errors.New("no such host"))
+		s := New(mocks.NewWebhooksRepository(t), eventmocks.NewConsumer(t), sender)
+		resp, err := s.Register(authCtx(t, "user1"), RegisterParams{URL: "https://unknown.invalid"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusBadRequest, err.HTTPStatusCode())
+	})
+
+	t.Run("register without auth", func(t *testing.T) {
+		s := New(mocks.NewWebhooksRepository(t), eventmocks.NewConsumer(t), webhookmocks.NewSender(t))
+		resp, err := s.Register(t.Context(), RegisterParams{URL: "https://example.com"})
+		assert.Nil(t, resp)
+		assert.Equal(t, http.StatusUnauthorized, err.HTTPStatusCode())
+	})
+}
+
+func Test_service_ListEndpoints(t *testing.T) {
+	t.Run("secret is never listed", func(t *testing.T) {
+		webhooksRepo := mocks.NewWebhooksRepository(t)
+		webhooksRepo.On("ListEndpoints", mock.Anything, "user1").Return([]dao.WebhookEndpointsModel{{UID: "ep1", Secret: "whsec_x"}}, nil)
+		s := New(webhooksRepo, eventmocks.NewConsumer(t), webhookmocks.NewSender(t))
+		resp, err := s.ListEndpoints(authCtx(t, "user1"), ListEndpointsParams{})
+		assert.Nil(t, err)
+		assert.Len(t, resp.Data, 1)
+		assert.Empty(t, resp.Data[0].Secret)
+	})
+}
+
+func Test_service_GetDelivery(t *testing.T) {
+	t.Run("ok get delivery with history", func(t *testing.T) {
+		webhooksRepo := mocks.NewWebhooksRepository(t)
+		webhooksRepo.On("GetDelivery", mock.Anything, "dlv1", "user1").Return(&dao.WebhookDeliveriesModel{ID: 7, UID: "dlv1", Status: dao.WebhookDeliveryDead, Attempts: 2}, nil)
+		webhooksRepo.On("ListAttempts", mock.Anything, int64(7), historyLimit).Return([]dao.WebhookAttemptsModel{
+			{Attempt: 2, StatusCode: 500, Error: "boom"},
+			{Attempt: 1, Error: "timeout"},
+		}, nil)
+		s := New(webhooksRepo, eventmocks.NewConsumer(t), webhookmocks.NewSender(t))
+		resp, err := s.GetDelivery(authCtx(t, "user1"), GetDeliveryParams{UID: "dlv1"})
+		assert.Nil(t, err)
+		assert.Nil(t, resp.NextAttemptAt)
+		assert.Len(t, resp.History, 2)
+		assert.Equal(t, 500, resp.History[0].StatusCode)
+	})
+}
+
+func Test_service_Redeliver(t *testing.T) {
+	tests := []struct {
+		name       string
+		daoErr     error
+		wantStatus int
+	}{
+		{name: "redeliver pending delivery", daoErr: dao.ErrWebhookDeliveryPending, wantStatus: http.StatusConflict},
+		{name: "redeliver delivery of another client", daoErr: apierr.NotFound, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhooksRepo := mocks.NewWebhooksRepository(t)
+			webhooksRepo.On("Redeliver", mock.Anything, "dlv1", "user1", mock.Anything).Return(nil, tt.daoErr)
+			s := New(webhooksRepo, eventmocks.NewConsumer(t), webhookmocks.NewSender(t))
+			resp, err := s.Redeliver(authCtx(t, "user1"), RedeliverParams{UID: "dlv1"})
+			assert.Nil(t, resp)
+			assert.Equal(t, tt.wantStatus, err.HTTPStatusCode())
+		})
+	}
+}
+
+func Test_service_Enqueue(t *testing.T) {
+	messages := []events.Message{
+		{ID: "1-0", Event: events.Event{UID: "evt1", Type: "wallet.debited", Key: "user1:SGD", Payload: []byte(`{}`)}},
+		{ID: "2-0", Event: events.Event{UID: "evt2", Type: "wallet.credited", Key: "user2:SGD", Payload: []byte(`{}`)}},
+	}
+
+	t.Run("ok enqueue and ack every event", func(t *testing.T) {
+		webhooksRepo := mocks.NewWebhooksRepository(t)
+		consumer := eventmocks.NewConsumer(t)
+		consumer.On("Read", mock.Anything, int64(enqueueBatchSize)).Return(messages, nil)
+		webhooksRepo.On("Enqueue", mock.Anything, dao.WebhookEvent{UID: "evt1", Type: "wallet.debited", Username: "user1", Payload: []byte(`{}`)}).Return(1, nil)
+		webhooksRepo.On("Enqueue", mock.Anything, dao.WebhookEvent{UID: "evt2", Type: "wallet.credited", Username: "user2", Payload: []byte(`{}`)}).Return(0, nil)
+		consumer.On("Ack", mock.Anything, "1-0", "2-0").Return(nil)
+		s := New(webhooksRepo, consumer, webhookmocks.NewSender(t))
+		assert.NoError(t, s.Enqueue(t.Context()))
+	})
+
+	t.Run("failed event is left unacknowledged", func(t *testing.T) {
+		webhooksRepo := mocks.NewWebhooksRepository(t)
+		consumer := eventmocks.NewConsumer(t)
+		consumer.On("Read", mock.Anything, int64(enqueueBatchSize)).Return(messages, nil)
+		webhooksRepo.On("Enqueue", mock.Anything, mock.Anything).Return(1, nil).Once()
+		webhooksRepo.On("Enqueue", mock.Anything, mock.Anything).Return(0, errors.New("err")).Once()
+		consumer.On("Ack", mock.Anything, "1-0").Return(nil)
+		s := New(webhooksRepo, consumer, webhookmocks.NewSender(t))
+		assert.Error(t, s.Enqueue(t.Context()))
+	})
+}
+
+func Test_service_Deliver(t *testing.T) {
+	job := func(url string, attempts int) dao.WebhookDeliveryJob {
+		return dao.WebhookDeliveryJob{
+			WebhookDeliveriesModel: dao.WebhookDeliveriesModel{ID: 1, UID: "dlv1", EventType: "wallet.credited", Payload: []byte(`{"amount":100}`),
+				Status: dao.WebhookDeliveryPending, Attempts: attempts},
+			URL:    url,
+			Secret: "whsec_x",
+		}
+	}
+
+	msg := webhooks.Message{ID: "dlv1", Event: "wallet.credited", Body: []byte(`{"amount":100}`)}
+
+	t.Run("ok delivery to the receiver", func(t *testing.T) {
+		webhooksRepo := mocks.NewWebhooksRepository(t)
+		sender := webhookmocks.NewSender(t)
+		webhooksRepo.On("ClaimDue", mock.Anything, mock.Anything, deliveryLease, deliverBatchSize).Return([]dao.WebhookDeliveryJob{job("https://receiver", 0)}, nil)
+		sender.On("Send", mock.Anything, "https://receiver", "whsec_x", msg).Return(http.StatusOK, nil)
+		webhooksRepo.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(delivery dao.WebhookDeliveriesModel) bool {
+			return delivery.Status == dao.WebhookDeliverySucceeded && delivery.Attempts == 1 && delivery.DeliveredAt != nil
+		}), mock.MatchedBy(func(attempt dao.WebhookAttemptsModel) bool {
+			return attempt.Attempt == 1 && attempt.StatusCode == http.StatusOK && attempt.Error == ""
+		})).Return(nil)
+		s := New(webhooksRepo, eventmocks.NewConsumer(t), sender)
+		assert.NoError(t, s.Deliver(t.Context()))
+	})
+
+	t.Run("failed attempt is retried with backoff", func(t *testing.T) {
+		webhooksRepo := mocks.NewWebhooksRepository(t)
+		sender := webhookmocks.NewSender(t)
+		webhooksRepo.On("ClaimDue", mock.Anything, mock.Anything, deliveryLease, deliverBatchSize).Return([]dao.WebhookDeliveryJob{job("https://receiver", 2)}, nil)
+		sender.On("Send", mock.Anything, "https://receiver", "whsec_x", msg).Return(http.StatusInternalServerError, errors.New("webhook responded 500"))
+		webhooksRepo.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(delivery dao.WebhookDeliveriesModel) bool {
+			wait := time.Until(delivery.NextAttemptAt)
+			return delivery.Status == dao.WebhookDeliveryPending && delivery.Attempts == 3 && wait > 110*time.Second && wait <= 2*time.Minute
+		}), mock.MatchedBy(func(attempt dao.WebhookAttemptsModel) bool {
+			return attempt.Attempt == 3 && attempt.StatusCode == http.StatusInternalServerError && attempt.Error == "webhook responded 500"
+		})).Return(nil)
+		s := New(webhooksRepo, eventmocks.NewConsumer(t), sender)
+		assert.NoError(t, s.Deliver(t.Context()))
+	})
+
+	t.Run("last failed attempt is dead", func(t *testing.T) {
+		webhooksRepo := mocks.NewWebhooksRepository(t)
+		sender := webhookmocks.NewSender(t)
+		webhooksRepo.On("ClaimDue", mock.Anything, mock.Anything, deliveryLease, deliverBatchSize).Return([]dao.WebhookDeliveryJob{job("http://receiver", maxAttempts-1)}, nil)
+		sender.On("Send", mock.Anything, "http://receiver", "whsec_x", msg).
+			Return(0, errors.New("connection refused"))
+		webhooksRepo.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(delivery dao.WebhookDeliveriesModel) bool {
+			return delivery.Status == dao.WebhookDeliveryDead && delivery.Attempts == maxAttempts
+		}), mock.Anything).Return(nil)
+		s := New(webhooksRepo, eventmocks.NewConsumer(t), sender)
+		assert.NoError(t, s.Deliver(t.Context()))
+	})
+
+	t.Run("claim error", func(t *testing.T) {
+		webhooksRepo := mocks.NewWebhooksRepository(t)
+		webhooksRepo.On("ClaimDue", mock.Anything, mock.Anything, deliveryLease, deliverBatchSize).Return(nil, errors.New("err"))
+		s := New(webhooksRepo, eventmocks.NewConsumer(t), webhookmocks.NewSender(t))
+		assert.Error(t, s.Deliver(t.Context()))
+	})
+}
@@ -4,4 +4,35 @@ import "time"
 
 const (
 	APIRequestTimeout = 60 * time.Second
+	SessionTokenTTL   = 24 * time.Hour
+	// HoldExpiryInterval is how often the stale holds are released
+	HoldExpiryInterval = time.Minute
+	// ReconciliationInterval is how often the wallet balances are compared against the ledgers
+	ReconciliationInterval = time.Hour
+	// ScheduleInterval is how often the due transfer schedules are executed
+	ScheduleInterval = time.Minute
+	// ScheduleRetryInterval is how long a schedule waits before retrying the occurrence refused for insufficient fund
+	ScheduleRetryInterval = time.Hour
+	// OutboxDispatchInterval is how often the pending wallet events are published
+	OutboxDispatchInterval = time.Second
+	// OutboxCleanupInterval is how often the published wallet events past the retention are removed
+	OutboxCleanupInterval = time.Hour
+	// WebhookEnqueueInterval is how often the published wallet events are turned into webhook deliveries
+	WebhookEnqueueInterval = time.Second
+	// WebhookDeliveryInterval is how often the due webhook deliveries are sent
+	WebhookDeliveryInterval = 5 * time.Second
+	// WebhookTimeout is the http client timeout of a webhook delivery
+	WebhookTimeout = 5 * time.Second
+	// WebhookConsumerGroup is the redis stream consumer group which reads the wallet events for the webhooks
+	WebhookConsumerGroup = "webhooks"
+	// RecoveryInterval is how often the transactions pending beyond the timeout are recovered
+	RecoveryInterval = time.Minute
+	// PeriodCloseInterval is how often the settled days are snapshotted into daily balances and closed
+	PeriodCloseInterval = 10 * time.Minute
+	// BalanceSettleDelay is how long after the end of a day it is closed
+	BalanceSettleDelay = 5 * time.Minute
+	// FXQuoteTTL is how long the rate of a fx quote is locked for
+	FXQuoteTTL = time.Minute
+	// FXProviderTimeout is the http client timeout of the fx rate provider
+	FXProviderTimeout = 3 * time.Second
 )
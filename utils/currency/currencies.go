@@ -1,45 +1,183 @@
 package currency
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"slices"
 	"strings"
+	"sync"
 )
 
 type Value struct {
-	Code    string
+	Code string
+	// Precise is the number of decimals of the smallest unit, e.g. 2 for SGD cents
 	Precise int
+	// Numeric is the ISO 4217 numeric code, it is empty for the custom unit
+	Numeric string
+	Name    string
+	// Custom is the non ISO 4217 unit such as loyalty points
+	Custom bool
 }
 
 var (
 	ErrUnSupportedCurrency = errors.New("unsupported currency")
 	ErrEmptyCurrency       = errors.New("currency is mandatory")
+	ErrUnknownISOCurrency  = errors.New("unknown ISO 4217 currency")
+	ErrInvalidCustomUnit   = errors.New("invalid custom unit")
 )
 
-var currencies = make(map[string]Value)
+// maxCustomPrecise keeps 1 major unit of the custom unit far from overflowing int64
+const maxCustomPrecise = 8
+
+// isoCurrencies is the full ISO 4217 table keyed by code, only the enabled ones are supported.
+var isoCurrencies = func() map[string]Value {
+	table := make(map[string]Value, len(iso4217))
+	for _, v := range iso4217 {
+		table[v.Code] = v
+	}
+	return table
+}()
+
+var (
+	mu         sync.RWMutex
+	currencies = make(map[string]Value)
+)
 
 var (
-	SGD = register("SGD", 2)
-	JPY = register("JPY", 0)
+	SGD = register(isoCurrencies["SGD"])
+	JPY = register(isoCurrencies["JPY"])
 )
 
-func register(code string, precise int) Value {
-	code = strings.ToUpper(code)
-	if _, ok := currencies[code]; ok {
-		panic(fmt.Sprintf("duplicated currencies found: %s", code))
+func register(v Value) Value {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := currencies[v.Code]; ok {
+		panic(fmt.Sprintf("duplicated currencies found: %s", v.Code))
+	}
+	currencies[v.Code] = v
+	return v
+}
+
+// ISO returns the currency of the ISO 4217 table whether or not it is enabled.
+func ISO(code string) (Value, bool) {
+	v, ok := isoCurrencies[strings.ToUpper(code)]
+	return v, ok
+}
+
+// Configure replaces the supported currencies with the enabled ISO 4217 codes and the custom units.
+// It is called once at startup before serving, SGD and JPY are supported until then.
+func Configure(enabled []string, custom []Value) error {
+	configured := make(map[string]Value, len(enabled)+len(custom))
+	for _, code := range enabled {
+		v, ok := ISO(strings.TrimSpace(code))
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownISOCurrency, code)
+		}
+		configured[v.Code] = v
+	}
+	for _, v := range custom {
+		if err := validateCustom(v); err != nil {
+			return err
+		}
+		if _, ok := configured[v.Code]; ok {
+			return fmt.Errorf("%w: %s is duplicated", ErrInvalidCustomUnit, v.Code)
+		}
+		v.Numeric = ""
+		v.Custom = true
+		configured[v.Code] = v
+	}
+	if len(configured) == 0 {
+		return errors.New("at least one currency must be enabled")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	currencies = configured
+	return nil
+}
+
+// validateCustom checks the custom unit fits the CHAR(3) currency columns and doesn't shadow an ISO 4217 code.
+func validateCustom(v Value) error {
+	if len(v.Code) != 3 || strings.ToUpper(v.Code) != v.Code || strings.ContainsFunc(v.Code, func(r rune) bool {
+		return (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	}) {
+		return fmt.Errorf("%w: code %q must be 3 uppercase letters or digits", ErrInvalidCustomUnit, v.Code)
 	}
-	currencies[code] = Value{Precise: precise, Code: code}
-	return currencies[code]
+	if _, ok := isoCurrencies[v.Code]; ok {
+		return fmt.Errorf("%w: %s is an ISO 4217 code", ErrInvalidCustomUnit, v.Code)
+	}
+	if v.Precise < 0 || v.Precise > maxCustomPrecise {
+		return fmt.Errorf("%w: minor units of %s must be between 0 and %d", ErrInvalidCustomUnit, v.Code, maxCustomPrecise)
+	}
+	return nil
+}
+
+type customUnit struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	MinorUnits int    `json:"minor_units"`
+}
+
+// LoadCustomUnits reads the custom units from the json file such as
+// [{"code": "PTS", "name": "Loyalty points", "minor_units": 0}].
+func LoadCustomUnits(path string) ([]Value, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read custom units: %w", err)
+	}
+	var units []customUnit
+	if err := json.Unmarshal(data, &units); err != nil {
+		return nil, fmt.Errorf("parse custom units: %w", err)
+	}
+	values := make([]Value, 0, len(units))
+	for _, u := range units {
+		values = append(values, Value{Code: u.Code, Name: u.Name, Precise: u.MinorUnits, Custom: true})
+	}
+	return values, nil
 }
 
 func Supported(code string) error {
+	_, err := Get(code)
+	return err
+}
+
+// Get returns the registered currency of the code
+func Get(code string) (Value, error) {
 	if strings.TrimSpace(code) == "" {
-		return ErrEmptyCurrency
+		return Value{}, ErrEmptyCurrency
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	v, ok := currencies[code]
+	if !ok {
+		return Value{}, ErrUnSupportedCurrency
 	}
+	return v, nil
+}
+
+// Codes returns all registered currency codes in alphabetical order
+func Codes() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	codes := make([]string, 0, len(currencies))
 	for k := range currencies {
-		if k == code {
-			return nil
-		}
+		codes = append(codes, k)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// All returns all registered currencies in alphabetical order of code
+func All() []Value {
+	mu.RLock()
+	defer mu.RUnlock()
+	values := make([]Value, 0, len(currencies))
+	for _, v := range currencies {
+		values = append(values, v)
 	}
-	return ErrUnSupportedCurrency
+	slices.SortFunc(values, func(a, b Value) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	return values
 }
@@ -1,20 +1,136 @@
 package currency
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// restore puts back the default currencies after the test configures its own.
+func restore(t *testing.T) {
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		currencies = map[string]Value{SGD.Code: SGD, JPY.Code: JPY}
+	})
+}
+
 func Test_register(t *testing.T) {
+	restore(t)
 	t.Run("currency register should panic", func(t *testing.T) {
 		assert.Panics(t, func() {
-			register("sgd", 2)
+			register(SGD)
 		})
 	})
 	t.Run("currency register ok, no panic", func(t *testing.T) {
 		assert.NotPanics(t, func() {
-			register("THB", 2)
+			register(isoCurrencies["THB"])
+		})
+	})
+}
+
+func TestCodes(t *testing.T) {
+	t.Run("codes contain registered currencies", func(t *testing.T) {
+		codes := Codes()
+		assert.Contains(t, codes, SGD.Code)
+		assert.Contains(t, codes, JPY.Code)
+		assert.IsNonDecreasing(t, codes)
+	})
+}
+
+func TestGet(t *testing.T) {
+	t.Run("get registered currency", func(t *testing.T) {
+		v, err := Get("JPY")
+		assert.NoError(t, err)
+		assert.Equal(t, JPY, v)
+		assert.Equal(t, "392", v.Numeric)
+		assert.Equal(t, "Yen", v.Name)
+	})
+	t.Run("get unsupported currency", func(t *testing.T) {
+		_, err := Get("XXX")
+		assert.ErrorIs(t, err, ErrUnSupportedCurrency)
+	})
+	t.Run("get empty currency", func(t *testing.T) {
+		_, err := Get(" ")
+		assert.ErrorIs(t, err, ErrEmptyCurrency)
+	})
+}
+
+func TestISO(t *testing.T) {
+	t.Run("table has unique codes and numeric codes", func(t *testing.T) {
+		numerics := map[string]string{}
+		for _, v := range iso4217 {
+			assert.Len(t, v.Code, 3)
+			assert.Len(t, v.Numeric, 3, v.Code)
+			assert.NotEmpty(t, v.Name, v.Code)
+			assert.Contains(t, []int{0, 2, 3, 4}, v.Precise, v.Code)
+			assert.NotContains(t, numerics, v.Numeric, v.Code)
+			numerics[v.Numeric] = v.Code
+		}
+		assert.Len(t, isoCurrencies, len(iso4217))
+	})
+	t.Run("not enabled iso currency", func(t *testing.T) {
+		v, ok := ISO("kwd")
+		assert.True(t, ok)
+		assert.Equal(t, 3, v.Precise)
+		assert.ErrorIs(t, Supported("KWD"), ErrUnSupportedCurrency)
+	})
+}
+
+func TestConfigure(t *testing.T) {
+	restore(t)
+	points := Value{Code: "PTS", Name: "Loyalty points"}
+
+	t.Run("enable subset and custom unit", func(t *testing.T) {
+		err := Configure([]string{"SGD", "usd"}, []Value{points})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"PTS", "SGD", "USD"}, Codes())
+		assert.ErrorIs(t, Supported("JPY"), ErrUnSupportedCurrency)
+		v, err := Get("PTS")
+		assert.NoError(t, err)
+		assert.True(t, v.Custom)
+		assert.Equal(t, []Value{v, SGD, isoCurrencies["USD"]}, All())
+	})
+
+	tests := []struct {
+		name    string
+		enabled []string
+		custom  []Value
+		wantErr error
+	}{
+		{name: "unknown iso code", enabled: []string{"SGD", "ABC"}, wantErr: ErrUnknownISOCurrency},
+		{name: "custom shadows iso code", enabled: []string{"SGD"}, custom: []Value{{Code: "USD"}}, wantErr: ErrInvalidCustomUnit},
+		{name: "custom code too long", enabled: []string{"SGD"}, custom: []Value{{Code: "POINT"}}, wantErr: ErrInvalidCustomUnit},
+		{name: "custom code lowercase", enabled: []string{"SGD"}, custom: []Value{{Code: "pts"}}, wantErr: ErrInvalidCustomUnit},
+		{name: "custom code duplicated", enabled: []string{"SGD"}, custom: []Value{points, points}, wantErr: ErrInvalidCustomUnit},
+		{name: "custom precise too large", enabled: []string{"SGD"}, custom: []Value{{Code: "PTS", Precise: 9}}, wantErr: ErrInvalidCustomUnit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := Codes()
+			err := Configure(tt.enabled, tt.custom)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, before, Codes())
 		})
+	}
+
+	t.Run("nothing enabled", func(t *testing.T) {
+		assert.Error(t, Configure(nil, nil))
 	})
 }
+
+func TestLoadCustomUnits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "units.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"code": "PTS", "name": "Loyalty points", "minor_units": 0}, {"code": "MIL", "name": "Miles", "minor_units": 2}]`), 0o600))
+	units, err := LoadCustomUnits(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []Value{
+		{Code: "PTS", Name: "Loyalty points", Custom: true},
+		{Code: "MIL", Name: "Miles", Precise: 2, Custom: true},
+	}, units)
+
+	_, err = LoadCustomUnits(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
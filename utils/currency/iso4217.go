@@ -0,0 +1,175 @@
+package currency
+
+// iso4217 is the ISO 4217 list of active currencies and funds with defined minor units,
+// the precious metals and testing codes such as XAU and XTS don't have minor units and are left out.
+var iso4217 = []Value{
+	iso("AED", "784", 2, "UAE Dirham"),
+	iso("AFN", "971", 2, "Afghani"),
+	iso("ALL", "008", 2, "Lek"),
+	iso("AMD", "051", 2, "Armenian Dram"),
+	iso("AOA", "973", 2, "Kwanza"),
+	iso("ARS", "032", 2, "Argentine Peso"),
+	iso("AUD", "036", 2, "Australian Dollar"),
+	iso("AWG", "533", 2, "Aruban Florin"),
+	iso("AZN", "944", 2, "Azerbaijan Manat"),
+	iso("BAM", "977", 2, "Convertible Mark"),
+	iso("BBD", "052", 2, "Barbados Dollar"),
+	iso("BDT", "050", 2, "Taka"),
+	iso("BGN", "975", 2, "Bulgarian Lev"),
+	iso("BHD", "048", 3, "Bahraini Dinar"),
+	iso("BIF", "108", 0, "Burundi Franc"),
+	iso("BMD", "060", 2, "Bermudian Dollar"),
+	iso("BND", "096", 2, "Brunei Dollar"),
+	iso("BOB", "068", 2, "Boliviano"),
+	iso("BOV", "984", 2, "Mvdol"),
+	iso("BRL", "986", 2, "Brazilian Real"),
+	iso("BSD", "044", 2, "Bahamian Dollar"),
+	iso("BTN", "064", 2, "Ngultrum"),
+	iso("BWP", "072", 2, "Pula"),
+	iso("BYN", "933", 2, "Belarusian Ruble"),
+	iso("BZD", "084", 2, "Belize Dollar"),
+	iso("CAD", "124", 2, "Canadian Dollar"),
+	iso("CDF", "976", 2, "Congolese Franc"),
+	iso("CHE", "947", 2, "WIR Euro"),
+	iso("CHF", "756", 2, "Swiss Franc"),
+	iso("CHW", "948", 2, "WIR Franc"),
+	iso("CLF", "990", 4, "Unidad de Fomento"),
+	iso("CLP", "152", 0, "Chilean Peso"),
+	iso("CNY", "156", 2, "Yuan Renminbi"),
+	iso("COP", "170", 2, "Colombian Peso"),
+	iso("COU", "970", 2, "Unidad de Valor Real"),
+	iso("CRC", "188", 2, "Costa Rican Colon"),
+	iso("CUP", "192", 2, "Cuban Peso"),
+	iso("CVE", "132", 2, "Cabo Verde Escudo"),
+	iso("CZK", "203", 2, "Czech Koruna"),
+	iso("DJF", "262", 0, "Djibouti Franc"),
+	iso("DKK", "208", 2, "Danish Krone"),
+	iso("DOP", "214", 2, "Dominican Peso"),
+	iso("DZD", "012", 2, "Algerian Dinar"),
+	iso("EGP", "818", 2, "Egyptian Pound"),
+	iso("ERN", "232", 2, "Nakfa"),
+	iso("ETB", "230", 2, "Ethiopian Birr"),
+	iso("EUR", "978", 2, "Euro"),
+	iso("FJD", "242", 2, "Fiji Dollar"),
+	iso("FKP", "238", 2, "Falkland Islands Pound"),
+	iso("GBP", "826", 2, "Pound Sterling"),
+	iso("GEL", "981", 2, "Lari"),
+	iso("GHS", "936", 2, "Ghana Cedi"),
+	iso("GIP", "292", 2, "Gibraltar Pound"),
+	iso("GMD", "270", 2, "Dalasi"),
+	iso("GNF", "324", 0, "Guinean Franc"),
+	iso("GTQ", "320", 2, "Quetzal"),
+	iso("GYD", "328", 2, "Guyana Dollar"),
+	iso("HKD", "344", 2, "Hong Kong Dollar"),
+	iso("HNL", "340", 2, "Lempira"),
+	iso("HTG", "332", 2, "Gourde"),
+	iso("HUF", "348", 2, "Forint"),
+	iso("IDR", "360", 2, "Rupiah"),
+	iso("ILS", "376", 2, "New Israeli Sheqel"),
+	iso("INR", "356", 2, "Indian Rupee"),
+	iso("IQD", "368", 3, "Iraqi Dinar"),
+	iso("IRR", "364", 2, "Iranian Rial"),
+	iso("ISK", "352", 0, "Iceland Krona"),
+	iso("JMD", "388", 2, "Jamaican Dollar"),
+	iso("JOD", "400", 3, "Jordanian Dinar"),
+	iso("JPY", "392", 0, "Yen"),
+	iso("KES", "404", 2, "Kenyan Shilling"),
+	iso("KGS", "417", 2, "Som"),
+	iso("KHR", "116", 2, "Riel"),
+	iso("KMF", "174", 0, "Comorian Franc"),
+	iso("KPW", "408", 2, "North Korean Won"),
+	iso("KRW", "410", 0, "Won"),
+	iso("KWD", "414", 3, "Kuwaiti Dinar"),
+	iso("KYD", "136", 2, "Cayman Islands Dollar"),
+	iso("KZT", "398", 2, "Tenge"),
+	iso("LAK", "418", 2, "Lao Kip"),
+	iso("LBP", "422", 2, "Lebanese Pound"),
+	iso("LKR", "144", 2, "Sri Lanka Rupee"),
+	iso("LRD", "430", 2, "Liberian Dollar"),
+	iso("LSL", "426", 2, "Loti"),
+	iso("LYD", "434", 3, "Libyan Dinar"),
+	iso("MAD", "504", 2, "Moroccan Dirham"),
+	iso("MDL", "498", 2, "Moldovan Leu"),
+	iso("MGA", "969", 2, "Malagasy Ariary"),
+	iso("MKD", "807", 2, "Denar"),
+	iso("MMK", "104", 2, "Kyat"),
+	iso("MNT", "496", 2, "Tugrik"),
+	iso("MOP", "446", 2, "Pataca"),
+	iso("MRU", "929", 2, "Ouguiya"),
+	iso("MUR", "480", 2, "Mauritius Rupee"),
+	iso("MVR", "462", 2, "Rufiyaa"),
+	iso("MWK", "454", 2, "Malawi Kwacha"),
+	iso("MXN", "484", 2, "Mexican Peso"),
+	iso("MXV", "979", 2, "Mexican Unidad de Inversion (UDI)"),
+	iso("MYR", "458", 2, "Malaysian Ringgit"),
+	iso("MZN", "943", 2, "Mozambique Metical"),
+	iso("NAD", "516", 2, "Namibia Dollar"),
+	iso("NGN", "566", 2, "Naira"),
+	iso("NIO", "558", 2, "Cordoba Oro"),
+	iso("NOK", "578", 2, "Norwegian Krone"),
+	iso("NPR", "524", 2, "Nepalese Rupee"),
+	iso("NZD", "554", 2, "New Zealand Dollar"),
+	iso("OMR", "512", 3, "Rial Omani"),
+	iso("PAB", "590", 2, "Balboa"),
+	iso("PEN", "604", 2, "Sol"),
+	iso("PGK", "598", 2, "Kina"),
+	iso("PHP", "608", 2, "Philippine Peso"),
+	iso("PKR", "586", 2, "Pakistan Rupee"),
+	iso("PLN", "985", 2, "Zloty"),
+	iso("PYG", "600", 0, "Guarani"),
+	iso("QAR", "634", 2, "Qatari Rial"),
+	iso("RON", "946", 2, "Romanian Leu"),
+	iso("RSD", "941", 2, "Serbian Dinar"),
+	iso("RUB", "643", 2, "Russian Ruble"),
+	iso("RWF", "646", 0, "Rwanda Franc"),
+	iso("SAR", "682", 2, "Saudi Riyal"),
+	iso("SBD", "090", 2, "Solomon Islands Dollar"),
+	iso("SCR", "690", 2, "Seychelles Rupee"),
+	iso("SDG", "938", 2, "Sudanese Pound"),
+	iso("SEK", "752", 2, "Swedish Krona"),
+	iso("SGD", "702", 2, "Singapore Dollar"),
+	iso("SHP", "654", 2, "Saint Helena Pound"),
+	iso("SLE", "925", 2, "Leone"),
+	iso("SOS", "706", 2, "Somali Shilling"),
+	iso("SRD", "968", 2, "Surinam Dollar"),
+	iso("SSP", "728", 2, "South Sudanese Pound"),
+	iso("STN", "930", 2, "Dobra"),
+	iso("SVC", "222", 2, "El Salvador Colon"),
+	iso("SYP", "760", 2, "Syrian Pound"),
+	iso("SZL", "748", 2, "Lilangeni"),
+	iso("THB", "764", 2, "Baht"),
+	iso("TJS", "972", 2, "Somoni"),
+	iso("TMT", "934", 2, "Turkmenistan New Manat"),
+	iso("TND", "788", 3, "Tunisian Dinar"),
+	iso("TOP", "776", 2, "Pa'anga"),
+	iso("TRY", "949", 2, "Turkish Lira"),
+	iso("TTD", "780", 2, "Trinidad and Tobago Dollar"),
+	iso("TWD", "901", 2, "New Taiwan Dollar"),
+	iso("TZS", "834", 2, "Tanzanian Shilling"),
+	iso("UAH", "980", 2, "Hryvnia"),
+	iso("UGX", "800", 0, "Uganda Shilling"),
+	iso("USD", "840", 2, "US Dollar"),
+	iso("USN", "997", 2, "US Dollar (Next day)"),
+	iso("UYI", "940", 0, "Uruguay Peso en Unidades Indexadas (UI)"),
+	iso("UYU", "858", 2, "Peso Uruguayo"),
+	iso("UYW", "927", 4, "Unidad Previsional"),
+	iso("UZS", "860", 2, "Uzbekistan Sum"),
+	iso("VED", "926", 2, "Bolivar Soberano"),
+	iso("VES", "928", 2, "Bolivar Soberano"),
+	iso("VND", "704", 0, "Dong"),
+	iso("VUV", "548", 0, "Vatu"),
+	iso("WST", "882", 2, "Tala"),
+	iso("XAF", "950", 0, "CFA Franc BEAC"),
+	iso("XCD", "951", 2, "East Caribbean Dollar"),
+	iso("XCG", "532", 2, "Caribbean Guilder"),
+	iso("XOF", "952", 0, "CFA Franc BCEAO"),
+	iso("XPF", "953", 0, "CFP Franc"),
+	iso("YER", "886", 2, "Yemeni Rial"),
+	iso("ZAR", "710", 2, "Rand"),
+	iso("ZMW", "967", 2, "Zambian Kwacha"),
+	iso("ZWG", "924", 2, "Zimbabwe Gold"),
+}
+
+func iso(code, numeric string, precise int, name string) Value {
+	return Value{Code: code, Numeric: numeric, Precise: precise, Name: name}
+}
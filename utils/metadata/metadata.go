@@ -0,0 +1,88 @@
+package metadata
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+const (
+	MaxKeys        = 20
+	MaxKeyLength   = 40
+	MaxValueLength = 500
+	// MaxSize is the limit of the encoded JSON stored in transactions.metadata
+	MaxSize = 4096
+)
+
+var (
+	ErrTooManyKeys  = fmt.Errorf("metadata can't have more than %d keys", MaxKeys)
+	ErrInvalidKey   = fmt.Errorf("metadata key must be 1 to %d characters of letters, digits, '_' or '-'", MaxKeyLength)
+	ErrValueTooLong = fmt.Errorf("metadata value can't be longer than %d characters", MaxValueLength)
+	ErrTooLarge     = fmt.Errorf("metadata can't be larger than %d bytes", MaxSize)
+)
+
+var keyPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Metadata is the key-value pair attached to a transaction by the caller, such as their order id.
+// It is stored as JSONB and NULL when empty.
+type Metadata map[string]string
+
+func (m Metadata) Validate() error {
+	if len(m) > MaxKeys {
+		return ErrTooManyKeys
+	}
+	for k, v := range m {
+		if err := ValidateKey(k); err != nil {
+			return err
+		}
+		if len(v) > MaxValueLength {
+			return ErrValueTooLong
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if len(b) > MaxSize {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+func ValidateKey(key string) error {
+	if len(key) > MaxKeyLength || !keyPattern.MatchString(key) {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// Value implements driver.Valuer so empty metadata is stored as NULL.
+func (m Metadata) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner for the JSONB column.
+func (m *Metadata) Scan(src any) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("unsupported metadata type")
+	}
+	return json.Unmarshal(b, m)
+}
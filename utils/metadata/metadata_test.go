@@ -0,0 +1,68 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadata_Validate(t *testing.T) {
+	tooManyKeys := Metadata{}
+	for i := 0; i <= MaxKeys; i++ {
+		tooManyKeys[fmt.Sprintf("key_%d", i)] = "v"
+	}
+	tooLarge := Metadata{}
+	for i := 0; i < 10; i++ {
+		tooLarge[fmt.Sprintf("key_%d", i)] = strings.Repeat("v", MaxValueLength)
+	}
+	tests := []struct {
+		name    string
+		m       Metadata
+		wantErr error
+	}{
+		{name: "ok empty", m: nil},
+		{name: "ok", m: Metadata{"order_id": "123", "channel-name": "web"}},
+		{name: "too many keys", m: tooManyKeys, wantErr: ErrTooManyKeys},
+		{name: "empty key", m: Metadata{"": "v"}, wantErr: ErrInvalidKey},
+		{name: "key with space", m: Metadata{"order id": "v"}, wantErr: ErrInvalidKey},
+		{name: "key too long", m: Metadata{strings.Repeat("k", MaxKeyLength+1): "v"}, wantErr: ErrInvalidKey},
+		{name: "value too long", m: Metadata{"k": strings.Repeat("v", MaxValueLength+1)}, wantErr: ErrValueTooLong},
+		{name: "too large", m: tooLarge, wantErr: ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, tt.m.Validate())
+		})
+	}
+}
+
+func TestMetadata_ValueAndScan(t *testing.T) {
+	t.Run("empty metadata stored as null", func(t *testing.T) {
+		v, err := Metadata{}.Value()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		v, err := Metadata{"order_id": "123"}.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, `{"order_id":"123"}`, v)
+
+		var m Metadata
+		assert.NoError(t, m.Scan([]byte(`{"order_id":"123"}`)))
+		assert.Equal(t, Metadata{"order_id": "123"}, m)
+	})
+
+	t.Run("scan null", func(t *testing.T) {
+		m := Metadata{"k": "v"}
+		assert.NoError(t, m.Scan(nil))
+		assert.Nil(t, m)
+	})
+
+	t.Run("scan unsupported type", func(t *testing.T) {
+		var m Metadata
+		assert.Error(t, m.Scan(1))
+	})
+}
@@ -0,0 +1,155 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+)
+
+var (
+	ErrInvalidAmount     = errors.New("amount must be a decimal number")
+	ErrTooManyDecimals   = errors.New("amount has more decimals than the currency allows")
+	ErrInvalidAmountType = errors.New("amount must be a number of the smallest unit or a decimal string")
+)
+
+// Parse parses the decimal string in the precision of the currency, e.g. "12.34" SGD is 1234 and "1200" JPY is 1200.
+// The amount is never rounded, ErrTooManyDecimals is returned when it has more decimals than the currency.
+func Parse(s string, cur currency.Value) (Money, error) {
+	value := strings.TrimSpace(s)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+	whole, fraction, hasPoint := strings.Cut(value, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(fraction)) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if len(fraction) > cur.Precise {
+		return Money{}, fmt.Errorf("%w: %s has %d decimals", ErrTooManyDecimals, cur.Code, cur.Precise)
+	}
+	// Pad the fraction to the precision so the digits are the amount in the smallest unit
+	digits := whole + fraction + strings.Repeat("0", cur.Precise-len(fraction))
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Money{}, ErrOverflow
+		}
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if negative {
+		amount = -amount
+	}
+	return New(amount, cur), nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decimal formats the amount in the precision of its currency, e.g. 1234 SGD is "12.34".
+func (m Money) Decimal() string {
+	return formatDecimal(m.amount, m.currency.Precise)
+}
+
+// FormatDecimal formats the amount in the smallest unit of the currency code as decimal string,
+// the amount is formatted as it is when the currency isn't registered.
+func FormatDecimal(amount int64, code string) string {
+	cur, err := currency.Get(code)
+	if err != nil {
+		return strconv.FormatInt(amount, 10)
+	}
+	return formatDecimal(amount, cur.Precise)
+}
+
+func formatDecimal(amount int64, precise int) string {
+	sign := ""
+	// Negate as unsigned, -MinInt64 doesn't fit in int64
+	abs := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		abs = -abs
+	}
+	digits := strconv.FormatUint(abs, 10)
+	if precise <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= precise {
+		digits = strings.Repeat("0", precise-len(digits)+1) + digits
+	}
+	point := len(digits) - precise
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Amount is the amount of a request, a JSON number is the amount in the smallest unit
+// while a JSON string is the decimal in the precision of the currency, e.g. 1234 and "12.34" are both 12.34 SGD.
+type Amount struct {
+	minor   int64
+	decimal string
+}
+
+// MinorUnits is the amount in the smallest unit of the currency.
+func MinorUnits(amount int64) Amount {
+	return Amount{minor: amount}
+}
+
+// DecimalAmount is the amount in the precision of the currency.
+func DecimalAmount(s string) Amount {
+	return Amount{decimal: s}
+}
+
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*a = DecimalAmount(s)
+		return nil
+	}
+	var minor int64
+	if err := json.Unmarshal(b, &minor); err != nil {
+		return ErrInvalidAmountType
+	}
+	*a = MinorUnits(minor)
+	return nil
+}
+
+// IsZero tells whether the amount is not given, e.g. omitted from the request.
+func (a Amount) IsZero() bool {
+	return a == Amount{}
+}
+
+// IsNegative tells whether the amount is below zero before its currency is known.
+func (a Amount) IsNegative() bool {
+	if a.decimal != "" {
+		return strings.HasPrefix(strings.TrimSpace(a.decimal), "-")
+	}
+	return a.minor < 0
+}
+
+// Money returns the amount in the currency.
+func (a Amount) Money(cur currency.Value) (Money, error) {
+	if a.decimal != "" {
+		return Parse(a.decimal, cur)
+	}
+	return New(a.minor, cur), nil
+}
+
+// Of returns the amount in the registered currency code.
+func (a Amount) Of(code string) (Money, error) {
+	cur, err := currency.Get(code)
+	if err != nil {
+		return Money{}, err
+	}
+	return a.Money(cur)
+}
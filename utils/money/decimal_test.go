@@ -0,0 +1,131 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		cur     currency.Value
+		want    int64
+		wantErr error
+	}{
+		{name: "sgd", value: "12.34", cur: currency.SGD, want: 1234},
+		{name: "sgd one decimal", value: "12.3", cur: currency.SGD, want: 1230},
+		{name: "sgd whole", value: "12", cur: currency.SGD, want: 1200},
+		{name: "sgd cents", value: "0.05", cur: currency.SGD, want: 5},
+		{name: "negative", value: "-12.34", cur: currency.SGD, want: -1234},
+		{name: "jpy", value: "1200", cur: currency.JPY, want: 1200},
+		{name: "sgd too many decimals", value: "12.345", cur: currency.SGD, wantErr: ErrTooManyDecimals},
+		{name: "jpy decimals", value: "1200.5", cur: currency.JPY, wantErr: ErrTooManyDecimals},
+		{name: "empty", value: "", cur: currency.SGD, wantErr: ErrInvalidAmount},
+		{name: "missing whole", value: ".5", cur: currency.SGD, wantErr: ErrInvalidAmount},
+		{name: "missing fraction", value: "5.", cur: currency.SGD, wantErr: ErrInvalidAmount},
+		{name: "exponent", value: "1e3", cur: currency.JPY, wantErr: ErrInvalidAmount},
+		{name: "plus sign", value: "+5", cur: currency.JPY, wantErr: ErrInvalidAmount},
+		{name: "overflow", value: "92233720368547758.08", cur: currency.SGD, wantErr: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.value, tt.cur)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, New(tt.want, tt.cur), got)
+		})
+	}
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	tests := []struct {
+		amount int64
+		cur    currency.Value
+		want   string
+	}{
+		{amount: 1234, cur: currency.SGD, want: "12.34"},
+		{amount: 5, cur: currency.SGD, want: "0.05"},
+		{amount: 0, cur: currency.SGD, want: "0.00"},
+		{amount: -1234, cur: currency.SGD, want: "-12.34"},
+		{amount: 1200, cur: currency.JPY, want: "1200"},
+		{amount: math.MinInt64, cur: currency.SGD, want: "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			m := New(tt.amount, tt.cur)
+			assert.Equal(t, tt.want, m.Decimal())
+			parsed, err := Parse(m.Decimal(), tt.cur)
+			if tt.amount != math.MinInt64 {
+				assert.NoError(t, err)
+				assert.Equal(t, m, parsed)
+			}
+		})
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	assert.Equal(t, "12.34", FormatDecimal(1234, "SGD"))
+	assert.Equal(t, "1234", FormatDecimal(1234, "XXX"))
+}
+
+func TestAmount_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		cur     currency.Value
+		want    int64
+		wantErr error
+	}{
+		{name: "minor units", body: `{"amount": 1234}`, cur: currency.SGD, want: 1234},
+		{name: "decimal string", body: `{"amount": "12.34"}`, cur: currency.SGD, want: 1234},
+		{name: "too many decimals", body: `{"amount": "12.345"}`, cur: currency.SGD, wantErr: ErrTooManyDecimals},
+		{name: "jpy decimal string", body: `{"amount": "1200"}`, cur: currency.JPY, want: 1200},
+		{name: "missing", body: `{}`, cur: currency.JPY, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params struct {
+				Amount Amount `json:"amount"`
+			}
+			assert.NoError(t, json.Unmarshal([]byte(tt.body), &params))
+			got, err := params.Amount.Money(tt.cur)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, New(tt.want, tt.cur), got)
+		})
+	}
+
+	t.Run("fractional number", func(t *testing.T) {
+		var a Amount
+		assert.ErrorIs(t, json.Unmarshal([]byte(`12.34`), &a), ErrInvalidAmountType)
+	})
+}
+
+func TestAmount_Of(t *testing.T) {
+	m, err := DecimalAmount("12.34").Of("SGD")
+	assert.NoError(t, err)
+	assert.Equal(t, New(1234, currency.SGD), m)
+
+	_, err = MinorUnits(1234).Of("XXX")
+	assert.ErrorIs(t, err, currency.ErrUnSupportedCurrency)
+}
+
+func TestAmount_IsNegative(t *testing.T) {
+	assert.True(t, MinorUnits(-1).IsNegative())
+	assert.True(t, DecimalAmount(" -0.01").IsNegative())
+	assert.False(t, DecimalAmount("12.34").IsNegative())
+	assert.False(t, Amount{}.IsNegative())
+	assert.True(t, Amount{}.IsZero())
+	assert.False(t, DecimalAmount("0").IsZero())
+}
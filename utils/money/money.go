@@ -0,0 +1,124 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrOverflow         = errors.New("amount overflow")
+)
+
+// Money is an amount in the smallest unit of its currency, e.g. 1050 SGD is 10.50 SGD.
+// The arithmetic never mixes currencies and fails instead of wrapping around on overflow.
+type Money struct {
+	amount   int64
+	currency currency.Value
+}
+
+func New(amount int64, cur currency.Value) Money {
+	return Money{amount: amount, currency: cur}
+}
+
+// Of returns the money of the registered currency code.
+func Of(amount int64, code string) (Money, error) {
+	cur, err := currency.Get(code)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(amount, cur), nil
+}
+
+func (m Money) Amount() int64 {
+	return m.amount
+}
+
+func (m Money) Currency() currency.Value {
+	return m.currency
+}
+
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.amount > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%d %s", m.amount, m.currency.Code)
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return Money{}, err
+	}
+	amount, err := AddInt64(m.amount, o.amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(amount, m.currency), nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return Money{}, err
+	}
+	amount, err := SubInt64(m.amount, o.amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(amount, m.currency), nil
+}
+
+func (m Money) Neg() (Money, error) {
+	if m.amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return New(-m.amount, m.currency), nil
+}
+
+// Cmp returns -1, 0 or +1 when m is less than, equal to or greater than o.
+func (m Money) Cmp(o Money) (int, error) {
+	if err := m.sameCurrency(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.amount < o.amount:
+		return -1, nil
+	case m.amount > o.amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (m Money) sameCurrency(o Money) error {
+	if m.currency != o.currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency.Code, o.currency.Code)
+	}
+	return nil
+}
+
+// AddInt64 returns a + b, or ErrOverflow when the sum doesn't fit in int64.
+func AddInt64(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, ErrOverflow
+	}
+	return a + b, nil
+}
+
+// SubInt64 returns a - b, or ErrOverflow when the difference doesn't fit in int64.
+func SubInt64(a, b int64) (int64, error) {
+	if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+		return 0, ErrOverflow
+	}
+	return a - b, nil
+}
@@ -0,0 +1,99 @@
+package money
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOf(t *testing.T) {
+	t.Run("registered currency", func(t *testing.T) {
+		m, err := Of(1050, "SGD")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1050), m.Amount())
+		assert.Equal(t, currency.SGD, m.Currency())
+		assert.Equal(t, "1050 SGD", m.String())
+	})
+	t.Run("unsupported currency", func(t *testing.T) {
+		_, err := Of(1050, "XXX")
+		assert.ErrorIs(t, err, currency.ErrUnSupportedCurrency)
+	})
+}
+
+func TestMoney_Add(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Money
+		want    Money
+		wantErr error
+	}{
+		{name: "same currency", a: New(100, currency.SGD), b: New(50, currency.SGD), want: New(150, currency.SGD)},
+		{name: "negative", a: New(100, currency.SGD), b: New(-150, currency.SGD), want: New(-50, currency.SGD)},
+		{name: "currency mismatch", a: New(100, currency.SGD), b: New(50, currency.JPY), wantErr: ErrCurrencyMismatch},
+		{name: "overflow", a: New(math.MaxInt64, currency.JPY), b: New(1, currency.JPY), wantErr: ErrOverflow},
+		{name: "underflow", a: New(math.MinInt64, currency.JPY), b: New(-1, currency.JPY), wantErr: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Add(tt.b)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMoney_Sub(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    Money
+		want    Money
+		wantErr error
+	}{
+		{name: "same currency", a: New(100, currency.SGD), b: New(150, currency.SGD), want: New(-50, currency.SGD)},
+		{name: "currency mismatch", a: New(100, currency.SGD), b: New(50, currency.JPY), wantErr: ErrCurrencyMismatch},
+		{name: "overflow", a: New(math.MaxInt64, currency.JPY), b: New(-1, currency.JPY), wantErr: ErrOverflow},
+		{name: "underflow", a: New(math.MinInt64, currency.JPY), b: New(1, currency.JPY), wantErr: ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Sub(tt.b)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMoney_Neg(t *testing.T) {
+	m, err := New(100, currency.SGD).Neg()
+	assert.NoError(t, err)
+	assert.Equal(t, New(-100, currency.SGD), m)
+	assert.True(t, m.IsNegative())
+
+	_, err = New(math.MinInt64, currency.SGD).Neg()
+	assert.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestMoney_Cmp(t *testing.T) {
+	c, err := New(100, currency.SGD).Cmp(New(150, currency.SGD))
+	assert.NoError(t, err)
+	assert.Equal(t, -1, c)
+	c, err = New(150, currency.SGD).Cmp(New(150, currency.SGD))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, c)
+	c, err = New(200, currency.SGD).Cmp(New(150, currency.SGD))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c)
+
+	_, err = New(100, currency.SGD).Cmp(New(100, currency.JPY))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
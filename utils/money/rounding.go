@@ -0,0 +1,74 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+)
+
+// RoundingMode decides how a fractional amount is rounded into the smallest unit of the currency.
+type RoundingMode string
+
+const (
+	// RoundDown rounds toward zero, e.g. 1.9 is 1 and -1.9 is -1
+	RoundDown RoundingMode = "down"
+	// RoundUp rounds away from zero, e.g. 1.1 is 2 and -1.1 is -2
+	RoundUp RoundingMode = "up"
+	// RoundHalfUp rounds to the nearest and the half away from zero, e.g. 1.5 is 2 and 2.5 is 3
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundHalfEven rounds to the nearest and the half to the even neighbour, e.g. 1.5 is 2 and 2.5 is 2
+	RoundHalfEven RoundingMode = "half_even"
+)
+
+var ErrUnknownRoundingMode = errors.New("unknown rounding mode")
+
+// ParseRoundingMode parses the mode from config, an empty value is RoundDown.
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch mode := RoundingMode(s); mode {
+	case "":
+		return RoundDown, nil
+	case RoundDown, RoundUp, RoundHalfUp, RoundHalfEven:
+		return mode, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownRoundingMode, s)
+}
+
+// FromRat rounds the amount in the major unit of the currency, e.g. 12.345 SGD, into the smallest unit with the mode.
+func FromRat(amount *big.Rat, cur currency.Value, mode RoundingMode) (Money, error) {
+	scaled := new(big.Rat).Mul(amount, new(big.Rat).SetInt(pow10(cur.Precise)))
+	// Quo truncates toward zero, the remainder has the same sign as the amount
+	quo, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		awayFromZero := false
+		switch mode {
+		case RoundDown:
+		case RoundUp:
+			awayFromZero = true
+		case RoundHalfUp, RoundHalfEven:
+			// Compare twice of the remainder with the denominator to find the nearest
+			half := new(big.Int).Abs(rem)
+			half.Lsh(half, 1)
+			switch half.Cmp(scaled.Denom()) {
+			case 1:
+				awayFromZero = true
+			case 0:
+				awayFromZero = mode == RoundHalfUp || quo.Bit(0) == 1
+			}
+		default:
+			return Money{}, fmt.Errorf("%w: %q", ErrUnknownRoundingMode, mode)
+		}
+		if awayFromZero {
+			quo.Add(quo, big.NewInt(int64(scaled.Sign())))
+		}
+	}
+	if !quo.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return New(quo.Int64(), cur), nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
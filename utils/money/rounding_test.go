@@ -0,0 +1,60 @@
+package money
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/lengzuo/fundflow/utils/currency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRoundingMode(t *testing.T) {
+	mode, err := ParseRoundingMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, RoundDown, mode)
+	mode, err = ParseRoundingMode("half_even")
+	assert.NoError(t, err)
+	assert.Equal(t, RoundHalfEven, mode)
+	_, err = ParseRoundingMode("ceiling")
+	assert.ErrorIs(t, err, ErrUnknownRoundingMode)
+}
+
+func TestFromRat(t *testing.T) {
+	tests := []struct {
+		amount string
+		mode   RoundingMode
+		want   int64
+	}{
+		{amount: "12.345", mode: RoundDown, want: 1234},
+		{amount: "12.345", mode: RoundUp, want: 1235},
+		{amount: "12.345", mode: RoundHalfUp, want: 1235},
+		{amount: "12.345", mode: RoundHalfEven, want: 1234},
+		{amount: "12.355", mode: RoundHalfEven, want: 1236},
+		{amount: "12.3449", mode: RoundHalfUp, want: 1234},
+		{amount: "12.3451", mode: RoundHalfEven, want: 1235},
+		{amount: "-12.345", mode: RoundDown, want: -1234},
+		{amount: "-12.341", mode: RoundUp, want: -1235},
+		{amount: "-12.345", mode: RoundHalfUp, want: -1235},
+		{amount: "-12.345", mode: RoundHalfEven, want: -1234},
+		{amount: "12.34", mode: RoundUp, want: 1234},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+string(tt.mode), func(t *testing.T) {
+			amount, ok := new(big.Rat).SetString(tt.amount)
+			assert.True(t, ok)
+			got, err := FromRat(amount, currency.SGD, tt.mode)
+			assert.NoError(t, err)
+			assert.Equal(t, New(tt.want, currency.SGD), got)
+		})
+	}
+
+	t.Run("overflow", func(t *testing.T) {
+		amount, _ := new(big.Rat).SetString("92233720368547758.08")
+		_, err := FromRat(amount, currency.SGD, RoundDown)
+		assert.ErrorIs(t, err, ErrOverflow)
+	})
+	t.Run("unknown mode", func(t *testing.T) {
+		_, err := FromRat(big.NewRat(1, 3), currency.SGD, "ceiling")
+		assert.ErrorIs(t, err, ErrUnknownRoundingMode)
+	})
+}
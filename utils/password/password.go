@@ -0,0 +1,29 @@
+package password
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const cost = 12
+
+var ErrMismatched = errors.New("password mismatched")
+
+// Hash returns the bcrypt hash of the plain password, bcrypt only take the first 72 bytes
+// into account so the caller should reject anything longer.
+func Hash(plain string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(plain), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func Verify(hashed, plain string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plain))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatched
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	t.Run("hash and verify ok", func(t *testing.T) {
+		hashed, err := Hash("secret123")
+		assert.NoError(t, err)
+		assert.NotEqual(t, "secret123", hashed)
+		assert.True(t, strings.HasPrefix(hashed, "$2a$"))
+		assert.NoError(t, Verify(hashed, "secret123"))
+	})
+
+	t.Run("verify mismatched", func(t *testing.T) {
+		hashed, err := Hash("secret123")
+		assert.NoError(t, err)
+		assert.ErrorIs(t, Verify(hashed, "secret124"), ErrMismatched)
+	})
+
+	t.Run("verify plaintext stored password", func(t *testing.T) {
+		err := Verify("password", "password")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrMismatched)
+	})
+
+	t.Run("verify lower cost hash from migrated rows", func(t *testing.T) {
+		assert.NoError(t, Verify("$2a$10$QC3QjvDerxSoFh6nHYhXPOYL2kgDdLzQo9QsH4BVA8j6qiobOsJ5G", "password"))
+	})
+}